@@ -0,0 +1,368 @@
+package odata
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ApplyQuery representa a query SQL gerada para uma opção $apply
+type ApplyQuery struct {
+	SQL        string
+	Args       []interface{}
+	Properties []PropertyMetadata // Propriedades do resultado, na ordem das colunas
+}
+
+// applyStage representa um nível da query de $apply; cada transformação que projeta
+// colunas próprias fecha o nível e a seguinte passa a consultá-lo como subquery
+type applyStage struct {
+	from     string
+	scope    EntityMetadata     // Propriedades visíveis e suas expressões SQL no nível
+	columns  []string           // Lista do SELECT (vazia projeta todo o escopo)
+	output   []PropertyMetadata // Propriedades projetadas pelo nível
+	where    []string
+	groupBy  []string
+	orderBy  []string
+	top      int
+	skip     int
+	closed   bool
+	subquery int
+}
+
+// BuildApplyQuery constrói a query SQL para $apply, aplicando em seguida $filter, $orderby, $skip e $top
+func (qb *QueryBuilder) BuildApplyQuery(ctx context.Context, metadata EntityMetadata, options QueryOptions) (*ApplyQuery, error) {
+	if options.Apply == nil || len(options.Apply.Transformations) == 0 {
+		return nil, fmt.Errorf("no $apply transformations to build")
+	}
+
+	tableName := metadata.TableName
+	if tableName == "" {
+		tableName = metadata.Name
+	}
+
+	stage := &applyStage{from: tableName, scope: EntityMetadata{Name: metadata.Name}}
+	for _, prop := range metadata.Properties {
+		if prop.IsNavigation {
+			continue
+		}
+		if prop.ColumnName == "" {
+			prop.ColumnName = prop.Name
+		}
+		stage.scope.Properties = append(stage.scope.Properties, prop)
+	}
+	stage.output = stage.scope.Properties
+
+	namedArgs := NewNamedArgs(qb.dialect)
+
+	for _, transformation := range options.Apply.Transformations {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		var err error
+		switch transformation.Type {
+		case ApplyFilter:
+			stage = qb.openApplyStage(stage)
+			err = qb.applyFilterToStage(ctx, stage, transformation.Filter, namedArgs)
+		case ApplyGroupBy, ApplyAggregate:
+			stage = qb.openApplyStage(stage)
+			err = qb.applyAggregationToStage(stage, transformation)
+		case ApplyCompute:
+			stage = qb.openApplyStage(stage)
+			err = qb.applyComputeToStage(ctx, stage, transformation.Compute, namedArgs)
+		case ApplyTopCount, ApplyBottomCount:
+			stage = qb.openApplyStage(stage)
+			err = qb.applyTopCountToStage(stage, transformation)
+		default:
+			err = fmt.Errorf("unsupported transformation '%s'", transformation.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to build %s transformation: %w", transformation.Type, err)
+		}
+	}
+
+	// $filter, $orderby, $skip e $top são avaliados sobre o resultado do $apply
+	top := GetTopValue(options.Top)
+	skip := GetSkipValue(options.Skip)
+	hasFilter := options.Filter != nil && options.Filter.Tree != nil
+	if hasFilter || options.OrderBy != "" || top > 0 || skip > 0 {
+		stage = qb.openApplyStage(stage)
+		if hasFilter {
+			if err := qb.applyFilterToStage(ctx, stage, options.Filter, namedArgs); err != nil {
+				return nil, fmt.Errorf("failed to build $filter over $apply: %w", err)
+			}
+		}
+		if err := qb.applyOrderByToStage(stage, options.OrderBy); err != nil {
+			return nil, err
+		}
+		stage.top = top
+		stage.skip = skip
+	}
+
+	return &ApplyQuery{
+		SQL:        qb.renderApplyStage(stage),
+		Args:       namedArgs.GetArgs(),
+		Properties: stage.output,
+	}, nil
+}
+
+// openApplyStage retorna o nível atual ou, se ele já estiver fechado, um novo nível sobre ele
+func (qb *QueryBuilder) openApplyStage(stage *applyStage) *applyStage {
+	if !stage.closed {
+		return stage
+	}
+
+	alias := fmt.Sprintf("apply%d", stage.subquery+1)
+	next := &applyStage{
+		from:     fmt.Sprintf("(%s) %s", qb.renderApplyStage(stage), alias),
+		scope:    EntityMetadata{Name: stage.scope.Name},
+		subquery: stage.subquery + 1,
+	}
+	for _, prop := range stage.output {
+		prop.ColumnName = qb.QuoteIdentifier(prop.Name)
+		next.scope.Properties = append(next.scope.Properties, prop)
+	}
+	next.output = next.scope.Properties
+	return next
+}
+
+// applyFilterToStage adiciona a expressão de filtro ao WHERE do nível
+func (qb *QueryBuilder) applyFilterToStage(ctx context.Context, stage *applyStage, filter *GoDataFilterQuery, namedArgs *NamedArgs) error {
+	if filter == nil || filter.Tree == nil {
+		return nil
+	}
+
+	where, err := qb.BuildWhereClauseNamed(ctx, filter.Tree, stage.scope, namedArgs)
+	if err != nil {
+		return err
+	}
+	if where != "" {
+		stage.where = append(stage.where, where)
+	}
+	return nil
+}
+
+// applyAggregationToStage projeta as propriedades de agrupamento e as agregações do nível
+func (qb *QueryBuilder) applyAggregationToStage(stage *applyStage, transformation ApplyTransformation) error {
+	if len(transformation.GroupBy) == 0 && len(transformation.Aggregates) == 0 {
+		return fmt.Errorf("groupby requires at least one property")
+	}
+
+	var output []PropertyMetadata
+	for _, name := range transformation.GroupBy {
+		prop, err := findApplyProperty(stage.scope, name)
+		if err != nil {
+			return err
+		}
+		stage.groupBy = append(stage.groupBy, prop.ColumnName)
+		stage.columns = append(stage.columns, qb.applyColumn(prop.ColumnName, prop.Name))
+		output = append(output, prop)
+	}
+
+	for _, aggregate := range transformation.Aggregates {
+		if hasApplyProperty(output, aggregate.Alias) {
+			return fmt.Errorf("duplicate property '%s'", aggregate.Alias)
+		}
+
+		expression, propType, err := qb.buildAggregateExpression(stage.scope, aggregate)
+		if err != nil {
+			return err
+		}
+		stage.columns = append(stage.columns, qb.applyColumn(expression, aggregate.Alias))
+		output = append(output, PropertyMetadata{Name: aggregate.Alias, Type: propType, IsNullable: true})
+	}
+
+	stage.output = output
+	stage.closed = true
+	return nil
+}
+
+// buildAggregateExpression constrói a função de agregação SQL e o tipo do resultado
+func (qb *QueryBuilder) buildAggregateExpression(scope EntityMetadata, aggregate AggregateExpression) (string, string, error) {
+	if aggregate.Method == AggregateCount {
+		return "COUNT(*)", "int64", nil
+	}
+
+	prop, err := findApplyProperty(scope, aggregate.Property)
+	if err != nil {
+		return "", "", err
+	}
+
+	switch aggregate.Method {
+	case AggregateSum:
+		propType := "float64"
+		if isIntegerType(prop.Type) {
+			propType = "int64"
+		}
+		return fmt.Sprintf("SUM(%s)", prop.ColumnName), propType, nil
+	case AggregateAverage:
+		return fmt.Sprintf("AVG(%s)", prop.ColumnName), "float64", nil
+	case AggregateMin:
+		return fmt.Sprintf("MIN(%s)", prop.ColumnName), prop.Type, nil
+	case AggregateMax:
+		return fmt.Sprintf("MAX(%s)", prop.ColumnName), prop.Type, nil
+	case AggregateCountDistinct:
+		return fmt.Sprintf("COUNT(DISTINCT %s)", prop.ColumnName), "int64", nil
+	default:
+		return "", "", fmt.Errorf("unsupported aggregate method '%s'", aggregate.Method)
+	}
+}
+
+// applyComputeToStage projeta o escopo atual acrescido das propriedades computadas
+func (qb *QueryBuilder) applyComputeToStage(ctx context.Context, stage *applyStage, compute *ComputeOption, namedArgs *NamedArgs) error {
+	if compute == nil || len(compute.Expressions) == 0 {
+		return fmt.Errorf("compute requires at least one expression")
+	}
+
+	output := append([]PropertyMetadata{}, stage.scope.Properties...)
+	for _, prop := range output {
+		stage.columns = append(stage.columns, qb.applyColumn(prop.ColumnName, prop.Name))
+	}
+
+	parser := NewComputeParser()
+	for _, expr := range compute.Expressions {
+		if hasApplyProperty(output, expr.Alias) {
+			return fmt.Errorf("duplicate property '%s'", expr.Alias)
+		}
+
+		sql, err := qb.buildNodeExpressionNamed(ctx, expr.ParseTree, stage.scope, namedArgs)
+		if err != nil {
+			return fmt.Errorf("failed to build compute expression '%s': %w", expr.Expression, err)
+		}
+		propType := parser.inferComputeFieldType(expr.ParseTree)
+		if propType == "number" {
+			propType = "float64"
+		}
+		stage.columns = append(stage.columns, qb.applyColumn("("+sql+")", expr.Alias))
+		output = append(output, PropertyMetadata{Name: expr.Alias, Type: propType, IsNullable: true})
+	}
+
+	stage.output = output
+	stage.closed = true
+	return nil
+}
+
+// applyTopCountToStage ordena o nível pela propriedade e limita a quantidade de linhas
+func (qb *QueryBuilder) applyTopCountToStage(stage *applyStage, transformation ApplyTransformation) error {
+	prop, err := findApplyProperty(stage.scope, transformation.Property)
+	if err != nil {
+		return err
+	}
+
+	direction := "DESC"
+	if transformation.Type == ApplyBottomCount {
+		direction = "ASC"
+	}
+	stage.orderBy = append(stage.orderBy, prop.ColumnName+" "+direction)
+	stage.top = transformation.Count
+	stage.closed = true
+	return nil
+}
+
+// applyOrderByToStage converte o $orderby em ORDER BY sobre as propriedades do nível
+func (qb *QueryBuilder) applyOrderByToStage(stage *applyStage, orderBy string) error {
+	if strings.TrimSpace(orderBy) == "" {
+		return nil
+	}
+
+	for _, item := range strings.Split(orderBy, ",") {
+		parts := strings.Fields(item)
+		if len(parts) == 0 || len(parts) > 2 {
+			return fmt.Errorf("invalid $orderby item '%s'", strings.TrimSpace(item))
+		}
+
+		prop, err := findApplyProperty(stage.scope, parts[0])
+		if err != nil {
+			return fmt.Errorf("invalid $orderby: %w", err)
+		}
+
+		direction := "ASC"
+		if len(parts) == 2 {
+			switch strings.ToLower(parts[1]) {
+			case "asc":
+			case "desc":
+				direction = "DESC"
+			default:
+				return fmt.Errorf("invalid $orderby direction '%s'", parts[1])
+			}
+		}
+		stage.orderBy = append(stage.orderBy, prop.ColumnName+" "+direction)
+	}
+	return nil
+}
+
+// renderApplyStage gera o SQL de um nível da query de $apply
+func (qb *QueryBuilder) renderApplyStage(stage *applyStage) string {
+	columns := stage.columns
+	if len(columns) == 0 {
+		for _, prop := range stage.output {
+			columns = append(columns, qb.applyColumn(prop.ColumnName, prop.Name))
+		}
+	}
+
+	var query strings.Builder
+	query.WriteString("SELECT ")
+	query.WriteString(strings.Join(columns, ", "))
+	query.WriteString(" FROM ")
+	query.WriteString(stage.from)
+
+	if len(stage.where) > 0 {
+		query.WriteString(" WHERE ")
+		query.WriteString(strings.Join(stage.where, " AND "))
+	}
+	if len(stage.groupBy) > 0 {
+		query.WriteString(" GROUP BY ")
+		query.WriteString(strings.Join(stage.groupBy, ", "))
+	}
+	if len(stage.orderBy) > 0 {
+		query.WriteString(" ORDER BY ")
+		query.WriteString(strings.Join(stage.orderBy, ", "))
+	}
+	if limit := qb.BuildLimitClause(stage.top, stage.skip); limit != "" {
+		query.WriteString(" ")
+		query.WriteString(limit)
+	}
+
+	return query.String()
+}
+
+// applyColumn gera o item do SELECT com o alias da propriedade
+func (qb *QueryBuilder) applyColumn(expression, alias string) string {
+	quoted := qb.QuoteIdentifier(alias)
+	if expression == quoted {
+		return expression
+	}
+	return fmt.Sprintf("%s AS %s", expression, quoted)
+}
+
+// findApplyProperty localiza uma propriedade visível no nível (comparação case-insensitive)
+func findApplyProperty(scope EntityMetadata, name string) (PropertyMetadata, error) {
+	for _, prop := range scope.Properties {
+		if strings.EqualFold(prop.Name, name) {
+			return prop, nil
+		}
+	}
+	return PropertyMetadata{}, fmt.Errorf("property '%s' not found", name)
+}
+
+// hasApplyProperty verifica se já existe uma propriedade com o nome informado
+func hasApplyProperty(props []PropertyMetadata, name string) bool {
+	for _, prop := range props {
+		if strings.EqualFold(prop.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isIntegerType verifica se o tipo Go representa um inteiro
+func isIntegerType(propType string) bool {
+	switch propType {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		return true
+	default:
+		return false
+	}
+}
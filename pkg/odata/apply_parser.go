@@ -0,0 +1,278 @@
+package odata
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ApplyTransformationType representa o tipo de uma transformação de $apply
+type ApplyTransformationType string
+
+const (
+	ApplyGroupBy     ApplyTransformationType = "groupby"
+	ApplyAggregate   ApplyTransformationType = "aggregate"
+	ApplyFilter      ApplyTransformationType = "filter"
+	ApplyCompute     ApplyTransformationType = "compute"
+	ApplyTopCount    ApplyTransformationType = "topcount"
+	ApplyBottomCount ApplyTransformationType = "bottomcount"
+)
+
+// AggregateMethod representa o método de agregação de uma expressão aggregate
+type AggregateMethod string
+
+const (
+	AggregateSum           AggregateMethod = "sum"
+	AggregateAverage       AggregateMethod = "average"
+	AggregateMin           AggregateMethod = "min"
+	AggregateMax           AggregateMethod = "max"
+	AggregateCountDistinct AggregateMethod = "countdistinct"
+	AggregateCount         AggregateMethod = "count" // $count as Alias
+)
+
+// AggregateExpression representa uma expressão "Propriedade with método as Alias"
+type AggregateExpression struct {
+	Property string          // Propriedade agregada (vazio para $count)
+	Method   AggregateMethod // Método de agregação
+	Alias    string          // Nome da propriedade dinâmica resultante
+}
+
+// ApplyTransformation representa uma transformação individual do pipeline $apply
+type ApplyTransformation struct {
+	Type       ApplyTransformationType
+	GroupBy    []string              // Propriedades de agrupamento (groupby)
+	Aggregates []AggregateExpression // Agregações (aggregate ou groupby com aggregate)
+	Filter     *GoDataFilterQuery    // Expressão de filter(...)
+	Compute    *ComputeOption        // Expressões de compute(...)
+	Count      int                   // Quantidade de topcount/bottomcount
+	Property   string                // Propriedade de ordenação de topcount/bottomcount
+}
+
+// ApplyOption representa a opção $apply como uma sequência de transformações
+type ApplyOption struct {
+	Transformations []ApplyTransformation
+	RawValue        string
+}
+
+var (
+	aggregateExpressionRegex = regexp.MustCompile(`^(?i)([a-zA-Z_][a-zA-Z0-9_]*)\s+with\s+([a-zA-Z]+)\s+as\s+([a-zA-Z_][a-zA-Z0-9_]*)$`)
+	aggregateCountRegex      = regexp.MustCompile(`^(?i)\$count\s+as\s+([a-zA-Z_][a-zA-Z0-9_]*)$`)
+	applyIdentifierRegex     = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// ParseApplyString faz o parsing de uma string de $apply
+func ParseApplyString(ctx context.Context, apply string) (*ApplyOption, error) {
+	apply = strings.TrimSpace(apply)
+	if apply == "" {
+		return nil, fmt.Errorf("empty $apply expression")
+	}
+
+	steps, err := splitApplyTopLevel(apply, '/')
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ApplyOption{RawValue: apply}
+	for _, step := range steps {
+		transformation, err := parseApplyTransformation(ctx, step)
+		if err != nil {
+			return nil, err
+		}
+		result.Transformations = append(result.Transformations, *transformation)
+	}
+
+	return result, nil
+}
+
+// parseApplyTransformation faz o parsing de uma transformação no formato nome(argumentos)
+func parseApplyTransformation(ctx context.Context, step string) (*ApplyTransformation, error) {
+	step = strings.TrimSpace(step)
+	open := strings.Index(step, "(")
+	if open <= 0 || !strings.HasSuffix(step, ")") {
+		return nil, fmt.Errorf("invalid transformation '%s'", step)
+	}
+
+	name := strings.ToLower(strings.TrimSpace(step[:open]))
+	body := strings.TrimSpace(step[open+1 : len(step)-1])
+	if body == "" {
+		return nil, fmt.Errorf("transformation '%s' requires arguments", name)
+	}
+
+	switch ApplyTransformationType(name) {
+	case ApplyGroupBy:
+		return parseGroupByTransformation(ctx, body)
+
+	case ApplyAggregate:
+		aggregates, err := parseAggregateExpressions(body)
+		if err != nil {
+			return nil, err
+		}
+		return &ApplyTransformation{Type: ApplyAggregate, Aggregates: aggregates}, nil
+
+	case ApplyFilter:
+		filter, err := ParseFilterString(ctx, body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter transformation: %w", err)
+		}
+		return &ApplyTransformation{Type: ApplyFilter, Filter: filter}, nil
+
+	case ApplyCompute:
+		compute, err := NewComputeParser().ParseCompute(ctx, body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid compute transformation: %w", err)
+		}
+		return &ApplyTransformation{Type: ApplyCompute, Compute: compute}, nil
+
+	case ApplyTopCount, ApplyBottomCount:
+		args, err := splitApplyTopLevel(body, ',')
+		if err != nil {
+			return nil, err
+		}
+		if len(args) != 2 {
+			return nil, fmt.Errorf("%s requires a count and a property", name)
+		}
+		count, err := strconv.Atoi(strings.TrimSpace(args[0]))
+		if err != nil || count <= 0 {
+			return nil, fmt.Errorf("invalid %s count '%s'", name, strings.TrimSpace(args[0]))
+		}
+		property := strings.TrimSpace(args[1])
+		if !applyIdentifierRegex.MatchString(property) {
+			return nil, fmt.Errorf("invalid %s property '%s'", name, property)
+		}
+		return &ApplyTransformation{Type: ApplyTransformationType(name), Count: count, Property: property}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported transformation '%s'", name)
+	}
+}
+
+// parseGroupByTransformation faz o parsing de groupby((props)[,aggregate(...)])
+func parseGroupByTransformation(ctx context.Context, body string) (*ApplyTransformation, error) {
+	args, err := splitApplyTopLevel(body, ',')
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 || len(args) > 2 {
+		return nil, fmt.Errorf("groupby requires a property list and an optional aggregate")
+	}
+
+	list := strings.TrimSpace(args[0])
+	if !strings.HasPrefix(list, "(") || !strings.HasSuffix(list, ")") {
+		return nil, fmt.Errorf("groupby properties must be enclosed in parentheses")
+	}
+
+	result := &ApplyTransformation{Type: ApplyGroupBy}
+	for _, prop := range strings.Split(list[1:len(list)-1], ",") {
+		prop = strings.TrimSpace(prop)
+		if strings.Contains(prop, "/") {
+			return nil, fmt.Errorf("navigation path '%s' is not supported in groupby", prop)
+		}
+		if !applyIdentifierRegex.MatchString(prop) {
+			return nil, fmt.Errorf("invalid groupby property '%s'", prop)
+		}
+		result.GroupBy = append(result.GroupBy, prop)
+	}
+
+	if len(args) == 2 {
+		nested, err := parseApplyTransformation(ctx, args[1])
+		if err != nil {
+			return nil, err
+		}
+		if nested.Type != ApplyAggregate {
+			return nil, fmt.Errorf("only aggregate is supported inside groupby, got '%s'", nested.Type)
+		}
+		result.Aggregates = nested.Aggregates
+	}
+
+	return result, nil
+}
+
+// parseAggregateExpressions faz o parsing da lista de expressões de aggregate(...)
+func parseAggregateExpressions(body string) ([]AggregateExpression, error) {
+	items, err := splitApplyTopLevel(body, ',')
+	if err != nil {
+		return nil, err
+	}
+
+	var aggregates []AggregateExpression
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+
+		if match := aggregateCountRegex.FindStringSubmatch(item); match != nil {
+			aggregates = append(aggregates, AggregateExpression{Method: AggregateCount, Alias: match[1]})
+			continue
+		}
+
+		match := aggregateExpressionRegex.FindStringSubmatch(item)
+		if match == nil {
+			return nil, fmt.Errorf("invalid aggregate expression '%s'", item)
+		}
+
+		method, err := parseAggregateMethod(match[2])
+		if err != nil {
+			return nil, err
+		}
+		aggregates = append(aggregates, AggregateExpression{Property: match[1], Method: method, Alias: match[3]})
+	}
+
+	return aggregates, nil
+}
+
+// parseAggregateMethod normaliza o nome do método de agregação
+func parseAggregateMethod(method string) (AggregateMethod, error) {
+	switch strings.ToLower(method) {
+	case "sum":
+		return AggregateSum, nil
+	case "average", "avg":
+		return AggregateAverage, nil
+	case "min":
+		return AggregateMin, nil
+	case "max":
+		return AggregateMax, nil
+	case "countdistinct":
+		return AggregateCountDistinct, nil
+	default:
+		return "", fmt.Errorf("unsupported aggregate method '%s'", method)
+	}
+}
+
+// splitApplyTopLevel divide a expressão pelo separador, ignorando parênteses e strings
+func splitApplyTopLevel(input string, separator byte) ([]string, error) {
+	var parts []string
+	depth := 0
+	inString := false
+	start := 0
+
+	for i := 0; i < len(input); i++ {
+		switch ch := input[i]; {
+		case ch == '\'':
+			inString = !inString
+		case inString:
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced parentheses in '%s'", input)
+			}
+		case ch == separator && depth == 0:
+			parts = append(parts, strings.TrimSpace(input[start:i]))
+			start = i + 1
+		}
+	}
+
+	if depth != 0 || inString {
+		return nil, fmt.Errorf("unbalanced expression '%s'", input)
+	}
+
+	parts = append(parts, strings.TrimSpace(input[start:]))
+	for _, part := range parts {
+		if part == "" {
+			return nil, fmt.Errorf("empty element in '%s'", input)
+		}
+	}
+
+	return parts, nil
+}
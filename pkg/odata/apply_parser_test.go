@@ -0,0 +1,175 @@
+package odata
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func applyTestMetadata() EntityMetadata {
+	return EntityMetadata{
+		Name:      "Sales",
+		TableName: "sales",
+		Properties: []PropertyMetadata{
+			{Name: "ID", Type: "int64", ColumnName: "id", IsKey: true},
+			{Name: "Category", Type: "string", ColumnName: "category"},
+			{Name: "Customer", Type: "string", ColumnName: "customer"},
+			{Name: "Amount", Type: "float64", ColumnName: "amount"},
+			{Name: "Quantity", Type: "int32", ColumnName: "quantity"},
+		},
+	}
+}
+
+func TestParseApplyString(t *testing.T) {
+	ctx := context.Background()
+
+	apply, err := ParseApplyString(ctx, "filter(Amount gt 10)/groupby((Category,Customer),aggregate(Amount with sum as Total,Amount with avg as Average,$count as Orders))/topcount(3,Total)")
+	require.NoError(t, err)
+	require.Len(t, apply.Transformations, 3)
+
+	assert.Equal(t, ApplyFilter, apply.Transformations[0].Type)
+	require.NotNil(t, apply.Transformations[0].Filter)
+
+	groupBy := apply.Transformations[1]
+	assert.Equal(t, ApplyGroupBy, groupBy.Type)
+	assert.Equal(t, []string{"Category", "Customer"}, groupBy.GroupBy)
+	assert.Equal(t, []AggregateExpression{
+		{Property: "Amount", Method: AggregateSum, Alias: "Total"},
+		{Property: "Amount", Method: AggregateAverage, Alias: "Average"},
+		{Method: AggregateCount, Alias: "Orders"},
+	}, groupBy.Aggregates)
+
+	assert.Equal(t, ApplyTopCount, apply.Transformations[2].Type)
+	assert.Equal(t, 3, apply.Transformations[2].Count)
+	assert.Equal(t, "Total", apply.Transformations[2].Property)
+
+	apply, err = ParseApplyString(ctx, "compute(Amount mul Quantity as Line)/aggregate(Line with max as Biggest)")
+	require.NoError(t, err)
+	require.Len(t, apply.Transformations, 2)
+	require.NotNil(t, apply.Transformations[0].Compute)
+	assert.Equal(t, "Line", apply.Transformations[0].Compute.Expressions[0].Alias)
+
+	invalid := []string{
+		"groupby(Category)",
+		"groupby((Customer/Name))",
+		"aggregate(Amount with median as M)",
+		"topcount(x,Amount)",
+		"expand(Items)",
+		"filter(Amount gt 10",
+	}
+	for _, expr := range invalid {
+		_, err := ParseApplyString(ctx, expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestODataParser_ParseApply(t *testing.T) {
+	parser := NewODataParser()
+	values := url.Values{"$apply": []string{"groupby((Category))"}}
+
+	options, err := parser.ParseQueryOptions(values)
+	require.NoError(t, err)
+	require.NotNil(t, options.Apply)
+	assert.Equal(t, "groupby((Category))", options.Apply.RawValue)
+
+	_, err = parser.ParseQueryOptions(url.Values{"$apply": []string{"groupby(Category)"}})
+	assert.Error(t, err)
+}
+
+func TestQueryBuilder_BuildApplyQuery(t *testing.T) {
+	ctx := context.Background()
+	metadata := applyTestMetadata()
+
+	tests := []struct {
+		name     string
+		dialect  string
+		apply    string
+		expected string
+		args     int
+		props    []string
+	}{
+		{
+			name:     "groupby mysql",
+			dialect:  "mysql",
+			apply:    "groupby((Category),aggregate(Amount with sum as Total,$count as Orders))",
+			expected: "SELECT category AS `Category`, SUM(amount) AS `Total`, COUNT(*) AS `Orders` FROM sales GROUP BY category",
+			props:    []string{"Category", "Total", "Orders"},
+		},
+		{
+			name:     "filter then aggregate postgresql",
+			dialect:  "pgx",
+			apply:    "filter(Quantity gt 2)/aggregate(Customer with countdistinct as Customers)",
+			expected: `SELECT COUNT(DISTINCT customer) AS "Customers" FROM sales WHERE (quantity > :param1)`,
+			args:     1,
+			props:    []string{"Customers"},
+		},
+		{
+			name:     "topcount oracle",
+			dialect:  "oracle",
+			apply:    "groupby((Category),aggregate(Amount with max as Biggest))/topcount(2,Biggest)",
+			expected: `SELECT "Category", "Biggest" FROM (SELECT category AS "Category", MAX(amount) AS "Biggest" FROM sales GROUP BY category) apply1 ORDER BY "Biggest" DESC FETCH FIRST 2 ROWS ONLY`,
+			props:    []string{"Category", "Biggest"},
+		},
+		{
+			name:     "filter over aggregate",
+			dialect:  "postgresql",
+			apply:    "groupby((Customer),aggregate(Amount with average as Average))/filter(Average ge 100)",
+			expected: `SELECT "Customer", "Average" FROM (SELECT customer AS "Customer", AVG(amount) AS "Average" FROM sales GROUP BY customer) apply1 WHERE ("Average" >= :param1)`,
+			args:     1,
+			props:    []string{"Customer", "Average"},
+		},
+		{
+			name:     "compute then groupby",
+			dialect:  "mysql",
+			apply:    "compute(Amount mul Quantity as Line)/groupby((Category),aggregate(Line with sum as Revenue))/bottomcount(1,Revenue)",
+			expected: "SELECT `Category`, `Revenue` FROM (SELECT `Category`, SUM(`Line`) AS `Revenue` FROM (SELECT id AS `ID`, category AS `Category`, customer AS `Customer`, amount AS `Amount`, quantity AS `Quantity`, ((amount * quantity)) AS `Line` FROM sales) apply1 GROUP BY `Category`) apply2 ORDER BY `Revenue` ASC LIMIT 1",
+			props:    []string{"Category", "Revenue"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apply, err := ParseApplyString(ctx, tt.apply)
+			require.NoError(t, err)
+
+			query, err := NewQueryBuilder(tt.dialect).BuildApplyQuery(ctx, metadata, QueryOptions{Apply: apply})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, query.SQL)
+			assert.Len(t, query.Args, tt.args)
+
+			var props []string
+			for _, prop := range query.Properties {
+				props = append(props, prop.Name)
+			}
+			assert.Equal(t, tt.props, props)
+		})
+	}
+}
+
+func TestQueryBuilder_BuildApplyQueryWithOptions(t *testing.T) {
+	ctx := context.Background()
+
+	apply, err := ParseApplyString(ctx, "groupby((Category),aggregate(Amount with sum as Total))")
+	require.NoError(t, err)
+	filter, err := ParseFilterString(ctx, "Total gt 50")
+	require.NoError(t, err)
+	top := GoDataTopQuery(5)
+
+	query, err := NewQueryBuilder("mysql").BuildApplyQuery(ctx, applyTestMetadata(), QueryOptions{
+		Apply:   apply,
+		Filter:  filter,
+		OrderBy: "Total desc,Category",
+		Top:     &top,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT `Category`, `Total` FROM (SELECT category AS `Category`, SUM(amount) AS `Total` FROM sales GROUP BY category) apply1 WHERE (`Total` > :param1) ORDER BY `Total` DESC, `Category` ASC LIMIT 5", query.SQL)
+
+	// Propriedades agregadas não existem antes do groupby
+	apply, err = ParseApplyString(ctx, "filter(Total gt 1)/groupby((Category),aggregate(Amount with sum as Total))")
+	require.NoError(t, err)
+	_, err = NewQueryBuilder("mysql").BuildApplyQuery(ctx, applyTestMetadata(), QueryOptions{Apply: apply})
+	assert.Error(t, err)
+}
@@ -0,0 +1,237 @@
+package odata
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/valyala/fasthttp"
+)
+
+// asyncMonitorSegment é o segmento das URLs de monitoramento das requisições assíncronas
+const asyncMonitorSegment = "$async"
+
+// asyncContextKeyType é a chave, no fasthttp.RequestCtx, do contexto cancelável da requisição assíncrona
+type asyncContextKeyType struct{}
+
+var asyncContextKey = asyncContextKeyType{}
+
+// errAsyncJobLimit indica que o limite de requisições assíncronas foi atingido
+var errAsyncJobLimit = errors.New("asynchronous request limit reached")
+
+// AsyncJobStatus representa a situação de uma requisição assíncrona
+type AsyncJobStatus string
+
+const (
+	AsyncJobRunning   AsyncJobStatus = "running"
+	AsyncJobCompleted AsyncJobStatus = "completed"
+)
+
+// asyncJob é uma requisição executada em segundo plano (Prefer: respond-async)
+type asyncJob struct {
+	id        string
+	owner     string // Tenant e usuário que iniciaram a requisição
+	status    AsyncJobStatus
+	createdAt time.Time
+	expiresAt time.Time          // Preenchido ao concluir
+	result    batchResult        // Resposta final da requisição
+	cancel    context.CancelFunc // Interrompe a execução em segundo plano
+}
+
+// asyncJobStore guarda em memória as requisições assíncronas e os seus resultados
+type asyncJobStore struct {
+	mu    sync.Mutex
+	jobs  map[string]*asyncJob
+	ttl   time.Duration // Tempo que o resultado fica disponível depois de concluído
+	limit int           // Número máximo de requisições guardadas, em execução ou concluídas
+}
+
+// newAsyncJobStore cria o armazenamento de requisições assíncronas
+func newAsyncJobStore(ttl time.Duration, limit int) *asyncJobStore {
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+	if limit <= 0 {
+		limit = 1000
+	}
+	return &asyncJobStore{jobs: make(map[string]*asyncJob), ttl: ttl, limit: limit}
+}
+
+// create registra uma nova requisição em execução do owner; falha quando o limite foi atingido
+func (st *asyncJobStore) create(owner string, cancel context.CancelFunc) (*asyncJob, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.purge()
+
+	if len(st.jobs) >= st.limit {
+		return nil, errAsyncJobLimit
+	}
+
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	job := &asyncJob{id: hex.EncodeToString(id), owner: owner, status: AsyncJobRunning, createdAt: time.Now(), cancel: cancel}
+	st.jobs[job.id] = job
+	return job, nil
+}
+
+// complete guarda a resposta final da requisição
+func (st *asyncJobStore) complete(id string, result batchResult) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	// A requisição pode ter sido cancelada pelo cliente (DELETE no monitor)
+	if job, exists := st.jobs[id]; exists {
+		job.status = AsyncJobCompleted
+		job.result = result
+		job.expiresAt = time.Now().Add(st.ttl)
+	}
+}
+
+// get retorna uma cópia da requisição; requisições de outro owner não são encontradas
+func (st *asyncJobStore) get(id, owner string) (asyncJob, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.purge()
+
+	job, exists := st.jobs[id]
+	if !exists || job.owner != owner {
+		return asyncJob{}, false
+	}
+	return *job, true
+}
+
+// remove cancela a execução e descarta a requisição e o seu resultado
+func (st *asyncJobStore) remove(id, owner string) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	job, exists := st.jobs[id]
+	if !exists || job.owner != owner {
+		return false
+	}
+	job.cancel()
+	delete(st.jobs, id)
+	return true
+}
+
+// purge remove os resultados expirados; deve ser chamado com o lock adquirido
+func (st *asyncJobStore) purge() {
+	now := time.Now()
+	for id, job := range st.jobs {
+		if job.status == AsyncJobCompleted && now.After(job.expiresAt) {
+			delete(st.jobs, id)
+		}
+	}
+}
+
+// acceptsAsync indica se a requisição pode ser executada em segundo plano: consultas (GET) e $batch
+func (s *Server) acceptsAsync(c fiber.Ctx) bool {
+	path := strings.TrimPrefix(c.Path(), s.config.RoutePrefix)
+	if strings.HasPrefix(path, "/"+asyncMonitorSegment+"/") {
+		return false
+	}
+	return c.Method() == fiber.MethodGet || (c.Method() == fiber.MethodPost && path == "/$batch")
+}
+
+// startAsyncRequest executa a requisição em segundo plano pelo roteador e responde 202 com a URL de monitoramento
+func (s *Server) startAsyncRequest(c fiber.Ctx) error {
+	req := &fasthttp.Request{}
+	c.Request().CopyTo(req)
+
+	// A execução em segundo plano não pode ser assíncrona novamente
+	var kept []string
+	for _, preference := range splitPreferences(c.Get("Prefer")) {
+		if name, _, _ := strings.Cut(preference, "="); !strings.EqualFold(strings.TrimSpace(name), "respond-async") {
+			kept = append(kept, preference)
+		}
+	}
+	req.Header.Del("Prefer")
+	if len(kept) > 0 {
+		req.Header.Set("Prefer", strings.Join(kept, ", "))
+	}
+
+	// O DELETE no monitor cancela o contexto, interrompendo as consultas em andamento
+	ctx, cancel := context.WithCancel(context.Background())
+	job, err := s.asyncJobs.create(s.asyncOwner(c), cancel)
+	if err != nil {
+		cancel()
+		s.writeError(c, fiber.StatusServiceUnavailable, "AsyncLimitReached", err.Error())
+		return nil
+	}
+	remoteAddr := c.RequestCtx().RemoteAddr()
+
+	go func() {
+		defer cancel()
+		var fctx fasthttp.RequestCtx
+		fctx.Init(req, remoteAddr, nil)
+		fctx.SetUserValue(asyncContextKey, ctx)
+		s.router.Handler()(&fctx)
+		s.asyncJobs.complete(job.id, newResponseResult("", &fctx.Response))
+		s.logger.Printf("✅ Requisição assíncrona %s concluída com status %d", job.id, fctx.Response.StatusCode())
+	}()
+
+	c.Set(fiber.HeaderLocation, s.asyncMonitorURL(c, job.id))
+	addPreferenceApplied(c, "respond-async")
+	return c.SendStatus(fiber.StatusAccepted)
+}
+
+// asyncOwner identifica quem inicia ou consulta uma requisição assíncrona: o tenant e o usuário do token.
+// A requisição em segundo plano reaplica a autenticação da rota; o monitor só a entrega ao mesmo owner
+func (s *Server) asyncOwner(c fiber.Ctx) string {
+	var owner string
+	if s.multiTenantPool != nil {
+		owner = GetCurrentTenant(c)
+	}
+	if s.jwtService != nil {
+		if token := extractToken(c); token != "" {
+			if user, err := s.jwtService.ValidateAndExtractUser(token); err == nil {
+				owner += "/" + user.Username
+			}
+		}
+	}
+	return owner
+}
+
+// asyncMonitorURL monta a URL de monitoramento de uma requisição assíncrona
+func (s *Server) asyncMonitorURL(c fiber.Ctx, id string) string {
+	return c.BaseURL() + s.config.RoutePrefix + "/" + asyncMonitorSegment + "/" + id
+}
+
+// handleAsyncMonitor lida com GET (situação e resultado) e DELETE (cancelamento) do monitor assíncrono
+func (s *Server) handleAsyncMonitor(c fiber.Ctx) error {
+	id := c.Params("id")
+	owner := s.asyncOwner(c)
+
+	if c.Method() == fiber.MethodDelete {
+		if !s.asyncJobs.remove(id, owner) {
+			s.writeError(c, fiber.StatusNotFound, "AsyncJobNotFound", "Asynchronous request not found")
+			return nil
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+
+	job, exists := s.asyncJobs.get(id, owner)
+	if !exists {
+		s.writeError(c, fiber.StatusNotFound, "AsyncJobNotFound", "Asynchronous request not found")
+		return nil
+	}
+
+	if job.status == AsyncJobRunning {
+		c.Set(fiber.HeaderLocation, s.asyncMonitorURL(c, id))
+		c.Set(fiber.HeaderRetryAfter, "1")
+		return c.SendStatus(fiber.StatusAccepted)
+	}
+
+	// A resposta final é devolvida como mensagem application/http
+	var buf bytes.Buffer
+	writeHTTPMessage(&buf, job.result)
+	c.Set(fiber.HeaderContentType, "application/http")
+	c.Set("Content-Transfer-Encoding", "binary")
+	return c.Status(fiber.StatusOK).Send(buf.Bytes())
+}
@@ -0,0 +1,716 @@
+package odata
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/valyala/fasthttp"
+)
+
+// TxContextKeyType define um tipo customizado para a chave da transação no contexto
+type TxContextKeyType struct{}
+
+var TxContextKey = TxContextKeyType{}
+
+// batchOperation representa uma requisição individual dentro de um $batch
+type batchOperation struct {
+	ID        string
+	Method    string
+	URL       string
+	Headers   map[string]string
+	Body      []byte
+	DependsOn []string // IDs de requisições ou atomicityGroups anteriores (formato JSON)
+}
+
+// batchGroup agrupa requisições do lote; grupos atômicos correspondem a changesets
+type batchGroup struct {
+	ID         string
+	Atomic     bool
+	Operations []*batchOperation
+}
+
+// batchResult representa a resposta de uma requisição individual do lote
+type batchResult struct {
+	ID      string
+	Status  int
+	Headers map[string]string
+	Body    []byte
+}
+
+// batchGroupResult contém as respostas produzidas por um grupo do lote
+type batchGroupResult struct {
+	Group     *batchGroup
+	Responses []batchResult
+	Failed    bool
+}
+
+// jsonBatchRequest representa uma requisição no formato JSON do $batch (OData 4.01)
+type jsonBatchRequest struct {
+	ID             string            `json:"id"`
+	AtomicityGroup string            `json:"atomicityGroup,omitempty"`
+	DependsOn      []string          `json:"dependsOn,omitempty"`
+	Method         string            `json:"method"`
+	URL            string            `json:"url"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	Body           json.RawMessage   `json:"body,omitempty"`
+}
+
+// jsonBatchResponse representa uma resposta no formato JSON do $batch (OData 4.01)
+type jsonBatchResponse struct {
+	ID             string            `json:"id,omitempty"`
+	AtomicityGroup string            `json:"atomicityGroup,omitempty"`
+	Status         int               `json:"status"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	Body           json.RawMessage   `json:"body,omitempty"`
+}
+
+// handleBatch lida com POST no endpoint $batch (multipart/mixed ou JSON)
+func (s *Server) handleBatch(c fiber.Ctx) error {
+	mediaType, params, err := mime.ParseMediaType(c.Get(fiber.HeaderContentType))
+	if err != nil {
+		s.writeError(c, fiber.StatusBadRequest, "InvalidBatch", "Invalid Content-Type for $batch request")
+		return nil
+	}
+
+	var groups []*batchGroup
+	isJSON := mediaType == fiber.MIMEApplicationJSON
+
+	switch {
+	case isJSON:
+		groups, err = parseJSONBatch(c.Body())
+	case mediaType == "multipart/mixed":
+		groups, err = parseMultipartBatch(c.Body(), params["boundary"])
+	default:
+		s.writeError(c, fiber.StatusUnsupportedMediaType, "UnsupportedMediaType",
+			fmt.Sprintf("Content-Type '%s' not supported for $batch", mediaType))
+		return nil
+	}
+
+	if err != nil {
+		s.writeError(c, fiber.StatusBadRequest, "InvalidBatch", err.Error())
+		return nil
+	}
+
+	results := s.executeBatch(c, groups, preferContinueOnError(c.Get("Prefer")))
+
+	c.Set("OData-Version", "4.0")
+	if isJSON {
+		return writeJSONBatchResponse(c, results)
+	}
+	return writeMultipartBatchResponse(c, results)
+}
+
+// executeBatch executa os grupos do lote na ordem recebida. Uma requisição cuja dependência (dependsOn)
+// falhou não é executada e responde 424 Failed Dependency
+func (s *Server) executeBatch(c fiber.Ctx, groups []*batchGroup, continueOnError bool) []batchGroupResult {
+	contentIDs := make(map[string]string)
+	results := make([]batchGroupResult, 0, len(groups))
+	failed := make(map[string]bool)
+
+	for _, group := range groups {
+		var result batchGroupResult
+		if op, dependency := failedDependency(group, failed); op != nil {
+			result = batchGroupResult{
+				Group: group,
+				Responses: []batchResult{newBatchErrorResult(op.ID, fiber.StatusFailedDependency, "FailedDependency",
+					fmt.Sprintf("request '%s' depends on failed request '%s'", op.ID, dependency))},
+				Failed: true,
+			}
+		} else if group.Atomic {
+			result = s.executeChangeSet(c, group, contentIDs)
+		} else {
+			response := s.dispatchBatchOperation(c, group.Operations[0], nil, nil, contentIDs)
+			result = batchGroupResult{
+				Group:     group,
+				Responses: []batchResult{response},
+				Failed:    response.Status >= fiber.StatusBadRequest,
+			}
+		}
+
+		results = append(results, result)
+		if result.Failed {
+			if group.ID != "" {
+				failed[group.ID] = true
+			}
+			for _, op := range group.Operations {
+				if op.ID != "" {
+					failed[op.ID] = true
+				}
+			}
+		}
+
+		// Sem odata.continue-on-error o processamento para na primeira falha
+		if result.Failed && !continueOnError {
+			break
+		}
+	}
+
+	return results
+}
+
+// failedDependency retorna a primeira requisição do grupo que depende de uma requisição que falhou
+func failedDependency(group *batchGroup, failed map[string]bool) (*batchOperation, string) {
+	for _, op := range group.Operations {
+		for _, dependency := range op.DependsOn {
+			if failed[dependency] {
+				return op, dependency
+			}
+		}
+	}
+	return nil, ""
+}
+
+// changeSetProvider retorna o provider das entidades alvo do changeset. A transação não abrange mais de um
+// banco, então requisições para entidades de providers diferentes são recusadas
+func (s *Server) changeSetProvider(c fiber.Ctx, group *batchGroup) (DatabaseProvider, error) {
+	var provider DatabaseProvider
+	for _, op := range group.Operations {
+		// Referências $<Content-ID> apontam para entidades criadas no próprio changeset
+		if strings.HasPrefix(strings.TrimSpace(op.URL), "$") {
+			continue
+		}
+		target, err := s.resolveBatchURL(op.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		path, _, _ := strings.Cut(target, "?")
+
+		current := s.getCurrentProvider(c)
+		if service, exists := s.entityService(s.extractEntityName(path)); exists {
+			current = s.serviceProvider(c, service)
+		}
+		if provider != nil && current != provider {
+			return nil, fmt.Errorf("changeset '%s' targets entities stored in different databases", group.ID)
+		}
+		provider = current
+	}
+	if provider == nil {
+		provider = s.getCurrentProvider(c)
+	}
+	return provider, nil
+}
+
+// executeChangeSet executa as requisições de um changeset em uma única transação,
+// revertendo todas as alterações se qualquer requisição falhar
+func (s *Server) executeChangeSet(c fiber.Ctx, group *batchGroup, contentIDs map[string]string) batchGroupResult {
+	result := batchGroupResult{Group: group}
+	fail := func(response batchResult) batchGroupResult {
+		result.Responses = []batchResult{response}
+		result.Failed = true
+		return result
+	}
+
+	firstID := ""
+	if len(group.Operations) > 0 {
+		firstID = group.Operations[0].ID
+	}
+
+	provider, err := s.changeSetProvider(c, group)
+	if err != nil {
+		return fail(newBatchErrorResult(firstID, fiber.StatusBadRequest, "InvalidBatchRequest", err.Error()))
+	}
+	if provider == nil || provider.GetConnection() == nil {
+		return fail(newBatchErrorResult(firstID, fiber.StatusInternalServerError, "TransactionError",
+			"database connection is nil - cannot start changeset transaction"))
+	}
+
+	tx, err := provider.GetConnection().BeginTx(c.Context(), s.txOptions())
+	if err != nil {
+		return fail(newBatchErrorResult(firstID, fiber.StatusInternalServerError, "TransactionError",
+			fmt.Sprintf("failed to begin transaction: %v", err)))
+	}
+
+	// Funções agendadas com AfterCommit pelas requisições do changeset
+	callbacks := &txCallbacks{}
+	for _, op := range group.Operations {
+		if op.Method == fiber.MethodGet {
+			tx.Rollback()
+			return fail(newBatchErrorResult(op.ID, fiber.StatusBadRequest, "InvalidBatchRequest",
+				"GET requests are not allowed inside a changeset"))
+		}
+
+		response := s.dispatchBatchOperation(c, op, tx, callbacks, contentIDs)
+		if response.Status >= fiber.StatusBadRequest {
+			if err := tx.Rollback(); err != nil {
+				s.logger.Printf("❌ Erro ao reverter changeset %s: %v", group.ID, err)
+			}
+			s.logger.Printf("❌ Changeset %s revertido: requisição %s %s retornou %d", group.ID, op.Method, op.URL, response.Status)
+			return fail(response)
+		}
+
+		result.Responses = append(result.Responses, response)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fail(newBatchErrorResult(firstID, fiber.StatusInternalServerError, "TransactionError",
+			fmt.Sprintf("failed to commit transaction: %v", err)))
+	}
+
+	for _, callback := range callbacks.take() {
+		callback()
+	}
+	return result
+}
+
+// dispatchBatchOperation executa uma requisição do lote através do roteador, passando
+// pelos mesmos middlewares de autenticação, autorização e eventos de uma requisição comum
+func (s *Server) dispatchBatchOperation(c fiber.Ctx, op *batchOperation, tx *sql.Tx, callbacks *txCallbacks, contentIDs map[string]string) batchResult {
+	target, err := s.resolveBatchURL(op.URL, contentIDs)
+	if err != nil {
+		return newBatchErrorResult(op.ID, fiber.StatusBadRequest, "InvalidBatchRequest", err.Error())
+	}
+
+	path, _, _ := strings.Cut(target, "?")
+	if strings.HasSuffix(path, "/$batch") {
+		return newBatchErrorResult(op.ID, fiber.StatusBadRequest, "InvalidBatchRequest", "nested $batch requests are not supported")
+	}
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	// Herda os cabeçalhos da requisição externa (autenticação, tenant, etc.)
+	c.Request().Header.VisitAll(func(key, value []byte) {
+		switch strings.ToLower(string(key)) {
+		case "content-type", "content-length", "transfer-encoding", "prefer":
+			return
+		}
+		req.Header.SetBytesKV(key, value)
+	})
+
+	for name, value := range op.Headers {
+		req.Header.Set(name, value)
+	}
+
+	req.Header.SetMethod(op.Method)
+	req.SetRequestURI(target)
+
+	if len(op.Body) > 0 {
+		req.SetBody(op.Body)
+		if len(req.Header.ContentType()) == 0 {
+			req.Header.SetContentType(fiber.MIMEApplicationJSON)
+		}
+	}
+
+	var fctx fasthttp.RequestCtx
+	fctx.Init(req, c.RequestCtx().RemoteAddr(), nil)
+	if tx != nil {
+		fctx.SetUserValue(TxContextKey, tx)
+		fctx.SetUserValue(txCallbacksContextKey, callbacks)
+	}
+
+	s.router.Handler()(&fctx)
+
+	result := newResponseResult(op.ID, &fctx.Response)
+
+	// Registra a URL da entidade criada para referências $<Content-ID>
+	if location := result.Headers[fiber.HeaderLocation]; op.ID != "" && location != "" && result.Status < fiber.StatusBadRequest {
+		contentIDs[op.ID] = location
+	}
+
+	return result
+}
+
+// newResponseResult copia a resposta de uma requisição despachada pelo roteador
+func newResponseResult(id string, response *fasthttp.Response) batchResult {
+	result := batchResult{
+		ID:      id,
+		Status:  response.StatusCode(),
+		Headers: make(map[string]string),
+		Body:    append([]byte(nil), response.Body()...),
+	}
+
+	response.Header.VisitAll(func(key, value []byte) {
+		name := string(key)
+		if strings.EqualFold(name, fiber.HeaderContentLength) || strings.EqualFold(name, fiber.HeaderServer) {
+			return
+		}
+		result.Headers[name] = string(value)
+	})
+	return result
+}
+
+// resolveBatchURL converte a URL de uma requisição do lote em um caminho absoluto do servidor
+func (s *Server) resolveBatchURL(rawURL string, contentIDs map[string]string) (string, error) {
+	target := strings.TrimSpace(rawURL)
+	if target == "" {
+		return "", fmt.Errorf("batch request URL is required")
+	}
+
+	// Referência a Content-ID de uma requisição anterior ($1, $1/Items)
+	if strings.HasPrefix(target, "$") {
+		ref, rest, hasRest := strings.Cut(target[1:], "/")
+		location, exists := contentIDs[ref]
+		if !exists {
+			return "", fmt.Errorf("unknown Content-ID reference '$%s'", ref)
+		}
+		target = location
+		if hasRest {
+			target += "/" + rest
+		}
+	}
+
+	// URL absoluta: mantém apenas o caminho e a query string
+	if idx := strings.Index(target, "://"); idx != -1 {
+		target = target[idx+3:]
+		slash := strings.Index(target, "/")
+		if slash == -1 {
+			return "", fmt.Errorf("invalid batch request URL '%s'", rawURL)
+		}
+		target = target[slash:]
+	}
+
+	// URL relativa à raiz do serviço
+	if !strings.HasPrefix(target, "/") {
+		target = s.config.RoutePrefix + "/" + target
+	}
+
+	return target, nil
+}
+
+// parseJSONBatch converte o corpo de um $batch no formato JSON em grupos de requisições, mantendo a ordem
+// recebida. As requisições de um atomicityGroup precisam ser adjacentes e dependsOn só pode citar
+// requisições ou grupos anteriores
+func parseJSONBatch(body []byte) ([]*batchGroup, error) {
+	var payload struct {
+		Requests []jsonBatchRequest `json:"requests"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid JSON batch: %w", err)
+	}
+
+	var groups []*batchGroup
+	var current *batchGroup
+	declared := make(map[string]bool)
+	closed := make(map[string]bool)
+
+	for i, request := range payload.Requests {
+		if request.Method == "" || request.URL == "" {
+			return nil, fmt.Errorf("batch request %d: method and url are required", i)
+		}
+
+		// O grupo anterior termina quando chega uma requisição de outro grupo
+		if current != nil && current.ID != request.AtomicityGroup {
+			declared[current.ID], closed[current.ID] = true, true
+			current = nil
+		}
+
+		for _, dependency := range request.DependsOn {
+			if !declared[dependency] {
+				return nil, fmt.Errorf("batch request %d: dependsOn '%s' must reference a preceding request or atomicity group", i, dependency)
+			}
+		}
+
+		op := &batchOperation{
+			ID:        request.ID,
+			Method:    strings.ToUpper(request.Method),
+			URL:       request.URL,
+			Headers:   request.Headers,
+			Body:      jsonBatchRequestBody(request),
+			DependsOn: request.DependsOn,
+		}
+		if request.ID != "" {
+			declared[request.ID] = true
+		}
+
+		if request.AtomicityGroup == "" {
+			groups = append(groups, &batchGroup{Operations: []*batchOperation{op}})
+			continue
+		}
+
+		if current == nil {
+			if closed[request.AtomicityGroup] {
+				return nil, fmt.Errorf("batch request %d: requests of atomicity group '%s' must be adjacent", i, request.AtomicityGroup)
+			}
+			current = &batchGroup{ID: request.AtomicityGroup, Atomic: true}
+			groups = append(groups, current)
+		}
+		current.Operations = append(current.Operations, op)
+	}
+
+	return groups, nil
+}
+
+// jsonBatchRequestBody extrai o corpo de uma requisição JSON do lote.
+// Corpos não-JSON são enviados como string JSON e precisam ser decodificados
+func jsonBatchRequestBody(request jsonBatchRequest) []byte {
+	if len(request.Body) == 0 || string(request.Body) == "null" {
+		return nil
+	}
+
+	contentType := ""
+	for name, value := range request.Headers {
+		if strings.EqualFold(name, fiber.HeaderContentType) {
+			contentType = value
+		}
+	}
+
+	if contentType != "" && !strings.Contains(strings.ToLower(contentType), "json") {
+		var text string
+		if err := json.Unmarshal(request.Body, &text); err == nil {
+			return []byte(text)
+		}
+	}
+
+	return request.Body
+}
+
+// parseMultipartBatch converte o corpo de um $batch multipart/mixed em grupos de requisições
+func parseMultipartBatch(body []byte, boundary string) ([]*batchGroup, error) {
+	if boundary == "" {
+		return nil, fmt.Errorf("missing boundary in multipart/mixed batch")
+	}
+
+	var groups []*batchGroup
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid multipart batch: %w", err)
+		}
+
+		mediaType, params, err := mime.ParseMediaType(part.Header.Get(fiber.HeaderContentType))
+		if err != nil {
+			return nil, fmt.Errorf("invalid batch part Content-Type: %w", err)
+		}
+
+		switch mediaType {
+		case "multipart/mixed":
+			// Changeset: todas as requisições devem ser executadas atomicamente
+			group := &batchGroup{ID: params["boundary"], Atomic: true}
+			changeset := multipart.NewReader(part, params["boundary"])
+			for {
+				changesetPart, err := changeset.NextPart()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return nil, fmt.Errorf("invalid changeset %s: %w", group.ID, err)
+				}
+
+				op, err := parseBatchHTTPPart(changesetPart)
+				if err != nil {
+					return nil, err
+				}
+				group.Operations = append(group.Operations, op)
+			}
+			groups = append(groups, group)
+
+		case "application/http":
+			op, err := parseBatchHTTPPart(part)
+			if err != nil {
+				return nil, err
+			}
+			groups = append(groups, &batchGroup{Operations: []*batchOperation{op}})
+
+		default:
+			return nil, fmt.Errorf("unsupported batch part Content-Type '%s'", mediaType)
+		}
+	}
+
+	return groups, nil
+}
+
+// parseBatchHTTPPart lê uma requisição HTTP embutida em uma parte application/http
+func parseBatchHTTPPart(part *multipart.Part) (*batchOperation, error) {
+	reader := textproto.NewReader(bufio.NewReader(part))
+
+	// Ignora linhas em branco antes da linha de requisição
+	line, err := reader.ReadLine()
+	for err == nil && strings.TrimSpace(line) == "" {
+		line, err = reader.ReadLine()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid request line in batch part: %w", err)
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("invalid request line in batch part: '%s'", line)
+	}
+
+	header, err := reader.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("invalid headers in batch part: %w", err)
+	}
+
+	body, err := io.ReadAll(reader.R)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch part body: %w", err)
+	}
+
+	op := &batchOperation{
+		ID:      part.Header.Get("Content-ID"),
+		Method:  strings.ToUpper(fields[0]),
+		URL:     fields[1],
+		Headers: make(map[string]string, len(header)),
+		Body:    bytes.TrimRight(body, "\r\n"),
+	}
+
+	for name := range header {
+		op.Headers[name] = header.Get(name)
+	}
+
+	return op, nil
+}
+
+// preferContinueOnError verifica se o cliente solicitou odata.continue-on-error
+func preferContinueOnError(prefer string) bool {
+	for _, preference := range strings.Split(prefer, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(preference), "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "odata.continue-on-error" || name == "continue-on-error" {
+			return !strings.EqualFold(strings.TrimSpace(value), "false")
+		}
+	}
+	return false
+}
+
+// newBatchErrorResult cria uma resposta de erro OData para uma requisição do lote
+func newBatchErrorResult(id string, statusCode int, code, message string) batchResult {
+	body, _ := json.Marshal(ODataResponse{
+		Error: &ODataError{
+			Code:    code,
+			Message: message,
+		},
+	})
+
+	return batchResult{
+		ID:      id,
+		Status:  statusCode,
+		Headers: map[string]string{fiber.HeaderContentType: fiber.MIMEApplicationJSON},
+		Body:    body,
+	}
+}
+
+// writeJSONBatchResponse escreve a resposta do lote no formato JSON
+func writeJSONBatchResponse(c fiber.Ctx, results []batchGroupResult) error {
+	responses := make([]jsonBatchResponse, 0)
+
+	for _, result := range results {
+		for _, response := range result.Responses {
+			item := jsonBatchResponse{
+				ID:      response.ID,
+				Status:  response.Status,
+				Headers: response.Headers,
+			}
+			if result.Group.Atomic {
+				item.AtomicityGroup = result.Group.ID
+			}
+
+			if len(response.Body) > 0 {
+				if json.Valid(response.Body) {
+					item.Body = response.Body
+				} else {
+					item.Body, _ = json.Marshal(string(response.Body))
+				}
+			}
+
+			responses = append(responses, item)
+		}
+	}
+
+	return c.JSON(map[string]interface{}{
+		"responses": responses,
+	})
+}
+
+// writeMultipartBatchResponse escreve a resposta do lote no formato multipart/mixed
+func writeMultipartBatchResponse(c fiber.Ctx, results []batchGroupResult) error {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	for _, result := range results {
+		// Changesets bem-sucedidos retornam um multipart aninhado;
+		// changesets com falha retornam apenas a resposta de erro
+		if result.Group.Atomic && !result.Failed {
+			var changesetBuf bytes.Buffer
+			changesetWriter := multipart.NewWriter(&changesetBuf)
+			for _, response := range result.Responses {
+				if err := writeBatchHTTPPart(changesetWriter, response); err != nil {
+					return err
+				}
+			}
+			if err := changesetWriter.Close(); err != nil {
+				return err
+			}
+
+			header := textproto.MIMEHeader{}
+			header.Set(fiber.HeaderContentType, "multipart/mixed; boundary="+changesetWriter.Boundary())
+			part, err := writer.CreatePart(header)
+			if err != nil {
+				return err
+			}
+			if _, err := part.Write(changesetBuf.Bytes()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, response := range result.Responses {
+			if err := writeBatchHTTPPart(writer, response); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, "multipart/mixed; boundary="+writer.Boundary())
+	return c.Send(buf.Bytes())
+}
+
+// writeBatchHTTPPart escreve uma resposta HTTP embutida em uma parte application/http
+func writeBatchHTTPPart(writer *multipart.Writer, response batchResult) error {
+	header := textproto.MIMEHeader{}
+	header.Set(fiber.HeaderContentType, "application/http")
+	header.Set("Content-Transfer-Encoding", "binary")
+	if response.ID != "" {
+		// Mantém a grafia da especificação OData (textproto normalizaria para Content-Id)
+		header["Content-ID"] = []string{response.ID}
+	}
+
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	writeHTTPMessage(&buf, response)
+
+	_, err = part.Write(buf.Bytes())
+	return err
+}
+
+// writeHTTPMessage escreve a resposta no formato de mensagem HTTP (linha de status, cabeçalhos e corpo)
+func writeHTTPMessage(buf *bytes.Buffer, response batchResult) {
+	fmt.Fprintf(buf, "HTTP/1.1 %d %s\r\n", response.Status, http.StatusText(response.Status))
+
+	names := make([]string, 0, len(response.Headers))
+	for name := range response.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(buf, "%s: %s\r\n", name, response.Headers[name])
+	}
+
+	fmt.Fprintf(buf, "Content-Length: %d\r\n\r\n", len(response.Body))
+	buf.Write(response.Body)
+}
@@ -0,0 +1,323 @@
+package odata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchTestService é um EntityService em memória que registra se recebeu transação
+type batchTestService struct {
+	mu      sync.Mutex
+	nextID  int64
+	inTx    []bool
+	created []map[string]interface{}
+}
+
+func (s *batchTestService) GetMetadata() EntityMetadata {
+	return EntityMetadata{
+		Name:      "Products",
+		TableName: "products",
+		Properties: []PropertyMetadata{
+			{Name: "ID", Type: "int64", IsKey: true},
+			{Name: "Name", Type: "string"},
+		},
+	}
+}
+
+func (s *batchTestService) Query(ctx context.Context, options QueryOptions) (*ODataResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := make([]interface{}, 0, len(s.created))
+	for _, entity := range s.created {
+		results = append(results, entity)
+	}
+	return &ODataResponse{Value: results}, nil
+}
+
+func (s *batchTestService) Get(ctx context.Context, keys map[string]interface{}) (interface{}, error) {
+	return nil, fmt.Errorf("entity not found")
+}
+
+func (s *batchTestService) Create(ctx context.Context, entity interface{}) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inTx = append(s.inTx, ctx.Value(TxContextKey) != nil)
+
+	data := entity.(map[string]interface{})
+	if data["Name"] == "fail" {
+		return nil, fmt.Errorf("forced failure")
+	}
+
+	s.nextID++
+	data["ID"] = s.nextID
+	s.created = append(s.created, data)
+	return data, nil
+}
+
+func (s *batchTestService) Update(ctx context.Context, keys map[string]interface{}, entity interface{}) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inTx = append(s.inTx, ctx.Value(TxContextKey) != nil)
+
+	data := entity.(map[string]interface{})
+	data["ID"] = keys["ID"]
+	return data, nil
+}
+
+func (s *batchTestService) Delete(ctx context.Context, keys map[string]interface{}) error {
+	return nil
+}
+
+func newBatchTestServer(t *testing.T) (*Server, *batchTestService, *fakeDB) {
+	db := newFakeDB(t, nil)
+	server := newTestServer(&MockDatabaseProvider{connection: db.DB})
+	service := &batchTestService{}
+	require.NoError(t, server.RegisterEntityWithService("Products", service))
+
+	return server, service, db
+}
+
+func TestBatch_JSONChangeSetCommits(t *testing.T) {
+	server, service, db := newBatchTestServer(t)
+
+	body := `{"requests":[
+		{"id":"1","atomicityGroup":"g1","method":"POST","url":"Products","body":{"Name":"Chair"}},
+		{"id":"2","atomicityGroup":"g1","method":"PATCH","url":"$1","body":{"Name":"Table"}},
+		{"id":"3","method":"GET","url":"/odata/Products"}
+	]}`
+
+	req := httptest.NewRequest("POST", "/odata/$batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := server.GetRouter().Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var payload struct {
+		Responses []jsonBatchResponse `json:"responses"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&payload))
+	require.Len(t, payload.Responses, 3)
+
+	assert.Equal(t, 201, payload.Responses[0].Status)
+	assert.Equal(t, "g1", payload.Responses[0].AtomicityGroup)
+	assert.Contains(t, payload.Responses[0].Headers["Location"], "/odata/Products(1)")
+	assert.Equal(t, 200, payload.Responses[1].Status)
+	assert.Equal(t, 200, payload.Responses[2].Status)
+
+	assert.Equal(t, 1, db.commits)
+	assert.Equal(t, 0, db.rollbacks)
+	assert.Equal(t, []bool{true, true}, service.inTx)
+}
+
+func TestBatch_JSONChangeSetRollsBack(t *testing.T) {
+	server, _, db := newBatchTestServer(t)
+
+	body := `{"requests":[
+		{"id":"1","atomicityGroup":"g1","method":"POST","url":"Products","body":{"Name":"Chair"}},
+		{"id":"2","atomicityGroup":"g1","method":"POST","url":"Products","body":{"Name":"fail"}},
+		{"id":"3","method":"GET","url":"Products"}
+	]}`
+
+	req := httptest.NewRequest("POST", "/odata/$batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := server.GetRouter().Test(req)
+	require.NoError(t, err)
+
+	var payload struct {
+		Responses []jsonBatchResponse `json:"responses"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&payload))
+
+	// O changeset falho retorna apenas a resposta de erro e interrompe o lote
+	require.Len(t, payload.Responses, 1)
+	assert.Equal(t, "2", payload.Responses[0].ID)
+	assert.Equal(t, 500, payload.Responses[0].Status)
+	assert.Equal(t, 0, db.commits)
+	assert.Equal(t, 1, db.rollbacks)
+}
+
+func sendJSONBatch(t *testing.T, server *Server, body, prefer string) (int, []jsonBatchResponse) {
+	req := httptest.NewRequest("POST", "/odata/$batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if prefer != "" {
+		req.Header.Set("Prefer", prefer)
+	}
+	resp, err := server.GetRouter().Test(req)
+	require.NoError(t, err)
+
+	var payload struct {
+		Responses []jsonBatchResponse `json:"responses"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&payload)
+	return resp.StatusCode, payload.Responses
+}
+
+func TestBatch_JSONDependsOnAndOrder(t *testing.T) {
+	server, service, _ := newBatchTestServer(t)
+
+	// Grupos não adjacentes e dependências de requisições posteriores são recusados
+	for _, body := range []string{
+		`{"requests":[
+			{"id":"1","atomicityGroup":"g1","method":"POST","url":"Products","body":{"Name":"A"}},
+			{"id":"2","method":"GET","url":"Products"},
+			{"id":"3","atomicityGroup":"g1","method":"POST","url":"Products","body":{"Name":"B"}}
+		]}`,
+		`{"requests":[
+			{"id":"1","method":"POST","url":"Products","body":{"Name":"A"},"dependsOn":["2"]},
+			{"id":"2","method":"POST","url":"Products","body":{"Name":"B"}}
+		]}`,
+		`{"requests":[
+			{"id":"1","atomicityGroup":"g1","method":"POST","url":"Products","body":{"Name":"A"},"dependsOn":["g1"]}
+		]}`,
+	} {
+		status, _ := sendJSONBatch(t, server, body, "")
+		assert.Equal(t, 400, status, body)
+	}
+	assert.Empty(t, service.created)
+
+	// Com continue-on-error, quem depende de uma requisição que falhou responde 424 sem ser executado
+	status, responses := sendJSONBatch(t, server, `{"requests":[
+		{"id":"1","atomicityGroup":"g1","method":"POST","url":"Products","body":{"Name":"fail"}},
+		{"id":"2","method":"POST","url":"Products","body":{"Name":"A"},"dependsOn":["g1"]},
+		{"id":"3","method":"POST","url":"Products","body":{"Name":"B"},"dependsOn":["2"]},
+		{"id":"4","method":"POST","url":"Products","body":{"Name":"C"}}
+	]}`, "odata.continue-on-error")
+	require.Equal(t, 200, status)
+	require.Len(t, responses, 4)
+	assert.Equal(t, 500, responses[0].Status)
+	assert.Equal(t, 424, responses[1].Status)
+	assert.Equal(t, "2", responses[1].ID)
+	assert.Equal(t, 424, responses[2].Status)
+	assert.Equal(t, 201, responses[3].Status)
+	require.Len(t, service.created, 1)
+	assert.Equal(t, "C", service.created[0]["Name"])
+}
+
+func TestBatch_ChangeSetRejectsMixedProviders(t *testing.T) {
+	server, service, db := newBatchTestServer(t)
+
+	other := newFakeDB(t, nil)
+	metadata := EntityMetadata{Name: "Logs", TableName: "logs", Properties: []PropertyMetadata{{Name: "ID", Type: "int64", IsKey: true}}}
+	require.NoError(t, server.RegisterEntityWithService("Logs", NewBaseEntityService(&MockDatabaseProvider{connection: other.DB}, metadata, server)))
+
+	status, responses := sendJSONBatch(t, server, `{"requests":[
+		{"id":"1","atomicityGroup":"g1","method":"POST","url":"Products","body":{"Name":"A"}},
+		{"id":"2","atomicityGroup":"g1","method":"POST","url":"Logs","body":{"ID":1}}
+	]}`, "")
+	require.Equal(t, 200, status)
+	require.Len(t, responses, 1)
+	assert.Equal(t, 400, responses[0].Status)
+	assert.Empty(t, service.created)
+	assert.Zero(t, db.commits+db.rollbacks)
+}
+
+func TestBatch_Multipart(t *testing.T) {
+	server, _, db := newBatchTestServer(t)
+
+	body := strings.Join([]string{
+		"--batch_1",
+		"Content-Type: multipart/mixed; boundary=changeset_1",
+		"",
+		"--changeset_1",
+		"Content-Type: application/http",
+		"Content-Transfer-Encoding: binary",
+		"Content-ID: 1",
+		"",
+		"POST Products HTTP/1.1",
+		"Content-Type: application/json",
+		"",
+		`{"Name":"Chair"}`,
+		"--changeset_1--",
+		"--batch_1",
+		"Content-Type: application/http",
+		"Content-Transfer-Encoding: binary",
+		"",
+		"GET Products HTTP/1.1",
+		"",
+		"",
+		"--batch_1--",
+		"",
+	}, "\r\n")
+
+	req := httptest.NewRequest("POST", "/odata/$batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "multipart/mixed; boundary=batch_1")
+
+	resp, err := server.GetRouter().Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "multipart/mixed; boundary=")
+
+	raw, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), "HTTP/1.1 201 Created")
+	assert.Contains(t, string(raw), "HTTP/1.1 200 OK")
+	assert.Contains(t, string(raw), "Content-ID: 1")
+	assert.Equal(t, 1, db.commits)
+}
+
+func TestParseMultipartBatch(t *testing.T) {
+	body := "--b\r\nContent-Type: multipart/mixed; boundary=cs\r\n\r\n" +
+		"--cs\r\nContent-Type: application/http\r\nContent-ID: 1\r\n\r\nPOST Products HTTP/1.1\r\nContent-Type: application/json\r\n\r\n{\"Name\":\"A\"}\r\n" +
+		"--cs\r\nContent-Type: application/http\r\nContent-ID: 2\r\n\r\nDELETE Products(5) HTTP/1.1\r\n\r\n\r\n" +
+		"--cs--\r\n" +
+		"--b\r\nContent-Type: application/http\r\n\r\nGET Products?$top=1 HTTP/1.1\r\n\r\n\r\n" +
+		"--b--\r\n"
+
+	groups, err := parseMultipartBatch([]byte(body), "b")
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+
+	assert.True(t, groups[0].Atomic)
+	require.Len(t, groups[0].Operations, 2)
+	assert.Equal(t, "POST", groups[0].Operations[0].Method)
+	assert.Equal(t, `{"Name":"A"}`, string(groups[0].Operations[0].Body))
+	assert.Equal(t, "application/json", groups[0].Operations[0].Headers["Content-Type"])
+	assert.Equal(t, "Products(5)", groups[0].Operations[1].URL)
+
+	assert.False(t, groups[1].Atomic)
+	assert.Equal(t, "Products?$top=1", groups[1].Operations[0].URL)
+
+	_, err = parseMultipartBatch([]byte(body), "")
+	assert.Error(t, err)
+}
+
+func TestResolveBatchURL(t *testing.T) {
+	server := &Server{config: &ServerConfig{RoutePrefix: "/odata"}}
+	contentIDs := map[string]string{"1": "http://localhost/odata/Orders(7)"}
+
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{"Products", "/odata/Products", false},
+		{"/odata/Products(1)", "/odata/Products(1)", false},
+		{"http://localhost:8080/odata/Products?$top=2", "/odata/Products?$top=2", false},
+		{"$1", "/odata/Orders(7)", false},
+		{"$1/Items", "/odata/Orders(7)/Items", false},
+		{"$9", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := server.resolveBatchURL(tt.input, contentIDs)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
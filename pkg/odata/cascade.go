@@ -0,0 +1,396 @@
+package odata
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// validateDeepUpdate verifica se as navegações aninhadas no corpo permitem atualização em cascata (SaveUpdate)
+func (s *Server) validateDeepUpdate(metadata EntityMetadata, data map[string]interface{}) error {
+	for _, prop := range metadata.Properties {
+		if !prop.IsNavigation {
+			continue
+		}
+		value, exists := data[prop.Name]
+		if !exists || value == nil {
+			continue
+		}
+
+		if !prop.HasCascade(CascadeSaveUpdate) {
+			return fmt.Errorf("navigation property '%s' does not allow nested changes (cascade SaveUpdate)", prop.Name)
+		}
+
+		_, relatedService, exists := s.findEntitySet(relatedEntityType(prop))
+		if !exists {
+			return fmt.Errorf("related entity of '%s' is not registered", prop.Name)
+		}
+
+		var items []interface{}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if prop.IsCollection {
+				return fmt.Errorf("navigation property '%s' expects an array", prop.Name)
+			}
+			items = []interface{}{v}
+		case []interface{}:
+			if !prop.IsCollection {
+				return fmt.Errorf("navigation property '%s' expects an object", prop.Name)
+			}
+			items = v
+		default:
+			return fmt.Errorf("invalid value for navigation property '%s'", prop.Name)
+		}
+
+		for _, item := range items {
+			nested, ok := item.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("navigation property '%s' expects entity objects", prop.Name)
+			}
+			if err := s.validateDeepUpdate(relatedService.GetMetadata(), nested); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// deepUpdate atualiza a entidade e as entidades aninhadas no corpo na mesma transação
+func (s *Server) deepUpdate(ctx context.Context, provider DatabaseProvider, service EntityService, keys, data map[string]interface{}) (interface{}, error) {
+	entityName := s.entitySetNameOf(service.GetMetadata())
+	return s.runInTransaction(ctx, provider, func(ctx context.Context) (interface{}, error) {
+		return s.executeDeepUpdate(ctx, entityName, service, keys, data, nil)
+	})
+}
+
+// executeDeepUpdate atualiza a entidade e faz o upsert das entidades aninhadas. Nas coleções, os membros
+// ausentes do corpo são removidos quando a navegação tem cascade RemoveOrphan. original é nil na raiz.
+func (s *Server) executeDeepUpdate(ctx context.Context, entityName string, service EntityService, keys, data map[string]interface{}, original interface{}) (interface{}, error) {
+	metadata := service.GetMetadata()
+	root := original == nil
+
+	// A condição do If-Match vale apenas para a entidade endereçada
+	relatedCtx := WithConcurrencyCondition(ctx, nil)
+
+	flat := make(map[string]interface{}, len(data))
+	for name, value := range data {
+		flat[name] = value
+	}
+
+	var links []*deepInsertLink
+	var values [][]map[string]interface{}
+	var nested []nestedEntity
+
+	for _, prop := range metadata.Properties {
+		value, exists := data[prop.Name]
+		if !prop.IsNavigation || !exists {
+			continue
+		}
+		delete(flat, prop.Name)
+		if value == nil {
+			continue
+		}
+
+		link, err := s.buildDeepInsertLink(metadata, prop)
+		if err != nil {
+			return nil, err
+		}
+		_, relatedService, _ := s.findEntitySet(relatedEntityType(prop))
+
+		items := nestedItems(value)
+		if link.joinTable == nil && isPrincipalNavigation(metadata, relatedService.GetMetadata(), link) {
+			// A entidade referenciada é gravada antes para que a chave estrangeira aponte para ela
+			child, err := s.upsertRelatedEntity(relatedCtx, link, items[0])
+			if err != nil {
+				return nil, err
+			}
+			flat[link.parentProperty] = entityValue(child, link.keyProperty)
+			nested = append(nested, nestedEntity{prop.Name, child})
+			continue
+		}
+
+		links = append(links, link)
+		values = append(values, items)
+	}
+
+	var updated interface{}
+	var err error
+	switch {
+	case len(flat) == 0 && root:
+		updated, err = service.Get(relatedCtx, keys)
+	case len(flat) == 0:
+		updated = original
+	default:
+		updated, err = s.modifyEntity(ctx, entityName, service, keys, flat, original)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i, link := range links {
+		parentValue := entityValue(updated, link.parentProperty)
+		if parentValue == nil {
+			return nil, fmt.Errorf("%s has no value for '%s' to relate '%s'", entityName, link.parentProperty, link.navigation.Name)
+		}
+
+		children, err := s.mergeRelatedCollection(relatedCtx, entityName, service, updated, link, parentValue, values[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if link.navigation.IsCollection {
+			nested = append(nested, nestedEntity{link.navigation.Name, children})
+		} else if len(children) > 0 {
+			nested = append(nested, nestedEntity{link.navigation.Name, children[0]})
+		}
+	}
+
+	return withNestedEntities(updated, nested), nil
+}
+
+// mergeRelatedCollection faz o upsert dos membros informados e trata os membros ausentes conforme o cascade
+func (s *Server) mergeRelatedCollection(ctx context.Context, entityName string, service EntityService, parent interface{}, link *deepInsertLink, parentValue interface{}, items []map[string]interface{}) ([]interface{}, error) {
+	relatedName, relatedService, _ := s.findEntitySet(relatedEntityType(link.navigation))
+	related := relatedService.GetMetadata()
+
+	existing, err := s.currentRelatedEntities(ctx, service, parent, link, parentValue)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]interface{}, len(existing))
+	for _, entity := range existing {
+		current[keyString(related, entity)] = entity
+	}
+
+	references, _ := service.(ReferenceService)
+	if link.joinTable != nil && references == nil {
+		return nil, fmt.Errorf("'%s' does not support join table references", entityName)
+	}
+
+	children := make([]interface{}, 0, len(items))
+	kept := make(map[string]bool, len(items))
+	for _, item := range items {
+		if link.joinTable == nil {
+			item[link.keyProperty] = parentValue
+		}
+
+		key := keyString(related, item)
+		if original, exists := current[key]; exists && key != "" {
+			kept[key] = true
+			child, err := s.executeDeepUpdate(ctx, relatedName, relatedService, payloadKeys(related, original), item, original)
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, child)
+			continue
+		}
+
+		child, err := s.upsertRelatedEntity(ctx, link, item)
+		if err != nil {
+			return nil, err
+		}
+		if link.joinTable != nil {
+			if err := references.LinkEntities(ctx, link.navigation.Name, parentValue, entityValue(child, link.keyProperty)); err != nil {
+				return nil, err
+			}
+		}
+		kept[keyString(related, child)] = true
+		children = append(children, child)
+	}
+
+	if !link.navigation.HasCascade(CascadeRemoveOrphan) {
+		return children, nil
+	}
+
+	for _, entity := range existing {
+		key := keyString(related, entity)
+		if kept[key] {
+			continue
+		}
+		if link.joinTable != nil {
+			if err := references.UnlinkEntities(ctx, link.navigation.Name, parentValue, entityValue(entity, link.keyProperty)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := s.executeCascadeDelete(ctx, relatedName, relatedService, payloadKeys(related, entity), entity); err != nil {
+			return nil, err
+		}
+	}
+
+	return children, nil
+}
+
+// upsertRelatedEntity atualiza a entidade aninhada quando a chave informada existe, ou a cria.
+// Só a ausência da entidade leva à criação; outras falhas da leitura interrompem a gravação
+func (s *Server) upsertRelatedEntity(ctx context.Context, link *deepInsertLink, item map[string]interface{}) (interface{}, error) {
+	relatedName, relatedService, _ := s.findEntitySet(relatedEntityType(link.navigation))
+	related := relatedService.GetMetadata()
+
+	if keys := payloadKeys(related, item); keys != nil {
+		original, err := relatedService.Get(ctx, keys)
+		if err != nil && !strings.Contains(err.Error(), "not found") {
+			return nil, err
+		}
+		if err == nil && original != nil {
+			return s.executeDeepUpdate(ctx, relatedName, relatedService, keys, item, original)
+		}
+	}
+
+	plan, err := s.buildDeepInsertPlan(relatedName, relatedService, item)
+	if err != nil {
+		return nil, err
+	}
+	return s.executeDeepInsert(ctx, plan)
+}
+
+// currentRelatedEntities carrega os membros atuais de uma navegação
+func (s *Server) currentRelatedEntities(ctx context.Context, service EntityService, parent interface{}, link *deepInsertLink, parentValue interface{}) ([]interface{}, error) {
+	if link.joinTable != nil {
+		references, ok := service.(ReferenceService)
+		parentEntity, isOrdered := parent.(*OrderedEntity)
+		if !ok || !isOrdered {
+			return nil, fmt.Errorf("navigation '%s' cannot be loaded for cascade", link.navigation.Name)
+		}
+		entities, _, err := references.QueryNavigation(ctx, parentEntity, link.navigation.Name, ExpandOption{})
+		return entities, err
+	}
+
+	_, relatedService, _ := s.findEntitySet(relatedEntityType(link.navigation))
+	response, err := relatedService.Query(ctx, QueryOptions{
+		Filter: &GoDataFilterQuery{Tree: keysetComparison("eq", link.keyProperty, parentValue)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	entities, _ := response.Value.([]interface{})
+	return entities, nil
+}
+
+// hasCascadeRemove verifica se alguma navegação da entidade tem cascade Remove
+func hasCascadeRemove(metadata EntityMetadata) bool {
+	for _, prop := range metadata.Properties {
+		if prop.IsNavigation && prop.HasCascade(CascadeRemove) {
+			return true
+		}
+	}
+	return false
+}
+
+// cascadeDelete remove a entidade e as entidades relacionadas com cascade Remove na mesma transação
+func (s *Server) cascadeDelete(ctx context.Context, provider DatabaseProvider, service EntityService, keys map[string]interface{}) error {
+	_, err := s.runInTransaction(ctx, provider, func(ctx context.Context) (interface{}, error) {
+		entity, err := service.Get(WithConcurrencyCondition(ctx, nil), keys)
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.executeCascadeDelete(ctx, "", service, keys, entity)
+	})
+	return err
+}
+
+// executeCascadeDelete remove primeiro as entidades dependentes das navegações com cascade Remove
+// (desvinculando as N:N) e depois a entidade. entityName vazio indica a entidade endereçada pela requisição.
+func (s *Server) executeCascadeDelete(ctx context.Context, entityName string, service EntityService, keys map[string]interface{}, entity interface{}) error {
+	metadata := service.GetMetadata()
+	relatedCtx := WithConcurrencyCondition(ctx, nil)
+
+	for _, prop := range metadata.Properties {
+		if !prop.IsNavigation || !prop.HasCascade(CascadeRemove) {
+			continue
+		}
+
+		link, err := s.buildDeepInsertLink(metadata, prop)
+		if err != nil {
+			return err
+		}
+		relatedName, relatedService, _ := s.findEntitySet(relatedEntityType(prop))
+		related := relatedService.GetMetadata()
+
+		// A entidade referenciada (N:1) não pertence à entidade removida
+		if link.joinTable == nil && isPrincipalNavigation(metadata, related, link) {
+			continue
+		}
+
+		parentValue := entityValue(entity, link.parentProperty)
+		if parentValue == nil {
+			continue
+		}
+
+		children, err := s.currentRelatedEntities(relatedCtx, service, entity, link, parentValue)
+		if err != nil {
+			return err
+		}
+
+		for _, child := range children {
+			if link.joinTable != nil {
+				references, ok := service.(ReferenceService)
+				if !ok {
+					return fmt.Errorf("'%s' does not support join table references", s.entitySetNameOf(metadata))
+				}
+				if err := references.UnlinkEntities(relatedCtx, prop.Name, parentValue, entityValue(child, link.keyProperty)); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := s.executeCascadeDelete(relatedCtx, relatedName, relatedService, payloadKeys(related, child), child); err != nil {
+				return err
+			}
+		}
+	}
+
+	if entityName == "" {
+		return s.removeEntity(ctx, s.entitySetNameOf(metadata), service, keys, entity, false)
+	}
+	return s.removeEntity(ctx, entityName, service, keys, entity, true)
+}
+
+// nestedItems normaliza o valor de uma navegação aninhada em uma lista de objetos
+func nestedItems(value interface{}) []map[string]interface{} {
+	var items []map[string]interface{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		items = append(items, v)
+	case []interface{}:
+		for _, item := range v {
+			if m, ok := item.(map[string]interface{}); ok {
+				items = append(items, m)
+			}
+		}
+	}
+	return items
+}
+
+// payloadKeys retorna as chaves da entidade, ou nil se alguma chave não foi informada
+func payloadKeys(metadata EntityMetadata, entity interface{}) map[string]interface{} {
+	keys := make(map[string]interface{})
+	for _, prop := range metadata.Properties {
+		if !prop.IsKey {
+			continue
+		}
+		value := entityValue(entity, prop.Name)
+		if value == nil {
+			return nil
+		}
+		keys[prop.Name] = value
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return keys
+}
+
+// keyString identifica a entidade pelos valores da chave, independente do tipo numérico do JSON
+func keyString(metadata EntityMetadata, entity interface{}) string {
+	keys := payloadKeys(metadata, entity)
+	if keys == nil {
+		return ""
+	}
+	var parts []string
+	for _, prop := range metadata.Properties {
+		if prop.IsKey {
+			parts = append(parts, fmt.Sprint(keys[prop.Name]))
+		}
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,147 @@
+package odata
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type CascadeTestOrder struct {
+	TableName string               `table:"orders"`
+	ID        int64                `json:"ID" column:"id" primaryKey:"idGenerator:sequence; name=seq_orders"`
+	Note      string               `json:"Note" column:"note"`
+	Lines     []DeepInsertTestLine `json:"Lines" manyAssociation:"foreignKey:order_id; references:id" cascade:"[SaveUpdate, Remove, RemoveOrphan]"`
+}
+
+func newCascadeTestServer(t *testing.T) (*Server, deepInsertTestServices, *fakeDB) {
+	db := newFakeDB(t, nil)
+	server := newTestServer(&MockDatabaseProvider{connection: db.DB})
+	services := deepInsertTestServices{
+		orders: newDeepInsertTestService(t, CascadeTestOrder{}, 100),
+		lines:  newDeepInsertTestService(t, DeepInsertTestLine{}, 1000),
+	}
+	services.orders.seed(map[string]interface{}{"ID": int64(10), "Note": "old"})
+	services.lines.seed(map[string]interface{}{"ID": int64(1), "OrderID": int64(10), "Product": "chair"})
+	services.lines.seed(map[string]interface{}{"ID": int64(2), "OrderID": int64(10), "Product": "table"})
+	services.lines.seed(map[string]interface{}{"ID": int64(3), "OrderID": int64(20), "Product": "lamp"})
+
+	require.NoError(t, server.RegisterEntityWithService("Orders", services.orders))
+	require.NoError(t, server.RegisterEntityWithService("OrderLines", services.lines))
+
+	return server, services, db
+}
+
+func TestServer_DeepUpdate(t *testing.T) {
+	t.Run("merges nested collection", func(t *testing.T) {
+		server, services, db := newCascadeTestServer(t)
+
+		body := `{"Note": "new", "Lines": [{"ID": 1, "Product": "sofa"}, {"Product": "rug"}]}`
+		req := httptest.NewRequest("PATCH", "/odata/Orders(10)", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.GetRouter().Test(req)
+		require.NoError(t, err)
+		require.Equal(t, 200, resp.StatusCode)
+
+		// A linha existente é atualizada, a nova é criada e a órfã removida (RemoveOrphan)
+		assert.Equal(t, "new", entityValue(services.orders.find(10), "Note"))
+		assert.Equal(t, "sofa", entityValue(services.lines.find(1), "Product"))
+		require.Len(t, services.lines.created, 1)
+		assert.EqualValues(t, 10, services.lines.created[0]["OrderID"])
+		assert.Equal(t, []int64{2}, services.lines.deleted)
+		assert.NotNil(t, services.lines.find(3))
+		assert.Equal(t, 1, db.commits)
+	})
+
+	t.Run("read failure does not create", func(t *testing.T) {
+		server, services, db := newCascadeTestServer(t)
+		services.lines.getErr = fmt.Errorf("connection reset")
+
+		req := httptest.NewRequest("PATCH", "/odata/Orders(10)", strings.NewReader(`{"Lines": [{"ID": 3, "Product": "lamp"}]}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.GetRouter().Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, 500, resp.StatusCode)
+
+		// Só a ausência da linha leva à criação; a falha da leitura desfaz a gravação
+		assert.Empty(t, services.lines.created)
+		assert.Empty(t, services.lines.deleted)
+		assert.Equal(t, 0, db.commits)
+		assert.Equal(t, 1, db.rollbacks)
+	})
+
+	t.Run("requires SaveUpdate", func(t *testing.T) {
+		server, services, _ := newDeepInsertTestServer(t)
+		services.orders.seed(map[string]interface{}{"ID": int64(10)})
+
+		req := httptest.NewRequest("PATCH", "/odata/Orders(10)", strings.NewReader(`{"Lines": [{"Product": "rug"}]}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.GetRouter().Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+		assert.Empty(t, services.lines.created)
+	})
+}
+
+func TestServer_CascadeDelete(t *testing.T) {
+	t.Run("removes dependents with events", func(t *testing.T) {
+		server, services, db := newCascadeTestServer(t)
+
+		var cascaded []interface{}
+		server.OnEntityDeleting("OrderLines", func(args EventArgs) error {
+			deleting := args.(*EntityDeletingArgs)
+			assert.True(t, deleting.CascadeDelete)
+			cascaded = append(cascaded, deleting.Keys["ID"])
+			return nil
+		})
+
+		resp, err := server.GetRouter().Test(httptest.NewRequest("DELETE", "/odata/Orders(10)", nil))
+		require.NoError(t, err)
+		require.Equal(t, 204, resp.StatusCode)
+
+		assert.Equal(t, []int64{1, 2}, services.lines.deleted)
+		assert.Equal(t, []int64{10}, services.orders.deleted)
+		assert.Len(t, cascaded, 2)
+		assert.Equal(t, 1, db.commits)
+	})
+
+	t.Run("canceled event rolls back", func(t *testing.T) {
+		server, services, db := newCascadeTestServer(t)
+		server.OnEntityDeleting("OrderLines", func(args EventArgs) error {
+			args.Cancel("line is locked")
+			return nil
+		})
+
+		resp, err := server.GetRouter().Test(httptest.NewRequest("DELETE", "/odata/Orders(10)", nil))
+		require.NoError(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+		assert.Empty(t, services.orders.deleted)
+		assert.Equal(t, 0, db.commits)
+		assert.Equal(t, 1, db.rollbacks)
+	})
+}
+
+func TestServer_CascadeUsesServiceProvider(t *testing.T) {
+	server, _, db := newCascadeTestServer(t)
+	orders, ordersDB := newConstraintTestProvider(t)
+	metadata, err := MapEntityFromStruct(CascadeTestOrder{})
+	require.NoError(t, err)
+	require.NoError(t, server.RegisterEntityWithService("Orders", NewBaseEntityService(orders, metadata, server)))
+
+	// A atualização com vínculos e a exclusão em cascata abrem a transação no banco de Orders
+	status, _ := sendLifecycleRequest(t, server, "PATCH", "/odata/Orders(10)", `{"Note": "new", "Lines@odata.bind": ["OrderLines(3)"]}`)
+	require.Equal(t, 200, status)
+	assert.Equal(t, []string{"BEGIN", "UPDATE users", "COMMIT"}, ordersDB.entries())
+
+	ordersDB.log = nil
+	status, _ = sendLifecycleRequest(t, server, "DELETE", "/odata/Orders(10)", "")
+	require.Equal(t, 204, status)
+	entries := ordersDB.entries()
+	require.NotEmpty(t, entries)
+	assert.Equal(t, "BEGIN", entries[0])
+	assert.Equal(t, "COMMIT", entries[len(entries)-1])
+	assert.Empty(t, db.entries())
+}
@@ -0,0 +1,105 @@
+package odata
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// PropertyConstraintError indica que um valor viola uma restrição declarada na tag prop (Required, Unique)
+type PropertyConstraintError struct {
+	Entity     string
+	Property   string
+	Constraint PropFlag
+	Value      interface{}
+}
+
+func (e *PropertyConstraintError) Error() string {
+	if e.Constraint == PropUnique {
+		return fmt.Sprintf("value '%v' of property '%s' already exists in %s", e.Value, e.Property, e.Entity)
+	}
+	return fmt.Sprintf("property '%s' of %s is required", e.Property, e.Entity)
+}
+
+// removeNonWritableProperties descarta as propriedades NoInsert (inserção) ou NoUpdate (atualização)
+func (s *BaseEntityService) removeNonWritableProperties(data map[string]any, insert bool) {
+	for _, prop := range s.metadata.Properties {
+		if _, exists := data[prop.Name]; !exists || prop.IsNavigation {
+			continue
+		}
+		if (insert && !prop.IsInsertable()) || (!insert && !prop.IsKey && !prop.IsUpdatable()) {
+			delete(data, prop.Name)
+		}
+	}
+}
+
+// checkUniqueProperties consulta se outra entidade já usa o valor das propriedades Unique.
+// Na atualização, keys exclui a própria entidade da verificação
+func (s *BaseEntityService) checkUniqueProperties(ctx context.Context, data, keys map[string]any) error {
+	for _, prop := range s.metadata.Properties {
+		if prop.IsNavigation || !prop.HasPropFlag(PropUnique) {
+			continue
+		}
+		value, exists := data[prop.Name]
+		if !exists || value == nil {
+			continue
+		}
+
+		condition := keysetComparison("eq", prop.Name, value)
+		if other := otherEntityCondition(s.metadata, keys); other != nil {
+			condition = keysetLogical("and", condition, other)
+		}
+
+		response, err := s.Query(ctx, QueryOptions{Filter: &GoDataFilterQuery{Tree: condition}})
+		if err != nil {
+			return fmt.Errorf("failed to check unique property '%s': %w", prop.Name, err)
+		}
+		if results, ok := response.Value.([]any); ok && len(results) > 0 {
+			return &PropertyConstraintError{Entity: s.metadata.Name, Property: prop.Name, Constraint: PropUnique, Value: value}
+		}
+	}
+	return nil
+}
+
+// otherEntityCondition monta a condição que exclui a entidade das chaves informadas
+func otherEntityCondition(metadata EntityMetadata, keys map[string]any) *ParseNode {
+	var condition *ParseNode
+	for _, prop := range metadata.Properties {
+		value, ok := keys[prop.Name]
+		if !prop.IsKey || !ok {
+			continue
+		}
+		comparison := keysetComparison("ne", prop.Name, value)
+		if condition == nil {
+			condition = comparison
+		} else {
+			condition = keysetLogical("or", condition, comparison)
+		}
+	}
+	return condition
+}
+
+// isGeneratedKey indica se a chave é gerada pelo servidor ou pelo banco
+func isGeneratedKey(prop PropertyMetadata) bool {
+	return prop.IsKey && prop.IDGenerator != "" && prop.IDGenerator != string(IDGeneratorNone)
+}
+
+// writeConstraintError responde a violação de uma restrição com o alvo apontando para a propriedade:
+// 409 para Unique e 400 para Required
+func (s *Server) writeConstraintError(c fiber.Ctx, err *PropertyConstraintError) {
+	status, code := fiber.StatusBadRequest, "RequiredProperty"
+	if err.Constraint == PropUnique {
+		status, code = fiber.StatusConflict, "UniqueViolation"
+	}
+
+	c.Set("Content-Type", "application/json")
+	c.Status(status)
+	c.JSON(ODataResponse{
+		Error: &ODataError{
+			Code:    code,
+			Message: err.Error(),
+			Target:  err.Property,
+		},
+	})
+}
@@ -0,0 +1,201 @@
+package odata
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ConstraintTestUser struct {
+	ID      string `json:"id" column:"id" primaryKey:"idGenerator:none"`
+	Email   string `json:"email" column:"email" prop:"[required, Unique]"`
+	Bio     string `json:"bio" column:"bio" prop:"[Lazy]"`
+	Created string `json:"created" column:"created" prop:"[NoUpdate]"`
+	Audit   string `json:"audit" column:"audit" prop:"[NoInsert, NoUpdate]"`
+}
+
+// constraintTestProvider registra os dados gravados e as condições da verificação de Unique
+type constraintTestProvider struct {
+	MockDatabaseProvider
+	inserted   map[string]interface{}
+	updated    map[string]interface{}
+	unique     []*ParseNode
+	duplicates int // Linhas devolvidas na verificação de Unique
+}
+
+// respond devolve um usuário nas leituras por chave e duplicates linhas na verificação de Unique
+func (p *constraintTestProvider) respond(query string) *fakeRows {
+	count := 1
+	if query == "SELECT unique" {
+		count = p.duplicates
+	}
+	rows := newFakeRows([]string{"id", "email"})
+	for i := 0; i < count; i++ {
+		rows.rows = append(rows.rows, []driver.Value{"u1", "ana@example.com"})
+	}
+	return rows
+}
+
+// newConstraintTestProvider cria o provider sobre um fakeDB que responde com respond
+func newConstraintTestProvider(t *testing.T) (*constraintTestProvider, *fakeDB) {
+	provider := &constraintTestProvider{}
+	db := newFakeDB(t, provider.respond)
+	provider.connection = db.DB
+	return provider, db
+}
+
+func (p *constraintTestProvider) BuildSelectQuery(metadata EntityMetadata, options QueryOptions) (string, []interface{}, error) {
+	if options.Filter != nil && options.Filter.Tree != nil && filterMentions(options.Filter.Tree, "email") {
+		p.unique = append(p.unique, options.Filter.Tree)
+		return "SELECT unique", nil, nil
+	}
+	return "SELECT users", nil, nil
+}
+
+func (p *constraintTestProvider) BuildInsertQuery(metadata EntityMetadata, data map[string]interface{}) (string, []interface{}, error) {
+	p.inserted = data
+	return "INSERT INTO users", nil, nil
+}
+
+func (p *constraintTestProvider) BuildUpdateQuery(metadata EntityMetadata, data map[string]interface{}, keys map[string]interface{}) (string, []interface{}, error) {
+	p.updated = data
+	return "UPDATE users", nil, nil
+}
+
+// filterMentions verifica se a árvore do filtro referencia a propriedade
+func filterMentions(node *ParseNode, property string) bool {
+	if node.Token != nil && node.Token.Value == property {
+		return true
+	}
+	for _, child := range node.Children {
+		if filterMentions(child, property) {
+			return true
+		}
+	}
+	return false
+}
+
+func newConstraintTestServer(t *testing.T) (*Server, *constraintTestProvider) {
+	provider, _ := newConstraintTestProvider(t)
+	server := newTestServer(provider)
+	require.NoError(t, server.RegisterEntity("Users", ConstraintTestUser{}))
+
+	return server, provider
+}
+
+func TestBaseEntityService_PropFlags(t *testing.T) {
+	server, provider := newConstraintTestServer(t)
+	service := server.entities["Users"].(*BaseEntityService)
+	ctx := context.Background()
+
+	t.Run("NoInsert is not written on create", func(t *testing.T) {
+		_, err := service.Create(ctx, map[string]interface{}{"id": "u1", "email": "ana@example.com", "created": "today", "audit": "x"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"id": "u1", "email": "ana@example.com", "created": "today"}, provider.inserted)
+	})
+
+	t.Run("NoUpdate is not written on update", func(t *testing.T) {
+		_, err := service.Update(ctx, map[string]interface{}{"id": "u1"}, map[string]interface{}{"bio": "hi", "created": "tomorrow", "audit": "x"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"bio": "hi"}, provider.updated)
+	})
+
+	t.Run("Required", func(t *testing.T) {
+		// A validação é aplicada pelo servidor, antes dos handlers de gravação
+		var validation *ValidationError
+
+		_, err := server.insertEntity(ctx, "Users", service, map[string]interface{}{"id": "u2"})
+		require.ErrorAs(t, err, &validation)
+		require.Len(t, validation.Details, 1)
+		assert.Equal(t, "RequiredProperty", validation.Details[0].Code)
+		assert.Equal(t, "email", validation.Details[0].Target)
+
+		_, err = server.modifyEntity(ctx, "Users", service, map[string]interface{}{"id": "u1"}, map[string]interface{}{"email": nil}, nil)
+		require.ErrorAs(t, err, &validation)
+
+		// Na atualização parcial a propriedade pode ser omitida
+		_, err = server.modifyEntity(ctx, "Users", service, map[string]interface{}{"id": "u1"}, map[string]interface{}{"bio": "hi"}, nil)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Unique excludes the entity being updated", func(t *testing.T) {
+		provider.unique = nil
+		_, err := service.Update(ctx, map[string]interface{}{"id": "u1"}, map[string]interface{}{"email": "ana@example.com"})
+		require.NoError(t, err)
+		require.Len(t, provider.unique, 1)
+		assert.Equal(t, "and", provider.unique[0].Token.Value)
+		assert.Equal(t, "ne", provider.unique[0].Children[1].Token.Value)
+	})
+}
+
+func TestServer_PropFlagErrors(t *testing.T) {
+	server, provider := newConstraintTestServer(t)
+	provider.duplicates = 1
+
+	req := httptest.NewRequest("POST", "/odata/Users", strings.NewReader(`{"id": "u2", "email": "ana@example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := server.GetRouter().Test(req)
+	require.NoError(t, err)
+	require.Equal(t, 409, resp.StatusCode)
+
+	var body ODataResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.NotNil(t, body.Error)
+	assert.Equal(t, "UniqueViolation", body.Error.Code)
+	assert.Equal(t, "email", body.Error.Target)
+
+	req = httptest.NewRequest("POST", "/odata/Users", strings.NewReader(`{"id": "u2"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = server.GetRouter().Test(req)
+	require.NoError(t, err)
+	require.Equal(t, 400, resp.StatusCode)
+
+	body = ODataResponse{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "ValidationFailed", body.Error.Code)
+	require.Len(t, body.Error.Details, 1)
+	assert.Equal(t, "RequiredProperty", body.Error.Details[0].Code)
+	assert.Equal(t, "email", body.Error.Details[0].Target)
+}
+
+func TestQueryBuilder_LazyProperties(t *testing.T) {
+	metadata, err := NewEntityMapper().MapEntity(ConstraintTestUser{})
+	require.NoError(t, err)
+
+	qb := NewQueryBuilder("postgresql")
+	assert.Equal(t, "id, email, created, audit", qb.BuildSelectClause(metadata, nil))
+	assert.Equal(t, "id, bio", qb.BuildSelectClause(metadata, []string{"id", "bio"}))
+}
+
+func TestServer_PropFlagsMetadata(t *testing.T) {
+	server, _ := newConstraintTestServer(t)
+
+	resp, err := server.GetRouter().Test(httptest.NewRequest("GET", "/odata/$metadata", nil))
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	raw, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	xmlBody := string(raw)
+
+	assert.Contains(t, xmlBody, `<Annotation Term="Core.Immutable" Bool="true"></Annotation>`)
+	assert.Contains(t, xmlBody, `<Annotation Term="Core.Computed" Bool="true"></Annotation>`)
+	assert.Contains(t, xmlBody, `<Property Name="email" Type="Edm.String" Nullable="false"></Property>`)
+
+	resp, err = server.GetRouter().Test(httptest.NewRequest("GET", "/odata/$metadata?$format=json", nil))
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+
+	var document map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&document))
+	entityType := document["Default"].(map[string]interface{})["ConstraintTestUser"].(map[string]interface{})
+	assert.Equal(t, true, entityType["audit"].(map[string]interface{})["@Core.Computed"])
+	assert.Equal(t, true, entityType["created"].(map[string]interface{})["@Core.Immutable"])
+	assert.NotContains(t, entityType["email"].(map[string]interface{}), "@Core.Computed")
+}
@@ -0,0 +1,254 @@
+package odata
+
+import (
+	"context"
+	"fmt"
+)
+
+// deepInsertNode é uma entidade do corpo de um deep insert com as entidades aninhadas
+type deepInsertNode struct {
+	entityName string
+	service    EntityService
+	data       map[string]interface{} // Propriedades estruturais da entidade
+	principals []*deepInsertLink      // Navegações que a entidade referencia (N:1), inseridas antes
+	dependents []*deepInsertLink      // Navegações que referenciam a entidade (1:N, N:N), inseridas depois
+}
+
+// deepInsertLink liga uma entidade às entidades aninhadas de uma navegação
+type deepInsertLink struct {
+	navigation     PropertyMetadata
+	parentProperty string                   // Propriedade da entidade de origem
+	keyProperty    string                   // Propriedade da entidade aninhada comparada com parentProperty
+	joinTable      *ManyAssociationMetadata // Tabela de junção (N:N)
+	nodes          []*deepInsertNode
+}
+
+// hasNestedEntities verifica se o corpo traz entidades aninhadas em propriedades de navegação
+func hasNestedEntities(metadata EntityMetadata, data map[string]interface{}) bool {
+	for _, prop := range metadata.Properties {
+		if !prop.IsNavigation {
+			continue
+		}
+		switch data[prop.Name].(type) {
+		case map[string]interface{}, []interface{}:
+			return true
+		}
+	}
+	return false
+}
+
+// buildDeepInsertPlan separa o corpo em entidades e resolve a direção de cada relacionamento
+func (s *Server) buildDeepInsertPlan(entityName string, service EntityService, data map[string]interface{}) (*deepInsertNode, error) {
+	metadata := service.GetMetadata()
+	node := &deepInsertNode{entityName: entityName, service: service, data: make(map[string]interface{}, len(data))}
+
+	for name, value := range data {
+		node.data[name] = value
+	}
+
+	// As navegações são percorridas na ordem dos metadados para manter a resposta estável
+	for i := range metadata.Properties {
+		prop := &metadata.Properties[i]
+		if !prop.IsNavigation {
+			continue
+		}
+		value, exists := data[prop.Name]
+		if !exists {
+			continue
+		}
+		delete(node.data, prop.Name)
+		if value == nil {
+			continue
+		}
+
+		link, err := s.buildDeepInsertLink(metadata, *prop)
+		if err != nil {
+			return nil, err
+		}
+
+		var items []interface{}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if prop.IsCollection {
+				return nil, fmt.Errorf("navigation property '%s' expects an array", prop.Name)
+			}
+			items = []interface{}{v}
+		case []interface{}:
+			if !prop.IsCollection {
+				return nil, fmt.Errorf("navigation property '%s' expects an object", prop.Name)
+			}
+			items = v
+		default:
+			return nil, fmt.Errorf("invalid value for navigation property '%s'", prop.Name)
+		}
+
+		relatedName, relatedService, _ := s.findEntitySet(relatedEntityType(*prop))
+		for _, item := range items {
+			nested, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("navigation property '%s' expects entity objects", prop.Name)
+			}
+			child, err := s.buildDeepInsertPlan(relatedName, relatedService, nested)
+			if err != nil {
+				return nil, err
+			}
+			link.nodes = append(link.nodes, child)
+		}
+
+		if link.joinTable == nil && isPrincipalNavigation(metadata, relatedService.GetMetadata(), link) {
+			node.principals = append(node.principals, link)
+		} else {
+			node.dependents = append(node.dependents, link)
+		}
+	}
+
+	return node, nil
+}
+
+// buildDeepInsertLink resolve as propriedades que ligam a entidade às entidades da navegação
+func (s *Server) buildDeepInsertLink(metadata EntityMetadata, prop PropertyMetadata) (*deepInsertLink, error) {
+	_, relatedService, exists := s.findEntitySet(relatedEntityType(prop))
+	if !exists {
+		return nil, fmt.Errorf("related entity of '%s' is not registered", prop.Name)
+	}
+	related := relatedService.GetMetadata()
+
+	// As propriedades da ligação não dependem do dialeto, e no modo multi-tenant não há provider padrão
+	source, err := (&QueryBuilder{}).BuildExpandSource(metadata, prop, related)
+	if err != nil {
+		return nil, err
+	}
+
+	link := &deepInsertLink{navigation: prop, parentProperty: source.ParentProperty}
+	if many := prop.ManyAssociation; many != nil && many.JoinTable != "" {
+		link.joinTable = many
+		link.keyProperty = expandKeyProperty(related)
+	} else {
+		link.keyProperty = expandPropertyName(related, source.KeyColumn, "")
+	}
+	return link, nil
+}
+
+// relatedEntityType retorna o tipo da entidade referenciada pela navegação
+func relatedEntityType(prop PropertyMetadata) string {
+	if prop.RelatedType == "" && prop.ManyAssociation != nil {
+		return prop.ManyAssociation.RelatedEntity
+	}
+	return prop.RelatedType
+}
+
+// isPrincipalNavigation indica se a entidade aninhada precisa existir antes da entidade de origem,
+// ou seja, se a origem guarda a chave estrangeira que aponta para a chave da entidade aninhada
+func isPrincipalNavigation(metadata, related EntityMetadata, link *deepInsertLink) bool {
+	if link.navigation.IsCollection {
+		return false
+	}
+	return isKeyProperty(related, link.keyProperty) && !isKeyProperty(metadata, link.parentProperty)
+}
+
+// isKeyProperty verifica se a propriedade faz parte da chave da entidade
+func isKeyProperty(metadata EntityMetadata, name string) bool {
+	for _, prop := range metadata.Properties {
+		if prop.Name == name {
+			return prop.IsKey
+		}
+	}
+	return false
+}
+
+// deepInsert cria a entidade e as entidades aninhadas na mesma transação
+func (s *Server) deepInsert(ctx context.Context, provider DatabaseProvider, plan *deepInsertNode) (interface{}, error) {
+	return s.runInTransaction(ctx, provider, func(ctx context.Context) (interface{}, error) {
+		return s.executeDeepInsert(ctx, plan)
+	})
+}
+
+// executeDeepInsert insere as entidades referenciadas, a entidade e as dependentes, propagando as chaves
+func (s *Server) executeDeepInsert(ctx context.Context, node *deepInsertNode) (interface{}, error) {
+	var nested []nestedEntity
+
+	for _, link := range node.principals {
+		child, err := s.executeDeepInsert(ctx, link.nodes[0])
+		if err != nil {
+			return nil, err
+		}
+		node.data[link.parentProperty] = entityValue(child, link.keyProperty)
+		nested = append(nested, nestedEntity{link.navigation.Name, child})
+	}
+
+	created, err := s.insertEntity(ctx, node.entityName, node.service, node.data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", node.entityName, err)
+	}
+
+	for _, link := range node.dependents {
+		parentValue := entityValue(created, link.parentProperty)
+		if parentValue == nil {
+			return nil, fmt.Errorf("%s has no value for '%s' to relate '%s'", node.entityName, link.parentProperty, link.navigation.Name)
+		}
+
+		children := make([]interface{}, 0, len(link.nodes))
+		for _, childNode := range link.nodes {
+			if link.joinTable == nil {
+				childNode.data[link.keyProperty] = parentValue
+			}
+
+			child, err := s.executeDeepInsert(ctx, childNode)
+			if err != nil {
+				return nil, err
+			}
+
+			if link.joinTable != nil {
+				references, ok := node.service.(ReferenceService)
+				if !ok {
+					return nil, fmt.Errorf("'%s' does not support join table references", node.entityName)
+				}
+				if err := references.LinkEntities(ctx, link.navigation.Name, parentValue, entityValue(child, link.keyProperty)); err != nil {
+					return nil, err
+				}
+			}
+			children = append(children, child)
+		}
+
+		if link.navigation.IsCollection {
+			nested = append(nested, nestedEntity{link.navigation.Name, children})
+		} else if len(children) > 0 {
+			nested = append(nested, nestedEntity{link.navigation.Name, children[0]})
+		}
+	}
+
+	return withNestedEntities(created, nested), nil
+}
+
+// entityValue lê uma propriedade de uma entidade criada
+func entityValue(entity interface{}, name string) interface{} {
+	switch e := entity.(type) {
+	case *OrderedEntity:
+		value, _ := e.Get(name)
+		return value
+	case map[string]interface{}:
+		return e[name]
+	}
+	return nil
+}
+
+// nestedEntity é o resultado de uma navegação do deep insert
+type nestedEntity struct {
+	name  string
+	value interface{}
+}
+
+// withNestedEntities anexa as entidades aninhadas criadas à entidade de origem
+func withNestedEntities(entity interface{}, nested []nestedEntity) interface{} {
+	switch e := entity.(type) {
+	case *OrderedEntity:
+		for _, n := range nested {
+			e.Set(n.name, n.value)
+		}
+	case map[string]interface{}:
+		for _, n := range nested {
+			e[n.name] = n.value
+		}
+	}
+	return entity
+}
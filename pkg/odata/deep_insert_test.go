@@ -0,0 +1,281 @@
+package odata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type DeepInsertTestLine struct {
+	TableName string `table:"order_lines"`
+	ID        int64  `json:"ID" column:"id" primaryKey:"idGenerator:sequence; name=seq_lines"`
+	OrderID   int64  `json:"OrderID" column:"order_id"`
+	Product   string `json:"Product" column:"product"`
+}
+
+type DeepInsertTestOrder struct {
+	TableName  string                  `table:"orders"`
+	ID         int64                   `json:"ID" column:"id" primaryKey:"idGenerator:sequence; name=seq_orders"`
+	CustomerID int64                   `json:"CustomerID" column:"customer_id"`
+	Customer   *NavigationTestCustomer `json:"Customer" association:"foreignKey:customer_id; references:id"`
+	Lines      []DeepInsertTestLine    `json:"Lines" manyAssociation:"foreignKey:order_id; references:id"`
+}
+
+// deepInsertTestService é um EntityService em memória que gera chaves e registra se recebeu transação
+type deepInsertTestService struct {
+	mu       sync.Mutex
+	metadata EntityMetadata
+	nextID   int64
+	rows     []*OrderedEntity
+	created  []map[string]interface{}
+	updated  []map[string]interface{}
+	deleted  []int64
+	inTx     []bool
+	getErr   error
+}
+
+func newDeepInsertTestService(t *testing.T, entity interface{}, firstID int64) *deepInsertTestService {
+	metadata, err := MapEntityFromStruct(entity)
+	require.NoError(t, err)
+	return &deepInsertTestService{metadata: metadata, nextID: firstID}
+}
+
+// seed adiciona uma linha existente ao serviço
+func (s *deepInsertTestService) seed(values map[string]interface{}) {
+	row := NewOrderedEntity()
+	for _, prop := range s.metadata.Properties {
+		if value, exists := values[prop.Name]; exists {
+			row.Set(prop.Name, value)
+		}
+	}
+	s.rows = append(s.rows, row)
+}
+
+func (s *deepInsertTestService) find(id interface{}) *OrderedEntity {
+	for _, row := range s.rows {
+		if value, _ := row.Get("ID"); fmt.Sprint(value) == fmt.Sprint(id) {
+			return row
+		}
+	}
+	return nil
+}
+
+func (s *deepInsertTestService) GetMetadata() EntityMetadata { return s.metadata }
+
+// Query entende apenas a comparação de igualdade usada para carregar os membros de uma navegação
+func (s *deepInsertTestService) Query(ctx context.Context, options QueryOptions) (*ODataResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := make([]interface{}, 0, len(s.rows))
+	for _, row := range s.rows {
+		if options.Filter != nil && options.Filter.Tree != nil {
+			children := options.Filter.Tree.Children
+			value, _ := row.Get(children[0].Token.Value)
+			if fmt.Sprint(value) != children[1].Token.Value {
+				continue
+			}
+		}
+		results = append(results, row)
+	}
+	return &ODataResponse{Value: results}, nil
+}
+
+func (s *deepInsertTestService) Get(ctx context.Context, keys map[string]interface{}) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.getErr != nil {
+		return nil, s.getErr
+	}
+	if row := s.find(keys["ID"]); row != nil {
+		return row, nil
+	}
+	return nil, fmt.Errorf("entity not found")
+}
+
+func (s *deepInsertTestService) Create(ctx context.Context, entity interface{}) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inTx = append(s.inTx, ctx.Value(TxContextKey) != nil)
+
+	data := entity.(map[string]interface{})
+	if data["Product"] == "fail" {
+		return nil, fmt.Errorf("forced failure")
+	}
+
+	s.nextID++
+	created := NewOrderedEntity()
+	created.Set("ID", s.nextID)
+	for _, prop := range s.metadata.Properties {
+		if value, exists := data[prop.Name]; exists && !prop.IsKey && !prop.IsNavigation {
+			created.Set(prop.Name, value)
+		}
+	}
+	s.created = append(s.created, data)
+	s.rows = append(s.rows, created)
+	return created, nil
+}
+
+func (s *deepInsertTestService) Update(ctx context.Context, keys map[string]interface{}, entity interface{}) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inTx = append(s.inTx, ctx.Value(TxContextKey) != nil)
+
+	row := s.find(keys["ID"])
+	if row == nil {
+		return nil, fmt.Errorf("entity not found")
+	}
+	data := entity.(map[string]interface{})
+	for _, prop := range s.metadata.Properties {
+		if value, exists := data[prop.Name]; exists && !prop.IsKey && !prop.IsNavigation {
+			row.Set(prop.Name, value)
+		}
+	}
+	s.updated = append(s.updated, data)
+	return row, nil
+}
+
+func (s *deepInsertTestService) Delete(ctx context.Context, keys map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inTx = append(s.inTx, ctx.Value(TxContextKey) != nil)
+
+	for i, row := range s.rows {
+		if value, _ := row.Get("ID"); fmt.Sprint(value) == fmt.Sprint(keys["ID"]) {
+			s.rows = append(s.rows[:i], s.rows[i+1:]...)
+			s.deleted = append(s.deleted, value.(int64))
+			return nil
+		}
+	}
+	return fmt.Errorf("entity not found")
+}
+
+type deepInsertTestServices struct {
+	customers *deepInsertTestService
+	orders    *deepInsertTestService
+	lines     *deepInsertTestService
+}
+
+func newDeepInsertTestServer(t *testing.T) (*Server, deepInsertTestServices, *fakeDB) {
+	db := newFakeDB(t, nil)
+	server := newTestServer(&MockDatabaseProvider{connection: db.DB})
+	return server, registerDeepInsertTestServices(t, server), db
+}
+
+// registerDeepInsertTestServices registra os serviços em memória de clientes, pedidos e linhas
+func registerDeepInsertTestServices(t *testing.T, server *Server) deepInsertTestServices {
+	services := deepInsertTestServices{
+		customers: newDeepInsertTestService(t, NavigationTestCustomer{}, 0),
+		orders:    newDeepInsertTestService(t, DeepInsertTestOrder{}, 100),
+		lines:     newDeepInsertTestService(t, DeepInsertTestLine{}, 1000),
+	}
+	require.NoError(t, server.RegisterEntityWithService("Customers", services.customers))
+	require.NoError(t, server.RegisterEntityWithService("Orders", services.orders))
+	require.NoError(t, server.RegisterEntityWithService("OrderLines", services.lines))
+	return services
+}
+
+func TestServer_DeepInsert(t *testing.T) {
+	t.Run("propagates generated keys", func(t *testing.T) {
+		server, services, db := newDeepInsertTestServer(t)
+
+		body := `{
+			"Customer": {"Name": "Ann"},
+			"Lines": [{"Product": "chair"}, {"Product": "table"}]
+		}`
+		req := httptest.NewRequest("POST", "/odata/Orders", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.GetRouter().Test(req)
+		require.NoError(t, err)
+		require.Equal(t, 201, resp.StatusCode)
+
+		// O cliente é criado antes do pedido e as linhas depois, todos na mesma transação
+		require.Len(t, services.customers.created, 1)
+		require.Len(t, services.orders.created, 1)
+		require.Len(t, services.lines.created, 2)
+		assert.EqualValues(t, 1, services.orders.created[0]["CustomerID"])
+		assert.EqualValues(t, 101, services.lines.created[0]["OrderID"])
+		assert.EqualValues(t, 101, services.lines.created[1]["OrderID"])
+		assert.Equal(t, []bool{true}, services.orders.inTx)
+		assert.Equal(t, []bool{true, true}, services.lines.inTx)
+		assert.Equal(t, 1, db.commits)
+
+		var created struct {
+			ID       int64                    `json:"ID"`
+			Customer map[string]interface{}   `json:"Customer"`
+			Lines    []map[string]interface{} `json:"Lines"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+		assert.EqualValues(t, 101, created.ID)
+		assert.Equal(t, "Ann", created.Customer["Name"])
+		require.Len(t, created.Lines, 2)
+		assert.EqualValues(t, 1001, created.Lines[0]["ID"])
+		assert.Equal(t, "table", created.Lines[1]["Product"])
+	})
+
+	t.Run("rolls back on failure", func(t *testing.T) {
+		server, _, db := newDeepInsertTestServer(t)
+
+		req := httptest.NewRequest("POST", "/odata/Orders", strings.NewReader(`{"CustomerID": 1, "Lines": [{"Product": "fail"}]}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.GetRouter().Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, 500, resp.StatusCode)
+		assert.Equal(t, 0, db.commits)
+		assert.Equal(t, 1, db.rollbacks)
+	})
+
+	t.Run("rejects invalid nested payload", func(t *testing.T) {
+		server, services, _ := newDeepInsertTestServer(t)
+
+		req := httptest.NewRequest("POST", "/odata/Orders", strings.NewReader(`{"Lines": {"Product": "chair"}}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.GetRouter().Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+		assert.Empty(t, services.orders.created)
+	})
+}
+
+func TestServer_DeepInsertMultiTenant(t *testing.T) {
+	db := newFakeDB(t, nil)
+	server := newMultiTenantTestServer(t, map[string]DatabaseProvider{
+		"default": &MockDatabaseProvider{connection: db.DB},
+		"acme":    &MockDatabaseProvider{connection: db.DB},
+	})
+	services := registerDeepInsertTestServices(t, server)
+	services.orders.seed(map[string]interface{}{"ID": int64(10)})
+
+	// Sem provider padrão, as ligações do deep insert, do deep update e do @odata.bind são resolvidas do mesmo modo
+	for _, body := range []string{
+		`{"Customer": {"Name": "Ann"}, "Lines": [{"Product": "chair"}]}`,
+		`{"Customer@odata.bind": "Customers(1)", "Lines": [{"Product": "table"}]}`,
+	} {
+		req := httptest.NewRequest("POST", "/odata/Orders", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Tenant-ID", "acme")
+		resp, err := server.GetRouter().Test(req)
+		require.NoError(t, err)
+		require.Equal(t, 201, resp.StatusCode, body)
+	}
+
+	require.Len(t, services.orders.created, 2)
+	require.Len(t, services.lines.created, 2)
+	assert.EqualValues(t, 1, services.orders.created[1]["CustomerID"])
+	assert.EqualValues(t, 102, services.lines.created[1]["OrderID"])
+
+	req := httptest.NewRequest("PATCH", "/odata/Orders(10)", strings.NewReader(`{"Customer@odata.bind": "Customers(1)"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Tenant-ID", "acme")
+	resp, err := server.GetRouter().Test(req)
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	assert.EqualValues(t, 1, entityValue(services.orders.find(10), "CustomerID"))
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -17,6 +18,7 @@ type BaseEntityService struct {
 	server        *Server
 	computeParser *ComputeParser
 	searchParser  *SearchParser
+	numericFilter bool // Compara números pelo valor no filtro em memória (filtros de eventos)
 }
 
 // NewBaseEntityService cria uma nova instância do serviço base
@@ -44,6 +46,14 @@ func (s *BaseEntityService) Query(ctx context.Context, options QueryOptions) (*O
 	default:
 	}
 
+	// Operadores lambda do $filter precisam resolver as entidades relacionadas
+	ctx = s.withEntityResolver(ctx)
+
+	// $apply produz um conjunto de resultados agregado, com formato próprio
+	if options.Apply != nil {
+		return s.queryApply(ctx, options)
+	}
+
 	// ORDEM CORRETA DE EXECUÇÃO OData v4:
 	// 1. $filter – aplica filtros sobre a entidade atual
 	// 2. $orderby – ordena os resultados filtrados
@@ -52,18 +62,28 @@ func (s *BaseEntityService) Query(ctx context.Context, options QueryOptions) (*O
 	// 5. $select – reduz os campos retornados
 	// 6. $expand – processa entidades relacionadas (recursivamente)
 
+	// Paginação dirigida pelo servidor: aplica o $skiptoken e busca um registro extra
+	// para saber se existe uma próxima página
+	paging, err := s.preparePaging(options)
+	if err != nil {
+		return nil, fmt.Errorf("invalid paging options: %w", err)
+	}
+	queryOptions := options
+	if paging != nil {
+		queryOptions = paging.options
+	}
+
 	// Constrói a query SQL seguindo a ordem correta
 	var query string
 	var args []any
-	var err error
 
 	// Aplica $filter, $orderby, $skip/$top primeiro na query SQL
 	if optimizedProvider, ok := s.provider.(interface {
 		BuildSelectQueryOptimized(ctx context.Context, metadata EntityMetadata, options QueryOptions) (string, []any, error)
 	}); ok {
-		query, args, err = optimizedProvider.BuildSelectQueryOptimized(ctx, s.metadata, options)
+		query, args, err = optimizedProvider.BuildSelectQueryOptimized(ctx, s.metadata, queryOptions)
 	} else {
-		query, args, err = s.provider.BuildSelectQuery(s.metadata, options)
+		query, args, err = s.provider.BuildSelectQuery(s.metadata, queryOptions)
 	}
 
 	if err != nil {
@@ -86,47 +106,7 @@ func (s *BaseEntityService) Query(ctx context.Context, options QueryOptions) (*O
 	// Converte os resultados, passando as propriedades expandidas
 	var expandOptions []ExpandOption
 	if options.Expand != nil {
-
-		// Converte GoDataExpandQuery para []ExpandOption
-		for _, item := range options.Expand.ExpandItems {
-			if len(item.Path) > 0 {
-				expandOption := ExpandOption{
-					Property: item.Path[0].Value,
-				}
-
-				// Converte opções de filtro
-				if item.Filter != nil {
-					expandOption.Filter = item.Filter.RawValue
-				}
-
-				// Converte opções de ordenação
-				if item.OrderBy != nil {
-					expandOption.OrderBy = item.OrderBy.RawValue
-				}
-
-				// Converte opções de select
-				if item.Select != nil {
-					expandOption.Select = GetSelectedProperties(item.Select)
-				}
-
-				// Converte opções de skip
-				if item.Skip != nil {
-					expandOption.Skip = GetSkipValue(item.Skip)
-				}
-
-				// Converte opções de top
-				if item.Top != nil {
-					expandOption.Top = GetTopValue(item.Top)
-				}
-
-				// Converte expansões recursivas
-				if item.Expand != nil {
-					expandOption.Expand = s.convertExpandItemsToExpandOptions(item.Expand.ExpandItems)
-				}
-
-				expandOptions = append(expandOptions, expandOption)
-			}
-		}
+		expandOptions = s.convertExpandItemsToExpandOptions(options.Expand.ExpandItems)
 	}
 
 	// Scana os resultados (aplicando paginação do SQL)
@@ -135,6 +115,16 @@ func (s *BaseEntityService) Query(ctx context.Context, options QueryOptions) (*O
 		return nil, fmt.Errorf("failed to scan rows: %w", err)
 	}
 
+	// Descarta o registro extra e gera o token da próxima página
+	var nextSkipToken string
+	if paging != nil && paging.pageSize > 0 && len(results) > paging.pageSize {
+		results = results[:paging.pageSize]
+		nextSkipToken, err = paging.nextSkipToken(results[len(results)-1])
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// 4. Processa $compute DEPOIS da execução SQL básica
 	if options.Compute != nil {
 		err := s.processComputeOption(ctx, options.Compute)
@@ -160,7 +150,9 @@ func (s *BaseEntityService) Query(ctx context.Context, options QueryOptions) (*O
 	// 6. Processa navegações expandidas seguindo a ordem recursivamente
 	if len(expandOptions) > 0 {
 		expandedResults, err := s.processExpandedNavigationWithOrder(ctx, results, expandOptions)
-		if err != nil {
+		if _, vetoed := isQueryVetoed(err); vetoed {
+			return nil, err
+		} else if err != nil {
 			// Log do erro mas tenta continuar com navigation links
 			log.Printf("Warning: Failed to process expanded navigation: %v. Continuing with navigation links.", err)
 		} else {
@@ -178,8 +170,9 @@ func (s *BaseEntityService) Query(ctx context.Context, options QueryOptions) (*O
 
 	// Constrói a resposta OData
 	response := &ODataResponse{
-		Context: fmt.Sprintf("$metadata#%s", s.metadata.Name),
-		Value:   results,
+		Context:       fmt.Sprintf("$metadata#%s", s.metadata.Name),
+		Value:         results,
+		NextSkipToken: nextSkipToken,
 	}
 
 	// Adiciona o count se solicitado
@@ -436,6 +429,38 @@ func (s *BaseEntityService) Create(ctx context.Context, entity any) (any, error)
 		return nil, fmt.Errorf("failed to convert entity to map: %w", err)
 	}
 
+	// Inicializa a coluna de versão usada como ETag
+	if versionProp := s.metadata.VersionProperty(); versionProp != nil && data[versionProp.Name] == nil {
+		data[versionProp.Name] = int64(1)
+	}
+
+	// Aplica as restrições da tag prop (NoInsert, Unique); as regras de validação são aplicadas pelo servidor
+	s.removeNonWritableProperties(data, true)
+	if err := s.checkUniqueProperties(ctx, data, nil); err != nil {
+		return nil, err
+	}
+
+	// Chaves com idGenerator (UUIDs e sequences) são geradas antes do INSERT
+	if err := s.assignGeneratedKeys(ctx, data); err != nil {
+		return nil, err
+	}
+
+	// Providers com RETURNING devolvem a linha inserida, com chaves e defaults do banco, na própria instrução
+	if returning, ok := s.provider.(ReturningProvider); ok {
+		conn, err := s.getExecutor(ctx)
+		if err != nil {
+			return nil, err
+		}
+		row, err := returning.InsertReturning(ctx, conn, s.metadata, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute insert: %w", err)
+		}
+		if row == nil {
+			return nil, fmt.Errorf("no rows inserted")
+		}
+		return s.buildReturnedEntity(row), nil
+	}
+
 	// Constrói a query SQL
 	query, args, err := s.provider.BuildInsertQuery(s.metadata, data)
 	if err != nil {
@@ -458,18 +483,21 @@ func (s *BaseEntityService) Create(ctx context.Context, entity any) (any, error)
 		return nil, fmt.Errorf("no rows inserted")
 	}
 
-	// Se há chaves auto-incrementais, busca o registro inserido
+	// Com todas as chaves conhecidas (informadas ou geradas) o registro é relido por elas
+	keys, complete := s.insertedKeys(data)
+	if complete {
+		return s.Get(ctx, keys)
+	}
+
+	// Senão a chave auto-incremental vem do LastInsertId
 	if s.hasAutoIncrementKey() {
+		keyProp := s.getAutoIncrementKey()
+
 		lastID, err := result.LastInsertId()
 		if err != nil {
 			return nil, fmt.Errorf("failed to get last insert id: %w", err)
 		}
-
-		// Busca o registro inserido
-		keyProp := s.getAutoIncrementKey()
-		keys := map[string]any{
-			keyProp.Name: lastID,
-		}
+		keys[keyProp.Name] = lastID
 
 		return s.Get(ctx, keys)
 	}
@@ -477,6 +505,23 @@ func (s *BaseEntityService) Create(ctx context.Context, entity any) (any, error)
 	return entity, nil
 }
 
+// insertedKeys retorna as chaves presentes nos dados gravados e se todas foram informadas
+func (s *BaseEntityService) insertedKeys(data map[string]any) (map[string]any, bool) {
+	keys := make(map[string]any)
+	complete := false
+	for _, prop := range s.metadata.Properties {
+		if !prop.IsKey {
+			continue
+		}
+		if isEmptyKeyValue(data[prop.Name]) {
+			return keys, false
+		}
+		keys[prop.Name] = data[prop.Name]
+		complete = true
+	}
+	return keys, complete
+}
+
 // Update atualiza uma entidade existente
 func (s *BaseEntityService) Update(ctx context.Context, keys map[string]any, entity any) (any, error) {
 	// Converte a entidade para map
@@ -490,8 +535,33 @@ func (s *BaseEntityService) Update(ctx context.Context, keys map[string]any, ent
 		delete(data, key)
 	}
 
+	// Aplica as restrições da tag prop (NoUpdate, Unique); as regras de validação são aplicadas pelo servidor
+	s.removeNonWritableProperties(data, false)
+	if err := s.checkUniqueProperties(ctx, data, keys); err != nil {
+		return nil, err
+	}
+
+	condition, hasCondition := ConcurrencyConditionFromContext(ctx)
+	whereValues := s.concurrencyWhereValues(keys, condition)
+
+	// Providers com RETURNING devolvem a linha atualizada, com valores de triggers, na própria instrução
+	if returning, ok := s.provider.(ReturningProvider); ok {
+		conn, err := s.getExecutor(ctx)
+		if err != nil {
+			return nil, err
+		}
+		row, err := returning.UpdateReturning(ctx, conn, s.metadata, data, whereValues)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute update: %w", err)
+		}
+		if row == nil {
+			return s.unchangedUpdate(ctx, keys, condition, hasCondition)
+		}
+		return s.buildReturnedEntity(row), nil
+	}
+
 	// Constrói a query SQL
-	query, args, err := s.provider.BuildUpdateQuery(s.metadata, data, keys)
+	query, args, err := s.provider.BuildUpdateQuery(s.metadata, data, whereValues)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build update query: %w", err)
 	}
@@ -509,17 +579,36 @@ func (s *BaseEntityService) Update(ctx context.Context, keys map[string]any, ent
 	}
 
 	if rowsAffected == 0 {
-		return nil, fmt.Errorf("no rows updated")
+		return s.unchangedUpdate(ctx, keys, condition, hasCondition)
 	}
 
 	// Busca o registro atualizado
 	return s.Get(ctx, keys)
 }
 
+// unchangedUpdate trata o UPDATE que não afetou linhas: sem condição de concorrência a entidade não existe
+func (s *BaseEntityService) unchangedUpdate(ctx context.Context, keys map[string]any, condition *ConcurrencyCondition, hasCondition bool) (any, error) {
+	if !hasCondition {
+		return nil, fmt.Errorf("no rows updated")
+	}
+
+	// Alguns bancos (ex.: MySQL) não contam linhas cujos valores não mudaram;
+	// só há conflito se o ETag atual for diferente do validado
+	current, err := s.Get(ctx, keys)
+	if err != nil {
+		return nil, ErrPreconditionFailed
+	}
+	if etag, _ := ComputeETag(s.metadata, current); etag != condition.ETag {
+		return nil, ErrPreconditionFailed
+	}
+	return current, nil
+}
+
 // Delete remove uma entidade
 func (s *BaseEntityService) Delete(ctx context.Context, keys map[string]any) error {
 	// Constrói a query SQL
-	query, args, err := s.provider.BuildDeleteQuery(s.metadata, keys)
+	condition, hasCondition := ConcurrencyConditionFromContext(ctx)
+	query, args, err := s.provider.BuildDeleteQuery(s.metadata, s.concurrencyWhereValues(keys, condition))
 	if err != nil {
 		return fmt.Errorf("failed to build delete query: %w", err)
 	}
@@ -537,12 +626,31 @@ func (s *BaseEntityService) Delete(ctx context.Context, keys map[string]any) err
 	}
 
 	if rowsAffected == 0 {
+		if hasCondition {
+			return ErrPreconditionFailed
+		}
 		return fmt.Errorf("no rows deleted")
 	}
 
 	return nil
 }
 
+// concurrencyWhereValues combina as chaves com os valores esperados do token de concorrência
+func (s *BaseEntityService) concurrencyWhereValues(keys map[string]any, condition *ConcurrencyCondition) map[string]any {
+	if condition == nil || len(condition.Values) == 0 {
+		return keys
+	}
+
+	values := make(map[string]any, len(keys)+len(condition.Values))
+	for key, value := range keys {
+		values[key] = value
+	}
+	for key, value := range condition.Values {
+		values[key] = value
+	}
+	return values
+}
+
 // scanRows converte os resultados SQL para maps
 func (s *BaseEntityService) scanRows(rows *sql.Rows, expandOptions []ExpandOption) ([]any, error) {
 	columns, err := rows.Columns()
@@ -566,117 +674,257 @@ func (s *BaseEntityService) scanRows(rows *sql.Rows, expandOptions []ExpandOptio
 			return nil, err
 		}
 
-		// Cria a entidade ordenada usando a ordem dos metadados
-		result := NewOrderedEntity()
+		results = append(results, s.buildRowEntity(columns, values, expandOptions))
+	}
 
-		// Primeiro, adiciona as propriedades normais
-		for _, prop := range s.metadata.Properties {
-			if !prop.IsNavigation {
-				// Para propriedades normais, busca o valor na consulta SQL
-				var colIndex = -1
-				var colName = prop.ColumnName
-				if colName == "" {
-					colName = prop.Name
-				}
+	return results, rows.Err()
+}
 
-				for i, col := range columns {
-					if col == colName {
-						colIndex = i
-						break
-					}
+// buildRowEntity converte os valores de uma linha na entidade ordenada conforme os metadados
+func (s *BaseEntityService) buildRowEntity(columns []string, values []any, expandOptions []ExpandOption) *OrderedEntity {
+	// Cria a entidade ordenada usando a ordem dos metadados
+	result := NewOrderedEntity()
+
+	// Primeiro, adiciona as propriedades normais
+	for _, prop := range s.metadata.Properties {
+		if !prop.IsNavigation {
+			// Para propriedades normais, busca o valor na consulta SQL
+			var colIndex = -1
+			var colName = prop.ColumnName
+			if colName == "" {
+				colName = prop.Name
+			}
+
+			for i, col := range columns {
+				if col == colName {
+					colIndex = i
+					break
 				}
+			}
 
-				// Se encontrou a coluna, adiciona o valor com conversão de tipo
-				if colIndex >= 0 {
-					val := values[colIndex]
-					if val != nil {
-						// CORREÇÃO: Usa convertValueToPropertyType para manter o tipo correto
-						convertedVal, err := s.convertValueToPropertyType(val, prop.Name, s.metadata)
-						if err != nil {
-							// Em caso de erro na conversão, usa a conversão original como fallback
-							switch v := val.(type) {
-							case []byte:
-								result.Set(prop.Name, string(v))
-							default:
-								result.Set(prop.Name, v)
-							}
-						} else {
-							result.Set(prop.Name, convertedVal)
+			// Se encontrou a coluna, adiciona o valor com conversão de tipo
+			if colIndex >= 0 {
+				val := values[colIndex]
+				if val != nil {
+					// CORREÇÃO: Usa convertValueToPropertyType para manter o tipo correto
+					convertedVal, err := s.convertValueToPropertyType(val, prop.Name, s.metadata)
+					if err != nil {
+						// Em caso de erro na conversão, usa a conversão original como fallback
+						switch v := val.(type) {
+						case []byte:
+							result.Set(prop.Name, string(v))
+						default:
+							result.Set(prop.Name, v)
 						}
 					} else {
-						result.Set(prop.Name, nil)
+						result.Set(prop.Name, convertedVal)
 					}
+				} else {
+					result.Set(prop.Name, nil)
 				}
 			}
 		}
+	}
 
-		// Depois, adiciona as propriedades de navegação (agora que as chaves estão disponíveis)
-		// Só adiciona navigationLink se a propriedade NÃO está sendo expandida
-		for _, prop := range s.metadata.Properties {
-			if prop.IsNavigation {
-				// Verifica se esta propriedade está sendo expandida (case-insensitive)
-				isExpanded := false
-				for _, expandOption := range expandOptions {
-					if strings.EqualFold(expandOption.Property, prop.Name) {
-						isExpanded = true
-						break
-					}
+	// Depois, adiciona as propriedades de navegação (agora que as chaves estão disponíveis)
+	// Só adiciona navigationLink se a propriedade NÃO está sendo expandida
+	for _, prop := range s.metadata.Properties {
+		if prop.IsNavigation {
+			// Verifica se esta propriedade está sendo expandida (case-insensitive)
+			isExpanded := false
+			for _, expandOption := range expandOptions {
+				if strings.EqualFold(expandOption.Property, prop.Name) {
+					isExpanded = true
+					break
 				}
+			}
 
-				// Só adiciona navigation link se NÃO está sendo expandida
-				if !isExpanded {
-					result.SetNavigationProperty(prop.Name, s.buildNavigationLink(prop, result))
-				}
+			// Só adiciona navigation link se NÃO está sendo expandida
+			if !isExpanded {
+				result.SetNavigationProperty(prop.Name, s.buildNavigationLink(prop, result))
 			}
 		}
+	}
 
-		// Adiciona colunas que não estão nos metadados (caso existam)
-		for i, col := range columns {
-			propName := s.getPropertyNameByColumn(col)
-			if propName == "" {
-				propName = col
-			}
+	// Adiciona colunas que não estão nos metadados (caso existam)
+	for i, col := range columns {
+		propName := s.getPropertyNameByColumn(col)
+		if propName == "" {
+			propName = col
+		}
 
-			// Verifica se já foi adicionada
-			if _, exists := result.Get(propName); !exists {
-				val := values[i]
-				if val != nil {
-					// CORREÇÃO: Também aplica conversão de tipo para colunas adicionais
-					// Busca a propriedade nos metadados para fazer conversão correta
-					var foundProp *PropertyMetadata
-					for _, prop := range s.metadata.Properties {
-						if strings.EqualFold(prop.Name, propName) || strings.EqualFold(prop.ColumnName, propName) {
-							foundProp = &prop
-							break
-						}
+		// Verifica se já foi adicionada
+		if _, exists := result.Get(propName); !exists {
+			val := values[i]
+			if val != nil {
+				// CORREÇÃO: Também aplica conversão de tipo para colunas adicionais
+				// Busca a propriedade nos metadados para fazer conversão correta
+				var foundProp *PropertyMetadata
+				for _, prop := range s.metadata.Properties {
+					if strings.EqualFold(prop.Name, propName) || strings.EqualFold(prop.ColumnName, propName) {
+						foundProp = &prop
+						break
 					}
+				}
 
-					if foundProp != nil {
-						convertedVal, err := s.convertValueToPropertyType(val, foundProp.Name, s.metadata)
-						if err != nil {
-							// Fallback para conversão original
-							switch v := val.(type) {
-							case []byte:
-								result.Set(propName, string(v))
-							default:
-								result.Set(propName, v)
-							}
-						} else {
-							result.Set(propName, convertedVal)
-						}
-					} else {
-						// Para colunas não mapeadas, mantém a conversão original
+				if foundProp != nil {
+					convertedVal, err := s.convertValueToPropertyType(val, foundProp.Name, s.metadata)
+					if err != nil {
+						// Fallback para conversão original
 						switch v := val.(type) {
 						case []byte:
 							result.Set(propName, string(v))
 						default:
 							result.Set(propName, v)
 						}
+					} else {
+						result.Set(propName, convertedVal)
 					}
 				} else {
-					result.Set(propName, nil)
+					// Para colunas não mapeadas, mantém a conversão original
+					switch v := val.(type) {
+					case []byte:
+						result.Set(propName, string(v))
+					default:
+						result.Set(propName, v)
+					}
 				}
+			} else {
+				result.Set(propName, nil)
+			}
+		}
+	}
+
+	return result
+}
+
+// buildReturnedEntity converte a linha devolvida por um ReturningProvider na entidade ordenada
+func (s *BaseEntityService) buildReturnedEntity(row map[string]interface{}) *OrderedEntity {
+	columns := make([]string, 0, len(row))
+	for column := range row {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	values := make([]any, len(columns))
+	for i, column := range columns {
+		values[i] = row[column]
+	}
+	return s.buildRowEntity(columns, values, nil)
+}
+
+// queryApply executa uma consulta com $apply, traduzida para GROUP BY pelo QueryBuilder
+func (s *BaseEntityService) queryApply(ctx context.Context, options QueryOptions) (*ODataResponse, error) {
+	if options.Expand != nil {
+		return nil, fmt.Errorf("$expand is not supported together with $apply")
+	}
+	if options.Search != nil || options.Compute != nil {
+		return nil, fmt.Errorf("$search and $compute are not supported together with $apply; use the search and compute transformations instead")
+	}
+
+	qb := s.queryBuilder()
+	applyQuery, err := qb.BuildApplyQuery(ctx, s.metadata, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build apply query: %w", err)
+	}
+
+	rows, err := s.executeQuery(ctx, applyQuery.SQL, applyQuery.Args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	results, err := s.scanApplyRows(rows, applyQuery.Properties)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows: %w", err)
+	}
+
+	if options.Select != nil {
+		results, err = s.applySelectToResults(results, options.Select)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply select to results: %w", err)
+		}
+	}
+
+	names := make([]string, 0, len(applyQuery.Properties))
+	for _, prop := range applyQuery.Properties {
+		names = append(names, prop.Name)
+	}
+
+	response := &ODataResponse{
+		Context: fmt.Sprintf("$metadata#%s(%s)", s.metadata.Name, strings.Join(names, ",")),
+		Value:   results,
+	}
+
+	// O count considera o resultado agregado, sem $skip/$top
+	if IsCountRequested(options.Count) {
+		countOptions := options
+		countOptions.Top = nil
+		countOptions.Skip = nil
+		countOptions.OrderBy = ""
+
+		countQuery, err := qb.BuildApplyQuery(ctx, s.metadata, countOptions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build apply count query: %w", err)
+		}
+
+		conn, err := s.getExecutor(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var count int64
+		row := conn.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM (%s) apply_count", countQuery.SQL), countQuery.Args...)
+		if err := row.Scan(&count); err != nil {
+			return nil, fmt.Errorf("failed to execute count query: %w", err)
+		}
+		response.Count = &count
+	}
+
+	return response, nil
+}
+
+// scanApplyRows converte as linhas agregadas usando as propriedades do resultado do $apply
+func (s *BaseEntityService) scanApplyRows(rows *sql.Rows, properties []PropertyMetadata) ([]any, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) != len(properties) {
+		return nil, fmt.Errorf("apply query returned %d columns, expected %d", len(columns), len(properties))
+	}
+
+	resultMetadata := EntityMetadata{Name: s.metadata.Name, Properties: properties}
+	results := []any{}
+
+	for rows.Next() {
+		values := make([]any, len(columns))
+		valuePtrs := make([]any, len(columns))
+		for i := range values {
+			valuePtrs[i] = &values[i]
+		}
+
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return nil, err
+		}
+
+		// As colunas seguem a ordem das propriedades, independente da caixa devolvida pelo banco
+		result := NewOrderedEntity()
+		for i, prop := range properties {
+			val := values[i]
+			if val == nil {
+				result.Set(prop.Name, nil)
+				continue
 			}
+
+			convertedVal, err := s.convertValueToPropertyType(val, prop.Name, resultMetadata)
+			if err != nil {
+				convertedVal = val
+			}
+			if b, ok := convertedVal.([]byte); ok {
+				convertedVal = string(b)
+			}
+			result.Set(prop.Name, convertedVal)
 		}
 
 		results = append(results, result)
@@ -685,6 +933,16 @@ func (s *BaseEntityService) scanRows(rows *sql.Rows, expandOptions []ExpandOptio
 	return results, rows.Err()
 }
 
+// queryBuilder retorna o QueryBuilder do provider ou um novo para o dialeto do driver
+func (s *BaseEntityService) queryBuilder() *QueryBuilder {
+	if provider, ok := s.provider.(interface{ GetQueryBuilder() *QueryBuilder }); ok {
+		if qb := provider.GetQueryBuilder(); qb != nil {
+			return qb
+		}
+	}
+	return NewQueryBuilder(s.provider.GetDriverName())
+}
+
 // GetCount obtém o total de registros
 func (s *BaseEntityService) GetCount(ctx context.Context, options QueryOptions) (int64, error) {
 	// Constrói a query de count usando o provider
@@ -699,7 +957,7 @@ func (s *BaseEntityService) GetCount(ctx context.Context, options QueryOptions)
 	var err error
 
 	if options.Filter != nil && options.Filter.Tree != nil {
-		whereClause, args, err = ConvertFilterToSQL(ctx, options.Filter, s.metadata)
+		whereClause, args, err = ConvertFilterToSQL(s.withEntityResolver(ctx), options.Filter, s.metadata)
 		if err != nil {
 			return 0, fmt.Errorf("failed to build where clause for count: %w", err)
 		}
@@ -712,7 +970,11 @@ func (s *BaseEntityService) GetCount(ctx context.Context, options QueryOptions)
 	}
 
 	// Executa a query
-	row := s.provider.GetConnection().QueryRowContext(ctx, query, args...)
+	conn, err := s.getExecutor(ctx)
+	if err != nil {
+		return 0, err
+	}
+	row := conn.QueryRowContext(ctx, query, args...)
 
 	var count int64
 	if err := row.Scan(&count); err != nil {
@@ -1012,6 +1274,17 @@ func (s *BaseEntityService) convertValueToPropertyType(value any, propertyName s
 	}
 }
 
+// withEntityResolver adiciona ao contexto o resolvedor de entidades relacionadas do servidor
+func (s *BaseEntityService) withEntityResolver(ctx context.Context) context.Context {
+	if s.server == nil {
+		return ctx
+	}
+	return WithEntityResolver(ctx, func(name string) (EntityMetadata, bool) {
+		metadata, err := s.getRelatedEntityMetadata(name)
+		return metadata, err == nil
+	})
+}
+
 // getRelatedEntityMetadata obtém metadados da entidade relacionada
 func (s *BaseEntityService) getRelatedEntityMetadata(relatedType string) (EntityMetadata, error) {
 	if s.server == nil {
@@ -1118,7 +1391,15 @@ func (s *BaseEntityService) buildNavigationLink(prop PropertyMetadata, entity *O
 	}
 
 	// Constrói o link: EntitySet(key)/NavigationProperty
-	return fmt.Sprintf("%s(%s)/%s", s.metadata.Name, keyPart, prop.Name)
+	return fmt.Sprintf("%s(%s)/%s", s.entitySetName(), keyPart, prop.Name)
+}
+
+// entitySetName retorna o nome com que a entidade foi registrada, usado nos caminhos de navegação
+func (s *BaseEntityService) entitySetName() string {
+	if s.server == nil {
+		return s.metadata.Name
+	}
+	return s.server.entitySetNameOf(s.metadata)
 }
 
 // getJSONTagName extrai o nome do tag JSON de uma propriedade
@@ -1213,8 +1494,18 @@ func (s *BaseEntityService) parseFilterWithTimeout(ctx context.Context, filter s
 	return ParseFilterString(ctx, filter)
 }
 
-// executeQuery executa uma query com os argumentos apropriados para o provider
-func (s *BaseEntityService) executeQuery(ctx context.Context, query string, args []any) (*sql.Rows, error) {
+// SQLExecutor abstrai *sql.DB e *sql.Tx para execução de comandos
+type SQLExecutor interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// getExecutor retorna a transação presente no contexto ou a conexão do provider
+func (s *BaseEntityService) getExecutor(ctx context.Context) (SQLExecutor, error) {
+	if tx, ok := ctx.Value(TxContextKey).(*sql.Tx); ok && tx != nil {
+		return tx, nil
+	}
 
 	// Verifica se a conexão está disponível
 	conn := s.provider.GetConnection()
@@ -1222,6 +1513,16 @@ func (s *BaseEntityService) executeQuery(ctx context.Context, query string, args
 		return nil, fmt.Errorf("database connection is nil - make sure the provider is properly connected")
 	}
 
+	return conn, nil
+}
+
+// executeQuery executa uma query com os argumentos apropriados para o provider
+func (s *BaseEntityService) executeQuery(ctx context.Context, query string, args []any) (*sql.Rows, error) {
+	conn, err := s.getExecutor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	rows, err := conn.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
@@ -1231,10 +1532,9 @@ func (s *BaseEntityService) executeQuery(ctx context.Context, query string, args
 
 // executeExec executa um comando com os argumentos apropriados para o provider
 func (s *BaseEntityService) executeExec(ctx context.Context, query string, args []any) (sql.Result, error) {
-	// Verifica se a conexão está disponível
-	conn := s.provider.GetConnection()
-	if conn == nil {
-		return nil, fmt.Errorf("database connection is nil - make sure the provider is properly connected")
+	conn, err := s.getExecutor(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	return conn.ExecContext(ctx, query, args...)
@@ -1360,6 +1660,15 @@ func (s *BaseEntityService) parseFilterLiteral(literal string) any {
 
 // compareValues compara dois valores usando o operador especificado
 func (s *BaseEntityService) compareValues(left, right any, operator string) bool {
+	// Nos filtros de eventos, números são comparados pelo valor (float64 do JSON, int64 do literal)
+	if s.numericFilter {
+		if leftNum, ok := numericValue(left); ok {
+			if rightNum, ok := numericValue(right); ok {
+				return compareNumbers(leftNum, rightNum, operator)
+			}
+		}
+	}
+
 	// Converte para string para comparação
 	leftStr := fmt.Sprintf("%v", left)
 	rightStr := fmt.Sprintf("%v", right)
@@ -1416,6 +1725,9 @@ func (s *BaseEntityService) convertExpandItemsToExpandOptions(items []*ExpandIte
 				expandOption.Top = GetTopValue(item.Top)
 			}
 
+			// Converte opção de count
+			expandOption.Count = IsCountRequested(item.Count)
+
 			// Converte expansões recursivas
 			if item.Expand != nil {
 				expandOption.Expand = s.convertExpandItemsToExpandOptions(item.Expand.ExpandItems)
@@ -1575,48 +1887,40 @@ func (s *BaseEntityService) convertToNumber(value any) (float64, error) {
 	}
 }
 
-// processExpandedNavigationWithOrder processa navegações expandidas seguindo a ordem OData v4
+// processExpandedNavigationWithOrder processa navegações expandidas seguindo a ordem OData v4.
+// Cada navegação é carregada com uma única consulta para todas as entidades de origem.
 func (s *BaseEntityService) processExpandedNavigationWithOrder(ctx context.Context, results []any, expandOptions []ExpandOption) ([]any, error) {
 	if len(results) == 0 {
 		return results, nil
 	}
 
-	// Para cada resultado, processa as navegações seguindo a ordem
-	for i, result := range results {
-		orderedEntity, ok := result.(*OrderedEntity)
-		if !ok {
-			continue
-		}
-
-		// Processa cada opção de expansão seguindo a ordem OData v4
-		for _, expandOption := range expandOptions {
-			expandedResult, err := s.expandNavigationPropertyWithOrder(ctx, orderedEntity, expandOption)
-			if err != nil {
-				// Log detalhado do erro para debug
-				errorMsg := fmt.Sprintf("%v", err)
-
-				// Log do erro mas tenta continuar processando outras propriedades
-				log.Printf("Warning: Failed to expand navigation property %s: %v. Property will remain as navigation link.", expandOption.Property, err)
+	// Processa cada opção de expansão seguindo a ordem OData v4
+	for _, expandOption := range expandOptions {
+		if err := s.expandNavigationPropertyWithOrder(ctx, results, expandOption); err != nil {
+			// A expansão recusada em EntityQuerying recusa a consulta inteira
+			if _, vetoed := isQueryVetoed(err); vetoed {
+				return nil, err
+			}
 
-				// Se o erro for crítico de estrutura (não de conexão), falha
-				if strings.Contains(errorMsg, "navigation property") && strings.Contains(errorMsg, "not found") {
-					// Erro de estrutura - propriedade não existe, isso é crítico
-					return nil, fmt.Errorf("critical error expanding navigation property %s: %w", expandOption.Property, err)
-				}
+			// Log detalhado do erro para debug
+			errorMsg := fmt.Sprintf("%v", err)
 
-				// Para outros erros (incluindo conexão), continua com navigation link
-				continue
+			// Se o erro for crítico de estrutura (não de conexão), falha
+			if strings.Contains(errorMsg, "navigation property") && strings.Contains(errorMsg, "not found") {
+				// Erro de estrutura - propriedade não existe, isso é crítico
+				return nil, fmt.Errorf("critical error expanding navigation property %s: %w", expandOption.Property, err)
 			}
 
-			results[i] = expandedResult
+			// Para outros erros (incluindo conexão), continua com navigation link
+			log.Printf("Warning: Failed to expand navigation property %s: %v. Property will remain as navigation link.", expandOption.Property, err)
 		}
 	}
 
 	return results, nil
 }
 
-// expandNavigationPropertyWithOrder expande uma propriedade de navegação seguindo a ordem OData v4
-func (s *BaseEntityService) expandNavigationPropertyWithOrder(ctx context.Context, entity *OrderedEntity, expandOption ExpandOption) (*OrderedEntity, error) {
+// expandNavigationPropertyWithOrder expande uma propriedade de navegação em todas as entidades de origem
+func (s *BaseEntityService) expandNavigationPropertyWithOrder(ctx context.Context, results []any, expandOption ExpandOption) error {
 	// Encontra a propriedade de navegação nos metadados (comparação case-insensitive)
 	var navProperty *PropertyMetadata
 	for _, prop := range s.metadata.Properties {
@@ -1634,25 +1938,38 @@ func (s *BaseEntityService) expandNavigationPropertyWithOrder(ctx context.Contex
 				availableProps = append(availableProps, prop.Name)
 			}
 		}
-		return entity, fmt.Errorf("navigation property %s not found. Available navigation properties: %v", expandOption.Property, availableProps)
+		return fmt.Errorf("navigation property %s not found. Available navigation properties: %v", expandOption.Property, availableProps)
 	}
 
 	// Busca entidades relacionadas aplicando a ordem OData v4
-	relatedEntities, err := s.findRelatedEntitiesWithOrder(ctx, navProperty, entity, expandOption)
+	related, err := s.findRelatedEntitiesWithOrder(ctx, navProperty, results, expandOption)
 	if err != nil {
-		return entity, fmt.Errorf("failed to find related entities for property %s: %w", expandOption.Property, err)
+		return fmt.Errorf("failed to find related entities for property %s: %w", expandOption.Property, err)
 	}
 
-	// Adiciona as entidades relacionadas ao resultado
-	if navProperty.IsCollection {
-		if relatedEntities == nil {
-			entity.Set(navProperty.Name, []any{})
-		} else {
-			entity.Set(navProperty.Name, relatedEntities)
+	// Adiciona as entidades relacionadas a cada resultado
+	for _, result := range results {
+		entity, ok := result.(*OrderedEntity)
+		if !ok {
+			continue
 		}
-	} else {
-		if relatedEntities == nil {
-			entity.Set(navProperty.Name, nil)
+
+		key := ""
+		if value, exists := entity.Get(related.parentProperty); exists && value != nil {
+			key = expandKeyValue(value)
+		}
+		relatedEntities := related.entities[key]
+
+		if expandOption.Count {
+			entity.Set(navProperty.Name+"@odata.count", related.counts[key])
+		}
+
+		if navProperty.IsCollection {
+			if relatedEntities == nil {
+				entity.Set(navProperty.Name, []any{})
+			} else {
+				entity.Set(navProperty.Name, relatedEntities)
+			}
 		} else if len(relatedEntities) > 0 {
 			entity.Set(navProperty.Name, relatedEntities[0])
 		} else {
@@ -1660,56 +1977,142 @@ func (s *BaseEntityService) expandNavigationPropertyWithOrder(ctx context.Contex
 		}
 	}
 
-	return entity, nil
+	return nil
+}
+
+// relatedEntities guarda as entidades relacionadas agrupadas pela chave da entidade de origem
+type relatedEntities struct {
+	parentProperty string
+	entities       map[string][]any
+	counts         map[string]int64
 }
 
-// findRelatedEntitiesWithOrder encontra entidades relacionadas seguindo a ordem OData v4
-func (s *BaseEntityService) findRelatedEntitiesWithOrder(ctx context.Context, navProperty *PropertyMetadata, entity *OrderedEntity, expandOption ExpandOption) ([]any, error) {
-	if navProperty.Relationship == nil {
-		return nil, fmt.Errorf("navigation property has no relationship metadata")
+// findRelatedEntitiesWithOrder busca as entidades relacionadas de todas as entidades de origem
+// com uma consulta WHERE chave IN (...) por lote, seguindo a ordem OData v4
+func (s *BaseEntityService) findRelatedEntitiesWithOrder(ctx context.Context, navProperty *PropertyMetadata, results []any, expandOption ExpandOption) (*relatedEntities, error) {
+	// Obtém os metadados da entidade relacionada
+	relatedType := navProperty.RelatedType
+	if relatedType == "" && navProperty.ManyAssociation != nil {
+		relatedType = navProperty.ManyAssociation.RelatedEntity
+	}
+	relatedMetadata, err := s.getRelatedEntityMetadata(relatedType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get related entity metadata: %w", err)
 	}
 
-	// Obtém o valor da chave para fazer a busca relacionada
-	localKeyValue, exists := entity.Get(navProperty.Relationship.LocalProperty)
-	if !exists {
-		// Tenta procurar por variações do nome
-		for _, prop := range entity.Properties {
-			if strings.EqualFold(prop.Name, navProperty.Relationship.LocalProperty) {
-				localKeyValue = prop.Value
-				exists = true
-				break
-			}
-		}
+	qb := s.queryBuilder()
+	source, err := qb.BuildExpandSource(s.metadata, *navProperty, relatedMetadata)
+	if err != nil {
+		return nil, err
+	}
+
+	related := &relatedEntities{
+		parentProperty: source.ParentProperty,
+		entities:       make(map[string][]any),
+		counts:         make(map[string]int64),
+	}
 
+	// Coleta as chaves distintas das entidades de origem
+	var keys []any
+	seen := make(map[string]bool)
+	for _, result := range results {
+		entity, ok := result.(*OrderedEntity)
+		if !ok {
+			continue
+		}
+		value, exists := entity.Get(source.ParentProperty)
 		if !exists {
-			return nil, fmt.Errorf("local key property %s not found in entity", navProperty.Relationship.LocalProperty)
+			return nil, fmt.Errorf("local key property %s not found in entity", source.ParentProperty)
 		}
+		if value == nil || seen[expandKeyValue(value)] {
+			continue
+		}
+		seen[expandKeyValue(value)] = true
+		keys = append(keys, value)
+	}
+	if len(keys) == 0 {
+		return related, nil
 	}
 
-	// Obtém os metadados da entidade relacionada
-	relatedMetadata, err := s.getRelatedEntityMetadata(navProperty.RelatedType)
+	queryOptions, err := s.buildExpandQueryOptions(ctx, expandOption)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get related entity metadata: %w", err)
+		return nil, err
+	}
+
+	// As regras de EntityQuerying da entidade relacionada também valem na expansão
+	if s.server != nil {
+		if err := s.server.interceptQuery(ctx, s.server.entitySetNameOf(relatedMetadata), QueryKindExpand, nil, &queryOptions); err != nil {
+			return nil, err
+		}
+	}
+
+	// Cria serviço para a entidade relacionada
+	relatedService := NewBaseEntityService(s.provider, relatedMetadata, s.server)
+
+	var all []any
+	for start := 0; start < len(keys); start += expandBatchSize {
+		batch := keys[start:min(start+expandBatchSize, len(keys))]
+
+		query, err := qb.BuildExpandQuery(ctx, relatedMetadata, source, batch, queryOptions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build expand query: %w", err)
+		}
+
+		entities, err := relatedService.queryRelatedEntities(ctx, query, expandOption.Expand)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entity := range entities {
+			key := takeExpandParentKey(entity)
+			related.entities[key] = append(related.entities[key], entity)
+			all = append(all, entity)
+		}
+
+		if expandOption.Count {
+			countQuery, err := qb.BuildExpandCountQuery(ctx, relatedMetadata, source, batch, queryOptions)
+			if err != nil {
+				return nil, fmt.Errorf("failed to build expand count query: %w", err)
+			}
+			if err := relatedService.countRelatedEntities(ctx, countQuery, related.counts); err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	// Constrói QueryOptions seguindo a ordem OData v4
-	queryOptions := QueryOptions{}
+	// $expand aninhado é processado em lote sobre todas as entidades relacionadas
+	if len(expandOption.Expand) > 0 {
+		if _, err := relatedService.processExpandedNavigationWithOrder(ctx, all, expandOption.Expand); err != nil {
+			return nil, err
+		}
+	}
+
+	// $select – reduz os campos retornados
+	if queryOptions.Select != nil {
+		for key, entities := range related.entities {
+			selected, err := relatedService.applySelectToResults(entities, queryOptions.Select)
+			if err != nil {
+				return nil, fmt.Errorf("failed to apply expand select: %w", err)
+			}
+			related.entities[key] = selected
+		}
+	}
+
+	return related, nil
+}
+
+// buildExpandQueryOptions converte as opções da expansão em QueryOptions
+func (s *BaseEntityService) buildExpandQueryOptions(ctx context.Context, expandOption ExpandOption) (QueryOptions, error) {
+	queryOptions := QueryOptions{OrderBy: expandOption.OrderBy}
 
-	// 1. $filter – aplica filtros sobre a entidade relacionada
 	if expandOption.Filter != "" {
 		filterQuery, err := s.parseFilterWithTimeout(ctx, expandOption.Filter)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse expand filter: %w", err)
+			return queryOptions, fmt.Errorf("failed to parse expand filter: %w", err)
 		}
 		queryOptions.Filter = filterQuery
 	}
 
-	// 2. $orderby – ordena os resultados filtrados
-	if expandOption.OrderBy != "" {
-		queryOptions.OrderBy = expandOption.OrderBy
-	}
-
-	// 3. $skip/$top – aplica paginação
 	if expandOption.Skip > 0 {
 		skip := GoDataSkipQuery(expandOption.Skip)
 		queryOptions.Skip = &skip
@@ -1719,108 +2122,64 @@ func (s *BaseEntityService) findRelatedEntitiesWithOrder(ctx context.Context, na
 		queryOptions.Top = &top
 	}
 
-	// 4. $compute seria aplicado aqui se suportado no expand
-	// 5. $select – reduz os campos retornados
 	if len(expandOption.Select) > 0 {
-		selectStr := strings.Join(expandOption.Select, ",")
-		selectQuery, err := ParseSelectString(ctx, selectStr)
+		selectQuery, err := ParseSelectString(ctx, strings.Join(expandOption.Select, ","))
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse expand select: %w", err)
+			return queryOptions, fmt.Errorf("failed to parse expand select: %w", err)
 		}
 		queryOptions.Select = selectQuery
 	}
 
-	// 6. $expand – processa entidades relacionadas recursivamente
-	if len(expandOption.Expand) > 0 {
-		// Constrói string de expand recursiva
-		var expandParts []string
-		for _, exp := range expandOption.Expand {
-			expandPart := exp.Property
-			var subOptions []string
-
-			if exp.Filter != "" {
-				subOptions = append(subOptions, fmt.Sprintf("$filter=%s", exp.Filter))
-			}
-			if exp.OrderBy != "" {
-				subOptions = append(subOptions, fmt.Sprintf("$orderby=%s", exp.OrderBy))
-			}
-			if len(exp.Select) > 0 {
-				subOptions = append(subOptions, fmt.Sprintf("$select=%s", strings.Join(exp.Select, ",")))
-			}
-			if exp.Skip > 0 {
-				subOptions = append(subOptions, fmt.Sprintf("$skip=%d", exp.Skip))
-			}
-			if exp.Top > 0 {
-				subOptions = append(subOptions, fmt.Sprintf("$top=%d", exp.Top))
-			}
-
-			if len(subOptions) > 0 {
-				expandPart = fmt.Sprintf("%s(%s)", expandPart, strings.Join(subOptions, ";"))
-			}
-
-			expandParts = append(expandParts, expandPart)
-		}
-
-		expandStr := strings.Join(expandParts, ",")
-		expandQuery, err := ParseExpandString(ctx, expandStr)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse nested expand: %w", err)
-		}
-		queryOptions.Expand = expandQuery
-	}
-
-	// Cria serviço para a entidade relacionada
-	relatedService := NewBaseEntityService(s.provider, relatedMetadata, s.server)
+	return queryOptions, nil
+}
 
-	// Executa a consulta seguindo a ordem OData v4
-	response, err := relatedService.Query(ctx, queryOptions)
+// queryRelatedEntities executa a consulta de expansão e converte as linhas em entidades
+func (s *BaseEntityService) queryRelatedEntities(ctx context.Context, query *ExpandQuery, expandOptions []ExpandOption) ([]any, error) {
+	rows, err := s.executeQuery(ctx, query.SQL, query.Args)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query related entities: %w", err)
 	}
+	defer rows.Close()
 
-	// Converte response.Value para []any
-	entities, ok := response.Value.([]any)
-	if !ok {
-		return nil, fmt.Errorf("unexpected response type: %T", response.Value)
+	entities, err := s.scanRows(rows, expandOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan related entities: %w", err)
 	}
-
-	// Filtra resultados pelo relacionamento
-	return s.filterRelatedEntities(entities, navProperty, localKeyValue, relatedMetadata)
+	return entities, nil
 }
 
-// filterRelatedEntities filtra entidades relacionadas baseado no relacionamento
-func (s *BaseEntityService) filterRelatedEntities(entities []any, navProperty *PropertyMetadata, keyValue any, relatedMetadata EntityMetadata) ([]any, error) {
-	var filtered []any
-
-	for _, entity := range entities {
-		orderedEntity, ok := entity.(*OrderedEntity)
-		if !ok {
-			continue
-		}
-
-		// Obtém o valor da propriedade de relacionamento
-		var relationshipValue any
-		var exists bool
+// countRelatedEntities executa a consulta de total por chave de origem
+func (s *BaseEntityService) countRelatedEntities(ctx context.Context, query *ExpandQuery, counts map[string]int64) error {
+	rows, err := s.executeQuery(ctx, query.SQL, query.Args)
+	if err != nil {
+		return fmt.Errorf("failed to count related entities: %w", err)
+	}
+	defer rows.Close()
 
-		if navProperty.IsCollection {
-			// Para collections (1:N), verifica a chave estrangeira
-			relationshipValue, exists = orderedEntity.Get(navProperty.Relationship.ReferencedProperty)
-		} else {
-			// Para associações (N:1), verifica a chave primária
-			for _, prop := range relatedMetadata.Properties {
-				if prop.IsKey {
-					relationshipValue, exists = orderedEntity.Get(prop.Name)
-					break
-				}
-			}
+	for rows.Next() {
+		var key any
+		var count int64
+		if err := rows.Scan(&key, &count); err != nil {
+			return fmt.Errorf("failed to scan related count: %w", err)
 		}
+		counts[expandKeyValue(key)] = count
+	}
+	return rows.Err()
+}
 
-		if exists && s.compareValues(relationshipValue, keyValue, "eq") {
-			filtered = append(filtered, entity)
+// takeExpandParentKey remove da entidade relacionada a coluna auxiliar com a chave de origem
+func takeExpandParentKey(entity any) string {
+	orderedEntity, ok := entity.(*OrderedEntity)
+	if !ok {
+		return ""
+	}
+	for _, prop := range orderedEntity.Properties {
+		if strings.EqualFold(prop.Name, expandParentKeyColumn) {
+			orderedEntity.Remove(prop.Name)
+			return expandKeyValue(prop.Value)
 		}
 	}
-
-	return filtered, nil
+	return ""
 }
 
 // applySelectToResults aplica seleção de campos aos resultados
@@ -0,0 +1,191 @@
+package odata
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// ErrPreconditionFailed indica que o token de concorrência não corresponde ao estado atual da entidade
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// concurrencyContextKeyType é o tipo da chave usada para propagar a condição de concorrência no contexto
+type concurrencyContextKeyType struct{}
+
+var concurrencyContextKey = concurrencyContextKeyType{}
+
+// ConcurrencyCondition representa a condição de concorrência validada a partir do If-Match
+type ConcurrencyCondition struct {
+	ETag   string                 // ETag validado contra o estado atual
+	Values map[string]interface{} // Valores esperados das propriedades do token, aplicados no WHERE
+}
+
+// WithConcurrencyCondition adiciona a condição de concorrência ao contexto
+func WithConcurrencyCondition(ctx context.Context, condition *ConcurrencyCondition) context.Context {
+	return context.WithValue(ctx, concurrencyContextKey, condition)
+}
+
+// ConcurrencyConditionFromContext obtém a condição de concorrência do contexto
+func ConcurrencyConditionFromContext(ctx context.Context) (*ConcurrencyCondition, bool) {
+	condition, ok := ctx.Value(concurrencyContextKey).(*ConcurrencyCondition)
+	return condition, ok && condition != nil
+}
+
+// VersionProperty retorna a propriedade de versão usada como ETag, se houver
+func (m EntityMetadata) VersionProperty() *PropertyMetadata {
+	if m.ETag == nil || m.ETag.Mode != ETagModeVersion || len(m.ETag.Properties) == 0 {
+		return nil
+	}
+	for i := range m.Properties {
+		if m.Properties[i].Name == m.ETag.Properties[0] {
+			return &m.Properties[i]
+		}
+	}
+	return nil
+}
+
+// ComputeETag calcula o ETag de uma entidade de acordo com seus metadados
+func ComputeETag(metadata EntityMetadata, entity interface{}) (string, bool) {
+	if metadata.ETag == nil || len(metadata.ETag.Properties) == 0 {
+		return "", false
+	}
+
+	values, ok := concurrencyValues(metadata, entity)
+	if !ok {
+		return "", false
+	}
+
+	if metadata.ETag.Mode == ETagModeVersion {
+		return fmt.Sprintf(`W/"%v"`, values[metadata.ETag.Properties[0]]), true
+	}
+
+	ordered := make([]interface{}, 0, len(metadata.ETag.Properties))
+	for _, name := range metadata.ETag.Properties {
+		ordered = append(ordered, values[name])
+	}
+
+	payload, err := json.Marshal(ordered)
+	if err != nil {
+		return "", false
+	}
+
+	sum := sha256.Sum256(payload)
+	return fmt.Sprintf(`W/"%s"`, hex.EncodeToString(sum[:16])), true
+}
+
+// concurrencyValues extrai os valores das propriedades que compõem o ETag
+func concurrencyValues(metadata EntityMetadata, entity interface{}) (map[string]interface{}, bool) {
+	var data map[string]interface{}
+	switch e := entity.(type) {
+	case *OrderedEntity:
+		data = e.ToMap()
+	case map[string]interface{}:
+		data = e
+	default:
+		return nil, false
+	}
+
+	values := make(map[string]interface{}, len(metadata.ETag.Properties))
+	for _, name := range metadata.ETag.Properties {
+		value, exists := data[name]
+		if !exists {
+			return nil, false
+		}
+		values[name] = value
+	}
+	return values, true
+}
+
+// etagMatches verifica se um ETag atende a um cabeçalho If-Match/If-None-Match (comparação fraca)
+func etagMatches(header, etag string) bool {
+	normalized := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == normalized {
+			return true
+		}
+	}
+	return false
+}
+
+// setEntityETag calcula o ETag da entidade, anota o payload e define o cabeçalho ETag
+func (s *Server) setEntityETag(c fiber.Ctx, metadata EntityMetadata, entity interface{}) {
+	etag, ok := ComputeETag(metadata, entity)
+	if !ok {
+		return
+	}
+
+	switch e := entity.(type) {
+	case *OrderedEntity:
+		e.ETag = etag
+	case map[string]interface{}:
+		e["@odata.etag"] = etag
+	}
+	c.Set(fiber.HeaderETag, etag)
+}
+
+// applyCollectionETags anota cada entidade de uma coleção com @odata.etag
+func (s *Server) applyCollectionETags(response *ODataResponse, metadata EntityMetadata) {
+	if response == nil || metadata.ETag == nil {
+		return
+	}
+
+	results, ok := response.Value.([]interface{})
+	if !ok {
+		return
+	}
+
+	for _, entity := range results {
+		etag, ok := ComputeETag(metadata, entity)
+		if !ok {
+			continue
+		}
+		switch e := entity.(type) {
+		case *OrderedEntity:
+			e.ETag = etag
+		case map[string]interface{}:
+			e["@odata.etag"] = etag
+		}
+	}
+}
+
+// checkIfMatch valida o cabeçalho If-Match e retorna o contexto com a condição de concorrência.
+// Quando a validação falha a resposta de erro já foi escrita e ok é false.
+func (s *Server) checkIfMatch(c fiber.Ctx, ctx context.Context, service EntityService, keys map[string]interface{}) (context.Context, bool) {
+	ifMatch := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	metadata := service.GetMetadata()
+	if ifMatch == "" || metadata.ETag == nil {
+		return ctx, true
+	}
+
+	current, err := service.Get(ctx, keys)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			s.writeError(c, fiber.StatusPreconditionFailed, "PreconditionFailed", "Entity does not exist")
+		} else {
+			s.writeError(c, fiber.StatusInternalServerError, "QueryError", err.Error())
+		}
+		return ctx, false
+	}
+
+	// If-Match: * aceita qualquer versão existente
+	if ifMatch == "*" {
+		return ctx, true
+	}
+
+	etag, ok := ComputeETag(metadata, current)
+	if !ok || !etagMatches(ifMatch, etag) {
+		s.writeError(c, fiber.StatusPreconditionFailed, "PreconditionFailed", "The entity has been modified")
+		return ctx, false
+	}
+
+	// Os valores atuais do token vão para o WHERE, tornando a verificação atômica
+	values, _ := concurrencyValues(metadata, current)
+	return WithConcurrencyCondition(ctx, &ConcurrencyCondition{ETag: etag, Values: values}), true
+}
@@ -0,0 +1,201 @@
+package odata
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ETagTestProduct struct {
+	ID      int64  `json:"id" column:"id" primaryKey:"idGenerator:none"`
+	Name    string `json:"name" column:"name"`
+	Version int64  `json:"version" column:"version" etag:"version"`
+}
+
+type ETagHashTestProduct struct {
+	ID    int64   `json:"id" column:"id" primaryKey:"idGenerator:none"`
+	Name  string  `json:"name" column:"name" etag:"hash"`
+	Price float64 `json:"price" column:"price" etag:"hash"`
+}
+
+// etagTestProvider registra os valores usados no WHERE de UPDATE e DELETE
+type etagTestProvider struct {
+	MockDatabaseProvider
+	whereValues map[string]interface{}
+}
+
+func (p *etagTestProvider) BuildUpdateQuery(metadata EntityMetadata, data map[string]interface{}, keys map[string]interface{}) (string, []interface{}, error) {
+	p.whereValues = keys
+	return "UPDATE products", nil, nil
+}
+
+func (p *etagTestProvider) BuildDeleteQuery(metadata EntityMetadata, keys map[string]interface{}) (string, []interface{}, error) {
+	p.whereValues = keys
+	return "DELETE FROM products", nil, nil
+}
+
+// newETagTestServer cria um servidor cujo banco devolve sempre o produto 1 na versão 3
+func newETagTestServer(t *testing.T) (*Server, *etagTestProvider, *fakeDB) {
+	db := newFakeDB(t, func(string) *fakeRows {
+		return newFakeRows([]string{"id", "name", "version"}, []driver.Value{int64(1), "Chair", int64(3)})
+	})
+	provider := &etagTestProvider{MockDatabaseProvider: MockDatabaseProvider{connection: db.DB}}
+	server := newTestServer(provider)
+	require.NoError(t, server.RegisterEntity("Products", ETagTestProduct{}))
+
+	return server, provider, db
+}
+
+func TestEntityMapper_ETag(t *testing.T) {
+	mapper := NewEntityMapper()
+
+	metadata, err := mapper.MapEntity(ETagTestProduct{})
+	require.NoError(t, err)
+	require.NotNil(t, metadata.ETag)
+	assert.Equal(t, ETagModeVersion, metadata.ETag.Mode)
+	assert.Equal(t, []string{"version"}, metadata.ETag.Properties)
+	require.NotNil(t, metadata.VersionProperty())
+	assert.Equal(t, "version", metadata.VersionProperty().ColumnName)
+
+	metadata, err = mapper.MapEntity(ETagHashTestProduct{})
+	require.NoError(t, err)
+	require.NotNil(t, metadata.ETag)
+	assert.Equal(t, ETagModeHash, metadata.ETag.Mode)
+	assert.Equal(t, []string{"name", "price"}, metadata.ETag.Properties)
+	assert.Nil(t, metadata.VersionProperty())
+
+	_, err = mapper.MapEntity(struct {
+		ID   int64  `json:"id" primaryKey:"idGenerator:none"`
+		Name string `json:"name" etag:"version"`
+	}{})
+	assert.Error(t, err)
+
+	_, err = mapper.MapEntity(struct {
+		ID      int64  `json:"id" primaryKey:"idGenerator:none"`
+		Name    string `json:"name" etag:"hash"`
+		Version int64  `json:"version" etag:"version"`
+	}{})
+	assert.Error(t, err)
+}
+
+func TestComputeETag(t *testing.T) {
+	metadata, err := NewEntityMapper().MapEntity(ETagHashTestProduct{})
+	require.NoError(t, err)
+
+	entity := NewOrderedEntity()
+	entity.Set("id", int64(1))
+	entity.Set("name", "Chair")
+	entity.Set("price", 10.5)
+
+	etag, ok := ComputeETag(metadata, entity)
+	require.True(t, ok)
+	assert.True(t, strings.HasPrefix(etag, `W/"`))
+
+	// Alterar uma propriedade fora do hash não altera o ETag
+	entity.Set("id", int64(2))
+	same, _ := ComputeETag(metadata, entity)
+	assert.Equal(t, etag, same)
+
+	entity.Set("price", 11.0)
+	changed, _ := ComputeETag(metadata, entity)
+	assert.NotEqual(t, etag, changed)
+
+	assert.True(t, etagMatches(`W/"1", W/"2"`, `W/"2"`))
+	assert.True(t, etagMatches(`"2"`, `W/"2"`))
+	assert.True(t, etagMatches(`*`, `W/"2"`))
+	assert.False(t, etagMatches(`W/"1"`, `W/"2"`))
+}
+
+func TestETag_Get(t *testing.T) {
+	server, _, _ := newETagTestServer(t)
+
+	resp, err := server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Products(1)", nil))
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, `W/"3"`, resp.Header.Get("ETag"))
+
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, `W/"3"`, body["@odata.etag"])
+
+	req := httptest.NewRequest("GET", "/odata/Products(1)", nil)
+	req.Header.Set("If-None-Match", `W/"3"`)
+	resp, err = server.GetRouter().Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, 304, resp.StatusCode)
+
+	req = httptest.NewRequest("GET", "/odata/Products(1)", nil)
+	req.Header.Set("If-None-Match", `W/"2"`)
+	resp, err = server.GetRouter().Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+}
+
+func TestETag_GetCollection(t *testing.T) {
+	server, _, _ := newETagTestServer(t)
+
+	resp, err := server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Products", nil))
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var body struct {
+		Value []map[string]interface{} `json:"value"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.Len(t, body.Value, 1)
+	assert.Equal(t, `W/"3"`, body.Value[0]["@odata.etag"])
+}
+
+func TestETag_IfMatch(t *testing.T) {
+	t.Run("Mismatch", func(t *testing.T) {
+		server, _, db := newETagTestServer(t)
+
+		req := httptest.NewRequest("PATCH", "/odata/Products(1)", strings.NewReader(`{"name":"Table"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", `W/"2"`)
+		resp, err := server.GetRouter().Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, 412, resp.StatusCode)
+		assert.Empty(t, db.execs)
+	})
+
+	t.Run("MatchAddsVersionToWhere", func(t *testing.T) {
+		server, provider, db := newETagTestServer(t)
+
+		req := httptest.NewRequest("PATCH", "/odata/Products(1)", strings.NewReader(`{"name":"Table"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", `W/"3"`)
+		resp, err := server.GetRouter().Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, int64(3), provider.whereValues["version"])
+		assert.Len(t, db.execs, 1)
+	})
+
+	t.Run("ConcurrentChange", func(t *testing.T) {
+		server, _, db := newETagTestServer(t)
+		db.rowsAffected = 0
+
+		req := httptest.NewRequest("DELETE", "/odata/Products(1)", nil)
+		req.Header.Set("If-Match", `W/"3"`)
+		resp, err := server.GetRouter().Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, 412, resp.StatusCode)
+	})
+
+	t.Run("Wildcard", func(t *testing.T) {
+		server, provider, _ := newETagTestServer(t)
+
+		req := httptest.NewRequest("DELETE", "/odata/Products(1)", nil)
+		req.Header.Set("If-Match", "*")
+		resp, err := server.GetRouter().Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, 204, resp.StatusCode)
+		assert.NotContains(t, provider.whereValues, "version")
+	})
+}
@@ -0,0 +1,326 @@
+package odata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrEventBusClosed indica que o barramento de eventos assíncronos foi encerrado
+var ErrEventBusClosed = errors.New("event bus closed")
+
+// AsyncEventConfig configura o barramento que executa os handlers assíncronos
+type AsyncEventConfig struct {
+	Workers         int              // Goroutines que executam os handlers (padrão 4)
+	QueueSize       int              // Capacidade da fila; com a fila cheia a publicação aguarda (padrão 1024)
+	MaxRetries      int              // Novas tentativas depois da primeira falha (padrão 3)
+	InitialBackoff  time.Duration    // Espera antes da primeira nova tentativa, dobrada a cada falha (padrão 1s)
+	MaxBackoff      time.Duration    // Espera máxima entre tentativas (padrão 1min)
+	DeadLetterLimit int              // Dead-letters mantidos em memória (padrão 1000)
+	OnDeadLetter    func(DeadLetter) // Chamado quando um handler esgota as tentativas
+}
+
+// DefaultAsyncEventConfig retorna a configuração padrão do barramento de eventos assíncronos
+func DefaultAsyncEventConfig() AsyncEventConfig {
+	return AsyncEventConfig{
+		Workers:         4,
+		QueueSize:       1024,
+		MaxRetries:      3,
+		InitialBackoff:  time.Second,
+		MaxBackoff:      time.Minute,
+		DeadLetterLimit: 1000,
+	}
+}
+
+// withDefaults completa os valores não informados com os da configuração padrão
+func (c AsyncEventConfig) withDefaults() AsyncEventConfig {
+	defaults := DefaultAsyncEventConfig()
+	if c.Workers <= 0 {
+		c.Workers = defaults.Workers
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = defaults.QueueSize
+	}
+	if c.MaxRetries < 0 {
+		c.MaxRetries = 0
+	}
+	if c.InitialBackoff <= 0 {
+		c.InitialBackoff = defaults.InitialBackoff
+	}
+	if c.MaxBackoff < c.InitialBackoff {
+		c.MaxBackoff = max(defaults.MaxBackoff, c.InitialBackoff)
+	}
+	if c.DeadLetterLimit <= 0 {
+		c.DeadLetterLimit = defaults.DeadLetterLimit
+	}
+	return c
+}
+
+// PermanentError é uma falha que não se resolve com novas tentativas: a entrega vai direto para o dead-letter
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent marca o erro de um handler assíncrono como permanente
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// DeadLetter é um evento cujo handler assíncrono falhou em todas as tentativas
+type DeadLetter struct {
+	EventType  EventType
+	EntityName string
+	Scope      string // "global" ou o nome da entidade do handler
+	Args       EventArgs
+	Attempts   int
+	Error      error
+	FailedAt   time.Time
+}
+
+// asyncDelivery é a execução pendente de um handler assíncrono
+type asyncDelivery struct {
+	handler  EventHandler
+	args     EventArgs
+	scope    string
+	attempts int
+}
+
+// EventBus executa handlers em um pool de workers, com novas tentativas e dead-letter
+type EventBus struct {
+	config      AsyncEventConfig
+	logger      *log.Logger
+	queue       chan *asyncDelivery
+	done        chan struct{}
+	workers     sync.WaitGroup
+	mu          sync.Mutex
+	closed      bool           // Recusa novas publicações; as novas tentativas ainda são enfileiradas
+	stopped     bool           // Workers parados: as novas tentativas vão direto para o dead-letter
+	senders     sync.WaitGroup // Entregas sendo colocadas na fila
+	inFlight    int            // Entregas não concluídas, inclusive as que aguardam nova tentativa
+	idle        chan struct{}  // Fechado quando não há entregas em andamento
+	deadLetters []DeadLetter
+}
+
+// NewEventBus cria o barramento e inicia os seus workers
+func NewEventBus(config AsyncEventConfig, logger *log.Logger) *EventBus {
+	if logger == nil {
+		logger = log.New(log.Writer(), "[EventBus] ", log.LstdFlags|log.Lshortfile)
+	}
+
+	config = config.withDefaults()
+	bus := &EventBus{
+		config: config,
+		logger: logger,
+		queue:  make(chan *asyncDelivery, config.QueueSize),
+		done:   make(chan struct{}),
+		idle:   make(chan struct{}),
+	}
+	close(bus.idle)
+
+	for i := 0; i < config.Workers; i++ {
+		bus.workers.Add(1)
+		go bus.work()
+	}
+	return bus
+}
+
+// Publish enfileira a execução do handler; com a fila cheia, aguarda espaço
+func (b *EventBus) Publish(handler EventHandler, args EventArgs, scope string) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrEventBusClosed
+	}
+	if b.inFlight == 0 {
+		b.idle = make(chan struct{})
+	}
+	b.inFlight++
+	b.mu.Unlock()
+
+	b.enqueue(&asyncDelivery{handler: handler, args: args, scope: scope})
+	return nil
+}
+
+// enqueue coloca a entrega na fila; com o barramento encerrado ela vai para o dead-letter
+func (b *EventBus) enqueue(delivery *asyncDelivery) {
+	// O estado é verificado sob o lock: uma nova tentativa agendada antes do Close não pode ficar na fila
+	// depois que os workers pararam
+	b.mu.Lock()
+	if b.stopped {
+		b.mu.Unlock()
+		b.deadLetter(delivery, ErrEventBusClosed)
+		return
+	}
+	b.senders.Add(1)
+	b.mu.Unlock()
+	defer b.senders.Done()
+
+	select {
+	case b.queue <- delivery:
+	case <-b.done:
+		b.deadLetter(delivery, ErrEventBusClosed)
+	}
+}
+
+// work executa as entregas da fila até o encerramento do barramento
+func (b *EventBus) work() {
+	defer b.workers.Done()
+	for {
+		select {
+		case delivery := <-b.queue:
+			b.deliver(delivery)
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// deliver executa o handler e, na falha, agenda uma nova tentativa ou envia a entrega ao dead-letter
+func (b *EventBus) deliver(delivery *asyncDelivery) {
+	delivery.attempts++
+	err := callEventHandler(delivery.handler, delivery.args)
+	if err == nil {
+		b.finish()
+		return
+	}
+
+	var permanent *PermanentError
+	if delivery.attempts > b.config.MaxRetries || errors.As(err, &permanent) {
+		b.deadLetter(delivery, err)
+		return
+	}
+
+	delay := b.backoff(delivery.attempts)
+	b.logger.Printf("⚠️ Handler assíncrono %s do evento %s falhou (tentativa %d), nova tentativa em %s: %v",
+		delivery.scope, delivery.args.GetEventType(), delivery.attempts, delay, err)
+	time.AfterFunc(delay, func() { b.enqueue(delivery) })
+}
+
+// backoff calcula a espera exponencial antes da próxima tentativa
+func (b *EventBus) backoff(attempts int) time.Duration {
+	delay := b.config.InitialBackoff
+	for i := 1; i < attempts && delay < b.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, b.config.MaxBackoff)
+}
+
+// deadLetter registra a entrega que esgotou as tentativas
+func (b *EventBus) deadLetter(delivery *asyncDelivery, err error) {
+	letter := DeadLetter{
+		EventType:  delivery.args.GetEventType(),
+		EntityName: delivery.args.GetEntityName(),
+		Scope:      delivery.scope,
+		Args:       delivery.args,
+		Attempts:   delivery.attempts,
+		Error:      err,
+		FailedAt:   time.Now(),
+	}
+	b.logger.Printf("❌ Handler assíncrono %s do evento %s enviado ao dead-letter após %d tentativa(s): %v",
+		letter.Scope, letter.EventType, letter.Attempts, err)
+
+	b.mu.Lock()
+	b.deadLetters = append(b.deadLetters, letter)
+	if len(b.deadLetters) > b.config.DeadLetterLimit {
+		b.deadLetters = b.deadLetters[len(b.deadLetters)-b.config.DeadLetterLimit:]
+	}
+	b.mu.Unlock()
+
+	if b.config.OnDeadLetter != nil {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					b.logger.Printf("PANIC no tratamento de dead-letter: %v", r)
+				}
+			}()
+			b.config.OnDeadLetter(letter)
+		}()
+	}
+	b.finish()
+}
+
+// finish conclui uma entrega
+func (b *EventBus) finish() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.inFlight--
+	if b.inFlight == 0 {
+		close(b.idle)
+	}
+}
+
+// DeadLetters retorna os eventos enviados ao dead-letter mantidos em memória
+func (b *EventBus) DeadLetters() []DeadLetter {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]DeadLetter(nil), b.deadLetters...)
+}
+
+// Wait aguarda a conclusão das entregas em andamento, inclusive das novas tentativas agendadas
+func (b *EventBus) Wait(ctx context.Context) error {
+	b.mu.Lock()
+	idle := b.idle
+	b.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close recusa novas publicações, aguarda as entregas em andamento até o fim do contexto e para os workers.
+// As entregas que não terminaram a tempo vão para o dead-letter
+func (b *EventBus) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.mu.Unlock()
+
+	err := b.Wait(ctx)
+
+	b.mu.Lock()
+	b.stopped = true
+	b.mu.Unlock()
+	close(b.done)
+	b.workers.Wait()
+	b.senders.Wait()
+
+	// Descarta para o dead-letter o que ficou na fila
+	for {
+		select {
+		case delivery := <-b.queue:
+			b.deadLetter(delivery, ErrEventBusClosed)
+		default:
+			return err
+		}
+	}
+}
+
+// callEventHandler executa o handler convertendo um pânico em erro
+func callEventHandler(handler EventHandler, args EventArgs) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handler.Handle(args)
+}
@@ -0,0 +1,142 @@
+package odata
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventBus_RetriesAndDeadLetters(t *testing.T) {
+	var notified atomic.Int32
+	bus := NewEventBus(AsyncEventConfig{
+		Workers:        2,
+		MaxRetries:     2,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     2 * time.Millisecond,
+		OnDeadLetter:   func(DeadLetter) { notified.Add(1) },
+	}, log.New(io.Discard, "", 0))
+	args := NewEntityInsertedArgs(&EventContext{EntityName: "Products"}, nil)
+
+	var calls atomic.Int32
+	flaky := EventHandlerFunc(func(EventArgs) error {
+		if calls.Add(1) < 3 {
+			return errors.New("search index unavailable")
+		}
+		return nil
+	})
+	require.NoError(t, bus.Publish(flaky, args, "Products"))
+	require.NoError(t, bus.Publish(EventHandlerFunc(func(EventArgs) error { panic("boom") }), args, "global"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, bus.Wait(ctx))
+	assert.Equal(t, int32(3), calls.Load())
+
+	// O pânico conta como falha e, esgotadas as tentativas, o evento vai para o dead-letter
+	letters := bus.DeadLetters()
+	require.Len(t, letters, 1)
+	assert.Equal(t, "global", letters[0].Scope)
+	assert.Equal(t, EventEntityInserted, letters[0].EventType)
+	assert.Equal(t, 3, letters[0].Attempts)
+	assert.EqualError(t, letters[0].Error, "panic: boom")
+	assert.Equal(t, int32(1), notified.Load())
+
+	require.NoError(t, bus.Close(ctx))
+	assert.ErrorIs(t, bus.Publish(flaky, args, "Products"), ErrEventBusClosed)
+}
+
+func TestEventBus_RetryAfterCloseTimeout(t *testing.T) {
+	bus := NewEventBus(AsyncEventConfig{Workers: 1, MaxRetries: 3, InitialBackoff: 30 * time.Millisecond}, log.New(io.Discard, "", 0))
+	failing := EventHandlerFunc(func(EventArgs) error { return errors.New("broker unavailable") })
+	require.NoError(t, bus.Publish(failing, NewEntityInsertedArgs(&EventContext{EntityName: "Products"}, nil), "Products"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, bus.Close(ctx), context.DeadlineExceeded)
+
+	// A nova tentativa agendada antes do Close vai para o dead-letter em vez de ficar presa na fila
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer waitCancel()
+	require.NoError(t, bus.Wait(waitCtx))
+	letters := bus.DeadLetters()
+	require.Len(t, letters, 1)
+	assert.ErrorIs(t, letters[0].Error, ErrEventBusClosed)
+	assert.Empty(t, bus.queue)
+}
+
+func TestServer_AsyncHandlersGetOwnArgs(t *testing.T) {
+	server, _, _, _ := newLifecycleTestServer(t)
+
+	// Os handlers alteram os argumentos em paralelo sem interferir um no outro
+	seen := make(chan interface{}, 2)
+	for _, marker := range []string{"a", "b"} {
+		require.NoError(t, server.OnEntityEventAsync(EventEntityInserted, "Products", func(args EventArgs) error {
+			inserted := args.(*EntityInsertedArgs)
+			inserted.NewID = marker
+			args.GetContext().Extra["handler"] = marker
+			time.Sleep(5 * time.Millisecond)
+			seen <- []interface{}{inserted.NewID, args.GetContext().Extra["handler"]}
+			return nil
+		}))
+	}
+
+	status, _ := sendLifecycleRequest(t, server, "POST", "/odata/Products", `{"id": 1, "name": "pen"}`)
+	require.Equal(t, 201, status)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, server.GetEventManager().WaitAsync(ctx))
+	close(seen)
+
+	var results []interface{}
+	for result := range seen {
+		results = append(results, result)
+	}
+	assert.ElementsMatch(t, []interface{}{[]interface{}{"a", "a"}, []interface{}{"b", "b"}}, results)
+}
+
+func TestServer_AsyncHandlersRunAfterCommit(t *testing.T) {
+	server, _, db, _ := newLifecycleTestServer(t)
+
+	delivered := make(chan *EventContext, 4)
+	require.NoError(t, server.OnEntityEventAsync(EventEntityInserted, "Products", func(args EventArgs) error {
+		delivered <- args.GetContext()
+		return nil
+	}))
+	assert.Error(t, server.OnEntityEventAsync(EventEntityInserting, "Products", func(args EventArgs) error { return nil }))
+
+	status, _ := sendLifecycleRequest(t, server, "POST", "/odata/Products", `{"id": 1, "name": "pen"}`)
+	require.Equal(t, 201, status)
+
+	// Uma gravação revertida não chega aos handlers assíncronos
+	service := server.entities["Products"]
+	err := server.RunInTransaction(context.Background(), func(ctx context.Context) error {
+		if _, err := server.insertEntity(ctx, "Products", service, map[string]interface{}{"id": 2, "name": "ink"}); err != nil {
+			return err
+		}
+		return errors.New("rollback everything")
+	})
+	assert.EqualError(t, err, "rollback everything")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, server.GetEventManager().WaitAsync(ctx))
+	close(delivered)
+
+	var contexts []*EventContext
+	for eventCtx := range delivered {
+		contexts = append(contexts, eventCtx)
+	}
+	require.Len(t, contexts, 1)
+	assert.Nil(t, contexts[0].FiberContext)
+	_, inTx := contexts[0].Tx()
+	assert.False(t, inTx)
+	assert.Equal(t, []string{"BEGIN", "INSERT INTO users", "COMMIT", "BEGIN", "INSERT INTO users", "ROLLBACK"}, db.entries())
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"maps"
 	"reflect"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,8 +18,9 @@ type EventType string
 
 const (
 	// Eventos de recuperação de dados
-	EventEntityGet  EventType = "EntityGet"
-	EventEntityList EventType = "EntityList"
+	EventEntityQuerying EventType = "EntityQuerying" // Antes da consulta
+	EventEntityGet      EventType = "EntityGet"
+	EventEntityList     EventType = "EntityList"
 
 	// Eventos de inserção (antes e depois)
 	EventEntityInserting EventType = "EntityInserting"
@@ -86,6 +89,9 @@ func (e *BaseEventArgs) CanCancel() bool              { return e.canCancel }
 func (e *BaseEventArgs) IsCanceled() bool             { return e.canceled }
 func (e *BaseEventArgs) GetCancelReason() string      { return e.cancelReason }
 
+// setContext substitui o contexto do evento (usado ao entregá-lo aos handlers assíncronos)
+func (e *BaseEventArgs) setContext(ctx *EventContext) { e.Context = ctx }
+
 func (e *BaseEventArgs) Cancel(reason string) {
 	if e.canCancel {
 		e.canceled = true
@@ -157,18 +163,29 @@ type EntityDeletedArgs struct {
 	DeletedEntity interface{}
 }
 
-// EntityValidatingArgs argumentos para evento OnEntityValidating
+// EntityValidatingArgs argumentos para evento OnEntityValidating. Details já traz as violações das regras
+// declaradas nas tags; validadores customizados acrescentam as suas com AddError
 type EntityValidatingArgs struct {
 	*BaseEventArgs
+	Keys             map[string]interface{} // Chaves da entidade (nil na inserção)
 	Data             map[string]interface{}
 	ValidationErrors []string
+	Details          []ODataErrorDetail
+}
+
+// AddError registra uma violação; target é a propriedade inválida (vazio para erros da entidade)
+func (e *EntityValidatingArgs) AddError(target, message string) {
+	e.ValidationErrors = append(e.ValidationErrors, message)
+	e.Details = append(e.Details, ODataErrorDetail{Code: "ValidationError", Message: message, Target: target})
 }
 
 // EntityValidatedArgs argumentos para evento OnEntityValidated
 type EntityValidatedArgs struct {
 	*BaseEventArgs
+	Keys             map[string]interface{}
 	Data             map[string]interface{}
 	ValidationErrors []string
+	Details          []ODataErrorDetail
 	IsValid          bool
 }
 
@@ -194,12 +211,19 @@ func (f EventHandlerFunc) Handle(args EventArgs) error {
 	return f(args)
 }
 
+// asyncHandler marca um handler executado pelo barramento de eventos assíncronos
+type asyncHandler struct {
+	EventHandler
+}
+
 // EntityEventManager gerencia todos os eventos de entidade
 type EntityEventManager struct {
-	mu       sync.RWMutex
-	handlers map[EventType]map[string][]EventHandler // EventType -> EntityName -> []Handler
-	global   map[EventType][]EventHandler            // Handlers globais por tipo
-	logger   *log.Logger
+	mu          sync.RWMutex
+	handlers    map[EventType]map[string][]EventHandler // EventType -> EntityName -> []Handler
+	global      map[EventType][]EventHandler            // Handlers globais por tipo
+	logger      *log.Logger
+	asyncConfig AsyncEventConfig // Configuração do barramento dos handlers assíncronos
+	bus         *EventBus        // Criado no registro do primeiro handler assíncrono
 }
 
 // NewEntityEventManager cria um novo gerenciador de eventos
@@ -247,24 +271,126 @@ func (em *EntityEventManager) SubscribeGlobalFunc(eventType EventType, handler f
 	em.SubscribeGlobal(eventType, EventHandlerFunc(handler))
 }
 
-// Emit dispara um evento
+// SetAsyncConfig define a configuração do barramento dos handlers assíncronos; deve ser chamado antes
+// do registro do primeiro handler assíncrono
+func (em *EntityEventManager) SetAsyncConfig(config AsyncEventConfig) {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	em.asyncConfig = config
+}
+
+// SubscribeAsync registra um handler executado em segundo plano pelo barramento de eventos, depois da
+// confirmação da transação da operação. Apenas eventos que não podem ser cancelados aceitam handlers assíncronos
+func (em *EntityEventManager) SubscribeAsync(eventType EventType, entityName string, handler EventHandler) error {
+	if err := em.startBus(eventType); err != nil {
+		return err
+	}
+	em.Subscribe(eventType, entityName, asyncHandler{handler})
+	return nil
+}
+
+// SubscribeGlobalAsync registra um handler global executado em segundo plano pelo barramento de eventos
+func (em *EntityEventManager) SubscribeGlobalAsync(eventType EventType, handler EventHandler) error {
+	if err := em.startBus(eventType); err != nil {
+		return err
+	}
+	em.SubscribeGlobal(eventType, asyncHandler{handler})
+	return nil
+}
+
+// startBus verifica se o evento aceita handlers assíncronos e inicia o barramento
+func (em *EntityEventManager) startBus(eventType EventType) error {
+	switch eventType {
+	case EventEntityGet, EventEntityList, EventEntityInserted, EventEntityModified, EventEntityDeleted, EventEntityError:
+	default:
+		return fmt.Errorf("event %s does not support asynchronous handlers", eventType)
+	}
+
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	if em.bus == nil {
+		em.bus = NewEventBus(em.asyncConfig, em.logger)
+	}
+	return nil
+}
+
+// DeadLetters retorna os eventos cujos handlers assíncronos esgotaram as tentativas
+func (em *EntityEventManager) DeadLetters() []DeadLetter {
+	if bus := em.eventBus(); bus != nil {
+		return bus.DeadLetters()
+	}
+	return nil
+}
+
+// WaitAsync aguarda a conclusão dos handlers assíncronos em andamento
+func (em *EntityEventManager) WaitAsync(ctx context.Context) error {
+	if bus := em.eventBus(); bus != nil {
+		return bus.Wait(ctx)
+	}
+	return nil
+}
+
+// Close aguarda os handlers assíncronos em andamento até o fim do contexto e encerra o barramento
+func (em *EntityEventManager) Close(ctx context.Context) error {
+	if bus := em.eventBus(); bus != nil {
+		return bus.Close(ctx)
+	}
+	return nil
+}
+
+// eventBus retorna o barramento dos handlers assíncronos, se iniciado
+func (em *EntityEventManager) eventBus() *EventBus {
+	em.mu.RLock()
+	defer em.mu.RUnlock()
+	return em.bus
+}
+
+// EventCanceledError indica que um handler cancelou a operação do evento
+type EventCanceledError struct {
+	EventType EventType
+	Reason    string
+}
+
+func (e *EventCanceledError) Error() string {
+	return fmt.Sprintf("evento cancelado: %s", e.Reason)
+}
+
+// Emit dispara um evento. Os handlers assíncronos são enfileirados depois que os síncronos terminam sem erro
+// e, se o evento ocorreu em uma transação, apenas após a sua confirmação
 func (em *EntityEventManager) Emit(args EventArgs) error {
+	deliveries, err := em.emit(args)
+	if err != nil {
+		return err
+	}
+	if len(deliveries) > 0 {
+		em.dispatchAsync(args, deliveries)
+	}
+	return nil
+}
+
+// emit executa os handlers síncronos e retorna as entregas dos assíncronos
+func (em *EntityEventManager) emit(args EventArgs) ([]asyncDelivery, error) {
 	em.mu.RLock()
 	defer em.mu.RUnlock()
 
 	eventType := args.GetEventType()
 	entityName := args.GetEntityName()
+	var deliveries []asyncDelivery
 
 	// Executa handlers globais primeiro
 	if globalHandlers, exists := em.global[eventType]; exists {
 		for _, handler := range globalHandlers {
+			if async, ok := handler.(asyncHandler); ok {
+				deliveries = append(deliveries, asyncDelivery{handler: async.EventHandler, scope: "global"})
+				continue
+			}
 			if err := em.executeHandler(handler, args, "global"); err != nil {
-				return err
+				return nil, err
 			}
 
 			// Verifica se o evento foi cancelado
 			if args.IsCanceled() {
-				return fmt.Errorf("evento cancelado: %s", args.GetCancelReason())
+				return nil, &EventCanceledError{EventType: eventType, Reason: args.GetCancelReason()}
 			}
 		}
 	}
@@ -273,19 +399,132 @@ func (em *EntityEventManager) Emit(args EventArgs) error {
 	if entityHandlers, exists := em.handlers[eventType]; exists {
 		if handlers, exists := entityHandlers[entityName]; exists {
 			for _, handler := range handlers {
+				if async, ok := handler.(asyncHandler); ok {
+					deliveries = append(deliveries, asyncDelivery{handler: async.EventHandler, scope: entityName})
+					continue
+				}
 				if err := em.executeHandler(handler, args, entityName); err != nil {
-					return err
+					return nil, err
 				}
 
 				// Verifica se o evento foi cancelado
 				if args.IsCanceled() {
-					return fmt.Errorf("evento cancelado: %s", args.GetCancelReason())
+					return nil, &EventCanceledError{EventType: eventType, Reason: args.GetCancelReason()}
 				}
 			}
 		}
 	}
 
-	return nil
+	return deliveries, nil
+}
+
+// dispatchAsync publica o evento para os handlers assíncronos depois da confirmação da transação do evento.
+// Os handlers recebem um contexto desvinculado da requisição e da transação, que já terminaram
+func (em *EntityEventManager) dispatchAsync(args EventArgs, deliveries []asyncDelivery) {
+	bus := em.eventBus()
+	eventCtx := args.GetContext()
+	ctx := context.Background()
+	if eventCtx != nil && eventCtx.Context != nil {
+		ctx = eventCtx.Context
+	}
+
+	// A cópia é feita agora: após a confirmação a requisição (de um $batch, por exemplo) pode já ter terminado
+	detached := detachEventContext(eventCtx)
+	AfterCommit(ctx, func() {
+		// Cada handler recebe a sua cópia dos argumentos, já que os workers os executam em paralelo
+		for _, delivery := range deliveries {
+			if err := bus.Publish(delivery.handler, copyEventArgs(args, detached), delivery.scope); err != nil {
+				em.logger.Printf("Erro ao publicar o evento %s para o handler assíncrono %s: %v", args.GetEventType(), delivery.scope, err)
+			}
+		}
+	})
+}
+
+// copyEventArgs copia os argumentos do evento com uma cópia do contexto informado. Os campos e os mapas
+// de primeiro nível são copiados; as entidades referenciadas continuam compartilhadas
+func copyEventArgs(args EventArgs, eventCtx *EventContext) EventArgs {
+	value := reflect.ValueOf(args)
+	if value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return args
+	}
+
+	copied := reflect.New(value.Elem().Type())
+	copied.Elem().Set(value.Elem())
+	for i := 0; i < copied.Elem().NumField(); i++ {
+		field := copied.Elem().Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		switch v := field.Interface().(type) {
+		case *BaseEventArgs:
+			if v != nil {
+				base := *v
+				field.Set(reflect.ValueOf(&base))
+			}
+		case map[string]interface{}:
+			if v != nil {
+				field.Set(reflect.ValueOf(cloneValues(v)))
+			}
+		}
+	}
+
+	result, ok := copied.Interface().(EventArgs)
+	if !ok {
+		return args
+	}
+	if detachable, ok := result.(interface{ setContext(*EventContext) }); ok && eventCtx != nil {
+		ctxCopy := *eventCtx
+		ctxCopy.Extra = maps.Clone(eventCtx.Extra)
+		detachable.setContext(&ctxCopy)
+	}
+	return result
+}
+
+// cloneValues copia o mapa e as strings dos valores: chaves lidas da URL apontam para o buffer da requisição,
+// reutilizado depois da resposta
+func cloneValues(values map[string]interface{}) map[string]interface{} {
+	if values == nil {
+		return nil
+	}
+	cloned := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		if text, ok := value.(string); ok {
+			value = strings.Clone(text)
+		}
+		cloned[name] = value
+	}
+	return cloned
+}
+
+// detachEventContext copia o contexto do evento sem a requisição e a transação; o tenant é mantido em TenantContextKey
+func detachEventContext(eventCtx *EventContext) *EventContext {
+	if eventCtx == nil {
+		return nil
+	}
+
+	detached := *eventCtx
+	detached.FiberContext = nil
+	detached.Context = context.Background()
+	if tenantID := contextTenant(eventCtx); tenantID != "" {
+		detached.Context = context.WithValue(detached.Context, TenantContextKey, tenantID)
+	}
+	return &detached
+}
+
+// contextTenant retorna o tenant da requisição ou do contexto do evento, se houver
+func contextTenant(eventCtx *EventContext) string {
+	if eventCtx.FiberContext != nil {
+		// O valor lido do cabeçalho aponta para o buffer da requisição, reutilizado depois da resposta
+		if tenantID, ok := eventCtx.FiberContext.Locals(TenantContextKey).(string); ok {
+			return strings.Clone(tenantID)
+		}
+	}
+	if eventCtx.Context != nil {
+		if tenantID, ok := eventCtx.Context.Value(TenantContextKey).(string); ok {
+			return tenantID
+		}
+	}
+	return ""
 }
 
 // executeHandler executa um handler com tratamento de erro
@@ -326,6 +565,16 @@ func (em *EntityEventManager) GetHandlerCount(eventType EventType, entityName st
 	return count
 }
 
+// HasHandlers verifica se há handlers globais ou da entidade para algum dos eventos
+func (em *EntityEventManager) HasHandlers(entityName string, eventTypes ...EventType) bool {
+	for _, eventType := range eventTypes {
+		if em.GetHandlerCount(eventType, entityName) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // ListSubscriptions lista todas as assinaturas de eventos
 func (em *EntityEventManager) ListSubscriptions() map[string]interface{} {
 	em.mu.RLock()
@@ -510,6 +759,51 @@ func NewEntityDeletedArgs(ctx *EventContext, keys map[string]interface{}, entity
 	}
 }
 
+// NewEntityValidatingArgs cria argumentos para evento EntityValidating
+func NewEntityValidatingArgs(ctx *EventContext, keys map[string]interface{}, data map[string]interface{}, details []ODataErrorDetail) *EntityValidatingArgs {
+	validationErrors := make([]string, 0, len(details))
+	for _, detail := range details {
+		validationErrors = append(validationErrors, detail.Message)
+	}
+
+	return &EntityValidatingArgs{
+		BaseEventArgs: &BaseEventArgs{
+			Context:    ctx,
+			EventType:  EventEntityValidating,
+			EntityName: ctx.EntityName,
+			Entity:     data,
+			canCancel:  true,
+		},
+		Keys:             keys,
+		Data:             data,
+		ValidationErrors: validationErrors,
+		Details:          details,
+	}
+}
+
+// NewEntityValidatedArgs cria argumentos para evento EntityValidated
+func NewEntityValidatedArgs(ctx *EventContext, keys map[string]interface{}, data map[string]interface{}, details []ODataErrorDetail) *EntityValidatedArgs {
+	validationErrors := make([]string, 0, len(details))
+	for _, detail := range details {
+		validationErrors = append(validationErrors, detail.Message)
+	}
+
+	return &EntityValidatedArgs{
+		BaseEventArgs: &BaseEventArgs{
+			Context:    ctx,
+			EventType:  EventEntityValidated,
+			EntityName: ctx.EntityName,
+			Entity:     data,
+			canCancel:  false,
+		},
+		Keys:             keys,
+		Data:             data,
+		ValidationErrors: validationErrors,
+		Details:          details,
+		IsValid:          len(details) == 0,
+	}
+}
+
 // NewEntityErrorArgs cria argumentos para evento EntityError
 func NewEntityErrorArgs(ctx *EventContext, err error, operation string, statusCode int) *EntityErrorArgs {
 	return &EntityErrorArgs{
@@ -0,0 +1,222 @@
+package odata
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	// expandParentKeyColumn é a coluna que liga cada entidade relacionada à entidade de origem
+	expandParentKeyColumn = "odata_parent_key"
+	// expandRowColumn numera as entidades relacionadas de cada entidade de origem
+	expandRowColumn = "odata_row"
+	// expandCountColumn guarda o total de entidades relacionadas de cada entidade de origem
+	expandCountColumn = "odata_count"
+	// expandBatchSize limita a quantidade de chaves por IN (o Oracle aceita no máximo 1000)
+	expandBatchSize = 1000
+)
+
+// ExpandSource descreve de onde vêm as entidades relacionadas de uma navegação expandida
+type ExpandSource struct {
+	From           string // Tabela (ou subquery com alias) das entidades relacionadas
+	KeyColumn      string // Coluna com o valor da chave da entidade de origem
+	ParentProperty string // Propriedade da entidade de origem comparada com KeyColumn
+}
+
+// ExpandQuery representa uma consulta em lote de entidades relacionadas
+type ExpandQuery struct {
+	SQL  string
+	Args []interface{}
+}
+
+// BuildExpandSource resolve a origem das entidades relacionadas a partir dos metadados da navegação
+func (qb *QueryBuilder) BuildExpandSource(metadata EntityMetadata, navProp PropertyMetadata, related EntityMetadata) (ExpandSource, error) {
+	relatedTable := lambdaTableName(related)
+
+	// N:N através da tabela de junção
+	if many := navProp.ManyAssociation; many != nil && many.JoinTable != "" {
+		if many.JoinColumn == "" || many.InverseJoinColumn == "" {
+			return ExpandSource{}, fmt.Errorf("navigation property '%s' requires joinColumn and inverseJoinColumn", navProp.Name)
+		}
+
+		relatedKey := lambdaKeyColumn(related)
+		parentProperty := expandPropertyName(metadata, many.References, expandKeyProperty(metadata))
+		if relatedKey == "" || parentProperty == "" {
+			return ExpandSource{}, fmt.Errorf("navigation property '%s' requires key properties on both entities", navProp.Name)
+		}
+
+		// O alias com o nome da tabela mantém válidas as referências qualificadas do $filter
+		from := fmt.Sprintf("(SELECT %s.*, %s.%s AS %s FROM %s INNER JOIN %s ON %s.%s = %s.%s) %s",
+			relatedTable, many.JoinTable, many.JoinColumn, expandParentKeyColumn,
+			relatedTable, many.JoinTable, many.JoinTable, many.InverseJoinColumn, relatedTable, relatedKey,
+			relatedTable)
+		return ExpandSource{From: from, KeyColumn: expandParentKeyColumn, ParentProperty: parentProperty}, nil
+	}
+
+	if navProp.Relationship == nil {
+		return ExpandSource{}, fmt.Errorf("navigation property has no relationship metadata")
+	}
+
+	// 1:N pela chave estrangeira na entidade relacionada, N:1 pela chave referenciada
+	keyColumn := lambdaColumnName(related, navProp.Relationship.ReferencedProperty, lambdaKeyColumn(related))
+	parentProperty := expandPropertyName(metadata, navProp.Relationship.LocalProperty, "")
+	if keyColumn == "" || parentProperty == "" {
+		return ExpandSource{}, fmt.Errorf("navigation property '%s' has incomplete relationship metadata", navProp.Name)
+	}
+
+	return ExpandSource{From: relatedTable, KeyColumn: keyColumn, ParentProperty: parentProperty}, nil
+}
+
+// BuildExpandQuery constrói a consulta das entidades relacionadas a um conjunto de chaves de origem.
+// $top/$skip são aplicados por entidade de origem usando ROW_NUMBER() particionado pela chave.
+func (qb *QueryBuilder) BuildExpandQuery(ctx context.Context, metadata EntityMetadata, source ExpandSource, keys []interface{}, options QueryOptions) (*ExpandQuery, error) {
+	namedArgs := NewNamedArgs(qb.dialect)
+
+	where, err := qb.buildExpandWhere(ctx, metadata, source, keys, options, namedArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	orderBy, err := qb.buildExpandOrderBy(metadata, options.OrderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := qb.BuildSelectClause(metadata, nil)
+	top := GetTopValue(options.Top)
+	skip := GetSkipValue(options.Skip)
+
+	var query string
+	if top == 0 && skip == 0 {
+		query = fmt.Sprintf("SELECT %s, %s AS %s FROM %s WHERE %s", columns, source.KeyColumn, expandParentKeyColumn, source.From, where)
+		if orderBy != "" {
+			query += " ORDER BY " + orderBy
+		}
+	} else {
+		// A numeração precisa de uma ordem determinística
+		windowOrder := orderBy
+		if windowOrder == "" {
+			windowOrder = qb.buildExpandKeyOrder(metadata)
+		}
+
+		inner := fmt.Sprintf("SELECT %s, %s AS %s, ROW_NUMBER() OVER (PARTITION BY %s ORDER BY %s) AS %s FROM %s WHERE %s",
+			columns, source.KeyColumn, expandParentKeyColumn, source.KeyColumn, windowOrder, expandRowColumn, source.From, where)
+
+		rows := fmt.Sprintf("%s > %d", expandRowColumn, skip)
+		if top > 0 {
+			rows += fmt.Sprintf(" AND %s <= %d", expandRowColumn, skip+top)
+		}
+
+		query = fmt.Sprintf("SELECT %s, %s FROM (%s) expand_rows WHERE %s ORDER BY %s",
+			columns, expandParentKeyColumn, inner, rows, expandRowColumn)
+	}
+
+	return &ExpandQuery{SQL: query, Args: namedArgs.GetArgs()}, nil
+}
+
+// BuildExpandCountQuery constrói a consulta do total de entidades relacionadas por chave de origem ($count no $expand)
+func (qb *QueryBuilder) BuildExpandCountQuery(ctx context.Context, metadata EntityMetadata, source ExpandSource, keys []interface{}, options QueryOptions) (*ExpandQuery, error) {
+	namedArgs := NewNamedArgs(qb.dialect)
+
+	where, err := qb.buildExpandWhere(ctx, metadata, source, keys, options, namedArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT %s AS %s, COUNT(*) AS %s FROM %s WHERE %s GROUP BY %s",
+		source.KeyColumn, expandParentKeyColumn, expandCountColumn, source.From, where, source.KeyColumn)
+
+	return &ExpandQuery{SQL: query, Args: namedArgs.GetArgs()}, nil
+}
+
+// buildExpandWhere restringe as entidades relacionadas às chaves de origem e aplica o $filter
+func (qb *QueryBuilder) buildExpandWhere(ctx context.Context, metadata EntityMetadata, source ExpandSource, keys []interface{}, options QueryOptions, namedArgs *NamedArgs) (string, error) {
+	if len(keys) == 0 {
+		return "", fmt.Errorf("expand query requires at least one key")
+	}
+
+	placeholders := make([]string, 0, len(keys))
+	for _, key := range keys {
+		placeholders = append(placeholders, namedArgs.AddArg(key))
+	}
+	where := fmt.Sprintf("%s IN (%s)", source.KeyColumn, strings.Join(placeholders, ", "))
+
+	if options.Filter != nil && options.Filter.Tree != nil {
+		condition, err := qb.BuildWhereClauseNamed(ctx, options.Filter.Tree, metadata, namedArgs)
+		if err != nil {
+			return "", fmt.Errorf("failed to build expand filter: %w", err)
+		}
+		if condition != "" {
+			where += " AND " + condition
+		}
+	}
+
+	return where, nil
+}
+
+// buildExpandOrderBy valida e traduz o $orderby da expansão
+func (qb *QueryBuilder) buildExpandOrderBy(metadata EntityMetadata, orderBy string) (string, error) {
+	expressions, err := NewODataParser().ParseOrderBy(orderBy)
+	if err != nil {
+		return "", err
+	}
+	for _, expr := range expressions {
+		prop, err := findFilterProperty(metadata, expr.Property)
+		if err != nil {
+			return "", err
+		}
+		if prop.IsNavigation {
+			return "", fmt.Errorf("navigation property '%s' cannot be used in $orderby", expr.Property)
+		}
+	}
+	return qb.BuildOrderByClause(metadata, expressions), nil
+}
+
+// buildExpandKeyOrder ordena pelas chaves da entidade relacionada
+func (qb *QueryBuilder) buildExpandKeyOrder(metadata EntityMetadata) string {
+	var columns []string
+	for _, prop := range metadata.Properties {
+		if prop.IsKey {
+			columns = append(columns, lambdaColumnName(metadata, prop.Name, prop.Name)+" ASC")
+		}
+	}
+	if len(columns) == 0 {
+		return expandParentKeyColumn
+	}
+	return strings.Join(columns, ", ")
+}
+
+// expandPropertyName resolve o nome da propriedade a partir do nome da propriedade ou da coluna
+func expandPropertyName(metadata EntityMetadata, name, fallback string) string {
+	if name == "" {
+		return fallback
+	}
+	for _, prop := range metadata.Properties {
+		if prop.IsNavigation {
+			continue
+		}
+		if strings.EqualFold(prop.Name, name) || strings.EqualFold(prop.ColumnName, name) {
+			return prop.Name
+		}
+	}
+	return name
+}
+
+// expandKeyProperty retorna a primeira propriedade chave da entidade
+func expandKeyProperty(metadata EntityMetadata) string {
+	for _, prop := range metadata.Properties {
+		if prop.IsKey {
+			return prop.Name
+		}
+	}
+	return ""
+}
+
+// expandKeyValue normaliza o valor de uma chave para agrupar as entidades relacionadas
+func expandKeyValue(value interface{}) string {
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,125 @@
+package odata
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryBuilder_BuildExpandQuery(t *testing.T) {
+	ctx := lambdaTestContext()
+	entities := lambdaTestEntities()
+	customers := entities["Customer"]
+	orders := entities["Order"]
+
+	ordersProp, err := findFilterProperty(customers, "Orders")
+	require.NoError(t, err)
+	tagsProp, err := findFilterProperty(customers, "Tags")
+	require.NoError(t, err)
+
+	qb := NewQueryBuilder("postgresql")
+	source, err := qb.BuildExpandSource(customers, ordersProp, orders)
+	require.NoError(t, err)
+	assert.Equal(t, ExpandSource{From: "orders", KeyColumn: "customer_id", ParentProperty: "ID"}, source)
+
+	keys := []interface{}{int64(1), int64(2)}
+
+	t.Run("filter and orderby", func(t *testing.T) {
+		filter, err := ParseFilterString(ctx, "Total gt 10")
+		require.NoError(t, err)
+
+		query, err := qb.BuildExpandQuery(ctx, orders, source, keys, QueryOptions{Filter: filter, OrderBy: "Total desc"})
+		require.NoError(t, err)
+		assert.Equal(t, "SELECT id, customer_id, total, customer_id AS odata_parent_key FROM orders WHERE customer_id IN (:param1, :param2) AND (total > :param3) ORDER BY total DESC", query.SQL)
+		assert.Len(t, query.Args, 3)
+	})
+
+	t.Run("top and skip per parent", func(t *testing.T) {
+		top := GoDataTopQuery(2)
+		skip := GoDataSkipQuery(1)
+
+		query, err := qb.BuildExpandQuery(ctx, orders, source, keys, QueryOptions{Top: &top, Skip: &skip})
+		require.NoError(t, err)
+		assert.Equal(t, "SELECT id, customer_id, total, odata_parent_key FROM (SELECT id, customer_id, total, customer_id AS odata_parent_key, "+
+			"ROW_NUMBER() OVER (PARTITION BY customer_id ORDER BY id ASC) AS odata_row FROM orders WHERE customer_id IN (:param1, :param2)) expand_rows "+
+			"WHERE odata_row > 1 AND odata_row <= 3 ORDER BY odata_row", query.SQL)
+	})
+
+	t.Run("count", func(t *testing.T) {
+		query, err := qb.BuildExpandCountQuery(ctx, orders, source, keys, QueryOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, "SELECT customer_id AS odata_parent_key, COUNT(*) AS odata_count FROM orders WHERE customer_id IN (:param1, :param2) GROUP BY customer_id", query.SQL)
+	})
+
+	t.Run("many to many", func(t *testing.T) {
+		source, err := qb.BuildExpandSource(customers, tagsProp, entities["Tag"])
+		require.NoError(t, err)
+
+		query, err := qb.BuildExpandQuery(ctx, entities["Tag"], source, keys, QueryOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, "SELECT id, label, odata_parent_key AS odata_parent_key FROM (SELECT tags.*, customer_tags.customer_id AS odata_parent_key "+
+			"FROM tags INNER JOIN customer_tags ON customer_tags.tag_id = tags.id) tags WHERE odata_parent_key IN (:param1, :param2)", query.SQL)
+	})
+
+	_, err = qb.BuildExpandQuery(ctx, orders, source, keys, QueryOptions{OrderBy: "Missing"})
+	assert.Error(t, err)
+}
+
+type ExpandTestCustomer struct {
+	TableName string            `table:"customers"`
+	ID        int64             `json:"ID" column:"id" primaryKey:"idGenerator:none"`
+	Name      string            `json:"Name" column:"name"`
+	Orders    []ExpandTestOrder `json:"Orders" manyAssociation:"foreignKey:customer_id; references:id"`
+}
+
+type ExpandTestOrder struct {
+	TableName  string `table:"orders"`
+	ID         int64  `json:"ID" column:"id" primaryKey:"idGenerator:none"`
+	CustomerID int64  `json:"CustomerID" column:"customer_id"`
+}
+
+func TestServer_ExpandBatched(t *testing.T) {
+	// O banco devolve clientes ou pedidos conforme a tabela consultada
+	db := newFakeDB(t, func(query string) *fakeRows {
+		switch {
+		case strings.Contains(query, "COUNT(*)"):
+			return newFakeRows([]string{"odata_parent_key", "odata_count"}, []driver.Value{int64(1), int64(2)})
+		case strings.Contains(query, "FROM orders"):
+			return newFakeRows([]string{"id", "customer_id", "odata_parent_key"},
+				[]driver.Value{int64(10), int64(1), int64(1)}, []driver.Value{int64(11), int64(1), int64(1)})
+		default:
+			return newFakeRows([]string{"id", "name"}, []driver.Value{int64(1), "Ann"}, []driver.Value{int64(2), "Bob"})
+		}
+	})
+	server := newTestServer(&MockDatabaseProvider{connection: db.DB})
+	require.NoError(t, server.RegisterEntity("Customers", ExpandTestCustomer{}))
+	require.NoError(t, server.RegisterEntity("Orders", ExpandTestOrder{}))
+
+	resp, err := server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Customers?$expand=Orders($count=true)", nil))
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+
+	var body struct {
+		Value []map[string]interface{} `json:"value"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.Len(t, body.Value, 2)
+
+	// Uma consulta para os clientes, uma para os pedidos e uma para o total
+	assert.Len(t, db.queries, 3)
+	assert.Contains(t, db.queries[1], "customer_id IN (:param1, :param2)")
+
+	orders, ok := body.Value[0]["Orders"].([]interface{})
+	require.True(t, ok)
+	require.Len(t, orders, 2)
+	assert.NotContains(t, orders[0], "odata_parent_key")
+	assert.Equal(t, float64(2), body.Value[0]["Orders@odata.count"])
+
+	assert.Equal(t, []interface{}{}, body.Value[1]["Orders"])
+	assert.Equal(t, float64(0), body.Value[1]["Orders@odata.count"])
+}
@@ -45,6 +45,7 @@ type ExpandItem struct {
 	Select  *GoDataSelectQuery
 	Compute *GoDataComputeQuery
 	Expand  *GoDataExpandQuery
+	Count   *GoDataCountQuery
 	Levels  int
 }
 
@@ -291,6 +292,12 @@ func ParseExpandOption(ctx context.Context, queue *TokenQueue, item *ExpandItem)
 			return fmt.Errorf("failed to parse nested expand: %w", err)
 		}
 		item.Expand = expand
+	case "count":
+		count, err := ParseCountString(body)
+		if err != nil {
+			return fmt.Errorf("failed to parse count in expand: %w", err)
+		}
+		item.Count = count
 	case "levels":
 		levels, err := strconv.Atoi(body)
 		if err != nil {
@@ -503,7 +510,7 @@ func IsSimpleExpand(expand *GoDataExpandQuery) bool {
 	for _, item := range expand.ExpandItems {
 		if item.Filter != nil || item.OrderBy != nil || item.Select != nil ||
 			item.Expand != nil || item.Skip != nil || item.Top != nil ||
-			item.Compute != nil || item.Search != nil || item.Levels > 0 {
+			item.Compute != nil || item.Search != nil || item.Count != nil || item.Levels > 0 {
 			return false
 		}
 	}
@@ -549,6 +556,9 @@ func formatExpandItem(item *ExpandItem) string {
 	if item.Top != nil {
 		options = append(options, fmt.Sprintf("$top=%d", int(*item.Top)))
 	}
+	if IsCountRequested(item.Count) {
+		options = append(options, "$count=true")
+	}
 	if item.Levels > 0 {
 		options = append(options, fmt.Sprintf("$levels=%d", item.Levels))
 	}
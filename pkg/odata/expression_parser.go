@@ -115,7 +115,7 @@ func (p *ExpressionParser) InfixToPostfix(ctx context.Context, tokens []*Token)
 	var output []*Token
 	var operatorStack []*Token
 
-	for _, token := range tokens {
+	for i, token := range tokens {
 		// Verifica cancelamento do contexto
 		select {
 		case <-ctx.Done():
@@ -126,12 +126,12 @@ func (p *ExpressionParser) InfixToPostfix(ctx context.Context, tokens []*Token)
 		switch token.Type {
 		case int(FilterTokenProperty), int(FilterTokenString), int(FilterTokenNumber), int(FilterTokenBoolean), int(FilterTokenNull),
 			int(FilterTokenDateTime), int(FilterTokenDate), int(FilterTokenTime), int(FilterTokenGuid), int(FilterTokenDuration),
-			int(FilterTokenGeographyPoint), int(FilterTokenGeometryPoint):
+			int(FilterTokenGeographyPoint), int(FilterTokenGeometryPoint), int(FilterTokenLambdaVariable):
 			// Operandos vão direto para output
 			output = append(output, token)
 
-		case int(FilterTokenFunction):
-			// Funções vão para stack
+		case int(FilterTokenFunction), int(FilterTokenLambda):
+			// Funções e operadores lambda vão para stack
 			operatorStack = append(operatorStack, token)
 
 		case int(FilterTokenComma):
@@ -160,8 +160,17 @@ func (p *ExpressionParser) InfixToPostfix(ctx context.Context, tokens []*Token)
 			operatorStack = operatorStack[:len(operatorStack)-1]
 
 			// Se há função no topo, move para output
-			if len(operatorStack) > 0 && operatorStack[len(operatorStack)-1].Type == int(FilterTokenFunction) {
-				output = append(output, operatorStack[len(operatorStack)-1])
+			if len(operatorStack) > 0 && isFunctionLikeToken(operatorStack[len(operatorStack)-1]) {
+				top := operatorStack[len(operatorStack)-1]
+				if top.Type == int(FilterTokenLambda) {
+					// any() sem variável não possui argumentos; com variável recebe variável e corpo
+					argCount := 2
+					if i > 0 && tokens[i-1].Type == int(FilterTokenOpenParen) {
+						argCount = 0
+					}
+					top.SemanticReference = argCount
+				}
+				output = append(output, top)
 				operatorStack = operatorStack[:len(operatorStack)-1]
 			}
 
@@ -179,7 +188,7 @@ func (p *ExpressionParser) InfixToPostfix(ctx context.Context, tokens []*Token)
 					break
 				}
 
-				if top.Type == int(FilterTokenFunction) {
+				if isFunctionLikeToken(top) {
 					output = append(output, top)
 					operatorStack = operatorStack[:len(operatorStack)-1]
 					continue
@@ -248,6 +257,19 @@ func (p *ExpressionParser) PostfixToTree(ctx context.Context, postfix []*Token)
 			// Operandos: nós folha
 			stack = append(stack, node)
 
+		case int(FilterTokenLambdaVariable):
+			// Variável de alcance: remove o ':' do token
+			node.Token = &Token{Type: token.Type, Value: strings.TrimSpace(strings.TrimSuffix(token.Value, ":"))}
+			stack = append(stack, node)
+
+		case int(FilterTokenLambda):
+			// Lambda: filhos são o caminho da coleção, a variável de alcance e o corpo
+			lambdaNode, err := p.buildLambdaNode(token, &stack)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, lambdaNode)
+
 		case int(FilterTokenFunction):
 			// Funções: determina número de argumentos
 			argCount := p.getFunctionArgCount(token.Value)
@@ -296,6 +318,54 @@ func (p *ExpressionParser) PostfixToTree(ctx context.Context, postfix []*Token)
 	return stack[0], nil
 }
 
+// buildLambdaNode constrói o nó de um operador lambda consumindo variável e corpo da pilha
+func (p *ExpressionParser) buildLambdaNode(token *Token, stack *[]*ParseNode) (*ParseNode, error) {
+	separator := strings.LastIndex(token.Value, "/")
+	if separator <= 0 {
+		return nil, fmt.Errorf("invalid lambda expression: %s", token.Value)
+	}
+
+	node := &ParseNode{
+		Token: &Token{Type: token.Type, Value: strings.ToLower(token.Value[separator+1:])},
+	}
+	path := &ParseNode{
+		Token:  &Token{Type: int(FilterTokenProperty), Value: token.Value[:separator]},
+		Parent: node,
+	}
+	node.Children = []*ParseNode{path}
+
+	argCount, _ := token.SemanticReference.(int)
+	if argCount == 0 {
+		if node.Token.Value == "all" {
+			return nil, fmt.Errorf("lambda operator all requires a range variable and a predicate")
+		}
+		return node, nil
+	}
+
+	if len(*stack) < 2 {
+		return nil, fmt.Errorf("insufficient arguments for lambda %s", token.Value)
+	}
+	variable := (*stack)[len(*stack)-2]
+	body := (*stack)[len(*stack)-1]
+	if variable.Token.Type != int(FilterTokenLambdaVariable) {
+		return nil, fmt.Errorf("lambda %s requires a range variable", token.Value)
+	}
+	if !p.isBooleanExpression(body.Token) {
+		return nil, fmt.Errorf("lambda %s predicate must be a boolean expression", token.Value)
+	}
+	*stack = (*stack)[:len(*stack)-2]
+
+	variable.Parent = node
+	body.Parent = node
+	node.Children = append(node.Children, variable, body)
+	return node, nil
+}
+
+// isFunctionLikeToken verifica se o token se comporta como função no shunting yard
+func isFunctionLikeToken(token *Token) bool {
+	return token.Type == int(FilterTokenFunction) || token.Type == int(FilterTokenLambda)
+}
+
 // getFunctionArgCount retorna o número de argumentos esperados para uma função
 func (p *ExpressionParser) getFunctionArgCount(funcName string) int {
 	switch strings.ToLower(funcName) {
@@ -378,7 +448,7 @@ func (p *ExpressionParser) isBooleanExpression(token *Token) bool {
 	}
 
 	switch token.Type {
-	case int(FilterTokenLogical), int(FilterTokenComparison):
+	case int(FilterTokenLogical), int(FilterTokenComparison), int(FilterTokenLambda):
 		return true
 	case int(FilterTokenBoolean):
 		return true
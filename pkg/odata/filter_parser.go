@@ -77,9 +77,21 @@ func SemanticizeFilterQuery(
 			return nil
 		}
 
+		// Lambdas: valida apenas a coleção; o corpo refere-se à entidade relacionada
+		if node.Token.Type == int(FilterTokenLambda) {
+			if len(node.Children) > 0 {
+				return semanticizeFilterNode(node.Children[0])
+			}
+			return nil
+		}
+
 		// Se é uma propriedade, valida se existe na entidade (case-insensitive)
 		if node.Token.Type == int(FilterTokenProperty) {
-			propertyName := node.Token.Value
+			// Em caminhos, valida o primeiro segmento da entidade ($it refere-se à própria entidade)
+			propertyName := strings.TrimPrefix(node.Token.Value, "$it/")
+			if idx := strings.Index(propertyName, "/"); idx > 0 {
+				propertyName = propertyName[:idx]
+			}
 			found := false
 
 			for _, prop := range metadata.Properties {
@@ -0,0 +1,162 @@
+package odata
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+)
+
+// fakeDB é o banco SQL em memória dos testes do servidor: registra as consultas, os comandos e as transações
+// e responde cada consulta com as linhas devolvidas por respond. Sem respond, as consultas falham
+type fakeDB struct {
+	*sql.DB
+	mu           sync.Mutex
+	respond      func(query string) *fakeRows
+	log          []string // BEGIN, comandos e COMMIT/ROLLBACK, na ordem em que ocorreram
+	queries      []string
+	execs        []string
+	commits      int
+	rollbacks    int
+	rowsAffected int64 // RowsAffected dos comandos (padrão 1)
+	lastInsertID int64 // LastInsertId dos comandos; zero indica que o driver não o suporta
+}
+
+func newFakeDB(t *testing.T, respond func(query string) *fakeRows) *fakeDB {
+	db := &fakeDB{respond: respond, rowsAffected: 1}
+	db.DB = sql.OpenDB(fakeConnector{db: db})
+	t.Cleanup(func() { db.DB.Close() })
+	return db
+}
+
+// entries retorna uma cópia do log de transações e comandos
+func (db *fakeDB) entries() []string {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return append([]string(nil), db.log...)
+}
+
+func (db *fakeDB) record(entry string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.log = append(db.log, entry)
+}
+
+type fakeConnector struct {
+	db *fakeDB
+}
+
+func (c fakeConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &fakeConn{db: c.db}, nil
+}
+func (c fakeConnector) Driver() driver.Driver { return nil }
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *fakeConn) Close() error                              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if sql.IsolationLevel(opts.Isolation) != sql.LevelDefault {
+		c.db.record("BEGIN " + sql.IsolationLevel(opts.Isolation).String())
+	} else {
+		c.db.record("BEGIN")
+	}
+	return &fakeTx{db: c.db}, nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.db.mu.Lock()
+	c.db.queries = append(c.db.queries, query)
+	respond := c.db.respond
+	c.db.mu.Unlock()
+
+	if respond == nil {
+		return nil, fmt.Errorf("unexpected query: %s", query)
+	}
+	return respond(query), nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.log = append(c.db.log, query)
+	c.db.execs = append(c.db.execs, query)
+	return fakeResult{rowsAffected: c.db.rowsAffected, lastInsertID: c.db.lastInsertID}, nil
+}
+
+type fakeTx struct {
+	db *fakeDB
+}
+
+func (t *fakeTx) Commit() error {
+	t.db.mu.Lock()
+	defer t.db.mu.Unlock()
+	t.db.log = append(t.db.log, "COMMIT")
+	t.db.commits++
+	return nil
+}
+
+func (t *fakeTx) Rollback() error {
+	t.db.mu.Lock()
+	defer t.db.mu.Unlock()
+	t.db.log = append(t.db.log, "ROLLBACK")
+	t.db.rollbacks++
+	return nil
+}
+
+type fakeResult struct {
+	rowsAffected int64
+	lastInsertID int64
+}
+
+func (r fakeResult) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+func (r fakeResult) LastInsertId() (int64, error) {
+	if r.lastInsertID == 0 {
+		return 0, fmt.Errorf("LastInsertId is not supported by this driver")
+	}
+	return r.lastInsertID, nil
+}
+
+// fakeRows é o resultado de uma consulta ao fakeDB
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+	next    int
+}
+
+func newFakeRows(columns []string, rows ...[]driver.Value) *fakeRows {
+	return &fakeRows{columns: columns, rows: rows}
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
+
+// newTestServer cria o servidor dos testes, sem log nem CORS, sobre o provider informado (nil no multi-tenant)
+func newTestServer(provider DatabaseProvider, configure ...func(config *ServerConfig)) *Server {
+	config := DefaultServerConfig()
+	config.EnableLogging = false
+	config.EnableCORS = false
+	for _, apply := range configure {
+		apply(config)
+	}
+	return newServerWithConfig(provider, config)
+}
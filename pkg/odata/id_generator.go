@@ -0,0 +1,164 @@
+package odata
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// smartGuidState garante a ordem dos smartGuids gerados no mesmo milissegundo
+var smartGuidState struct {
+	mu       sync.Mutex
+	lastMs   uint64
+	sequence uint16
+}
+
+// GenerateID gera o valor de uma chave conforme o idGenerator: guid e uuid36 no formato canônico
+// (8-4-4-4-12), uuid38 entre chaves, uuid32 sem hífens e smartGuid sequencial no tempo.
+// Retorna false para geradores que não são calculados na aplicação (sequence, identity, none)
+func GenerateID(generator IDGeneratorType) (string, bool) {
+	var id [16]byte
+	switch generator {
+	case IDGeneratorGuid, IDGeneratorUuid36, IDGeneratorUuid38, IDGeneratorUuid32:
+		id = newRandomUUID()
+	case IDGeneratorSmartGuid:
+		id = newSmartGuid()
+	default:
+		return "", false
+	}
+
+	switch generator {
+	case IDGeneratorUuid32:
+		return hex.EncodeToString(id[:]), true
+	case IDGeneratorUuid38:
+		return "{" + formatUUID(id) + "}", true
+	}
+	return formatUUID(id), true
+}
+
+// newRandomUUID gera um UUID versão 4 (aleatório)
+func newRandomUUID() [16]byte {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	id[6] = (id[6] & 0x0f) | 0x40
+	id[8] = (id[8] & 0x3f) | 0x80
+	return id
+}
+
+// newSmartGuid gera um UUID versão 7: os 48 bits iniciais são o timestamp em milissegundos, de modo que
+// valores novos são inseridos no fim do índice. No mesmo milissegundo, um contador mantém a ordem
+func newSmartGuid() [16]byte {
+	id := newRandomUUID()
+
+	smartGuidState.mu.Lock()
+	ms := uint64(time.Now().UnixMilli())
+	if ms <= smartGuidState.lastMs {
+		ms = smartGuidState.lastMs
+		smartGuidState.sequence++
+		if smartGuidState.sequence > 0x0fff {
+			// Contador esgotado: avança o timestamp para não repetir a ordem
+			ms++
+			smartGuidState.sequence = 0
+		}
+	} else {
+		smartGuidState.sequence = 0
+	}
+	smartGuidState.lastMs = ms
+	sequence := smartGuidState.sequence
+	smartGuidState.mu.Unlock()
+
+	var timestamp [8]byte
+	binary.BigEndian.PutUint64(timestamp[:], ms)
+	copy(id[0:6], timestamp[2:8])
+	id[6] = 0x70 | byte(sequence>>8)
+	id[7] = byte(sequence)
+	return id
+}
+
+// formatUUID formata o UUID no formato canônico 8-4-4-4-12
+func formatUUID(id [16]byte) string {
+	buf := make([]byte, 36)
+	hex.Encode(buf[0:8], id[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], id[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], id[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], id[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], id[10:])
+	return string(buf)
+}
+
+// assignGeneratedKeys preenche as chaves omitidas conforme o idGenerator: UUIDs são gerados na aplicação
+// e sequences obtidas pelo NEXTVAL do banco antes do INSERT
+func (s *BaseEntityService) assignGeneratedKeys(ctx context.Context, data map[string]any) error {
+	for _, prop := range s.metadata.Properties {
+		if !prop.IsKey || !isEmptyKeyValue(data[prop.Name]) {
+			continue
+		}
+
+		generator := IDGeneratorType(prop.IDGenerator)
+		if id, ok := GenerateID(generator); ok {
+			data[prop.Name] = id
+			continue
+		}
+		if generator != IDGeneratorSequence {
+			continue
+		}
+
+		value, ok, err := s.nextSequenceValue(ctx, prop)
+		if err != nil {
+			return err
+		}
+		if ok {
+			data[prop.Name] = value
+		} else {
+			// Sem sequence nativa a chave é gerada pelo auto incremento do banco
+			delete(data, prop.Name)
+		}
+	}
+	return nil
+}
+
+// nextSequenceValue obtém o próximo valor da sequence da chave no dialeto do provider.
+// Retorna false quando o banco não possui sequences (MySQL) ou a sequence não foi informada
+func (s *BaseEntityService) nextSequenceValue(ctx context.Context, prop PropertyMetadata) (int64, bool, error) {
+	if prop.SequenceName == "" {
+		return 0, false, nil
+	}
+
+	var query string
+	switch s.provider.GetDriverName() {
+	case "oracle":
+		query = fmt.Sprintf("SELECT %s.NEXTVAL FROM DUAL", prop.SequenceName)
+	case "postgresql", "postgres", "pgx":
+		query = fmt.Sprintf("SELECT nextval('%s')", prop.SequenceName)
+	default:
+		return 0, false, nil
+	}
+
+	conn, err := s.getExecutor(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	var value int64
+	if err := conn.QueryRowContext(ctx, query).Scan(&value); err != nil {
+		return 0, false, fmt.Errorf("failed to get next value of sequence %s: %w", prop.SequenceName, err)
+	}
+	return value, true, nil
+}
+
+// isEmptyKeyValue indica se a chave não foi informada: ausente, null ou valor zero do tipo
+// (campos não preenchidos de uma struct)
+func isEmptyKeyValue(value any) bool {
+	if value == nil {
+		return true
+	}
+	return reflect.ValueOf(value).IsZero()
+}
@@ -0,0 +1,58 @@
+package odata
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type IDGeneratorTestToken struct {
+	ID    string `json:"id" column:"id" primaryKey:"idGenerator:uuid32"`
+	Email string `json:"email" column:"email"`
+}
+
+func TestGenerateID(t *testing.T) {
+	formats := map[IDGeneratorType]*regexp.Regexp{
+		IDGeneratorGuid:      regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`),
+		IDGeneratorUuid36:    regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`),
+		IDGeneratorUuid38:    regexp.MustCompile(`^\{[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}\}$`),
+		IDGeneratorUuid32:    regexp.MustCompile(`^[0-9a-f]{12}4[0-9a-f]{3}[89ab][0-9a-f]{15}$`),
+		IDGeneratorSmartGuid: regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`),
+	}
+	for generator, format := range formats {
+		id, ok := GenerateID(generator)
+		require.True(t, ok, generator)
+		assert.Regexp(t, format, id)
+	}
+
+	for _, generator := range []IDGeneratorType{IDGeneratorNone, IDGeneratorSequence, ""} {
+		_, ok := GenerateID(generator)
+		assert.False(t, ok, generator)
+	}
+
+	// smartGuids gerados em sequência mantêm a ordem, inclusive no mesmo milissegundo
+	previous, _ := GenerateID(IDGeneratorSmartGuid)
+	for i := 0; i < 1000; i++ {
+		next, _ := GenerateID(IDGeneratorSmartGuid)
+		require.Less(t, previous, next)
+		previous = next
+	}
+}
+
+func TestBaseEntityService_GeneratedKeys(t *testing.T) {
+	server, provider := newConstraintTestServer(t)
+	require.NoError(t, server.RegisterEntity("Tokens", IDGeneratorTestToken{}))
+	service := server.entities["Tokens"].(*BaseEntityService)
+
+	_, err := service.Create(context.Background(), map[string]interface{}{"email": "ana@example.com"})
+	require.NoError(t, err)
+	assert.Regexp(t, `^[0-9a-f]{32}$`, provider.inserted["id"])
+
+	// A chave informada no corpo é mantida
+	_, err = service.Create(context.Background(), &IDGeneratorTestToken{ID: "fixed", Email: "ana@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "fixed", provider.inserted["id"])
+}
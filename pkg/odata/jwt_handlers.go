@@ -168,61 +168,82 @@ func (s *Server) GetEntityAuth(entityName string) (EntityAuthConfig, bool) {
 // RequireEntityAuth aplica middleware de autenticação baseado na configuração da entidade
 func (s *Server) RequireEntityAuth(entityName string) fiber.Handler {
 	return func(c fiber.Ctx) error {
-		// Se JWT não estiver habilitado, pular verificação
-		if !s.config.EnableJWT {
-			return c.Next()
+		if err := s.checkEntityAuth(c, entityName); err != nil {
+			return err
 		}
+		return c.Next()
+	}
+}
 
-		// Obter configuração da entidade
-		authConfig, exists := s.GetEntityAuth(entityName)
-		if !exists {
-			// Se não há configuração específica, usar configuração global
-			if s.config.RequireAuth {
-				return RequireAuth()(c)
-			}
-			return c.Next()
-		}
+// checkEntityAuth verifica se o usuário atual pode acessar a entidade
+func (s *Server) checkEntityAuth(c fiber.Ctx, entityName string) error {
+	// Se JWT não estiver habilitado, pular verificação
+	if !s.config.EnableJWT {
+		return nil
+	}
 
-		// Verificar se autenticação é necessária
-		if authConfig.RequireAuth {
-			user := GetCurrentUser(c)
-			if user == nil {
-				return fiber.NewError(fiber.StatusUnauthorized, "Autenticação requerida para acessar "+entityName)
-			}
+	// Obter configuração da entidade
+	authConfig, exists := s.GetEntityAuth(entityName)
+	return s.checkAuthConfig(c, entityName, authConfig, exists)
+}
+
+// checkAuthConfig aplica uma configuração de autenticação ao usuário atual
+func (s *Server) checkAuthConfig(c fiber.Ctx, entityName string, authConfig EntityAuthConfig, exists bool) error {
+	if !exists {
+		// Se não há configuração específica, usar configuração global
+		if s.config.RequireAuth && GetCurrentUser(c) == nil {
+			return fiber.NewError(fiber.StatusUnauthorized, "Autenticação requerida")
+		}
+		return nil
+	}
 
-			// Verificar se é admin
-			if authConfig.RequireAdmin && !user.IsAdmin() {
-				return fiber.NewError(fiber.StatusForbidden, "Privilégios de administrador requeridos para acessar "+entityName)
-			}
+	// Verificar se autenticação é necessária
+	if authConfig.RequireAuth {
+		user := GetCurrentUser(c)
+		if user == nil {
+			return fiber.NewError(fiber.StatusUnauthorized, "Autenticação requerida para acessar "+entityName)
+		}
 
-			// Verificar roles
-			if len(authConfig.RequiredRoles) > 0 && !user.HasAnyRole(authConfig.RequiredRoles...) {
-				return fiber.NewError(fiber.StatusForbidden, "Role necessária para acessar "+entityName)
-			}
+		// Verificar se é admin
+		if authConfig.RequireAdmin && !user.IsAdmin() {
+			return fiber.NewError(fiber.StatusForbidden, "Privilégios de administrador requeridos para acessar "+entityName)
+		}
 
-			// Verificar scopes
-			if len(authConfig.RequiredScopes) > 0 && !user.HasAnyScope(authConfig.RequiredScopes...) {
-				return fiber.NewError(fiber.StatusForbidden, "Scope necessário para acessar "+entityName)
-			}
+		// Verificar roles
+		if len(authConfig.RequiredRoles) > 0 && !user.HasAnyRole(authConfig.RequiredRoles...) {
+			return fiber.NewError(fiber.StatusForbidden, "Role necessária para acessar "+entityName)
 		}
 
-		return c.Next()
+		// Verificar scopes
+		if len(authConfig.RequiredScopes) > 0 && !user.HasAnyScope(authConfig.RequiredScopes...) {
+			return fiber.NewError(fiber.StatusForbidden, "Scope necessário para acessar "+entityName)
+		}
 	}
+
+	return nil
 }
 
 // CheckEntityReadOnly verifica se a entidade é apenas leitura
 func (s *Server) CheckEntityReadOnly(entityName string, method string) fiber.Handler {
 	return func(c fiber.Ctx) error {
-		authConfig, exists := s.GetEntityAuth(entityName)
-		if !exists {
-			return c.Next()
+		if err := s.checkEntityReadOnly(entityName, method); err != nil {
+			return err
 		}
+		return c.Next()
+	}
+}
 
-		// Se é read-only e método não é GET, bloquear
-		if authConfig.ReadOnly && method != "GET" {
-			return fiber.NewError(fiber.StatusForbidden, "Entidade "+entityName+" é apenas leitura")
-		}
+// checkEntityReadOnly bloqueia métodos de escrita em entidades apenas leitura
+func (s *Server) checkEntityReadOnly(entityName string, method string) error {
+	authConfig, exists := s.GetEntityAuth(entityName)
+	if !exists {
+		return nil
+	}
 
-		return c.Next()
+	// Se é read-only e método não é GET, bloquear
+	if authConfig.ReadOnly && method != "GET" {
+		return fiber.NewError(fiber.StatusForbidden, "Entidade "+entityName+" é apenas leitura")
 	}
+
+	return nil
 }
@@ -0,0 +1,48 @@
+package odata
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newJWTTestServer cria um servidor com JWT habilitado e a entidade Notes em memória
+func newJWTTestServer(t *testing.T, expiresIn time.Duration) (*Server, *lifecycleTestNotes) {
+	provider, _ := newConstraintTestProvider(t)
+	server := newTestServer(provider, func(config *ServerConfig) {
+		config.EnableJWT = true
+		config.JWTConfig = &JWTConfig{SecretKey: "godata-test-secret", Issuer: "godata", ExpiresIn: expiresIn}
+	})
+
+	notes := &lifecycleTestNotes{notes: map[string]map[string]interface{}{
+		"n1": {"id": "n1", "text": "first"},
+	}}
+	require.NoError(t, server.RegisterEntityWithService("Notes", notes))
+	return server, notes
+}
+
+func TestServer_EntityAuthUsesBearerToken(t *testing.T) {
+	server, _ := newJWTTestServer(t, time.Minute)
+	server.SetEntityAuth("Notes", EntityAuthConfig{RequireAuth: true, RequiredRoles: []string{"dashboard"}})
+
+	viewer, err := server.jwtService.GenerateToken(&UserIdentity{Username: "ana", Roles: []string{"viewer"}})
+	require.NoError(t, err)
+	dashboard, err := server.jwtService.GenerateToken(&UserIdentity{Username: "bia", Roles: []string{"dashboard"}})
+	require.NoError(t, err)
+
+	// O token é lido antes da verificação da entidade em todas as rotas
+	for _, path := range []string{"/odata/Notes", "/odata/Notes/$count"} {
+		for token, expected := range map[string]int{"": 401, viewer: 403, dashboard: 200} {
+			req := httptest.NewRequest("GET", path, nil)
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			resp, err := server.GetRouter().Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, expected, resp.StatusCode, path)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package odata
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// EntityResolver resolve os metadados de uma entidade relacionada pelo nome do tipo ou do entity set
+type EntityResolver func(name string) (EntityMetadata, bool)
+
+// entityResolverContextKeyType é o tipo da chave usada para propagar o EntityResolver no contexto
+type entityResolverContextKeyType struct{}
+
+var entityResolverContextKey = entityResolverContextKeyType{}
+
+// WithEntityResolver adiciona ao contexto o resolvedor usado pelos operadores lambda
+func WithEntityResolver(ctx context.Context, resolver EntityResolver) context.Context {
+	return context.WithValue(ctx, entityResolverContextKey, resolver)
+}
+
+// entityResolverFromContext obtém o resolvedor de entidades do contexto
+func entityResolverFromContext(ctx context.Context) (EntityResolver, bool) {
+	resolver, ok := ctx.Value(entityResolverContextKey).(EntityResolver)
+	return resolver, ok && resolver != nil
+}
+
+// lambdaRange representa uma variável de alcance e a tabela (alias) a que ela se refere
+type lambdaRange struct {
+	alias    string
+	metadata EntityMetadata
+}
+
+// lambdaScope guarda as variáveis visíveis dentro do corpo de um lambda
+type lambdaScope struct {
+	root      lambdaRange            // $it: a entidade da consulta principal
+	variables map[string]lambdaRange // Variáveis de alcance dos lambdas envolventes
+	counter   *int                   // Contador compartilhado para gerar aliases únicos
+}
+
+// lambdaScopeContextKeyType é o tipo da chave usada para propagar o escopo do lambda no contexto
+type lambdaScopeContextKeyType struct{}
+
+var lambdaScopeContextKey = lambdaScopeContextKeyType{}
+
+// lambdaScopeFromContext obtém o escopo do lambda atual, se houver
+func lambdaScopeFromContext(ctx context.Context) (*lambdaScope, bool) {
+	scope, ok := ctx.Value(lambdaScopeContextKey).(*lambdaScope)
+	return scope, ok && scope != nil
+}
+
+// isScopedProperty verifica se a propriedade precisa de resolução de caminho ou de variável de alcance
+func (qb *QueryBuilder) isScopedProperty(ctx context.Context, node *ParseNode) bool {
+	if _, inLambda := lambdaScopeFromContext(ctx); inLambda {
+		return true
+	}
+	return strings.Contains(node.Token.Value, "/") || node.Token.Value == "$it"
+}
+
+// buildScopedPropertyExpression resolve propriedades com $it, variáveis de alcance ou dentro de lambdas
+func (qb *QueryBuilder) buildScopedPropertyExpression(ctx context.Context, node *ParseNode, metadata EntityMetadata) (string, error) {
+	scope, inLambda := lambdaScopeFromContext(ctx)
+	source, segments := qb.resolveLambdaRange(scope, node.Token.Value, metadata)
+
+	if len(segments) != 1 {
+		return "", fmt.Errorf("navigation path '%s' is not supported in $filter; use any/all for collections", node.Token.Value)
+	}
+
+	prop, err := findFilterProperty(source.metadata, segments[0])
+	if err != nil {
+		return "", err
+	}
+	if prop.IsNavigation {
+		return "", fmt.Errorf("navigation property '%s' cannot be used as a value", node.Token.Value)
+	}
+
+	column := prop.ColumnName
+	if column == "" {
+		column = prop.Name
+	}
+
+	// Dentro do lambda as colunas precisam ser qualificadas para a correlação
+	if !inLambda {
+		return column, nil
+	}
+	return source.alias + "." + column, nil
+}
+
+// resolveLambdaRange identifica a que entidade o caminho se refere e retorna os segmentos restantes
+func (qb *QueryBuilder) resolveLambdaRange(scope *lambdaScope, path string, metadata EntityMetadata) (lambdaRange, []string) {
+	segments := strings.Split(path, "/")
+
+	root := lambdaRange{alias: lambdaTableName(metadata), metadata: metadata}
+	if scope != nil {
+		root = scope.root
+	}
+
+	if segments[0] == "$it" {
+		return root, segments[1:]
+	}
+	if scope != nil {
+		if variable, ok := scope.variables[segments[0]]; ok {
+			return variable, segments[1:]
+		}
+	}
+	return root, segments
+}
+
+// buildLambdaExpression traduz any/all em subqueries correlacionadas EXISTS/NOT EXISTS
+func (qb *QueryBuilder) buildLambdaExpression(ctx context.Context, node *ParseNode, metadata EntityMetadata, buildBody func(ctx context.Context, body *ParseNode) (string, error)) (string, error) {
+	if len(node.Children) != 1 && len(node.Children) != 3 {
+		return "", fmt.Errorf("lambda %s expects a collection path, a range variable and a predicate", node.Token.Value)
+	}
+
+	resolver, ok := entityResolverFromContext(ctx)
+	if !ok {
+		return "", fmt.Errorf("lambda operator %s requires an entity resolver", node.Token.Value)
+	}
+
+	scope, _ := lambdaScopeFromContext(ctx)
+	if scope == nil {
+		counter := 0
+		scope = &lambdaScope{
+			root:      lambdaRange{alias: lambdaTableName(metadata), metadata: metadata},
+			variables: map[string]lambdaRange{},
+			counter:   &counter,
+		}
+	}
+
+	path := node.Children[0].Token.Value
+	source, segments := qb.resolveLambdaRange(scope, path, metadata)
+	if len(segments) != 1 {
+		return "", fmt.Errorf("invalid lambda collection path '%s'", path)
+	}
+
+	navProp, err := findFilterProperty(source.metadata, segments[0])
+	if err != nil {
+		return "", err
+	}
+	if !navProp.IsNavigation || (!navProp.IsCollection && navProp.ManyAssociation == nil) {
+		return "", fmt.Errorf("property '%s' is not a navigation collection", path)
+	}
+
+	relatedName := navProp.RelatedType
+	if relatedName == "" && navProp.ManyAssociation != nil {
+		relatedName = navProp.ManyAssociation.RelatedEntity
+	}
+	related, ok := resolver(relatedName)
+	if !ok {
+		return "", fmt.Errorf("related entity '%s' not found for '%s'", relatedName, path)
+	}
+
+	*scope.counter++
+	alias := fmt.Sprintf("lambda%d", *scope.counter)
+
+	from, correlation, err := qb.buildLambdaJoin(source, navProp, related, alias)
+	if err != nil {
+		return "", err
+	}
+	subquery := fmt.Sprintf("SELECT 1 FROM %s WHERE %s", from, correlation)
+
+	// any() sem predicado verifica apenas se a coleção não está vazia
+	if len(node.Children) == 1 {
+		return fmt.Sprintf("EXISTS (%s)", subquery), nil
+	}
+
+	variables := make(map[string]lambdaRange, len(scope.variables)+1)
+	for name, variable := range scope.variables {
+		variables[name] = variable
+	}
+	variables[node.Children[1].Token.Value] = lambdaRange{alias: alias, metadata: related}
+
+	bodyCtx := context.WithValue(ctx, lambdaScopeContextKey, &lambdaScope{
+		root:      scope.root,
+		variables: variables,
+		counter:   scope.counter,
+	})
+
+	body, err := buildBody(bodyCtx, node.Children[2])
+	if err != nil {
+		return "", err
+	}
+
+	if strings.EqualFold(node.Token.Value, "all") {
+		return fmt.Sprintf("NOT EXISTS (%s AND NOT (%s))", subquery, body), nil
+	}
+	return fmt.Sprintf("EXISTS (%s AND %s)", subquery, body), nil
+}
+
+// buildLambdaJoin constrói o FROM e a condição de correlação entre a entidade de origem e a coleção
+func (qb *QueryBuilder) buildLambdaJoin(source lambdaRange, navProp PropertyMetadata, related EntityMetadata, alias string) (string, string, error) {
+	relatedTable := lambdaTableName(related)
+
+	// N:N através da tabela de junção
+	if many := navProp.ManyAssociation; many != nil && many.JoinTable != "" {
+		if many.JoinColumn == "" || many.InverseJoinColumn == "" {
+			return "", "", fmt.Errorf("navigation property '%s' requires joinColumn and inverseJoinColumn", navProp.Name)
+		}
+
+		joinAlias := alias + "_join"
+		localColumn := lambdaColumnName(source.metadata, many.References, lambdaKeyColumn(source.metadata))
+		relatedKey := lambdaKeyColumn(related)
+		if localColumn == "" || relatedKey == "" {
+			return "", "", fmt.Errorf("navigation property '%s' requires key properties on both entities", navProp.Name)
+		}
+
+		from := fmt.Sprintf("%s %s INNER JOIN %s %s ON %s.%s = %s.%s",
+			many.JoinTable, joinAlias, relatedTable, alias, alias, relatedKey, joinAlias, many.InverseJoinColumn)
+		correlation := fmt.Sprintf("%s.%s = %s.%s", joinAlias, many.JoinColumn, source.alias, localColumn)
+		return from, correlation, nil
+	}
+
+	// 1:N pela chave estrangeira na entidade relacionada
+	if navProp.Relationship == nil {
+		return "", "", fmt.Errorf("navigation property '%s' has no relationship metadata", navProp.Name)
+	}
+
+	localColumn := lambdaColumnName(source.metadata, navProp.Relationship.LocalProperty, "")
+	relatedColumn := lambdaColumnName(related, navProp.Relationship.ReferencedProperty, "")
+	if localColumn == "" || relatedColumn == "" {
+		return "", "", fmt.Errorf("navigation property '%s' has incomplete relationship metadata", navProp.Name)
+	}
+
+	from := fmt.Sprintf("%s %s", relatedTable, alias)
+	correlation := fmt.Sprintf("%s.%s = %s.%s", alias, relatedColumn, source.alias, localColumn)
+	return from, correlation, nil
+}
+
+// findFilterProperty localiza uma propriedade pelo nome (comparação case-insensitive)
+func findFilterProperty(metadata EntityMetadata, name string) (PropertyMetadata, error) {
+	for _, prop := range metadata.Properties {
+		if strings.EqualFold(prop.Name, name) {
+			return prop, nil
+		}
+	}
+	return PropertyMetadata{}, fmt.Errorf("property '%s' not found in entity '%s'", name, metadata.Name)
+}
+
+// lambdaTableName retorna o nome da tabela usado para qualificar colunas da entidade
+func lambdaTableName(metadata EntityMetadata) string {
+	if metadata.TableName != "" {
+		return metadata.TableName
+	}
+	return metadata.Name
+}
+
+// lambdaColumnName resolve o nome da coluna a partir do nome da propriedade ou da própria coluna
+func lambdaColumnName(metadata EntityMetadata, name, fallback string) string {
+	if name == "" {
+		return fallback
+	}
+	for _, prop := range metadata.Properties {
+		if prop.IsNavigation {
+			continue
+		}
+		if strings.EqualFold(prop.Name, name) || strings.EqualFold(prop.ColumnName, name) {
+			if prop.ColumnName != "" {
+				return prop.ColumnName
+			}
+			return prop.Name
+		}
+	}
+	return name
+}
+
+// lambdaKeyColumn retorna a coluna da primeira chave da entidade
+func lambdaKeyColumn(metadata EntityMetadata) string {
+	for _, prop := range metadata.Properties {
+		if prop.IsKey {
+			if prop.ColumnName != "" {
+				return prop.ColumnName
+			}
+			return prop.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,186 @@
+package odata
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lambdaTestEntities() map[string]EntityMetadata {
+	customers := EntityMetadata{
+		Name:      "Customer",
+		TableName: "customers",
+		Properties: []PropertyMetadata{
+			{Name: "ID", Type: "int64", ColumnName: "id", IsKey: true},
+			{Name: "Name", Type: "string", ColumnName: "name"},
+			{
+				Name:            "Orders",
+				IsNavigation:    true,
+				IsCollection:    true,
+				RelatedType:     "Order",
+				Relationship:    &RelationshipMetadata{LocalProperty: "id", ReferencedProperty: "customer_id"},
+				ManyAssociation: &ManyAssociationMetadata{ForeignKey: "customer_id", References: "id"},
+			},
+			{
+				Name:         "Tags",
+				IsNavigation: true,
+				IsCollection: true,
+				RelatedType:  "Tag",
+				ManyAssociation: &ManyAssociationMetadata{
+					References:        "id",
+					JoinTable:         "customer_tags",
+					JoinColumn:        "customer_id",
+					InverseJoinColumn: "tag_id",
+				},
+			},
+		},
+	}
+	orders := EntityMetadata{
+		Name:      "Order",
+		TableName: "orders",
+		Properties: []PropertyMetadata{
+			{Name: "ID", Type: "int64", ColumnName: "id", IsKey: true},
+			{Name: "CustomerID", Type: "int64", ColumnName: "customer_id"},
+			{Name: "Total", Type: "float64", ColumnName: "total"},
+			{
+				Name:         "Items",
+				IsNavigation: true,
+				IsCollection: true,
+				RelatedType:  "OrderItem",
+				Relationship: &RelationshipMetadata{LocalProperty: "ID", ReferencedProperty: "OrderID"},
+			},
+		},
+	}
+	items := EntityMetadata{
+		Name:      "OrderItem",
+		TableName: "order_items",
+		Properties: []PropertyMetadata{
+			{Name: "ID", Type: "int64", ColumnName: "id", IsKey: true},
+			{Name: "OrderID", Type: "int64", ColumnName: "order_id"},
+			{Name: "Quantity", Type: "int32", ColumnName: "quantity"},
+		},
+	}
+	tags := EntityMetadata{
+		Name:      "Tag",
+		TableName: "tags",
+		Properties: []PropertyMetadata{
+			{Name: "ID", Type: "int64", ColumnName: "id", IsKey: true},
+			{Name: "Label", Type: "string", ColumnName: "label"},
+		},
+	}
+
+	return map[string]EntityMetadata{
+		"Customer":  customers,
+		"Order":     orders,
+		"OrderItem": items,
+		"Tag":       tags,
+	}
+}
+
+func lambdaTestContext() context.Context {
+	entities := lambdaTestEntities()
+	return WithEntityResolver(context.Background(), func(name string) (EntityMetadata, bool) {
+		metadata, ok := entities[name]
+		return metadata, ok
+	})
+}
+
+func TestParseFilterString_Lambda(t *testing.T) {
+	ctx := context.Background()
+
+	filter, err := ParseFilterString(ctx, "Orders/any(o: o/Total gt 100)")
+	require.NoError(t, err)
+	tree := filter.Tree
+	assert.Equal(t, int(FilterTokenLambda), tree.Token.Type)
+	assert.Equal(t, "any", tree.Token.Value)
+	require.Len(t, tree.Children, 3)
+	assert.Equal(t, "Orders", tree.Children[0].Token.Value)
+	assert.Equal(t, "o", tree.Children[1].Token.Value)
+	assert.Equal(t, "gt", tree.Children[2].Token.Value)
+	assert.Equal(t, "o/Total", tree.Children[2].Children[0].Token.Value)
+
+	filter, err = ParseFilterString(ctx, "Orders/any()")
+	require.NoError(t, err)
+	assert.Len(t, filter.Tree.Children, 1)
+
+	filter, err = ParseFilterString(ctx, "Name eq 'A' and Orders/all(o: o/Items/any(i: i/Quantity gt 1 and $it/Name ne 'B'))")
+	require.NoError(t, err)
+	all := filter.Tree.Children[1]
+	assert.Equal(t, "all", all.Token.Value)
+	assert.Equal(t, "any", all.Children[2].Token.Value)
+	assert.Equal(t, "o/Items", all.Children[2].Children[0].Token.Value)
+
+	_, err = ParseFilterString(ctx, "Orders/all()")
+	assert.Error(t, err)
+	_, err = ParseFilterString(ctx, "Orders/any(o: o/Total)")
+	assert.Error(t, err)
+}
+
+func TestQueryBuilder_Lambda(t *testing.T) {
+	ctx := lambdaTestContext()
+	customers := lambdaTestEntities()["Customer"]
+
+	tests := []struct {
+		name     string
+		filter   string
+		expected string
+		args     int
+	}{
+		{
+			name:     "any",
+			filter:   "Orders/any(o: o/Total gt 100)",
+			expected: "EXISTS (SELECT 1 FROM orders lambda1 WHERE lambda1.customer_id = customers.id AND (lambda1.total > :param1))",
+			args:     1,
+		},
+		{
+			name:     "any without predicate",
+			filter:   "Orders/any()",
+			expected: "EXISTS (SELECT 1 FROM orders lambda1 WHERE lambda1.customer_id = customers.id)",
+		},
+		{
+			name:     "all",
+			filter:   "Orders/all(o: o/Total ge 10)",
+			expected: "NOT EXISTS (SELECT 1 FROM orders lambda1 WHERE lambda1.customer_id = customers.id AND NOT ((lambda1.total >= :param1)))",
+			args:     1,
+		},
+		{
+			name:   "nested with $it",
+			filter: "Name eq 'Ann' and Orders/any(o: o/Items/any(i: i/Quantity gt 5 and $it/Name eq Name))",
+			expected: "((name = :param1) AND EXISTS (SELECT 1 FROM orders lambda1 WHERE lambda1.customer_id = customers.id AND " +
+				"EXISTS (SELECT 1 FROM order_items lambda2 WHERE lambda2.order_id = lambda1.id AND ((lambda2.quantity > :param2) AND (customers.name = customers.name)))))",
+			args: 2,
+		},
+		{
+			name:     "many to many",
+			filter:   "Tags/any(t: t/Label eq 'vip')",
+			expected: "EXISTS (SELECT 1 FROM customer_tags lambda1_join INNER JOIN tags lambda1 ON lambda1.id = lambda1_join.tag_id WHERE lambda1_join.customer_id = customers.id AND (lambda1.label = :param1))",
+			args:     1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := ParseFilterString(ctx, tt.filter)
+			require.NoError(t, err)
+
+			sql, args, err := NewQueryBuilder("mysql").BuildWhereClause(ctx, filter.Tree, customers)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, sql)
+			assert.Len(t, args, tt.args)
+		})
+	}
+
+	// Sem resolvedor no contexto não é possível montar a subquery
+	filter, err := ParseFilterString(ctx, "Orders/any()")
+	require.NoError(t, err)
+	_, _, err = NewQueryBuilder("mysql").BuildWhereClause(context.Background(), filter.Tree, customers)
+	assert.Error(t, err)
+
+	// Caminhos de navegação fora de lambdas não são suportados
+	filter, err = ParseFilterString(ctx, "Orders/Total gt 1")
+	require.NoError(t, err)
+	_, _, err = NewQueryBuilder("mysql").BuildWhereClause(ctx, filter.Tree, customers)
+	assert.Error(t, err)
+}
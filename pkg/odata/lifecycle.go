@@ -0,0 +1,210 @@
+package odata
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// Os métodos deste arquivo são o ponto único de gravação do servidor: toda inserção, atualização e exclusão
+// (requisição, $batch, deep insert/update, cascata, @odata.bind e $ref) passa por eles, qualquer que seja
+// a implementação do EntityService (base, multi-tenant ou customizada), dispara o ciclo de eventos e grava
+// os eventos "-ed" no outbox, quando habilitado.
+
+// eventContext cria o contexto dos eventos de uma entidade, com os dados da requisição quando houver
+func (s *Server) eventContext(ctx context.Context, entityName string) *EventContext {
+	if c, ok := ctx.Value(FiberContextKey).(fiber.Ctx); ok && c != nil {
+		eventCtx := createEventContext(c, entityName)
+		eventCtx.Context = ctx
+		return eventCtx
+	}
+	return &EventContext{Context: ctx, EntityName: entityName, Timestamp: time.Now().Unix(), Extra: make(map[string]interface{})}
+}
+
+// insertEntity valida a entidade e a cria disparando EntityInserting, que pode alterar (SetEntity) ou cancelar
+// a inserção, e EntityInserted com a entidade gravada
+func (s *Server) insertEntity(ctx context.Context, entityName string, service EntityService, data map[string]interface{}) (interface{}, error) {
+	return s.writeLifecycle(ctx, entityName, service, "insert", func(ctx context.Context, eventCtx *EventContext) (interface{}, error) {
+		if err := s.validateEntity(eventCtx, service.GetMetadata(), nil, data); err != nil {
+			return nil, err
+		}
+
+		inserting := NewEntityInsertingArgs(eventCtx, data)
+		if err := s.eventManager.Emit(inserting); err != nil {
+			return nil, err
+		}
+
+		created, err := service.Create(ctx, inserting.GetEntity())
+		if err != nil {
+			return nil, err
+		}
+
+		inserted := NewEntityInsertedArgs(eventCtx, created)
+		keyNames := keyPropertyNames(service.GetMetadata())
+		keys := make(map[string]interface{}, len(keyNames))
+		for _, name := range keyNames {
+			keys[name] = entityValue(created, name)
+		}
+		if len(keyNames) == 1 {
+			inserted.NewID = keys[keyNames[0]]
+		}
+		if err := s.eventManager.Emit(inserted); err != nil {
+			return nil, err
+		}
+		if err := s.appendOutbox(ctx, eventCtx, EventEntityInserted, keys, created); err != nil {
+			return nil, err
+		}
+		return created, nil
+	})
+}
+
+// modifyEntity valida os dados e atualiza a entidade disparando EntityModifying, que pode alterar ou cancelar a atualização,
+// e EntityModified com a entidade gravada e a original. Sem original, ela é lida antes da atualização
+// quando há handlers de modificação
+func (s *Server) modifyEntity(ctx context.Context, entityName string, service EntityService, keys, data map[string]interface{}, original interface{}) (interface{}, error) {
+	return s.writeLifecycle(ctx, entityName, service, "update", func(ctx context.Context, eventCtx *EventContext) (interface{}, error) {
+		if original == nil && s.eventManager.HasHandlers(entityName, EventEntityModifying, EventEntityModified) {
+			current, err := service.Get(WithConcurrencyCondition(ctx, nil), keys)
+			if err != nil {
+				return nil, err
+			}
+			original = current
+		}
+
+		if err := s.validateEntity(eventCtx, service.GetMetadata(), keys, data); err != nil {
+			return nil, err
+		}
+
+		modifying := NewEntityModifyingArgs(eventCtx, keys, data, original)
+		if err := s.eventManager.Emit(modifying); err != nil {
+			return nil, err
+		}
+
+		updated, err := service.Update(ctx, keys, modifying.GetEntity())
+		if err != nil {
+			return nil, err
+		}
+
+		modified := NewEntityModifiedArgs(eventCtx, keys, updated, original)
+		modified.ModifiedFields = modifiedFields(modifying.GetEntity())
+		if err := s.eventManager.Emit(modified); err != nil {
+			return nil, err
+		}
+		if err := s.appendOutbox(ctx, eventCtx, EventEntityModified, keys, updated); err != nil {
+			return nil, err
+		}
+		return updated, nil
+	})
+}
+
+// removeEntity remove a entidade disparando EntityDeleting, que pode cancelar a exclusão, e EntityDeleted.
+// cascade indica remoção em cascata; sem entity, ela é lida antes da exclusão quando há handlers ou outbox
+func (s *Server) removeEntity(ctx context.Context, entityName string, service EntityService, keys map[string]interface{}, entity interface{}, cascade bool) error {
+	_, err := s.writeLifecycle(ctx, entityName, service, "delete", func(ctx context.Context, eventCtx *EventContext) (interface{}, error) {
+		if entity == nil && (s.eventManager.HasHandlers(entityName, EventEntityDeleting, EventEntityDeleted) || s.outboxStore(entityName) != nil) {
+			current, err := service.Get(WithConcurrencyCondition(ctx, nil), keys)
+			if err != nil {
+				return nil, err
+			}
+			entity = current
+		}
+
+		deleting := NewEntityDeletingArgs(eventCtx, keys, entity)
+		deleting.CascadeDelete = cascade
+		if err := s.eventManager.Emit(deleting); err != nil {
+			return nil, err
+		}
+
+		if err := service.Delete(ctx, keys); err != nil {
+			return nil, err
+		}
+
+		if err := s.eventManager.Emit(NewEntityDeletedArgs(eventCtx, keys, entity)); err != nil {
+			return nil, err
+		}
+		return nil, s.appendOutbox(ctx, eventCtx, EventEntityDeleted, keys, entity)
+	})
+	return err
+}
+
+// writeLifecycle executa a gravação e os handlers de escrita na mesma transação, de modo que o erro de
+// qualquer handler desfaz a operação. A transação é aberta no provider do serviço. Na falha dispara EntityError
+func (s *Server) writeLifecycle(ctx context.Context, entityName string, service EntityService, operation string, write func(ctx context.Context, eventCtx *EventContext) (interface{}, error)) (interface{}, error) {
+	run := func(ctx context.Context) (interface{}, error) {
+		return write(ctx, s.eventContext(ctx, entityName))
+	}
+
+	var result interface{}
+	var err error
+	if s.hasWriteHandlers(entityName) {
+		result, err = s.runInTransaction(ctx, s.contextServiceProvider(ctx, service), run)
+	} else {
+		result, err = run(ctx)
+	}
+	if err != nil {
+		s.emitEntityError(ctx, entityName, operation, err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// emitEntityError dispara EntityError para a falha de uma gravação; erros dos handlers são apenas registrados
+func (s *Server) emitEntityError(ctx context.Context, entityName, operation string, err error) {
+	if !s.eventManager.HasHandlers(entityName, EventEntityError) {
+		return
+	}
+	args := NewEntityErrorArgs(s.eventContext(ctx, entityName), err, operation, writeErrorStatus(err))
+	if emitErr := s.eventManager.Emit(args); emitErr != nil {
+		s.logger.Printf("⚠️ Erro no handler de EntityError de %s: %v", entityName, emitErr)
+	}
+}
+
+// writeErrorStatus retorna o status HTTP com que a falha de uma gravação é respondida
+func writeErrorStatus(err error) int {
+	var canceled *EventCanceledError
+	var validation *ValidationError
+	var constraint *PropertyConstraintError
+	switch {
+	case errors.As(err, &canceled), errors.As(err, &validation):
+		return fiber.StatusBadRequest
+	case errors.As(err, &constraint):
+		if constraint.Constraint == PropUnique {
+			return fiber.StatusConflict
+		}
+		return fiber.StatusBadRequest
+	case errors.Is(err, ErrPreconditionFailed):
+		return fiber.StatusPreconditionFailed
+	case strings.Contains(err.Error(), "not found"):
+		return fiber.StatusNotFound
+	}
+	return fiber.StatusInternalServerError
+}
+
+// keyPropertyNames retorna os nomes das propriedades chave da entidade
+func keyPropertyNames(metadata EntityMetadata) []string {
+	var names []string
+	for _, prop := range metadata.Properties {
+		if prop.IsKey {
+			names = append(names, prop.Name)
+		}
+	}
+	return names
+}
+
+// modifiedFields lista, em ordem, as propriedades enviadas na atualização
+func modifiedFields(data interface{}) []string {
+	values, ok := data.(map[string]interface{})
+	if !ok {
+		return []string{}
+	}
+	fields := make([]string, 0, len(values))
+	for name := range values {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	return fields
+}
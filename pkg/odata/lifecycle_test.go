@@ -0,0 +1,195 @@
+package odata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lifecycleTestNotes é um EntityService customizado, em memória
+type lifecycleTestNotes struct {
+	notes map[string]map[string]interface{}
+}
+
+func (n *lifecycleTestNotes) GetMetadata() EntityMetadata {
+	return EntityMetadata{Name: "Note", TableName: "notes", Properties: []PropertyMetadata{
+		{Name: "id", Type: "string", ColumnName: "id", IsKey: true},
+		{Name: "text", Type: "string", ColumnName: "text"},
+	}}
+}
+
+func (n *lifecycleTestNotes) Query(ctx context.Context, options QueryOptions) (*ODataResponse, error) {
+	return &ODataResponse{Value: []interface{}{}}, nil
+}
+
+func (n *lifecycleTestNotes) Get(ctx context.Context, keys map[string]interface{}) (interface{}, error) {
+	note, ok := n.notes[fmt.Sprint(keys["id"])]
+	if !ok {
+		return nil, fmt.Errorf("entity not found")
+	}
+	return note, nil
+}
+
+func (n *lifecycleTestNotes) Create(ctx context.Context, entity interface{}) (interface{}, error) {
+	note := entity.(map[string]interface{})
+	n.notes[fmt.Sprint(note["id"])] = note
+	return note, nil
+}
+
+func (n *lifecycleTestNotes) Update(ctx context.Context, keys map[string]interface{}, entity interface{}) (interface{}, error) {
+	note := map[string]interface{}{}
+	for name, value := range n.notes[fmt.Sprint(keys["id"])] {
+		note[name] = value
+	}
+	for name, value := range entity.(map[string]interface{}) {
+		note[name] = value
+	}
+	n.notes[fmt.Sprint(keys["id"])] = note
+	return note, nil
+}
+
+func (n *lifecycleTestNotes) Delete(ctx context.Context, keys map[string]interface{}) error {
+	delete(n.notes, fmt.Sprint(keys["id"]))
+	return nil
+}
+
+func newLifecycleTestServer(t *testing.T) (*Server, *constraintTestProvider, *fakeDB, *lifecycleTestNotes) {
+	provider, db := newConstraintTestProvider(t)
+	server := newTestServer(provider)
+	require.NoError(t, server.RegisterEntity("Products", ValidationTestProduct{}))
+
+	notes := &lifecycleTestNotes{notes: map[string]map[string]interface{}{
+		"n1": {"id": "n1", "text": "first"},
+	}}
+	require.NoError(t, server.RegisterEntityWithService("Notes", notes))
+	return server, provider, db, notes
+}
+
+func sendLifecycleRequest(t *testing.T, server *Server, method, url, body string) (int, *ODataError) {
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := server.GetRouter().Test(req)
+	require.NoError(t, err)
+
+	var response ODataResponse
+	_ = json.NewDecoder(resp.Body).Decode(&response)
+	return resp.StatusCode, response.Error
+}
+
+func TestServer_InsertLifecycleEvents(t *testing.T) {
+	server, provider, db, _ := newLifecycleTestServer(t)
+
+	var events []EventType
+	var inserted *EntityInsertedArgs
+	server.OnEntityInserting("Products", func(args EventArgs) error {
+		events = append(events, args.GetEventType())
+		data := args.(*EntityInsertingArgs).Data
+		args.SetEntity(map[string]interface{}{"id": data["id"], "name": strings.ToUpper(data["name"].(string))})
+		return nil
+	})
+	server.OnEntityInserted("Products", func(args EventArgs) error {
+		events = append(events, args.GetEventType())
+		inserted = args.(*EntityInsertedArgs)
+		return nil
+	})
+
+	status, _ := sendLifecycleRequest(t, server, "POST", "/odata/Products", `{"id": 1, "name": "pen"}`)
+	require.Equal(t, 201, status)
+	assert.Equal(t, []EventType{EventEntityInserting, EventEntityInserted}, events)
+	assert.Equal(t, "PEN", provider.inserted["name"])
+	require.NotNil(t, inserted)
+	assert.NotNil(t, inserted.CreatedEntity)
+	assert.Equal(t, []string{"BEGIN", "INSERT INTO users", "COMMIT"}, db.entries())
+}
+
+func TestServer_CancelAndRollbackLifecycleEvents(t *testing.T) {
+	server, provider, db, _ := newLifecycleTestServer(t)
+
+	var failures []*EntityErrorArgs
+	server.OnEntityError("Products", func(args EventArgs) error {
+		failures = append(failures, args.(*EntityErrorArgs))
+		return nil
+	})
+	server.OnEntityInserting("Products", func(args EventArgs) error {
+		if args.(*EntityInsertingArgs).Data["name"] == "blocked" {
+			args.Cancel("product is blocked")
+		}
+		return nil
+	})
+	server.OnEntityInserted("Products", func(args EventArgs) error {
+		return errors.New("audit unavailable")
+	})
+
+	status, odataErr := sendLifecycleRequest(t, server, "POST", "/odata/Products", `{"id": 1, "name": "blocked"}`)
+	require.Equal(t, 400, status)
+	assert.Equal(t, "EventCanceled", odataErr.Code)
+	assert.Nil(t, provider.inserted)
+
+	// O erro de um handler "-ed" desfaz a inserção
+	db.log = nil
+	status, _ = sendLifecycleRequest(t, server, "POST", "/odata/Products", `{"id": 2, "name": "pen"}`)
+	require.Equal(t, 500, status)
+	assert.Equal(t, []string{"BEGIN", "INSERT INTO users", "ROLLBACK"}, db.entries())
+
+	require.Len(t, failures, 2)
+	assert.Equal(t, "insert", failures[0].Operation)
+	assert.Equal(t, 400, failures[0].StatusCode)
+	assert.Equal(t, 500, failures[1].StatusCode)
+}
+
+func TestServer_CustomServiceLifecycleEvents(t *testing.T) {
+	server, _, _, notes := newLifecycleTestServer(t)
+
+	var modified *EntityModifiedArgs
+	var deleted *EntityDeletedArgs
+	server.OnEntityModifying("Notes", func(args EventArgs) error {
+		args.(*EntityModifyingArgs).Data["text"] = "edited: " + args.(*EntityModifyingArgs).Data["text"].(string)
+		return nil
+	})
+	server.OnEntityModified("Notes", func(args EventArgs) error {
+		modified = args.(*EntityModifiedArgs)
+		return nil
+	})
+	server.OnEntityDeleted("Notes", func(args EventArgs) error {
+		deleted = args.(*EntityDeletedArgs)
+		return nil
+	})
+
+	status, _ := sendLifecycleRequest(t, server, "PATCH", "/odata/Notes('n1')", `{"text": "second"}`)
+	require.Equal(t, 200, status)
+	require.NotNil(t, modified)
+	assert.Equal(t, "first", modified.OriginalEntity.(map[string]interface{})["text"])
+	assert.Equal(t, "edited: second", modified.UpdatedEntity.(map[string]interface{})["text"])
+	assert.Equal(t, []string{"text"}, modified.ModifiedFields)
+
+	status, _ = sendLifecycleRequest(t, server, "DELETE", "/odata/Notes('n1')", "")
+	require.Equal(t, 204, status)
+	require.NotNil(t, deleted)
+	assert.Equal(t, "edited: second", deleted.DeletedEntity.(map[string]interface{})["text"])
+	assert.Empty(t, notes.notes)
+}
+
+func TestServer_LifecycleUsesServiceProvider(t *testing.T) {
+	server, _, db, _ := newLifecycleTestServer(t)
+	archive, archiveDB := newConstraintTestProvider(t)
+	metadata, err := NewEntityMapper().MapEntity(ValidationTestProduct{})
+	require.NoError(t, err)
+	metadata.Name = "Archive"
+	require.NoError(t, server.RegisterEntityWithService("Archive", NewBaseEntityService(archive, metadata, server)))
+
+	server.OnEntityInserting("Archive", func(args EventArgs) error { return nil })
+
+	// A transação dos handlers é aberta no banco do serviço, não no provider padrão
+	status, _ := sendLifecycleRequest(t, server, "POST", "/odata/Archive", `{"id": 1, "name": "pen"}`)
+	require.Equal(t, 201, status)
+	assert.Equal(t, []string{"BEGIN", "INSERT INTO users", "COMMIT"}, archiveDB.entries())
+	assert.Empty(t, db.entries())
+	assert.Equal(t, "pen", archive.inserted["name"])
+}
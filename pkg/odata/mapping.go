@@ -3,6 +3,7 @@ package odata
 import (
 	"fmt"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -59,6 +60,13 @@ func (m *EntityMapper) MapEntity(entity interface{}) (EntityMetadata, error) {
 			if prop.IsKey {
 				metadata.Keys = append(metadata.Keys, prop.Name)
 			}
+
+			// Tag etag (token de concorrência otimista)
+			if etag := field.Tag.Get("etag"); etag != "" {
+				if err := m.parseETag(etag, prop, &metadata); err != nil {
+					return EntityMetadata{}, fmt.Errorf("error mapping field %s: %w", field.Name, err)
+				}
+			}
 		}
 	}
 
@@ -215,13 +223,18 @@ func (m *EntityMapper) mapTags(field reflect.StructField, prop *PropertyMetadata
 
 		// Processa as flags para definir propriedades do metadata
 		for _, flag := range propFlags {
-			switch flag {
-			case "Required":
+			switch strings.ToLower(flag) {
+			case "required":
 				prop.IsNullable = false
-			case "Unique":
+			case "unique":
 				// Será processado pelos providers
 			}
 		}
+
+		// Os segmentos após as flags usam a mesma sintaxe da tag odata (length, precision, scale, default)
+		if err := m.parseODataTag(propTag, prop); err != nil {
+			return err
+		}
 	}
 
 	// Tag odata
@@ -244,9 +257,16 @@ func (m *EntityMapper) mapTags(field reflect.StructField, prop *PropertyMetadata
 	return nil
 }
 
-// parseODataTag processa a tag odata
+// parseODataTag processa a tag odata. A regra pattern deve ser a última: tudo o que vem depois de "pattern:"
+// é a expressão regular, que pode conter ";"
 func (m *EntityMapper) parseODataTag(odata string, prop *PropertyMetadata) error {
 	parts := strings.Split(odata, ";")
+	for i, part := range parts {
+		if strings.HasPrefix(strings.TrimSpace(part), "pattern:") {
+			parts = append(parts[:i], strings.Join(parts[i:], ";"))
+			break
+		}
+	}
 
 	for _, part := range parts {
 		part = strings.TrimSpace(part)
@@ -270,12 +290,64 @@ func (m *EntityMapper) parseODataTag(odata string, prop *PropertyMetadata) error
 			if scale, err := strconv.Atoi(strings.TrimPrefix(part, "scale:")); err == nil {
 				prop.Scale = scale
 			}
+		case strings.HasPrefix(part, "contentType:"):
+			prop.ContentType = strings.TrimSpace(strings.TrimPrefix(part, "contentType:"))
+		case strings.HasPrefix(part, "min:"):
+			minimum, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimPrefix(part, "min:")), 64)
+			if err != nil {
+				return fmt.Errorf("invalid min for property %s: %w", prop.Name, err)
+			}
+			prop.Minimum = &minimum
+		case strings.HasPrefix(part, "max:"):
+			maximum, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimPrefix(part, "max:")), 64)
+			if err != nil {
+				return fmt.Errorf("invalid max for property %s: %w", prop.Name, err)
+			}
+			prop.Maximum = &maximum
+		case strings.HasPrefix(part, "pattern:"):
+			pattern, err := regexp.Compile(strings.TrimPrefix(part, "pattern:"))
+			if err != nil {
+				return fmt.Errorf("invalid pattern for property %s: %w", prop.Name, err)
+			}
+			prop.Pattern = pattern
+		case strings.HasPrefix(part, "enum:"):
+			prop.EnumValues = nil
+			for _, value := range strings.Split(strings.TrimPrefix(part, "enum:"), "|") {
+				prop.EnumValues = append(prop.EnumValues, strings.TrimSpace(value))
+			}
 		}
 	}
 
 	return nil
 }
 
+// parseETag processa a tag etag, que pode ser "version" ou "hash"
+func (m *EntityMapper) parseETag(etag string, prop *PropertyMetadata, metadata *EntityMetadata) error {
+	mode := ETagMode(strings.ToLower(strings.TrimSpace(etag)))
+	if mode != ETagModeVersion && mode != ETagModeHash {
+		return fmt.Errorf("invalid etag mode '%s', expected 'version' or 'hash'", etag)
+	}
+
+	if prop.IsNavigation {
+		return fmt.Errorf("navigation properties cannot be part of the etag")
+	}
+
+	if mode == ETagModeVersion && prop.Type != "int32" && prop.Type != "int64" {
+		return fmt.Errorf("etag version property must be an integer, got %s", prop.Type)
+	}
+
+	if metadata.ETag == nil {
+		metadata.ETag = &ETagMetadata{Mode: mode}
+	} else if metadata.ETag.Mode != mode {
+		return fmt.Errorf("etag modes cannot be mixed in the same entity")
+	} else if mode == ETagModeVersion {
+		return fmt.Errorf("only one etag version property is allowed")
+	}
+
+	metadata.ETag.Properties = append(metadata.ETag.Properties, prop.Name)
+	return nil
+}
+
 // parsePrimaryKey processa a tag primaryKey
 func (m *EntityMapper) parsePrimaryKey(pk string, prop *PropertyMetadata) error {
 	parts := strings.Split(pk, ";")
@@ -417,11 +489,17 @@ func (m *EntityMapper) parseCascade(cascade string) ([]string, error) {
 
 // parseProp processa a tag prop
 func (m *EntityMapper) parseProp(propTag string) ([]string, error) {
+	// As flags ficam no primeiro segmento entre colchetes; os demais segmentos são facetas
+	segment := strings.TrimSpace(strings.Split(propTag, ";")[0])
+	if strings.Contains(segment, ":") || segment == "default" {
+		return nil, nil
+	}
+
 	// Remove colchetes se existirem
-	propTag = strings.Trim(propTag, "[]")
+	segment = strings.Trim(segment, "[]")
 
 	// Divide por vírgulas
-	parts := strings.Split(propTag, ",")
+	parts := strings.Split(segment, ",")
 
 	var propFlags []string
 	for _, part := range parts {
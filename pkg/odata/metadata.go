@@ -0,0 +1,768 @@
+package odata
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+const (
+	// csdlNamespace é o namespace do schema publicado no $metadata
+	csdlNamespace = "Default"
+	// csdlContainerName é o nome do EntityContainer publicado no $metadata
+	csdlContainerName = "Container"
+	// csdlCoreVocabularyURI é o endereço do vocabulário Core referenciado pelas anotações
+	csdlCoreVocabularyURI = "https://oasis-tcs.github.io/odata-vocabularies/vocabularies/Org.OData.Core.V1.xml"
+)
+
+// edmxDocument representa o documento CSDL XML (OData 4.0)
+type edmxDocument struct {
+	XMLName      xml.Name         `xml:"edmx:Edmx"`
+	Version      string           `xml:"Version,attr"`
+	XmlnsEdmx    string           `xml:"xmlns:edmx,attr"`
+	References   []edmxReference  `xml:"edmx:Reference"`
+	DataServices edmxDataServices `xml:"edmx:DataServices"`
+}
+
+// edmxReference referencia um documento de vocabulário externo
+type edmxReference struct {
+	URI      string        `xml:"Uri,attr"`
+	Includes []edmxInclude `xml:"edmx:Include"`
+}
+
+// edmxInclude inclui um namespace do documento referenciado
+type edmxInclude struct {
+	Namespace string `xml:"Namespace,attr"`
+	Alias     string `xml:"Alias,attr,omitempty"`
+}
+
+// edmxDataServices agrupa os schemas do documento
+type edmxDataServices struct {
+	Schemas []*csdlSchema `xml:"Schema"`
+}
+
+// csdlSchema representa um schema CSDL
+type csdlSchema struct {
+	Xmlns           string               `xml:"xmlns,attr"`
+	Namespace       string               `xml:"Namespace,attr"`
+	EntityTypes     []*csdlEntityType    `xml:"EntityType"`
+	Actions         []*csdlOperation     `xml:"Action"`
+	Functions       []*csdlOperation     `xml:"Function"`
+	EntityContainer *csdlEntityContainer `xml:"EntityContainer,omitempty"`
+}
+
+// csdlEntityType representa um EntityType
+type csdlEntityType struct {
+	Name                 string                    `xml:"Name,attr"`
+	Key                  *csdlKey                  `xml:"Key,omitempty"`
+	Properties           []*csdlProperty           `xml:"Property"`
+	NavigationProperties []*csdlNavigationProperty `xml:"NavigationProperty"`
+}
+
+// csdlKey representa a chave de um EntityType
+type csdlKey struct {
+	PropertyRefs []csdlPropertyRef `xml:"PropertyRef"`
+}
+
+// csdlPropertyRef referencia uma propriedade da chave
+type csdlPropertyRef struct {
+	Name string `xml:"Name,attr"`
+}
+
+// csdlProperty representa uma propriedade estrutural
+type csdlProperty struct {
+	Name      string `xml:"Name,attr"`
+	Type      string `xml:"Type,attr"`
+	Nullable  string `xml:"Nullable,attr,omitempty"`
+	MaxLength int    `xml:"MaxLength,attr,omitempty"`
+	Precision int    `xml:"Precision,attr,omitempty"`
+	Scale     int    `xml:"Scale,attr,omitempty"`
+
+	Annotations []csdlAnnotation `xml:"Annotation"`
+}
+
+// csdlNavigationProperty representa uma propriedade de navegação
+type csdlNavigationProperty struct {
+	Name                   string                      `xml:"Name,attr"`
+	Type                   string                      `xml:"Type,attr"`
+	Nullable               string                      `xml:"Nullable,attr,omitempty"`
+	Partner                string                      `xml:"Partner,attr,omitempty"`
+	ReferentialConstraints []csdlReferentialConstraint `xml:"ReferentialConstraint"`
+	OnDelete               *csdlOnDelete               `xml:"OnDelete,omitempty"`
+
+	collection bool
+	target     string
+}
+
+// csdlReferentialConstraint representa uma restrição referencial
+type csdlReferentialConstraint struct {
+	Property           string `xml:"Property,attr"`
+	ReferencedProperty string `xml:"ReferencedProperty,attr"`
+}
+
+// csdlOnDelete representa a ação de exclusão de uma navegação
+type csdlOnDelete struct {
+	Action string `xml:"Action,attr"`
+}
+
+// csdlEntityContainer representa o EntityContainer
+type csdlEntityContainer struct {
+	Name            string                `xml:"Name,attr"`
+	EntitySets      []*csdlEntitySet      `xml:"EntitySet"`
+	ActionImports   []*csdlActionImport   `xml:"ActionImport"`
+	FunctionImports []*csdlFunctionImport `xml:"FunctionImport"`
+}
+
+// csdlEntitySet representa um EntitySet
+type csdlEntitySet struct {
+	Name                       string                          `xml:"Name,attr"`
+	EntityType                 string                          `xml:"EntityType,attr"`
+	NavigationPropertyBindings []csdlNavigationPropertyBinding `xml:"NavigationPropertyBinding"`
+	Annotations                []csdlAnnotation                `xml:"Annotation"`
+}
+
+// csdlAnnotation representa uma anotação de vocabulário
+type csdlAnnotation struct {
+	Term       string          `xml:"Term,attr"`
+	Bool       string          `xml:"Bool,attr,omitempty"`
+	Collection *csdlCollection `xml:"Collection,omitempty"`
+}
+
+// csdlCollection representa uma coleção de caminhos de propriedade em uma anotação
+type csdlCollection struct {
+	PropertyPaths []string `xml:"PropertyPath"`
+}
+
+// csdlNavigationPropertyBinding associa uma navegação ao EntitySet de destino
+type csdlNavigationPropertyBinding struct {
+	Path   string `xml:"Path,attr"`
+	Target string `xml:"Target,attr"`
+}
+
+// csdlOperation representa uma Action ou Function
+type csdlOperation struct {
+	Name       string           `xml:"Name,attr"`
+	IsBound    bool             `xml:"IsBound,attr,omitempty"`
+	Parameters []*csdlParameter `xml:"Parameter"`
+	ReturnType *csdlReturnType  `xml:"ReturnType,omitempty"`
+	function   bool
+	entitySet  string
+}
+
+// csdlParameter representa um parâmetro de operação
+type csdlParameter struct {
+	Name     string `xml:"Name,attr"`
+	Type     string `xml:"Type,attr"`
+	Nullable string `xml:"Nullable,attr,omitempty"`
+}
+
+// csdlReturnType representa o tipo de retorno de uma operação
+type csdlReturnType struct {
+	Type string `xml:"Type,attr"`
+}
+
+// csdlActionImport expõe uma ação não vinculada no EntityContainer
+type csdlActionImport struct {
+	Name   string `xml:"Name,attr"`
+	Action string `xml:"Action,attr"`
+}
+
+// csdlFunctionImport expõe uma função não vinculada no EntityContainer
+type csdlFunctionImport struct {
+	Name                     string `xml:"Name,attr"`
+	Function                 string `xml:"Function,attr"`
+	EntitySet                string `xml:"EntitySet,attr,omitempty"`
+	IncludeInServiceDocument bool   `xml:"IncludeInServiceDocument,attr,omitempty"`
+}
+
+// csdlEntityRef identifica uma entidade registrada no $metadata
+type csdlEntityRef struct {
+	setName  string
+	typeName string
+	metadata EntityMetadata
+}
+
+// handleMetadata lida com GET dos metadados
+func (s *Server) handleMetadata(c fiber.Ctx) error {
+	c.Set("OData-Version", "4.0")
+
+	if s.wantsJSONMetadata(c) {
+		return c.JSON(s.buildCSDLJSON())
+	}
+
+	body, err := xml.MarshalIndent(s.buildCSDLDocument(), "", "  ")
+	if err != nil {
+		s.writeError(c, fiber.StatusInternalServerError, "MetadataError", err.Error())
+		return nil
+	}
+
+	c.Set(fiber.HeaderContentType, "application/xml")
+	return c.Send(append([]byte(xml.Header), body...))
+}
+
+// wantsJSONMetadata verifica se o cliente solicitou o CSDL em JSON
+func (s *Server) wantsJSONMetadata(c fiber.Ctx) bool {
+	if format := strings.ToLower(c.Query("$format")); format != "" {
+		return format == "json" || strings.HasPrefix(format, "application/json")
+	}
+
+	accept := strings.ToLower(c.Get(fiber.HeaderAccept))
+	return strings.Contains(accept, "application/json") && !strings.Contains(accept, "application/xml")
+}
+
+// collectCSDLEntities retorna as entidades registradas em ordem determinística
+func (s *Server) collectCSDLEntities() []csdlEntityRef {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	refs := make([]csdlEntityRef, 0, len(s.entities))
+	for name, service := range s.entities {
+		metadata := service.GetMetadata()
+		typeName := metadata.Name
+		if typeName == "" {
+			typeName = name
+		}
+		refs = append(refs, csdlEntityRef{setName: name, typeName: typeName, metadata: metadata})
+	}
+
+	sort.Slice(refs, func(i, j int) bool { return refs[i].setName < refs[j].setName })
+	return refs
+}
+
+// buildCSDLDocument constrói o documento CSDL XML a partir das entidades registradas
+func (s *Server) buildCSDLDocument() *edmxDocument {
+	refs := s.collectCSDLEntities()
+
+	// Índice por nome do tipo e do entity set, usado para resolver navegações
+	index := make(map[string]*csdlEntityRef, len(refs)*2)
+	for i := range refs {
+		index[refs[i].setName] = &refs[i]
+	}
+	for i := range refs {
+		if _, exists := index[refs[i].typeName]; !exists {
+			index[refs[i].typeName] = &refs[i]
+		}
+	}
+
+	schema := &csdlSchema{
+		Xmlns:           "http://docs.oasis-open.org/odata/ns/edm",
+		Namespace:       csdlNamespace,
+		EntityContainer: &csdlEntityContainer{Name: csdlContainerName},
+	}
+
+	emittedTypes := make(map[string]bool)
+	for i := range refs {
+		ref := &refs[i]
+		entityType := s.buildCSDLEntityType(ref, index)
+
+		if !emittedTypes[ref.typeName] {
+			emittedTypes[ref.typeName] = true
+			schema.EntityTypes = append(schema.EntityTypes, entityType)
+		}
+
+		entitySet := &csdlEntitySet{
+			Name:       ref.setName,
+			EntityType: csdlNamespace + "." + ref.typeName,
+		}
+		for _, nav := range entityType.NavigationProperties {
+			entitySet.NavigationPropertyBindings = append(entitySet.NavigationPropertyBindings, csdlNavigationPropertyBinding{
+				Path:   nav.Name,
+				Target: nav.target,
+			})
+		}
+		if ref.metadata.ETag != nil {
+			entitySet.Annotations = append(entitySet.Annotations, csdlAnnotation{
+				Term:       "Core.OptimisticConcurrency",
+				Collection: &csdlCollection{PropertyPaths: ref.metadata.ETag.Properties},
+			})
+		}
+		schema.EntityContainer.EntitySets = append(schema.EntityContainer.EntitySets, entitySet)
+	}
+
+	s.buildCSDLOperations(schema, index)
+
+	return &edmxDocument{
+		Version:   "4.0",
+		XmlnsEdmx: "http://docs.oasis-open.org/odata/ns/edmx",
+		References: []edmxReference{{
+			URI:      csdlCoreVocabularyURI,
+			Includes: []edmxInclude{{Namespace: "Org.OData.Core.V1", Alias: "Core"}},
+		}},
+		DataServices: edmxDataServices{Schemas: []*csdlSchema{schema}},
+	}
+}
+
+// buildCSDLOperations publica as ações e funções registradas e os imports das não vinculadas
+func (s *Server) buildCSDLOperations(schema *csdlSchema, index map[string]*csdlEntityRef) {
+	for _, op := range s.collectOperations() {
+		operation := &csdlOperation{Name: op.Name, IsBound: op.Binding != BindingNone, function: op.kind == OperationFunction}
+
+		if op.Binding != BindingNone {
+			bindingType := s.csdlOperationType(op.EntitySet, op.Binding == BindingEntitySet, index)
+			operation.Parameters = append(operation.Parameters, &csdlParameter{Name: "bindingParameter", Type: bindingType, Nullable: "false"})
+		}
+		for _, param := range op.Parameters {
+			parameter := &csdlParameter{Name: param.Name, Type: s.csdlOperationType(param.Type, param.Collection, index)}
+			if !param.Nullable {
+				parameter.Nullable = "false"
+			}
+			operation.Parameters = append(operation.Parameters, parameter)
+		}
+		if op.ReturnType != "" {
+			operation.ReturnType = &csdlReturnType{Type: s.csdlOperationType(op.ReturnType, op.ReturnsCollection, index)}
+			if ref, exists := index[op.ReturnType]; exists && !operationPrimitiveTypes[op.ReturnType] {
+				operation.entitySet = ref.setName
+			}
+		}
+
+		if operation.function {
+			schema.Functions = append(schema.Functions, operation)
+		} else {
+			schema.Actions = append(schema.Actions, operation)
+		}
+
+		if op.Binding != BindingNone {
+			continue
+		}
+		if operation.function {
+			schema.EntityContainer.FunctionImports = append(schema.EntityContainer.FunctionImports, &csdlFunctionImport{
+				Name:                     op.Name,
+				Function:                 op.QualifiedName(),
+				EntitySet:                operation.entitySet,
+				IncludeInServiceDocument: len(op.Parameters) == 0,
+			})
+		} else {
+			schema.EntityContainer.ActionImports = append(schema.EntityContainer.ActionImports, &csdlActionImport{
+				Name:   op.Name,
+				Action: op.QualifiedName(),
+			})
+		}
+	}
+}
+
+// csdlOperationType retorna o tipo CSDL de um parâmetro ou retorno, resolvendo entity sets para o EntityType
+func (s *Server) csdlOperationType(typeName string, collection bool, index map[string]*csdlEntityRef) string {
+	edmType := s.mapODataType(typeName)
+	if ref, exists := index[typeName]; exists && !operationPrimitiveTypes[typeName] {
+		edmType = csdlNamespace + "." + ref.typeName
+	}
+	if collection {
+		return "Collection(" + edmType + ")"
+	}
+	return edmType
+}
+
+// csdlOperationJSON converte uma Action ou Function para o CSDL JSON
+func csdlOperationJSON(operation *csdlOperation) *csdlObject {
+	object := newCSDLObject()
+	if operation.function {
+		object.Set("$Kind", "Function")
+	} else {
+		object.Set("$Kind", "Action")
+	}
+	if operation.IsBound {
+		object.Set("$IsBound", true)
+	}
+
+	if len(operation.Parameters) > 0 {
+		parameters := make([]*csdlObject, 0, len(operation.Parameters))
+		for _, param := range operation.Parameters {
+			paramObject := newCSDLObject()
+			paramObject.Set("$Name", param.Name)
+			setCSDLJSONType(paramObject, param.Type)
+			if param.Nullable != "false" {
+				paramObject.Set("$Nullable", true)
+			}
+			parameters = append(parameters, paramObject)
+		}
+		object.Set("$Parameter", parameters)
+	}
+
+	if operation.ReturnType != nil {
+		returnObject := newCSDLObject()
+		setCSDLJSONType(returnObject, operation.ReturnType.Type)
+		object.Set("$ReturnType", returnObject)
+	}
+	return object
+}
+
+// setCSDLJSONType define $Type e $Collection, omitindo Edm.String que é o padrão do CSDL JSON
+func setCSDLJSONType(object *csdlObject, typeName string) {
+	if inner, ok := strings.CutPrefix(typeName, "Collection("); ok {
+		object.Set("$Collection", true)
+		typeName = strings.TrimSuffix(inner, ")")
+	}
+	if typeName != "Edm.String" {
+		object.Set("$Type", typeName)
+	}
+}
+
+// buildCSDLEntityType constrói o EntityType de uma entidade
+func (s *Server) buildCSDLEntityType(ref *csdlEntityRef, index map[string]*csdlEntityRef) *csdlEntityType {
+	entityType := &csdlEntityType{Name: ref.typeName}
+
+	var keys []csdlPropertyRef
+	for _, prop := range ref.metadata.Properties {
+		if prop.IsNavigation {
+			continue
+		}
+
+		property := &csdlProperty{
+			Name:      prop.Name,
+			Type:      s.csdlPropertyType(prop),
+			MaxLength: prop.MaxLength,
+			Precision: prop.Precision,
+			Scale:     prop.Scale,
+		}
+		if prop.IsKey || !prop.IsNullable {
+			property.Nullable = "false"
+		}
+		if prop.IsKey {
+			keys = append(keys, csdlPropertyRef{Name: prop.Name})
+		}
+		property.Annotations = propertyAnnotations(ref.metadata, prop)
+
+		entityType.Properties = append(entityType.Properties, property)
+	}
+	if len(keys) > 0 {
+		entityType.Key = &csdlKey{PropertyRefs: keys}
+	}
+
+	for _, prop := range ref.metadata.Properties {
+		if !prop.IsNavigation {
+			continue
+		}
+
+		related, exists := index[prop.RelatedType]
+		if !exists {
+			// Navegações para entidades não registradas tornariam o documento inválido
+			continue
+		}
+
+		nav := &csdlNavigationProperty{
+			Name:       prop.Name,
+			Type:       csdlNamespace + "." + related.typeName,
+			Partner:    findNavigationPartner(ref, prop, related),
+			collection: prop.IsCollection,
+			target:     related.setName,
+		}
+
+		if prop.IsCollection {
+			nav.Type = "Collection(" + nav.Type + ")"
+		} else if prop.Relationship != nil && prop.Relationship.LocalProperty != "" && prop.Relationship.ReferencedProperty != "" {
+			local := resolveCSDLPropertyName(ref.metadata, prop.Relationship.LocalProperty)
+			nav.ReferentialConstraints = []csdlReferentialConstraint{{
+				Property:           local,
+				ReferencedProperty: resolveCSDLPropertyName(related.metadata, prop.Relationship.ReferencedProperty),
+			}}
+			if localProp := findCSDLProperty(ref.metadata, local); localProp != nil && !localProp.IsNullable {
+				nav.Nullable = "false"
+			}
+		}
+
+		if prop.Relationship != nil {
+			if action := csdlOnDeleteAction(prop.Relationship.OnDelete); action != "" {
+				nav.OnDelete = &csdlOnDelete{Action: action}
+			}
+		}
+
+		entityType.NavigationProperties = append(entityType.NavigationProperties, nav)
+	}
+
+	return entityType
+}
+
+// propertyAnnotations anota as propriedades calculadas pelo servidor (Core.Computed) e as que não podem
+// ser alteradas depois da inserção (Core.Immutable)
+func propertyAnnotations(metadata EntityMetadata, prop PropertyMetadata) []csdlAnnotation {
+	computed := isGeneratedKey(prop) || (!prop.IsKey && isReadOnlyProperty(metadata, prop, true) && isReadOnlyProperty(metadata, prop, false))
+	if computed {
+		return []csdlAnnotation{{Term: "Core.Computed", Bool: "true"}}
+	}
+	if !prop.IsKey && prop.HasPropFlag(PropNoUpdate) {
+		return []csdlAnnotation{{Term: "Core.Immutable", Bool: "true"}}
+	}
+	return nil
+}
+
+// csdlPropertyType retorna o tipo Edm de uma propriedade considerando suas facetas
+func (s *Server) csdlPropertyType(prop PropertyMetadata) string {
+	// Números de ponto flutuante com precisão declarada correspondem a colunas decimais
+	if (prop.Type == "float32" || prop.Type == "float64") && prop.Precision > 0 {
+		return "Edm.Decimal"
+	}
+	return s.mapODataType(prop.Type)
+}
+
+// navigationLinkKey identifica a chave que liga os dois lados de um relacionamento
+func navigationLinkKey(prop PropertyMetadata) string {
+	switch {
+	case prop.Association != nil:
+		return strings.ToLower(prop.Association.ForeignKey)
+	case prop.ManyAssociation != nil && prop.ManyAssociation.JoinTable != "":
+		return "join:" + strings.ToLower(prop.ManyAssociation.JoinTable)
+	case prop.ManyAssociation != nil:
+		return strings.ToLower(prop.ManyAssociation.ForeignKey)
+	}
+	return ""
+}
+
+// findNavigationPartner procura a navegação inversa na entidade relacionada
+func findNavigationPartner(source *csdlEntityRef, nav PropertyMetadata, related *csdlEntityRef) string {
+	linkKey := navigationLinkKey(nav)
+
+	var candidates []string
+	for _, prop := range related.metadata.Properties {
+		if !prop.IsNavigation {
+			continue
+		}
+		if prop.RelatedType != source.typeName && prop.RelatedType != source.setName {
+			continue
+		}
+		if related.setName == source.setName && prop.Name == nav.Name {
+			continue
+		}
+		if otherKey := navigationLinkKey(prop); linkKey != "" && otherKey != "" && otherKey != linkKey {
+			continue
+		}
+		candidates = append(candidates, prop.Name)
+	}
+
+	if len(candidates) == 1 {
+		return candidates[0]
+	}
+	return ""
+}
+
+// findCSDLProperty busca uma propriedade pelo nome ou pelo nome da coluna
+func findCSDLProperty(metadata EntityMetadata, name string) *PropertyMetadata {
+	for i := range metadata.Properties {
+		if metadata.Properties[i].Name == name {
+			return &metadata.Properties[i]
+		}
+	}
+	for i := range metadata.Properties {
+		if strings.EqualFold(metadata.Properties[i].ColumnName, name) || strings.EqualFold(metadata.Properties[i].Name, name) {
+			return &metadata.Properties[i]
+		}
+	}
+	return nil
+}
+
+// resolveCSDLPropertyName converte nomes de coluna usados nas tags para nomes de propriedade
+func resolveCSDLPropertyName(metadata EntityMetadata, name string) string {
+	if prop := findCSDLProperty(metadata, name); prop != nil {
+		return prop.Name
+	}
+	return name
+}
+
+// csdlOnDeleteAction normaliza a ação de exclusão para os valores aceitos pelo CSDL
+func csdlOnDeleteAction(action string) string {
+	switch strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(action), "_", " ")) {
+	case "CASCADE":
+		return "Cascade"
+	case "SET NULL", "SETNULL":
+		return "SetNull"
+	case "SET DEFAULT", "SETDEFAULT":
+		return "SetDefault"
+	case "NO ACTION", "NOACTION", "RESTRICT", "NONE":
+		return "None"
+	}
+	return ""
+}
+
+// csdlObject é um objeto JSON que preserva a ordem de inserção dos membros
+type csdlObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// newCSDLObject cria um objeto JSON ordenado
+func newCSDLObject() *csdlObject {
+	return &csdlObject{values: make(map[string]interface{})}
+}
+
+// Set define um membro do objeto mantendo a ordem da primeira inserção
+func (o *csdlObject) Set(key string, value interface{}) {
+	if _, exists := o.values[key]; !exists {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// MarshalJSON serializa o objeto respeitando a ordem dos membros
+func (o *csdlObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// buildCSDLJSON constrói o CSDL JSON (OData 4.01) a partir do mesmo modelo do XML
+func (s *Server) buildCSDLJSON() *csdlObject {
+	document := s.buildCSDLDocument()
+	schema := document.DataServices.Schemas[0]
+
+	schemaObject := newCSDLObject()
+	for _, entityType := range schema.EntityTypes {
+		typeObject := newCSDLObject()
+		typeObject.Set("$Kind", "EntityType")
+
+		if entityType.Key != nil {
+			keys := make([]string, 0, len(entityType.Key.PropertyRefs))
+			for _, ref := range entityType.Key.PropertyRefs {
+				keys = append(keys, ref.Name)
+			}
+			typeObject.Set("$Key", keys)
+		}
+
+		for _, prop := range entityType.Properties {
+			propObject := newCSDLObject()
+			if prop.Type != "Edm.String" {
+				propObject.Set("$Type", prop.Type)
+			}
+			// No CSDL JSON a ausência de $Nullable significa false
+			if prop.Nullable != "false" {
+				propObject.Set("$Nullable", true)
+			}
+			if prop.MaxLength > 0 {
+				propObject.Set("$MaxLength", prop.MaxLength)
+			}
+			if prop.Precision > 0 {
+				propObject.Set("$Precision", prop.Precision)
+			}
+			if prop.Scale > 0 {
+				propObject.Set("$Scale", prop.Scale)
+			}
+			for _, annotation := range prop.Annotations {
+				propObject.Set("@"+annotation.Term, annotation.Bool == "true")
+			}
+			typeObject.Set(prop.Name, propObject)
+		}
+
+		for _, nav := range entityType.NavigationProperties {
+			navObject := newCSDLObject()
+			navObject.Set("$Kind", "NavigationProperty")
+			if nav.collection {
+				navObject.Set("$Collection", true)
+				navObject.Set("$Type", strings.TrimSuffix(strings.TrimPrefix(nav.Type, "Collection("), ")"))
+			} else {
+				navObject.Set("$Type", nav.Type)
+				if nav.Nullable != "false" {
+					navObject.Set("$Nullable", true)
+				}
+			}
+			if nav.Partner != "" {
+				navObject.Set("$Partner", nav.Partner)
+			}
+			if len(nav.ReferentialConstraints) > 0 {
+				constraints := newCSDLObject()
+				for _, constraint := range nav.ReferentialConstraints {
+					constraints.Set(constraint.Property, constraint.ReferencedProperty)
+				}
+				navObject.Set("$ReferentialConstraint", constraints)
+			}
+			if nav.OnDelete != nil {
+				navObject.Set("$OnDelete", nav.OnDelete.Action)
+			}
+			typeObject.Set(nav.Name, navObject)
+		}
+
+		schemaObject.Set(entityType.Name, typeObject)
+	}
+
+	// Sobrecargas de uma operação são agrupadas em um array com o nome da operação
+	overloads := make(map[string][]*csdlObject)
+	var operationNames []string
+	for _, operation := range append(append([]*csdlOperation(nil), schema.Actions...), schema.Functions...) {
+		if _, exists := overloads[operation.Name]; !exists {
+			operationNames = append(operationNames, operation.Name)
+		}
+		overloads[operation.Name] = append(overloads[operation.Name], csdlOperationJSON(operation))
+	}
+	for _, name := range operationNames {
+		schemaObject.Set(name, overloads[name])
+	}
+
+	container := newCSDLObject()
+	container.Set("$Kind", "EntityContainer")
+	for _, entitySet := range schema.EntityContainer.EntitySets {
+		setObject := newCSDLObject()
+		setObject.Set("$Collection", true)
+		setObject.Set("$Type", entitySet.EntityType)
+		if len(entitySet.NavigationPropertyBindings) > 0 {
+			bindings := newCSDLObject()
+			for _, binding := range entitySet.NavigationPropertyBindings {
+				bindings.Set(binding.Path, binding.Target)
+			}
+			setObject.Set("$NavigationPropertyBinding", bindings)
+		}
+		for _, annotation := range entitySet.Annotations {
+			if annotation.Collection != nil {
+				setObject.Set("@"+annotation.Term, annotation.Collection.PropertyPaths)
+			}
+		}
+		container.Set(entitySet.Name, setObject)
+	}
+	for _, actionImport := range schema.EntityContainer.ActionImports {
+		importObject := newCSDLObject()
+		importObject.Set("$Action", actionImport.Action)
+		container.Set(actionImport.Name, importObject)
+	}
+	for _, functionImport := range schema.EntityContainer.FunctionImports {
+		importObject := newCSDLObject()
+		importObject.Set("$Function", functionImport.Function)
+		if functionImport.EntitySet != "" {
+			importObject.Set("$EntitySet", functionImport.EntitySet)
+		}
+		if functionImport.IncludeInServiceDocument {
+			importObject.Set("$IncludeInServiceDocument", true)
+		}
+		container.Set(functionImport.Name, importObject)
+	}
+	schemaObject.Set(schema.EntityContainer.Name, container)
+
+	references := newCSDLObject()
+	for _, reference := range document.References {
+		includes := make([]*csdlObject, 0, len(reference.Includes))
+		for _, include := range reference.Includes {
+			includeObject := newCSDLObject()
+			includeObject.Set("$Namespace", include.Namespace)
+			if include.Alias != "" {
+				includeObject.Set("$Alias", include.Alias)
+			}
+			includes = append(includes, includeObject)
+		}
+		referenceObject := newCSDLObject()
+		referenceObject.Set("$Include", includes)
+		references.Set(reference.URI, referenceObject)
+	}
+
+	root := newCSDLObject()
+	root.Set("$Version", "4.0")
+	root.Set("$Reference", references)
+	root.Set("$EntityContainer", csdlNamespace+"."+csdlContainerName)
+	root.Set(csdlNamespace, schemaObject)
+	return root
+}
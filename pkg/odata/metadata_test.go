@@ -0,0 +1,156 @@
+package odata
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetadataTestServer(t *testing.T) *Server {
+	server := newTestServer(&MockDatabaseProvider{})
+	require.NoError(t, server.RegisterEntity("Users", TestUser{}))
+	require.NoError(t, server.RegisterEntity("Orders", TestOrder{}))
+	require.NoError(t, server.RegisterEntity("OrderItems", TestOrderItem{}))
+	require.NoError(t, server.RegisterEntity("Products", TestProduct{}))
+	return server
+}
+
+func findCSDLEntityType(schema *csdlSchema, name string) *csdlEntityType {
+	for _, entityType := range schema.EntityTypes {
+		if entityType.Name == name {
+			return entityType
+		}
+	}
+	return nil
+}
+
+func findCSDLNavigation(entityType *csdlEntityType, name string) *csdlNavigationProperty {
+	for _, nav := range entityType.NavigationProperties {
+		if nav.Name == name {
+			return nav
+		}
+	}
+	return nil
+}
+
+func TestMetadata_XML(t *testing.T) {
+	server := newMetadataTestServer(t)
+
+	resp, err := server.GetRouter().Test(httptest.NewRequest("GET", "/odata/$metadata", nil))
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "application/xml")
+	assert.Equal(t, "4.0", resp.Header.Get("OData-Version"))
+
+	raw, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), `<edmx:Edmx Version="4.0" xmlns:edmx="http://docs.oasis-open.org/odata/ns/edmx">`)
+
+	var document struct {
+		DataServices struct {
+			Schemas []*csdlSchema `xml:"Schema"`
+		} `xml:"DataServices"`
+	}
+	require.NoError(t, xml.Unmarshal(raw, &document))
+	require.Len(t, document.DataServices.Schemas, 1)
+	schema := document.DataServices.Schemas[0]
+	assert.Equal(t, "Default", schema.Namespace)
+
+	user := findCSDLEntityType(schema, "TestUser")
+	require.NotNil(t, user)
+	require.NotNil(t, user.Key)
+	assert.Equal(t, []csdlPropertyRef{{Name: "id"}}, user.Key.PropertyRefs)
+	for _, prop := range user.Properties {
+		switch prop.Name {
+		case "nome":
+			assert.Equal(t, 100, prop.MaxLength)
+			assert.Equal(t, "false", prop.Nullable)
+		case "salario":
+			assert.Equal(t, "Edm.Decimal", prop.Type)
+			assert.Equal(t, 10, prop.Precision)
+			assert.Equal(t, 2, prop.Scale)
+			assert.Empty(t, prop.Nullable)
+		}
+	}
+
+	order := findCSDLEntityType(schema, "TestOrder")
+	require.NotNil(t, order)
+
+	userNav := findCSDLNavigation(order, "User")
+	require.NotNil(t, userNav)
+	assert.Equal(t, "Default.TestUser", userNav.Type)
+	assert.Equal(t, []csdlReferentialConstraint{{Property: "user_id", ReferencedProperty: "id"}}, userNav.ReferentialConstraints)
+
+	itemsNav := findCSDLNavigation(order, "Items")
+	require.NotNil(t, itemsNav)
+	assert.Equal(t, "Collection(Default.TestOrderItem)", itemsNav.Type)
+	assert.Equal(t, "Order", itemsNav.Partner)
+	assert.Empty(t, itemsNav.ReferentialConstraints)
+
+	item := findCSDLEntityType(schema, "TestOrderItem")
+	require.NotNil(t, item)
+	orderNav := findCSDLNavigation(item, "Order")
+	require.NotNil(t, orderNav)
+	assert.Equal(t, "Items", orderNav.Partner)
+
+	require.NotNil(t, schema.EntityContainer)
+	assert.Equal(t, "Container", schema.EntityContainer.Name)
+	require.Len(t, schema.EntityContainer.EntitySets, 4)
+	orders := schema.EntityContainer.EntitySets[1]
+	assert.Equal(t, "Orders", orders.Name)
+	assert.Equal(t, "Default.TestOrder", orders.EntityType)
+	assert.Equal(t, []csdlNavigationPropertyBinding{
+		{Path: "User", Target: "Users"},
+		{Path: "Items", Target: "OrderItems"},
+	}, orders.NavigationPropertyBindings)
+}
+
+func TestMetadata_JSON(t *testing.T) {
+	server := newMetadataTestServer(t)
+
+	for _, tt := range []struct {
+		target string
+		accept string
+	}{
+		{"/odata/$metadata", "application/json"},
+		{"/odata/$metadata?$format=json", ""},
+	} {
+		req := httptest.NewRequest("GET", tt.target, nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+
+		resp, err := server.GetRouter().Test(req)
+		require.NoError(t, err)
+		assert.Contains(t, resp.Header.Get("Content-Type"), "application/json")
+
+		var document map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&document))
+		assert.Equal(t, "4.0", document["$Version"])
+		assert.Equal(t, "Default.Container", document["$EntityContainer"])
+
+		schema := document["Default"].(map[string]interface{})
+		order := schema["TestOrder"].(map[string]interface{})
+		assert.Equal(t, "EntityType", order["$Kind"])
+		assert.Equal(t, []interface{}{"id"}, order["$Key"])
+
+		userNav := order["User"].(map[string]interface{})
+		assert.Equal(t, "NavigationProperty", userNav["$Kind"])
+		assert.Equal(t, "Default.TestUser", userNav["$Type"])
+		assert.Equal(t, map[string]interface{}{"user_id": "id"}, userNav["$ReferentialConstraint"])
+
+		itemsNav := order["Items"].(map[string]interface{})
+		assert.Equal(t, true, itemsNav["$Collection"])
+		assert.Equal(t, "Order", itemsNav["$Partner"])
+
+		container := schema["Container"].(map[string]interface{})
+		orders := container["Orders"].(map[string]interface{})
+		assert.Equal(t, "Default.TestOrder", orders["$Type"])
+		assert.Equal(t, map[string]interface{}{"User": "Users", "Items": "OrderItems"}, orders["$NavigationPropertyBinding"])
+	}
+}
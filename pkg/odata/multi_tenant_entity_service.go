@@ -211,6 +211,51 @@ func (s *MultiTenantEntityService) Delete(ctx context.Context, keys map[string]a
 	return nil
 }
 
+// QueryNavigation consulta as entidades relacionadas usando o provider apropriado
+func (s *MultiTenantEntityService) QueryNavigation(ctx context.Context, entity *OrderedEntity, navigation string, option ExpandOption) ([]any, int64, error) {
+	// Resolve o provider dinamicamente
+	originalProvider := s.provider
+	s.provider = s.getProviderForContext(ctx)
+	defer func() { s.provider = originalProvider }()
+
+	if s.provider == nil {
+		return nil, 0, fmt.Errorf("provider não disponível para o tenant")
+	}
+
+	s.logTenantOperation(ctx, "QueryNavigation", fmt.Sprintf("Navigation: %s", navigation))
+	return s.BaseEntityService.QueryNavigation(ctx, entity, navigation, option)
+}
+
+// LinkEntities vincula entidades na tabela de junção usando o provider apropriado
+func (s *MultiTenantEntityService) LinkEntities(ctx context.Context, navigation string, parentValue, relatedValue any) error {
+	// Resolve o provider dinamicamente
+	originalProvider := s.provider
+	s.provider = s.getProviderForContext(ctx)
+	defer func() { s.provider = originalProvider }()
+
+	if s.provider == nil {
+		return fmt.Errorf("provider não disponível para o tenant")
+	}
+
+	s.logTenantOperation(ctx, "LinkEntities", fmt.Sprintf("Navigation: %s", navigation))
+	return s.BaseEntityService.LinkEntities(ctx, navigation, parentValue, relatedValue)
+}
+
+// UnlinkEntities remove vínculos da tabela de junção usando o provider apropriado
+func (s *MultiTenantEntityService) UnlinkEntities(ctx context.Context, navigation string, parentValue, relatedValue any) error {
+	// Resolve o provider dinamicamente
+	originalProvider := s.provider
+	s.provider = s.getProviderForContext(ctx)
+	defer func() { s.provider = originalProvider }()
+
+	if s.provider == nil {
+		return fmt.Errorf("provider não disponível para o tenant")
+	}
+
+	s.logTenantOperation(ctx, "UnlinkEntities", fmt.Sprintf("Navigation: %s", navigation))
+	return s.BaseEntityService.UnlinkEntities(ctx, navigation, parentValue, relatedValue)
+}
+
 // GetTenantProvider retorna o provider para um tenant específico
 func (s *MultiTenantEntityService) GetTenantProvider(tenantID string) DatabaseProvider {
 	if s.server.multiTenantPool != nil {
@@ -0,0 +1,574 @@
+package odata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// entitySetLocalKey guarda nos Locals o entity set alvo de um caminho de navegação
+const entitySetLocalKey = "odata_entity_set"
+
+// ResourceSegment representa um segmento do caminho de recurso, como Orders(1) ou Items
+type ResourceSegment struct {
+	Name   string // Nome do entity set, da propriedade de navegação ou segmento especial ($count)
+	Key    string // Conteúdo entre parênteses, sem os parênteses
+	HasKey bool
+}
+
+// ParseResourcePath divide o caminho de recurso (sem o prefixo das rotas) em segmentos
+func ParseResourcePath(path string) ([]ResourceSegment, error) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil, fmt.Errorf("empty resource path")
+	}
+
+	var segments []ResourceSegment
+	var current strings.Builder
+	depth := 0
+	inQuote := false
+
+	flush := func() error {
+		segment, err := parseResourceSegment(current.String())
+		if err != nil {
+			return err
+		}
+		segments = append(segments, segment)
+		current.Reset()
+		return nil
+	}
+
+	for _, char := range path {
+		switch {
+		case char == '\'':
+			inQuote = !inQuote
+		case inQuote:
+		case char == '(':
+			depth++
+		case char == ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced parentheses in resource path '%s'", path)
+			}
+		case char == '/' && depth == 0:
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		current.WriteRune(char)
+	}
+
+	if depth != 0 || inQuote {
+		return nil, fmt.Errorf("unbalanced parentheses in resource path '%s'", path)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return segments, nil
+}
+
+// parseResourceSegment separa o nome e a chave de um segmento
+func parseResourceSegment(segment string) (ResourceSegment, error) {
+	if segment == "" {
+		return ResourceSegment{}, fmt.Errorf("empty segment in resource path")
+	}
+
+	open := strings.Index(segment, "(")
+	if open == -1 {
+		return ResourceSegment{Name: segment}, nil
+	}
+	if open == 0 || !strings.HasSuffix(segment, ")") {
+		return ResourceSegment{}, fmt.Errorf("invalid resource path segment '%s'", segment)
+	}
+
+	return ResourceSegment{
+		Name:   segment[:open],
+		Key:    segment[open+1 : len(segment)-1],
+		HasKey: true,
+	}, nil
+}
+
+// isNavigationPath verifica se o caminho tem mais de um segmento
+func isNavigationPath(path string) bool {
+	segments, err := ParseResourcePath(path)
+	return err == nil && len(segments) > 1
+}
+
+// resourcePathError representa um erro de resolução do caminho com o status HTTP correspondente
+type resourcePathError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *resourcePathError) Error() string {
+	return e.message
+}
+
+// navigationTarget é o recurso endereçado por um caminho de navegação
+type navigationTarget struct {
+	entityName  string                 // Entity set alvo
+	service     EntityService          // Serviço do entity set alvo
+	condition   *ParseNode             // Restrição do relacionamento (e da chave) sobre o conjunto alvo
+	single      bool                   // Se o alvo é uma entidade única
+	keyed       bool                   // Se o último segmento informou a chave
+	count       bool                   // Se o caminho termina em $count
+	navigation  *PropertyMetadata      // Última propriedade de navegação
+	keyProperty string                 // Propriedade do alvo que referencia a entidade de origem
+	parentValue interface{}            // Valor da chave da entidade de origem
+	keys        map[string]interface{} // Chaves informadas no último segmento
+
+	ref              bool           // Se o caminho termina em $ref
+	joinTable        bool           // Se a navegação passa por uma tabela de junção
+	parent           *OrderedEntity // Entidade de origem da última navegação
+	parentService    EntityService  // Serviço da entidade de origem
+	parentEntityName string         // Entity set da entidade de origem
+	parentProperty   string         // Propriedade da origem comparada com keyProperty
+
+	property  *PropertyMetadata // Propriedade estrutural endereçada (/Products(1)/Name)
+	rawValue  bool              // Se o caminho termina em $value
+	operation *ResourceSegment  // Operação vinculada invocada no alvo (/Orders(1)/Default.Approve)
+}
+
+// resolveNavigationPath percorre os segmentos, carregando as entidades intermediárias
+func (s *Server) resolveNavigationPath(ctx context.Context, segments []ResourceSegment) (*navigationTarget, error) {
+	target := &navigationTarget{}
+	if last := segments[len(segments)-1]; !last.HasKey && (last.Name == "$count" || last.Name == "$ref" || last.Name == "$value") {
+		target.count = last.Name == "$count"
+		target.ref = last.Name == "$ref"
+		target.rawValue = last.Name == "$value"
+		segments = segments[:len(segments)-1]
+	}
+
+	root := segments[0]
+	service, exists := s.entityService(root.Name)
+	if !exists {
+		return nil, &resourcePathError{fiber.StatusNotFound, "EntityNotFound", fmt.Sprintf("Entity '%s' not found", root.Name)}
+	}
+	if !root.HasKey {
+		return nil, &resourcePathError{fiber.StatusBadRequest, "InvalidPath", fmt.Sprintf("segment '%s' must be followed by a key", root.Name)}
+	}
+
+	target.entityName = root.Name
+	target.service = service
+	if err := s.navigateToKey(target, root.Key); err != nil {
+		return nil, err
+	}
+
+	for i, segment := range segments[1:] {
+		// Operações vinculadas encerram o caminho
+		if isOperationSegment(segment.Name) {
+			if i != len(segments)-2 || target.count || target.ref || target.rawValue {
+				return nil, &resourcePathError{fiber.StatusBadRequest, "InvalidPath", fmt.Sprintf("operation '%s' must be the last segment", segment.Name)}
+			}
+			if target.joinTable {
+				return nil, &resourcePathError{fiber.StatusNotImplemented, "NotImplemented", fmt.Sprintf("operations bound to the join table of '%s' are not supported", target.navigation.Name)}
+			}
+			target.operation = &segment
+			break
+		}
+
+		// Propriedades estruturais encerram o caminho
+		if prop, ok := structuralProperty(target.service.GetMetadata(), segment.Name); ok {
+			if !target.single || segment.HasKey || i != len(segments)-2 {
+				return nil, &resourcePathError{fiber.StatusBadRequest, "InvalidPath", fmt.Sprintf("property '%s' must be the last segment of a single entity path", segment.Name)}
+			}
+			if target.joinTable {
+				return nil, &resourcePathError{fiber.StatusNotImplemented, "NotImplemented", fmt.Sprintf("navigation beyond the join table of '%s' is not supported", target.navigation.Name)}
+			}
+			target.property = &prop
+			break
+		}
+
+		if !target.single {
+			return nil, &resourcePathError{fiber.StatusBadRequest, "InvalidPath", fmt.Sprintf("collection '%s' must be followed by a key", target.navigation.Name)}
+		}
+		if target.joinTable {
+			return nil, &resourcePathError{fiber.StatusNotImplemented, "NotImplemented", fmt.Sprintf("navigation beyond the join table of '%s' is not supported", target.navigation.Name)}
+		}
+
+		// Carrega a entidade de origem do próximo segmento
+		parent, err := s.loadNavigationEntity(ctx, target)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.navigate(target, parent, segment); err != nil {
+			return nil, err
+		}
+	}
+
+	if target.count && (target.single || target.property != nil) {
+		return nil, &resourcePathError{fiber.StatusBadRequest, "InvalidPath", "$count can only be applied to collections"}
+	}
+	if target.ref && target.property != nil {
+		return nil, &resourcePathError{fiber.StatusBadRequest, "InvalidPath", "$ref can only be applied to entities"}
+	}
+	if target.rawValue && target.property == nil {
+		return nil, &resourcePathError{fiber.StatusBadRequest, "InvalidPath", "$value can only be applied to properties"}
+	}
+
+	return target, nil
+}
+
+// navigate avança o alvo pela propriedade de navegação do segmento
+func (s *Server) navigate(target *navigationTarget, parent *OrderedEntity, segment ResourceSegment) error {
+	metadata := target.service.GetMetadata()
+
+	var navProp *PropertyMetadata
+	for i := range metadata.Properties {
+		if metadata.Properties[i].IsNavigation && strings.EqualFold(metadata.Properties[i].Name, segment.Name) {
+			navProp = &metadata.Properties[i]
+			break
+		}
+	}
+	if navProp == nil {
+		return &resourcePathError{fiber.StatusNotFound, "NavigationNotFound", fmt.Sprintf("navigation property '%s' not found in '%s'", segment.Name, target.entityName)}
+	}
+
+	relatedType := navProp.RelatedType
+	if relatedType == "" && navProp.ManyAssociation != nil {
+		relatedType = navProp.ManyAssociation.RelatedEntity
+	}
+	entityName, service, exists := s.findEntitySet(relatedType)
+	if !exists {
+		return &resourcePathError{fiber.StatusNotFound, "EntityNotFound", fmt.Sprintf("related entity '%s' is not registered", relatedType)}
+	}
+	related := service.GetMetadata()
+
+	// A origem da navegação não depende do dialeto, e no multi-tenant o servidor não tem provider padrão
+	source, err := (&QueryBuilder{}).BuildExpandSource(metadata, *navProp, related)
+	if err != nil {
+		return &resourcePathError{fiber.StatusInternalServerError, "NavigationError", err.Error()}
+	}
+
+	parentValue, _ := parent.Get(source.ParentProperty)
+
+	target.parent = parent
+	target.parentService = target.service
+	target.parentEntityName = target.entityName
+	target.parentProperty = source.ParentProperty
+	target.entityName = entityName
+	target.service = service
+	target.navigation = navProp
+	target.parentValue = parentValue
+	target.single = !navProp.IsCollection
+	target.keyed = false
+	target.keys = nil
+	target.joinTable = navProp.ManyAssociation != nil && navProp.ManyAssociation.JoinTable != ""
+
+	// Na tabela de junção a restrição do relacionamento não é expressa sobre o conjunto alvo
+	if target.joinTable {
+		target.keyProperty = ""
+		target.condition = nil
+	} else {
+		target.keyProperty = expandPropertyName(related, source.KeyColumn, "")
+		target.condition = nil
+		// Sem valor na entidade de origem a navegação não tem destino
+		if parentValue != nil {
+			target.condition = keysetComparison("eq", target.keyProperty, parentValue)
+		}
+	}
+
+	if segment.HasKey {
+		if !navProp.IsCollection {
+			return &resourcePathError{fiber.StatusBadRequest, "InvalidPath", fmt.Sprintf("single-valued navigation '%s' does not accept a key", navProp.Name)}
+		}
+		return s.navigateToKey(target, segment.Key)
+	}
+
+	return nil
+}
+
+// navigateToKey restringe o alvo à entidade com a chave informada
+func (s *Server) navigateToKey(target *navigationTarget, key string) error {
+	metadata := target.service.GetMetadata()
+	keys, err := s.extractKeys("("+key+")", metadata)
+	if err != nil {
+		return &resourcePathError{fiber.StatusBadRequest, "InvalidKey", err.Error()}
+	}
+
+	condition := keyCondition(metadata, keys)
+	if target.navigation != nil && !target.joinTable {
+		// O membro precisa pertencer à entidade de origem
+		if target.condition == nil {
+			condition = nil
+		} else {
+			condition = keysetLogical("and", target.condition, condition)
+		}
+	}
+
+	target.condition = condition
+	target.keys = keys
+	target.keyed = true
+	target.single = true
+	return nil
+}
+
+// structuralProperty localiza uma propriedade que não é de navegação pelo nome
+func structuralProperty(metadata EntityMetadata, name string) (PropertyMetadata, bool) {
+	for _, prop := range metadata.Properties {
+		if !prop.IsNavigation && prop.Name == name {
+			return prop, true
+		}
+	}
+	return PropertyMetadata{}, false
+}
+
+// keyCondition converte as chaves em condições de igualdade
+func keyCondition(metadata EntityMetadata, keys map[string]interface{}) *ParseNode {
+	var condition *ParseNode
+	for _, prop := range metadata.Properties {
+		value, ok := keys[prop.Name]
+		if !prop.IsKey || !ok {
+			continue
+		}
+		term := keysetComparison("eq", prop.Name, value)
+		if condition == nil {
+			condition = term
+		} else {
+			condition = keysetLogical("and", condition, term)
+		}
+	}
+	return condition
+}
+
+// loadNavigationEntity carrega a entidade única endereçada pelo alvo
+func (s *Server) loadNavigationEntity(ctx context.Context, target *navigationTarget) (*OrderedEntity, error) {
+	if target.condition == nil {
+		return nil, &resourcePathError{fiber.StatusNotFound, "EntityNotFound", "Entity not found"}
+	}
+
+	options := QueryOptions{Filter: &GoDataFilterQuery{Tree: target.condition}}
+	if err := s.interceptQuery(ctx, target.entityName, QueryKindEntity, target.keys, &options); err != nil {
+		if canceled, ok := isQueryVetoed(err); ok {
+			return nil, &resourcePathError{fiber.StatusForbidden, "QueryForbidden", canceled.Reason}
+		}
+		return nil, &resourcePathError{fiber.StatusInternalServerError, "QueryError", err.Error()}
+	}
+
+	response, err := target.service.Query(ctx, options)
+	if err != nil {
+		return nil, &resourcePathError{fiber.StatusInternalServerError, "QueryError", err.Error()}
+	}
+
+	if results, ok := response.Value.([]any); ok && len(results) > 0 {
+		if entity, ok := results[0].(*OrderedEntity); ok {
+			// Pela tabela de junção, o membro precisa estar vinculado à entidade de origem
+			if target.joinTable && target.keyed {
+				member, err := s.isJoinTableMember(ctx, target)
+				if err != nil {
+					return nil, err
+				}
+				if !member {
+					return nil, &resourcePathError{fiber.StatusNotFound, "EntityNotFound", "Entity not found"}
+				}
+			}
+			return entity, nil
+		}
+	}
+	return nil, &resourcePathError{fiber.StatusNotFound, "EntityNotFound", "Entity not found"}
+}
+
+// findEntitySet localiza o entity set pelo nome do conjunto ou do tipo
+func (s *Server) findEntitySet(name string) (string, EntityService, bool) {
+	entities := s.GetEntities()
+	if service, exists := entities[name]; exists {
+		return name, service, true
+	}
+	for entityName, service := range entities {
+		if service.GetMetadata().Name == name {
+			return entityName, service, true
+		}
+	}
+	return "", nil, false
+}
+
+// entitySetNameOf retorna o nome com que a entidade foi registrada
+func (s *Server) entitySetNameOf(metadata EntityMetadata) string {
+	entities := s.GetEntities()
+	if _, exists := entities[metadata.Name]; exists {
+		return metadata.Name
+	}
+	for entityName, service := range entities {
+		if service.GetMetadata().Name == metadata.Name {
+			return entityName
+		}
+	}
+	return metadata.Name
+}
+
+// formatKeyLiteral formata o valor de uma chave como literal OData para URLs
+func formatKeyLiteral(value interface{}) string {
+	if str, ok := value.(string); ok {
+		return "'" + strings.ReplaceAll(str, "'", "''") + "'"
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// handleNavigationPath lida com caminhos de navegação, como /Orders(1)/Items e /Orders(1)/Customer
+func (s *Server) handleNavigationPath(c fiber.Ctx) error {
+	segments, err := ParseResourcePath(strings.TrimPrefix(c.Path(), s.config.RoutePrefix))
+	if err != nil {
+		s.writeError(c, fiber.StatusBadRequest, "InvalidPath", err.Error())
+		return nil
+	}
+
+	ctx := s.requestContext(c)
+	target, err := s.resolveNavigationPath(ctx, segments)
+	if err != nil {
+		return s.writeNavigationError(c, err)
+	}
+
+	// A entidade alvo tem suas próprias regras de acesso
+	if err := s.checkEntityAuth(c, target.entityName); err != nil {
+		return err
+	}
+	// Vínculos por tabela de junção ou na entidade de origem não alteram o conjunto alvo
+	if !target.ref || (target.navigation != nil && target.navigation.IsCollection && !target.joinTable) {
+		if err := s.checkEntityReadOnly(target.entityName, c.Method()); err != nil {
+			return err
+		}
+	}
+	c.Locals(entitySetLocalKey, target.entityName)
+
+	if target.operation != nil {
+		return s.invokeBoundOperation(c, target, *target.operation)
+	}
+	if target.ref {
+		return s.handleReference(c, target)
+	}
+	if target.property != nil {
+		return s.handleProperty(c, target)
+	}
+	if target.count {
+		return s.handleNavigationCount(c, target)
+	}
+	if target.single {
+		if target.joinTable {
+			s.writeError(c, fiber.StatusNotImplemented, "NotImplemented", "addressing a member through a join table is only supported with $ref")
+			return nil
+		}
+		return s.handleNavigationEntity(c, target)
+	}
+
+	switch c.Method() {
+	case "GET":
+		options, err := s.parseQueryOptions(c)
+		if err != nil {
+			s.writeError(c, fiber.StatusBadRequest, "InvalidQuery", err.Error())
+			return nil
+		}
+		if target.joinTable {
+			entities, count, err := s.queryJoinTable(c, target, options, IsCountRequested(options.Count))
+			if err != nil {
+				return s.writeNavigationError(c, err)
+			}
+			response := &ODataResponse{Context: fmt.Sprintf("$metadata#%s", target.service.GetMetadata().Name), Value: entities}
+			if IsCountRequested(options.Count) {
+				response.Count = &count
+			}
+			return c.JSON(response)
+		}
+		if target.condition == nil {
+			return c.JSON(&ODataResponse{Context: fmt.Sprintf("$metadata#%s", target.service.GetMetadata().Name), Value: []any{}})
+		}
+		options.Filter = combineFilters(options.Filter, target.condition)
+		return s.writeCollection(c, target.service, options)
+	case "POST":
+		var entity map[string]interface{}
+		if err := c.Bind().Body(&entity); err != nil {
+			s.writeError(c, fiber.StatusBadRequest, "InvalidRequest", "Invalid JSON")
+			return nil
+		}
+		if target.joinTable {
+			s.writeError(c, fiber.StatusNotImplemented, "NotImplemented", "creating entities through a join table is not supported; create the entity and POST to $ref")
+			return nil
+		}
+		if target.parentValue == nil || target.keyProperty == "" {
+			s.writeError(c, fiber.StatusBadRequest, "InvalidPath", "the parent entity has no value for the relationship")
+			return nil
+		}
+
+		// A chave estrangeira vem da entidade de origem
+		entity[target.keyProperty] = target.parentValue
+		return s.createEntity(c, target.service, entity, nil)
+	default:
+		s.writeError(c, fiber.StatusMethodNotAllowed, "MethodNotAllowed", "Method not allowed")
+		return nil
+	}
+}
+
+// handleNavigationEntity lida com uma entidade única alcançada por navegação
+func (s *Server) handleNavigationEntity(c fiber.Ctx, target *navigationTarget) error {
+	entity, err := s.loadNavigationEntity(s.requestContext(c), target)
+	if err != nil {
+		// Navegação para um relacionamento simples vazio
+		var pathErr *resourcePathError
+		if c.Method() == "GET" && !target.keyed && errors.As(err, &pathErr) && pathErr.status == fiber.StatusNotFound {
+			return c.SendStatus(fiber.StatusNoContent)
+		}
+		return s.writeNavigationError(c, err)
+	}
+
+	keys := entityKeyValues(target.service.GetMetadata(), entity)
+
+	switch c.Method() {
+	case "GET":
+		return s.handleGetEntity(c, target.service, keys)
+	case "PUT", "PATCH":
+		return s.handleUpdateEntity(c, target.service, keys)
+	case "DELETE":
+		return s.handleDeleteEntity(c, target.service, keys)
+	default:
+		s.writeError(c, fiber.StatusMethodNotAllowed, "MethodNotAllowed", "Method not allowed")
+		return nil
+	}
+}
+
+// handleNavigationCount lida com /Orders(1)/Items/$count
+func (s *Server) handleNavigationCount(c fiber.Ctx, target *navigationTarget) error {
+	if c.Method() != "GET" {
+		s.writeError(c, fiber.StatusMethodNotAllowed, "MethodNotAllowed", "Method not allowed")
+		return nil
+	}
+
+	options, err := s.parseQueryOptions(c)
+	if err != nil {
+		s.writeError(c, fiber.StatusBadRequest, "InvalidQuery", err.Error())
+		return nil
+	}
+
+	var count int64
+	if target.joinTable {
+		if _, count, err = s.queryJoinTable(c, target, options, true); err != nil {
+			return s.writeNavigationError(c, err)
+		}
+	} else if target.condition != nil {
+		options.Filter = combineFilters(options.Filter, target.condition)
+		count, err = s.getEntityCount(s.requestContext(c), target.service, options)
+		if err != nil {
+			s.writeQueryError(c, "CountError", err)
+			return nil
+		}
+	}
+
+	c.Set("Content-Type", "text/plain")
+	return c.SendString(fmt.Sprintf("%d", count))
+}
+
+// writeNavigationError escreve o erro de resolução do caminho
+func (s *Server) writeNavigationError(c fiber.Ctx, err error) error {
+	var pathErr *resourcePathError
+	if errors.As(err, &pathErr) {
+		s.writeError(c, pathErr.status, pathErr.code, pathErr.message)
+		return nil
+	}
+	s.writeError(c, fiber.StatusInternalServerError, "NavigationError", err.Error())
+	return nil
+}
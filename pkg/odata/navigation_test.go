@@ -0,0 +1,232 @@
+package odata
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type NavigationTestCustomer struct {
+	TableName string                `table:"customers"`
+	ID        int64                 `json:"ID" column:"id" primaryKey:"idGenerator:none"`
+	Name      string                `json:"Name" column:"name"`
+	Orders    []NavigationTestOrder `json:"Orders" manyAssociation:"foreignKey:customer_id; references:id"`
+}
+
+type NavigationTestOrder struct {
+	TableName  string                  `table:"orders"`
+	ID         int64                   `json:"ID" column:"id" primaryKey:"idGenerator:none"`
+	CustomerID int64                   `json:"CustomerID" column:"customer_id"`
+	Customer   *NavigationTestCustomer `json:"Customer" association:"foreignKey:customer_id; references:id"`
+}
+
+// navigationTestProvider registra os filtros e os dados de inserção e atualização recebidos
+type navigationTestProvider struct {
+	MockDatabaseProvider
+	filters    []string
+	insert     map[string]interface{}
+	update     map[string]interface{}
+	updateKeys map[string]interface{}
+}
+
+func (p *navigationTestProvider) BuildSelectQuery(metadata EntityMetadata, options QueryOptions) (string, []interface{}, error) {
+	if options.Filter != nil && options.Filter.Tree != nil {
+		where, _, err := NewQueryBuilder("mysql").BuildWhereClause(context.Background(), options.Filter.Tree, metadata)
+		if err != nil {
+			return "", nil, err
+		}
+		p.filters = append(p.filters, where)
+	}
+	return "SELECT * FROM " + metadata.TableName, nil, nil
+}
+
+func (p *navigationTestProvider) BuildInsertQuery(metadata EntityMetadata, data map[string]interface{}) (string, []interface{}, error) {
+	p.insert = data
+	return "INSERT INTO " + metadata.TableName, nil, nil
+}
+
+func (p *navigationTestProvider) BuildUpdateQuery(metadata EntityMetadata, data map[string]interface{}, keys map[string]interface{}) (string, []interface{}, error) {
+	p.update = data
+	p.updateKeys = keys
+	return "UPDATE " + metadata.TableName, nil, nil
+}
+
+func newNavigationTestServer(t *testing.T) (*Server, *navigationTestProvider) {
+	server, provider, _ := newNavigationTestServerWithDB(t)
+	return server, provider
+}
+
+func newNavigationTestServerWithDB(t *testing.T) (*Server, *navigationTestProvider, *fakeDB) {
+	db := newNavigationTestDB(t)
+	provider := &navigationTestProvider{MockDatabaseProvider: MockDatabaseProvider{connection: db.DB}}
+	server := newTestServer(provider)
+	require.NoError(t, server.RegisterEntity("Customers", NavigationTestCustomer{}))
+	require.NoError(t, server.RegisterEntity("Orders", NavigationTestOrder{}))
+
+	return server, provider, db
+}
+
+// newNavigationTestDB cria um banco que devolve uma linha da tabela consultada
+func newNavigationTestDB(t *testing.T) *fakeDB {
+	db := newFakeDB(t, func(query string) *fakeRows {
+		switch {
+		case strings.Contains(query, "COUNT(*)"):
+			return newFakeRows([]string{"id", "name"}, []driver.Value{int64(2), ""})
+		case strings.Contains(query, expandParentKeyColumn):
+			return newFakeRows([]string{"id", "label", expandParentKeyColumn}, []driver.Value{int64(3), "sale", int64(1)})
+		case strings.Contains(query, "FROM documents"):
+			return newFakeRows([]string{"id", "title", "notes", "content"}, []driver.Value{int64(1), "Report", nil, []byte("\x89PNG")})
+		case strings.Contains(query, "FROM tags"):
+			return newFakeRows([]string{"id", "label"}, []driver.Value{int64(3), "sale"})
+		case strings.Contains(query, "FROM orders"):
+			return newFakeRows([]string{"id", "customer_id"}, []driver.Value{int64(10), int64(1)})
+		default:
+			return newFakeRows([]string{"id", "name"}, []driver.Value{int64(1), "Ann"})
+		}
+	})
+	db.lastInsertID = 11
+	return db
+}
+
+// newMultiTenantTestServer cria um servidor multi-tenant, sem provider padrão, com o tenant lido do X-Tenant-ID
+func newMultiTenantTestServer(t *testing.T, providers map[string]DatabaseProvider) *Server {
+	server := newTestServer(nil)
+
+	tenants := make(map[string]*TenantConfig)
+	for tenantID := range providers {
+		tenants[tenantID] = &TenantConfig{TenantID: tenantID}
+	}
+	server.multiTenantConfig = &MultiTenantConfig{Enabled: true, IdentificationMode: "header", DefaultTenant: "default", Tenants: tenants}
+	server.multiTenantPool = NewMultiTenantProviderPool(server.multiTenantConfig, log.New(io.Discard, "", 0))
+	server.multiTenantPool.providers = providers
+	server.multiTenantPool.defaultProvider = providers["default"]
+	server.router.Use(server.TenantMiddleware())
+	return server
+}
+
+func TestParseResourcePath(t *testing.T) {
+	segments, err := ParseResourcePath("/Orders(1)/Items('a/b')/$count")
+	require.NoError(t, err)
+	assert.Equal(t, []ResourceSegment{
+		{Name: "Orders", Key: "1", HasKey: true},
+		{Name: "Items", Key: "'a/b'", HasKey: true},
+		{Name: "$count"},
+	}, segments)
+
+	assert.True(t, isNavigationPath("Orders(1)/Customer"))
+	assert.False(t, isNavigationPath("Orders(1)"))
+
+	_, err = ParseResourcePath("Orders(1/Items")
+	assert.Error(t, err)
+	_, err = ParseResourcePath("Orders(1)//Items")
+	assert.Error(t, err)
+}
+
+func TestServer_NavigationPaths(t *testing.T) {
+	t.Run("collection", func(t *testing.T) {
+		server, provider := newNavigationTestServer(t)
+
+		resp, err := server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Customers(1)/Orders?$filter=ID%20gt%205", nil))
+		require.NoError(t, err)
+		require.Equal(t, 200, resp.StatusCode)
+
+		var body struct {
+			Value []map[string]interface{} `json:"value"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		require.Len(t, body.Value, 1)
+		assert.Equal(t, float64(10), body.Value[0]["ID"])
+
+		// O cliente é carregado pela chave e os pedidos pela chave estrangeira, junto com o $filter
+		require.Len(t, provider.filters, 2)
+		assert.Equal(t, "(id = :param1)", provider.filters[0])
+		assert.Equal(t, "((id > :param1) AND (customer_id = :param2))", provider.filters[1])
+	})
+
+	t.Run("single valued", func(t *testing.T) {
+		server, provider := newNavigationTestServer(t)
+
+		resp, err := server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Orders(10)/Customer", nil))
+		require.NoError(t, err)
+		require.Equal(t, 200, resp.StatusCode)
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, "Ann", body["Name"])
+		assert.Contains(t, provider.filters, "(id = :param1)")
+	})
+
+	t.Run("keyed collection member", func(t *testing.T) {
+		server, provider := newNavigationTestServer(t)
+
+		resp, err := server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Customers(1)/Orders(10)", nil))
+		require.NoError(t, err)
+		require.Equal(t, 200, resp.StatusCode)
+		assert.Contains(t, provider.filters, "((customer_id = :param1) AND (id = :param2))")
+	})
+
+	t.Run("count", func(t *testing.T) {
+		server, _ := newNavigationTestServer(t)
+
+		resp, err := server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Customers(1)/Orders/$count", nil))
+		require.NoError(t, err)
+		require.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, "text/plain", resp.Header.Get("Content-Type"))
+	})
+
+	t.Run("create sets foreign key", func(t *testing.T) {
+		server, provider := newNavigationTestServer(t)
+
+		req := httptest.NewRequest("POST", "/odata/Customers(1)/Orders", strings.NewReader(`{"ID": 11}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.GetRouter().Test(req)
+		require.NoError(t, err)
+		require.Equal(t, 201, resp.StatusCode)
+		require.NotNil(t, provider.insert)
+		assert.EqualValues(t, 1, provider.insert["CustomerID"])
+	})
+
+	t.Run("invalid paths", func(t *testing.T) {
+		server, _ := newNavigationTestServer(t)
+
+		resp, err := server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Customers(1)/Missing", nil))
+		require.NoError(t, err)
+		assert.Equal(t, 404, resp.StatusCode)
+
+		resp, err = server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Orders(10)/Customer(1)", nil))
+		require.NoError(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+
+		resp, err = server.GetRouter().Test(httptest.NewRequest("DELETE", "/odata/Customers(1)/Orders", nil))
+		require.NoError(t, err)
+		assert.Equal(t, 405, resp.StatusCode)
+	})
+}
+
+func TestServer_NavigationPathsMultiTenant(t *testing.T) {
+	db := newNavigationTestDB(t)
+	acme := &navigationTestProvider{MockDatabaseProvider: MockDatabaseProvider{connection: db.DB}}
+	server := newMultiTenantTestServer(t, map[string]DatabaseProvider{
+		"default": &navigationTestProvider{MockDatabaseProvider: MockDatabaseProvider{connection: db.DB}},
+		"acme":    acme,
+	})
+	require.NoError(t, server.RegisterEntity("Customers", NavigationTestCustomer{}))
+	require.NoError(t, server.RegisterEntity("Orders", NavigationTestOrder{}))
+
+	req := httptest.NewRequest("GET", "/odata/Customers(1)/Orders", nil)
+	req.Header.Set("X-Tenant-ID", "acme")
+	resp, err := server.GetRouter().Test(req)
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+
+	// As consultas usam o provider do tenant da requisição
+	assert.Equal(t, []string{"(id = :param1)", "(customer_id = :param1)"}, acme.filters)
+}
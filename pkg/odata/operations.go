@@ -0,0 +1,565 @@
+package odata
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// OperationKind distingue ações (POST, podem alterar dados) de funções (GET, sem efeitos colaterais)
+type OperationKind int
+
+const (
+	OperationAction OperationKind = iota
+	OperationFunction
+)
+
+// OperationBinding define o recurso ao qual a operação está vinculada
+type OperationBinding int
+
+const (
+	BindingNone      OperationBinding = iota // Raiz do serviço: /Default.TopSellers(count=5)
+	BindingEntitySet                         // Coleção: /Orders/Default.Recalculate
+	BindingEntity                            // Entidade: /Orders(1)/Default.Approve
+)
+
+// OperationParameter descreve um parâmetro tipado de uma operação
+type OperationParameter struct {
+	Name       string
+	Type       string // Tipo interno (string, int64, float64, bool, time.Time, []byte...)
+	Nullable   bool
+	Collection bool
+}
+
+// OperationContext é entregue ao handler da operação
+type OperationContext struct {
+	Context    context.Context        // Contexto da requisição (tenant, transação do $batch)
+	Fiber      fiber.Ctx              // Requisição HTTP original
+	Provider   DatabaseProvider       // Provider do tenant da requisição
+	EntitySet  string                 // Entity set vinculado (vazio em operações não vinculadas)
+	Service    EntityService          // Serviço do entity set vinculado
+	Entity     *OrderedEntity         // Entidade vinculada (BindingEntity)
+	Keys       map[string]interface{} // Chaves da entidade vinculada
+	Filter     *GoDataFilterQuery     // Restrição da coleção vinculada quando alcançada por navegação
+	Parameters map[string]interface{} // Parâmetros convertidos para os tipos declarados
+}
+
+// OperationHandler implementa a lógica de negócio de uma operação
+type OperationHandler func(ctx *OperationContext) (interface{}, error)
+
+// Operation define uma ação ou função OData
+type Operation struct {
+	Name              string
+	Binding           OperationBinding
+	EntitySet         string // Entity set vinculado (obrigatório quando Binding != BindingNone)
+	Parameters        []OperationParameter
+	ReturnType        string // Tipo interno ou nome de entity set; vazio para ações sem retorno
+	ReturnsCollection bool
+	Auth              *EntityAuthConfig // Exigências próprias, somadas às do entity set vinculado
+	Handler           OperationHandler
+
+	kind OperationKind
+}
+
+// QualifiedName retorna o nome da operação qualificado pelo namespace do $metadata
+func (o *Operation) QualifiedName() string {
+	return csdlNamespace + "." + o.Name
+}
+
+// RegisterAction registra uma ação, invocada com POST e parâmetros no corpo JSON
+func (s *Server) RegisterAction(action Operation) error {
+	action.kind = OperationAction
+	return s.registerOperation(&action)
+}
+
+// RegisterFunction registra uma função, invocada com GET e parâmetros na URL
+func (s *Server) RegisterFunction(function Operation) error {
+	function.kind = OperationFunction
+	return s.registerOperation(&function)
+}
+
+// registerOperation valida e registra a operação
+func (s *Server) registerOperation(op *Operation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if op.Name == "" || strings.ContainsAny(op.Name, "./()") {
+		return fmt.Errorf("invalid operation name '%s'", op.Name)
+	}
+	if op.Handler == nil {
+		return fmt.Errorf("operation %s requires a handler", op.Name)
+	}
+	if op.kind == OperationFunction && op.ReturnType == "" {
+		return fmt.Errorf("function %s requires a return type", op.Name)
+	}
+	if op.Binding != BindingNone {
+		if _, exists := s.entities[op.EntitySet]; !exists {
+			return fmt.Errorf("operation %s is bound to unregistered entity set '%s'", op.Name, op.EntitySet)
+		}
+	} else {
+		op.EntitySet = ""
+	}
+
+	seen := make(map[string]bool)
+	for _, param := range op.Parameters {
+		if param.Name == "" || seen[param.Name] {
+			return fmt.Errorf("operation %s has an invalid or duplicated parameter '%s'", op.Name, param.Name)
+		}
+		seen[param.Name] = true
+	}
+
+	for _, existing := range s.operations {
+		if existing.Name == op.Name && existing.kind != op.kind {
+			return fmt.Errorf("operation %s is already registered as a different kind", op.Name)
+		}
+		if existing.Name == op.Name && existing.Binding == op.Binding && existing.EntitySet == op.EntitySet {
+			return fmt.Errorf("operation %s is already registered for this binding", op.Name)
+		}
+	}
+
+	s.operations = append(s.operations, op)
+	s.logger.Printf("Operação '%s' registrada", op.QualifiedName())
+	return nil
+}
+
+// findOperation localiza a operação pelo nome qualificado e pelo vínculo
+func (s *Server) findOperation(qualifiedName string, binding OperationBinding, entitySet string) (*Operation, bool) {
+	name, ok := strings.CutPrefix(qualifiedName, csdlNamespace+".")
+	if !ok {
+		return nil, false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, op := range s.operations {
+		if op.Name == name && op.Binding == binding && op.EntitySet == entitySet {
+			return op, true
+		}
+	}
+	return nil, false
+}
+
+// isOperationSegment verifica se o segmento do caminho é o nome qualificado de uma operação
+func isOperationSegment(name string) bool {
+	return strings.HasPrefix(name, csdlNamespace+".")
+}
+
+// handleUnboundOperation lida com operações não vinculadas, como /Default.TopSellers(count=5)
+func (s *Server) handleUnboundOperation(c fiber.Ctx) error {
+	segments, err := ParseResourcePath(strings.TrimPrefix(c.Path(), s.config.RoutePrefix))
+	if err != nil || len(segments) != 1 {
+		s.writeError(c, fiber.StatusNotFound, "OperationNotFound", "Operation not found")
+		return nil
+	}
+
+	op, exists := s.findOperation(segments[0].Name, BindingNone, "")
+	if !exists {
+		s.writeError(c, fiber.StatusNotFound, "OperationNotFound", fmt.Sprintf("Operation '%s' not found", segments[0].Name))
+		return nil
+	}
+
+	return s.invokeOperation(c, op, nil, segments[0])
+}
+
+// handleCollectionOperation lida com operações vinculadas a um entity set, como /Orders/Default.Recalculate
+func (s *Server) handleCollectionOperation(c fiber.Ctx) error {
+	segments, err := ParseResourcePath(strings.TrimPrefix(c.Path(), s.config.RoutePrefix))
+	if err != nil || len(segments) != 2 || segments[0].HasKey || !isOperationSegment(segments[1].Name) {
+		s.writeError(c, fiber.StatusNotFound, "NotFound", "Resource not found")
+		return nil
+	}
+
+	service, exists := s.entityService(segments[0].Name)
+	if !exists {
+		s.writeError(c, fiber.StatusNotFound, "EntityNotFound", fmt.Sprintf("Entity '%s' not found", segments[0].Name))
+		return nil
+	}
+
+	target := &navigationTarget{entityName: segments[0].Name, service: service}
+	return s.invokeBoundOperation(c, target, segments[1])
+}
+
+// invokeBoundOperation localiza a operação vinculada ao alvo e a executa
+func (s *Server) invokeBoundOperation(c fiber.Ctx, target *navigationTarget, segment ResourceSegment) error {
+	binding := BindingEntitySet
+	if target.single {
+		binding = BindingEntity
+	}
+
+	op, exists := s.findOperation(segment.Name, binding, target.entityName)
+	if !exists {
+		s.writeError(c, fiber.StatusNotFound, "OperationNotFound", fmt.Sprintf("Operation '%s' not found for '%s'", segment.Name, target.entityName))
+		return nil
+	}
+
+	return s.invokeOperation(c, op, target, segment)
+}
+
+// invokeOperation valida o acesso e os parâmetros, executa o handler e escreve o resultado
+func (s *Server) invokeOperation(c fiber.Ctx, op *Operation, target *navigationTarget, segment ResourceSegment) error {
+	switch {
+	case op.kind == OperationAction && c.Method() != "POST":
+		s.writeError(c, fiber.StatusMethodNotAllowed, "MethodNotAllowed", "Actions must be invoked with POST")
+		return nil
+	case op.kind == OperationFunction && c.Method() != "GET":
+		s.writeError(c, fiber.StatusMethodNotAllowed, "MethodNotAllowed", "Functions must be invoked with GET")
+		return nil
+	case op.kind == OperationAction && segment.HasKey:
+		s.writeError(c, fiber.StatusBadRequest, "InvalidRequest", "Action parameters must be sent in the request body")
+		return nil
+	}
+
+	if err := s.checkOperationAuth(c, op); err != nil {
+		return err
+	}
+
+	var parameters map[string]interface{}
+	var err error
+	if op.kind == OperationAction {
+		parameters, err = parseActionParameters(c.Body(), op)
+	} else {
+		parameters, err = parseFunctionParameters(c, segment, op)
+	}
+	if err != nil {
+		s.writeError(c, fiber.StatusBadRequest, "InvalidParameters", err.Error())
+		return nil
+	}
+
+	ctx := &OperationContext{
+		Context:    s.requestContext(c),
+		Fiber:      c,
+		Provider:   s.getCurrentProvider(c),
+		EntitySet:  op.EntitySet,
+		Parameters: parameters,
+	}
+
+	if target != nil {
+		ctx.Service = target.service
+		if target.single {
+			entity, err := s.loadNavigationEntity(ctx.Context, target)
+			if err != nil {
+				return s.writeNavigationError(c, err)
+			}
+			ctx.Entity = entity
+			ctx.Keys = entityKeyValues(target.service.GetMetadata(), entity)
+		} else if target.condition != nil {
+			ctx.Filter = &GoDataFilterQuery{Tree: target.condition}
+		}
+	}
+
+	result, err := op.Handler(ctx)
+	if err != nil {
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			s.writeError(c, fiberErr.Code, "OperationError", fiberErr.Message)
+		} else {
+			s.writeError(c, fiber.StatusInternalServerError, "OperationError", err.Error())
+		}
+		return nil
+	}
+
+	return s.writeOperationResult(c, op, result)
+}
+
+// checkOperationAuth aplica a autenticação da operação, do entity set vinculado ou a global
+func (s *Server) checkOperationAuth(c fiber.Ctx, op *Operation) error {
+	if op.Auth != nil && s.config.EnableJWT {
+		if err := s.checkAuthConfig(c, op.QualifiedName(), *op.Auth, true); err != nil {
+			return err
+		}
+	}
+
+	name := op.EntitySet
+	if op.Binding == BindingNone {
+		// Operações não vinculadas podem ser configuradas com SetEntityAuth("Default.Nome", ...)
+		name = op.QualifiedName()
+	}
+	if err := s.checkEntityAuth(c, name); err != nil {
+		return err
+	}
+
+	// Ações podem alterar dados e respeitam o modo apenas leitura
+	if op.kind == OperationAction && op.Binding != BindingNone {
+		return s.checkEntityReadOnly(op.EntitySet, "POST")
+	}
+	return nil
+}
+
+// writeOperationResult serializa o retorno da operação
+func (s *Server) writeOperationResult(c fiber.Ctx, op *Operation, result interface{}) error {
+	if op.ReturnType == "" || result == nil {
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+
+	entitySet, isEntity := s.operationEntitySet(op.ReturnType)
+	switch {
+	case isEntity && op.ReturnsCollection:
+		return c.JSON(fiber.Map{"@odata.context": "$metadata#" + entitySet, "value": result})
+	case isEntity:
+		return c.JSON(result)
+	case op.ReturnsCollection:
+		return c.JSON(fiber.Map{"@odata.context": "$metadata#Collection(" + s.mapODataType(op.ReturnType) + ")", "value": result})
+	default:
+		return c.JSON(fiber.Map{"@odata.context": "$metadata#" + s.mapODataType(op.ReturnType), "value": result})
+	}
+}
+
+// operationEntitySet resolve um tipo de operação que referencia um entity set ou tipo de entidade
+func (s *Server) operationEntitySet(typeName string) (string, bool) {
+	if _, isPrimitive := operationPrimitiveTypes[typeName]; isPrimitive {
+		return "", false
+	}
+	entityName, _, exists := s.findEntitySet(typeName)
+	return entityName, exists
+}
+
+// operationPrimitiveTypes são os tipos internos aceitos em parâmetros e retornos
+var operationPrimitiveTypes = map[string]bool{
+	"string": true, "int": true, "int32": true, "int64": true, "float32": true, "float64": true,
+	"bool": true, "time.Time": true, "[]byte": true, "object": true,
+}
+
+// parseActionParameters lê os parâmetros do corpo JSON de uma ação
+func parseActionParameters(body []byte, op *Operation) (map[string]interface{}, error) {
+	raw := make(map[string]interface{})
+	if len(strings.TrimSpace(string(body))) > 0 {
+		if err := json.Unmarshal(body, &raw); err != nil {
+			return nil, fmt.Errorf("invalid JSON body")
+		}
+	}
+
+	parameters := make(map[string]interface{}, len(op.Parameters))
+	for _, param := range op.Parameters {
+		value, exists := raw[param.Name]
+		delete(raw, param.Name)
+		if !exists || value == nil {
+			if !param.Nullable {
+				return nil, fmt.Errorf("parameter '%s' is required", param.Name)
+			}
+			parameters[param.Name] = nil
+			continue
+		}
+
+		converted, err := convertOperationValue(value, param)
+		if err != nil {
+			return nil, err
+		}
+		parameters[param.Name] = converted
+	}
+
+	for name := range raw {
+		return nil, fmt.Errorf("unknown parameter '%s'", name)
+	}
+	return parameters, nil
+}
+
+// parseFunctionParameters lê os parâmetros da URL de uma função, incluindo aliases (@p)
+func parseFunctionParameters(c fiber.Ctx, segment ResourceSegment, op *Operation) (map[string]interface{}, error) {
+	literals, err := splitOperationArguments(segment.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	parameters := make(map[string]interface{}, len(op.Parameters))
+	for _, param := range op.Parameters {
+		literal, exists := literals[param.Name]
+		delete(literals, param.Name)
+
+		if exists && strings.HasPrefix(literal, "@") {
+			literal = c.Query(literal)
+			exists = literal != ""
+		}
+		if !exists || literal == "null" {
+			if !param.Nullable {
+				return nil, fmt.Errorf("parameter '%s' is required", param.Name)
+			}
+			parameters[param.Name] = nil
+			continue
+		}
+
+		value, err := parseOperationLiteral(literal, param)
+		if err != nil {
+			return nil, err
+		}
+		parameters[param.Name] = value
+	}
+
+	for name := range literals {
+		return nil, fmt.Errorf("unknown parameter '%s'", name)
+	}
+	return parameters, nil
+}
+
+// splitOperationArguments separa "a=1,b='x,y'" em pares nome/literal
+func splitOperationArguments(args string) (map[string]string, error) {
+	result := make(map[string]string)
+	if strings.TrimSpace(args) == "" {
+		return result, nil
+	}
+
+	var parts []string
+	var current strings.Builder
+	inQuote := false
+	depth := 0
+	for _, char := range args {
+		switch {
+		case char == '\'':
+			inQuote = !inQuote
+		case !inQuote && (char == '[' || char == '('):
+			depth++
+		case !inQuote && (char == ']' || char == ')'):
+			depth--
+		case !inQuote && depth == 0 && char == ',':
+			parts = append(parts, current.String())
+			current.Reset()
+			continue
+		}
+		current.WriteRune(char)
+	}
+	parts = append(parts, current.String())
+
+	for _, part := range parts {
+		name, value, found := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid function parameter '%s'", part)
+		}
+		result[name] = strings.TrimSpace(value)
+	}
+	return result, nil
+}
+
+// parseOperationLiteral converte um literal OData da URL para o tipo do parâmetro
+func parseOperationLiteral(literal string, param OperationParameter) (interface{}, error) {
+	// Coleções são informadas em JSON: ids=[1,2,3]
+	if param.Collection {
+		var values []interface{}
+		if err := json.Unmarshal([]byte(literal), &values); err != nil {
+			return nil, fmt.Errorf("parameter '%s' expects a JSON array", param.Name)
+		}
+		return convertOperationValue(values, param)
+	}
+
+	if strings.HasPrefix(literal, "'") && strings.HasSuffix(literal, "'") && len(literal) >= 2 {
+		return convertOperationValue(strings.ReplaceAll(literal[1:len(literal)-1], "''", "'"), param)
+	}
+
+	switch param.Type {
+	case "string":
+		return nil, fmt.Errorf("parameter '%s' expects a quoted string", param.Name)
+	case "int", "int32", "int64":
+		value, err := strconv.ParseInt(literal, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parameter '%s' expects an integer", param.Name)
+		}
+		return value, nil
+	case "float32", "float64":
+		value, err := strconv.ParseFloat(literal, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parameter '%s' expects a number", param.Name)
+		}
+		return value, nil
+	case "bool":
+		value, err := strconv.ParseBool(literal)
+		if err != nil {
+			return nil, fmt.Errorf("parameter '%s' expects a boolean", param.Name)
+		}
+		return value, nil
+	default:
+		return convertOperationValue(literal, param)
+	}
+}
+
+// convertOperationValue converte um valor JSON para o tipo declarado do parâmetro
+func convertOperationValue(value interface{}, param OperationParameter) (interface{}, error) {
+	if param.Collection {
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("parameter '%s' expects a collection", param.Name)
+		}
+		single := param
+		single.Collection = false
+		converted := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			v, err := convertOperationValue(item, single)
+			if err != nil {
+				return nil, err
+			}
+			converted = append(converted, v)
+		}
+		return converted, nil
+	}
+
+	switch param.Type {
+	case "string":
+		if str, ok := value.(string); ok {
+			return str, nil
+		}
+	case "int", "int32", "int64":
+		switch v := value.(type) {
+		case float64:
+			if v == float64(int64(v)) {
+				return int64(v), nil
+			}
+		case int64:
+			return v, nil
+		case string:
+			if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return i, nil
+			}
+		}
+	case "float32", "float64":
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int64:
+			return float64(v), nil
+		case string:
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return f, nil
+			}
+		}
+	case "bool":
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+	case "time.Time":
+		if str, ok := value.(string); ok {
+			if t, err := time.Parse(time.RFC3339Nano, str); err == nil {
+				return t, nil
+			}
+			if t, err := time.Parse("2006-01-02", str); err == nil {
+				return t, nil
+			}
+		}
+	case "[]byte":
+		if str, ok := value.(string); ok {
+			if b, err := base64.StdEncoding.DecodeString(str); err == nil {
+				return b, nil
+			}
+		}
+	default:
+		return value, nil
+	}
+
+	return nil, fmt.Errorf("parameter '%s' expects a value of type %s", param.Name, param.Type)
+}
+
+// collectOperations retorna as operações registradas em ordem determinística
+func (s *Server) collectOperations() []*Operation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	operations := append([]*Operation(nil), s.operations...)
+	sort.SliceStable(operations, func(i, j int) bool { return operations[i].Name < operations[j].Name })
+	return operations
+}
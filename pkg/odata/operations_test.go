@@ -0,0 +1,206 @@
+package odata
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newOperationTestServer(t *testing.T) (*Server, *[]*OperationContext) {
+	server, _ := newNavigationTestServer(t)
+	var calls []*OperationContext
+
+	require.NoError(t, server.RegisterAction(Operation{
+		Name:       "Approve",
+		Binding:    BindingEntity,
+		EntitySet:  "Orders",
+		Parameters: []OperationParameter{{Name: "Note", Type: "string", Nullable: true}},
+		Handler: func(ctx *OperationContext) (interface{}, error) {
+			calls = append(calls, ctx)
+			return nil, nil
+		},
+	}))
+	require.NoError(t, server.RegisterFunction(Operation{
+		Name:       "Total",
+		Binding:    BindingEntitySet,
+		EntitySet:  "Orders",
+		ReturnType: "float64",
+		Handler: func(ctx *OperationContext) (interface{}, error) {
+			calls = append(calls, ctx)
+			return 42.5, nil
+		},
+	}))
+	require.NoError(t, server.RegisterFunction(Operation{
+		Name:              "TopSellers",
+		Parameters:        []OperationParameter{{Name: "count", Type: "int64"}},
+		ReturnType:        "Customers",
+		ReturnsCollection: true,
+		Handler: func(ctx *OperationContext) (interface{}, error) {
+			calls = append(calls, ctx)
+			if ctx.Parameters["count"].(int64) <= 0 {
+				return nil, fiber.NewError(fiber.StatusBadRequest, "count must be positive")
+			}
+			return []map[string]interface{}{{"ID": 1, "Name": "Ann"}}, nil
+		},
+	}))
+
+	return server, &calls
+}
+
+func TestServer_RegisterOperation(t *testing.T) {
+	server, _ := newOperationTestServer(t)
+	handler := func(ctx *OperationContext) (interface{}, error) { return nil, nil }
+
+	assert.Error(t, server.RegisterAction(Operation{Name: "Default.Bad", Handler: handler}))
+	assert.Error(t, server.RegisterAction(Operation{Name: "NoHandler"}))
+	assert.Error(t, server.RegisterFunction(Operation{Name: "NoReturn", Handler: handler}))
+	assert.Error(t, server.RegisterAction(Operation{Name: "Ship", Binding: BindingEntity, EntitySet: "Missing", Handler: handler}))
+	assert.Error(t, server.RegisterAction(Operation{Name: "Approve", Binding: BindingEntity, EntitySet: "Orders", Handler: handler}))
+	assert.Error(t, server.RegisterAction(Operation{Name: "Total", Handler: handler}))
+
+	// Sobrecargas com vínculos diferentes são permitidas
+	assert.NoError(t, server.RegisterAction(Operation{Name: "Approve", Binding: BindingEntitySet, EntitySet: "Orders", Handler: handler}))
+}
+
+func TestServer_InvokeOperations(t *testing.T) {
+	t.Run("entity bound action", func(t *testing.T) {
+		server, calls := newOperationTestServer(t)
+
+		req := httptest.NewRequest("POST", "/odata/Orders(10)/Default.Approve", strings.NewReader(`{"Note": "ok"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.GetRouter().Test(req)
+		require.NoError(t, err)
+		require.Equal(t, 204, resp.StatusCode)
+
+		require.Len(t, *calls, 1)
+		ctx := (*calls)[0]
+		assert.Equal(t, "Orders", ctx.EntitySet)
+		assert.Equal(t, "ok", ctx.Parameters["Note"])
+		assert.EqualValues(t, map[string]interface{}{"ID": int64(10)}, ctx.Keys)
+		assert.NotNil(t, ctx.Entity)
+		assert.NotNil(t, ctx.Provider)
+
+		// Ações exigem POST e rejeitam parâmetros desconhecidos
+		resp, err = server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Orders(10)/Default.Approve", nil))
+		require.NoError(t, err)
+		assert.Equal(t, 405, resp.StatusCode)
+
+		req = httptest.NewRequest("POST", "/odata/Orders(10)/Default.Approve", strings.NewReader(`{"Other": 1}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err = server.GetRouter().Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+	})
+
+	t.Run("collection bound function", func(t *testing.T) {
+		server, calls := newOperationTestServer(t)
+
+		resp, err := server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Orders/Default.Total()", nil))
+		require.NoError(t, err)
+		require.Equal(t, 200, resp.StatusCode)
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, "$metadata#Edm.Double", body["@odata.context"])
+		assert.Equal(t, 42.5, body["value"])
+		assert.Nil(t, (*calls)[0].Filter)
+
+		// Alcançada por navegação, a coleção vinculada chega restrita ao relacionamento
+		resp, err = server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Customers(1)/Orders/Default.Total()", nil))
+		require.NoError(t, err)
+		require.Equal(t, 200, resp.StatusCode)
+		require.Len(t, *calls, 2)
+		assert.NotNil(t, (*calls)[1].Filter)
+	})
+
+	t.Run("unbound function with alias", func(t *testing.T) {
+		server, calls := newOperationTestServer(t)
+
+		resp, err := server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Default.TopSellers(count=5)", nil))
+		require.NoError(t, err)
+		require.Equal(t, 200, resp.StatusCode)
+
+		var body struct {
+			Context string                   `json:"@odata.context"`
+			Value   []map[string]interface{} `json:"value"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, "$metadata#Customers", body.Context)
+		assert.Len(t, body.Value, 1)
+		assert.Equal(t, int64(5), (*calls)[0].Parameters["count"])
+
+		resp, err = server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Default.TopSellers(count=@c)?@c=3", nil))
+		require.NoError(t, err)
+		require.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, int64(3), (*calls)[1].Parameters["count"])
+
+		// Erros do handler preservam o status informado
+		resp, err = server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Default.TopSellers(count=0)", nil))
+		require.NoError(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+
+		resp, err = server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Default.TopSellers()", nil))
+		require.NoError(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+
+		resp, err = server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Default.Missing()", nil))
+		require.NoError(t, err)
+		assert.Equal(t, 404, resp.StatusCode)
+	})
+
+	t.Run("read only entity set blocks actions", func(t *testing.T) {
+		server, calls := newOperationTestServer(t)
+		server.SetEntityAuth("Orders", EntityAuthConfig{ReadOnly: true})
+
+		resp, err := server.GetRouter().Test(httptest.NewRequest("POST", "/odata/Orders(10)/Default.Approve", nil))
+		require.NoError(t, err)
+		assert.Equal(t, 403, resp.StatusCode)
+
+		resp, err = server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Orders/Default.Total()", nil))
+		require.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Len(t, *calls, 1)
+	})
+}
+
+func TestServer_OperationsMetadata(t *testing.T) {
+	server, _ := newOperationTestServer(t)
+
+	resp, err := server.GetRouter().Test(httptest.NewRequest("GET", "/odata/$metadata", nil))
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	raw, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	xmlBody := string(raw)
+
+	assert.Contains(t, xmlBody, `<Action Name="Approve" IsBound="true">`)
+	assert.Contains(t, xmlBody, `<Parameter Name="bindingParameter" Type="Default.NavigationTestOrder" Nullable="false"></Parameter>`)
+	assert.Contains(t, xmlBody, `<Parameter Name="bindingParameter" Type="Collection(Default.NavigationTestOrder)" Nullable="false"></Parameter>`)
+	assert.Contains(t, xmlBody, `<ReturnType Type="Collection(Default.NavigationTestCustomer)"></ReturnType>`)
+	assert.Contains(t, xmlBody, `<FunctionImport Name="TopSellers" Function="Default.TopSellers" EntitySet="Customers"></FunctionImport>`)
+
+	resp, err = server.GetRouter().Test(httptest.NewRequest("GET", "/odata/$metadata?$format=json", nil))
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+
+	var document map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&document))
+	schema := document["Default"].(map[string]interface{})
+
+	approve := schema["Approve"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "Action", approve["$Kind"])
+	assert.Equal(t, true, approve["$IsBound"])
+
+	total := schema["Total"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "Function", total["$Kind"])
+	assert.Equal(t, map[string]interface{}{"$Type": "Edm.Double"}, total["$ReturnType"])
+
+	container := schema["Container"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"$Function": "Default.TopSellers", "$EntitySet": "Customers"}, container["TopSellers"])
+}
@@ -0,0 +1,566 @@
+package odata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Situações das mensagens do outbox
+const (
+	OutboxStatusPending   = "pending"   // Aguardando publicação
+	OutboxStatusPublished = "published" // Publicada em todos os sinks
+	OutboxStatusDead      = "dead"      // Esgotou as tentativas
+)
+
+// OutboxMessage é um evento "-ed" gravado no outbox na mesma transação da alteração da entidade
+type OutboxMessage struct {
+	ID            string                 `json:"id"`
+	EntityName    string                 `json:"entityName"`
+	EventType     EventType              `json:"eventType"`
+	Keys          map[string]interface{} `json:"keys,omitempty"`
+	Payload       json.RawMessage        `json:"payload,omitempty"` // Entidade gravada (ou removida) em JSON
+	TenantID      string                 `json:"tenantId,omitempty"`
+	Attempts      int                    `json:"attempts"`
+	LastError     string                 `json:"lastError,omitempty"`
+	CreatedAt     time.Time              `json:"createdAt"`
+	NextAttemptAt time.Time              `json:"nextAttemptAt"`
+}
+
+// OutboxStore persiste as mensagens do outbox. Append deve gravar na transação presente no contexto
+type OutboxStore interface {
+	Append(ctx context.Context, message *OutboxMessage) error
+	Pending(ctx context.Context, limit int) ([]*OutboxMessage, error)
+	MarkPublished(ctx context.Context, message *OutboxMessage) error
+	// MarkFailed grava Attempts, LastError e NextAttemptAt da mensagem; dead encerra as tentativas
+	MarkFailed(ctx context.Context, message *OutboxMessage, dead bool) error
+}
+
+// TenantOutboxStore é um OutboxStore que pode ser aberto no banco de cada tenant. No modo multi-tenant o
+// servidor grava cada mensagem no store do provider do tenant, na transação da alteração, e o relay lê todos
+type TenantOutboxStore interface {
+	OutboxStore
+	ForProvider(provider DatabaseProvider) OutboxStore
+}
+
+// OutboxSink é o destino para onde o relay publica as mensagens (fila, broker, webhook, índice de busca)
+type OutboxSink interface {
+	Publish(ctx context.Context, message *OutboxMessage) error
+}
+
+// OutboxSinkFunc é uma função que pode ser usada como sink
+type OutboxSinkFunc func(ctx context.Context, message *OutboxMessage) error
+
+// Implementa a interface OutboxSink
+func (f OutboxSinkFunc) Publish(ctx context.Context, message *OutboxMessage) error {
+	return f(ctx, message)
+}
+
+// DefaultOutboxTable é a tabela usada pelo SQLOutboxStore quando nenhuma é informada
+const DefaultOutboxTable = "godata_outbox"
+
+// SQLOutboxStore grava o outbox em uma tabela do banco usando as queries do provider
+type SQLOutboxStore struct {
+	provider DatabaseProvider
+	service  *BaseEntityService
+}
+
+// NewSQLOutboxStore cria o store do outbox na tabela informada (padrão godata_outbox)
+func NewSQLOutboxStore(provider DatabaseProvider, tableName string) *SQLOutboxStore {
+	if tableName == "" {
+		tableName = DefaultOutboxTable
+	}
+	return &SQLOutboxStore{
+		provider: provider,
+		service:  NewBaseEntityService(provider, outboxMetadata(tableName), nil),
+	}
+}
+
+// ForProvider abre o store na mesma tabela no banco do provider informado
+func (st *SQLOutboxStore) ForProvider(provider DatabaseProvider) OutboxStore {
+	return &SQLOutboxStore{provider: provider, service: NewBaseEntityService(provider, st.service.metadata, nil)}
+}
+
+// outboxMetadata descreve a tabela do outbox
+func outboxMetadata(tableName string) EntityMetadata {
+	return EntityMetadata{
+		Name:      "OutboxMessage",
+		TableName: tableName,
+		Keys:      []string{"ID"},
+		Properties: []PropertyMetadata{
+			{Name: "ID", Type: "string", ColumnName: "id", IsKey: true, MaxLength: 36},
+			{Name: "EntityName", Type: "string", ColumnName: "entity_name", MaxLength: 128},
+			{Name: "EventType", Type: "string", ColumnName: "event_type", MaxLength: 64},
+			{Name: "EntityKeys", Type: "string", ColumnName: "entity_keys", IsNullable: true},
+			{Name: "Payload", Type: "string", ColumnName: "payload", IsNullable: true},
+			{Name: "TenantID", Type: "string", ColumnName: "tenant_id", IsNullable: true, MaxLength: 128},
+			{Name: "Status", Type: "string", ColumnName: "status", MaxLength: 16},
+			{Name: "Attempts", Type: "int64", ColumnName: "attempts"},
+			{Name: "LastError", Type: "string", ColumnName: "last_error", IsNullable: true},
+			{Name: "CreatedAt", Type: "time.Time", ColumnName: "created_at"},
+			{Name: "NextAttemptAt", Type: "time.Time", ColumnName: "next_attempt_at"},
+			{Name: "PublishedAt", Type: "time.Time", ColumnName: "published_at", IsNullable: true},
+		},
+	}
+}
+
+// Append grava a mensagem na transação do contexto
+func (st *SQLOutboxStore) Append(ctx context.Context, message *OutboxMessage) error {
+	keys, err := json.Marshal(message.Keys)
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox keys: %w", err)
+	}
+
+	data := map[string]interface{}{
+		"ID":            message.ID,
+		"EntityName":    message.EntityName,
+		"EventType":     string(message.EventType),
+		"EntityKeys":    string(keys),
+		"Payload":       string(message.Payload),
+		"Status":        OutboxStatusPending,
+		"Attempts":      int64(message.Attempts),
+		"CreatedAt":     message.CreatedAt,
+		"NextAttemptAt": message.NextAttemptAt,
+	}
+	if message.TenantID != "" {
+		data["TenantID"] = message.TenantID
+	}
+
+	query, args, err := st.provider.BuildInsertQuery(st.service.metadata, data)
+	if err != nil {
+		return fmt.Errorf("failed to build outbox insert: %w", err)
+	}
+	if _, err := st.service.executeExec(ctx, query, args); err != nil {
+		return fmt.Errorf("failed to write outbox message: %w", err)
+	}
+	return nil
+}
+
+// Pending retorna as mensagens pendentes cuja próxima tentativa já venceu, das mais antigas para as mais novas
+func (st *SQLOutboxStore) Pending(ctx context.Context, limit int) ([]*OutboxMessage, error) {
+	top := GoDataTopQuery(limit)
+	options := QueryOptions{
+		Filter: &GoDataFilterQuery{Tree: keysetLogical("and",
+			keysetComparison("eq", "Status", OutboxStatusPending),
+			keysetComparison("le", "NextAttemptAt", time.Now()),
+		)},
+		OrderBy: "NextAttemptAt asc",
+		Top:     &top,
+	}
+
+	response, err := st.service.Query(ctx, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+
+	values, _ := response.Value.([]interface{})
+	messages := make([]*OutboxMessage, 0, len(values))
+	for _, value := range values {
+		message, err := outboxMessageFromRow(value)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// MarkPublished marca a mensagem como publicada
+func (st *SQLOutboxStore) MarkPublished(ctx context.Context, message *OutboxMessage) error {
+	return st.update(ctx, message, map[string]interface{}{
+		"Status":      OutboxStatusPublished,
+		"Attempts":    int64(message.Attempts),
+		"PublishedAt": time.Now(),
+	})
+}
+
+// MarkFailed registra a falha da publicação e agenda a próxima tentativa
+func (st *SQLOutboxStore) MarkFailed(ctx context.Context, message *OutboxMessage, dead bool) error {
+	status := OutboxStatusPending
+	if dead {
+		status = OutboxStatusDead
+	}
+	return st.update(ctx, message, map[string]interface{}{
+		"Status":        status,
+		"Attempts":      int64(message.Attempts),
+		"LastError":     message.LastError,
+		"NextAttemptAt": message.NextAttemptAt,
+	})
+}
+
+// update altera as colunas da mensagem
+func (st *SQLOutboxStore) update(ctx context.Context, message *OutboxMessage, data map[string]interface{}) error {
+	query, args, err := st.provider.BuildUpdateQuery(st.service.metadata, data, map[string]interface{}{"ID": message.ID})
+	if err != nil {
+		return fmt.Errorf("failed to build outbox update: %w", err)
+	}
+	if _, err := st.service.executeExec(ctx, query, args); err != nil {
+		return fmt.Errorf("failed to update outbox message: %w", err)
+	}
+	return nil
+}
+
+// outboxMessageFromRow converte uma linha da tabela do outbox na mensagem
+func outboxMessageFromRow(row interface{}) (*OutboxMessage, error) {
+	get := func(name string) interface{} { return entityValue(row, name) }
+	text := func(name string) string {
+		if value := get(name); value != nil {
+			return fmt.Sprint(value)
+		}
+		return ""
+	}
+
+	message := &OutboxMessage{
+		ID:         text("ID"),
+		EntityName: text("EntityName"),
+		EventType:  EventType(text("EventType")),
+		TenantID:   text("TenantID"),
+		LastError:  text("LastError"),
+	}
+	if attempts, ok := get("Attempts").(int64); ok {
+		message.Attempts = int(attempts)
+	}
+	if keys := text("EntityKeys"); keys != "" {
+		if err := json.Unmarshal([]byte(keys), &message.Keys); err != nil {
+			return nil, fmt.Errorf("invalid keys in outbox message %s: %w", message.ID, err)
+		}
+	}
+	if payload := text("Payload"); payload != "" {
+		message.Payload = json.RawMessage(payload)
+	}
+	message.CreatedAt, _ = get("CreatedAt").(time.Time)
+	message.NextAttemptAt, _ = get("NextAttemptAt").(time.Time)
+	return message, nil
+}
+
+// OutboxRelayConfig configura o relay que publica as mensagens do outbox
+type OutboxRelayConfig struct {
+	PollInterval   time.Duration // Intervalo entre as leituras do outbox (padrão 1s)
+	BatchSize      int           // Mensagens lidas por vez (padrão 100)
+	MaxAttempts    int           // Tentativas antes de a mensagem ficar como dead (padrão 10)
+	InitialBackoff time.Duration // Espera depois da primeira falha, dobrada a cada nova falha (padrão 1s)
+	MaxBackoff     time.Duration // Espera máxima entre tentativas (padrão 5min)
+}
+
+// DefaultOutboxRelayConfig retorna a configuração padrão do relay
+func DefaultOutboxRelayConfig() OutboxRelayConfig {
+	return OutboxRelayConfig{
+		PollInterval:   time.Second,
+		BatchSize:      100,
+		MaxAttempts:    10,
+		InitialBackoff: time.Second,
+		MaxBackoff:     5 * time.Minute,
+	}
+}
+
+// OutboxRelay lê as mensagens pendentes do outbox e as publica nos sinks, com entrega ao menos uma vez:
+// a mensagem só é marcada como publicada depois de aceita por todos os sinks
+type OutboxRelay struct {
+	store  OutboxStore
+	sinks  []OutboxSink
+	config OutboxRelayConfig
+	logger *log.Logger
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewOutboxRelay cria o relay do store para os sinks informados
+func NewOutboxRelay(store OutboxStore, config OutboxRelayConfig, logger *log.Logger, sinks ...OutboxSink) *OutboxRelay {
+	if logger == nil {
+		logger = log.New(log.Writer(), "[OutboxRelay] ", log.LstdFlags|log.Lshortfile)
+	}
+
+	defaults := DefaultOutboxRelayConfig()
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaults.PollInterval
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaults.BatchSize
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaults.MaxAttempts
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = defaults.InitialBackoff
+	}
+	if config.MaxBackoff < config.InitialBackoff {
+		config.MaxBackoff = max(defaults.MaxBackoff, config.InitialBackoff)
+	}
+
+	return &OutboxRelay{store: store, sinks: sinks, config: config, logger: logger}
+}
+
+// Start inicia a leitura periódica do outbox em segundo plano
+func (r *OutboxRelay) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+	go r.run(ctx, r.done)
+}
+
+// Stop interrompe o relay e aguarda a publicação em andamento
+func (r *OutboxRelay) Stop() {
+	r.mu.Lock()
+	cancel, done := r.cancel, r.done
+	r.cancel, r.done = nil, nil
+	r.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// run publica as mensagens a cada PollInterval; um lote cheio é seguido imediatamente pelo próximo
+func (r *OutboxRelay) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	for {
+		published, err := r.RelayOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.logger.Printf("❌ Erro ao publicar o outbox: %v", err)
+		}
+
+		wait := r.config.PollInterval
+		if err == nil && published == r.config.BatchSize {
+			wait = 0
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// RelayOnce publica um lote de mensagens pendentes e retorna quantas foram processadas
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	messages, err := r.store.Pending(ctx, r.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, message := range messages {
+		if err := r.publish(ctx, message); err != nil {
+			return 0, err
+		}
+	}
+	return len(messages), nil
+}
+
+// publish entrega a mensagem aos sinks e registra o resultado no store
+func (r *OutboxRelay) publish(ctx context.Context, message *OutboxMessage) error {
+	message.Attempts++
+	for _, sink := range r.sinks {
+		if err := sink.Publish(ctx, message); err != nil {
+			dead := message.Attempts >= r.config.MaxAttempts
+			message.LastError = err.Error()
+			message.NextAttemptAt = time.Now().Add(r.backoff(message.Attempts))
+			if dead {
+				r.logger.Printf("❌ Mensagem %s do outbox (%s %s) descartada após %d tentativa(s): %v",
+					message.ID, message.EntityName, message.EventType, message.Attempts, err)
+			}
+			return r.store.MarkFailed(ctx, message, dead)
+		}
+	}
+	return r.store.MarkPublished(ctx, message)
+}
+
+// backoff calcula a espera exponencial antes da próxima tentativa
+func (r *OutboxRelay) backoff(attempts int) time.Duration {
+	delay := r.config.InitialBackoff
+	for i := 1; i < attempts && delay < r.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, r.config.MaxBackoff)
+}
+
+// tenantOutboxStore distribui o outbox pelos bancos dos tenants: cada provider tem o seu store, as mensagens
+// são gravadas e marcadas no store do tenant da mensagem e as pendentes são lidas de todos eles
+type tenantOutboxStore struct {
+	pool   *MultiTenantProviderPool
+	base   TenantOutboxStore
+	mu     sync.Mutex
+	stores map[DatabaseProvider]OutboxStore
+}
+
+// storeFor retorna o store do banco do tenant, criando-o no primeiro uso
+func (st *tenantOutboxStore) storeFor(tenantID string) (OutboxStore, error) {
+	provider := st.pool.GetProvider(tenantID)
+	if provider == nil {
+		return nil, fmt.Errorf("no provider for tenant '%s'", tenantID)
+	}
+	return st.providerStore(provider), nil
+}
+
+// providerStore retorna o store do provider, criando-o no primeiro uso
+func (st *tenantOutboxStore) providerStore(provider DatabaseProvider) OutboxStore {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	store, exists := st.stores[provider]
+	if !exists {
+		store = st.base.ForProvider(provider)
+		st.stores[provider] = store
+	}
+	return store
+}
+
+// Append grava a mensagem no banco do seu tenant
+func (st *tenantOutboxStore) Append(ctx context.Context, message *OutboxMessage) error {
+	store, err := st.storeFor(message.TenantID)
+	if err != nil {
+		return err
+	}
+	return store.Append(ctx, message)
+}
+
+// Pending lê as mensagens pendentes de todos os bancos, das mais antigas para as mais novas
+func (st *tenantOutboxStore) Pending(ctx context.Context, limit int) ([]*OutboxMessage, error) {
+	var messages []*OutboxMessage
+	read := make(map[DatabaseProvider]bool)
+	for _, tenantID := range st.pool.GetTenantList() {
+		provider := st.pool.GetProvider(tenantID)
+		if provider == nil || read[provider] {
+			continue
+		}
+		read[provider] = true
+
+		pending, err := st.providerStore(provider).Pending(ctx, limit)
+		if err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", tenantID, err)
+		}
+		// A mensagem é marcada depois no banco de onde foi lida
+		for _, message := range pending {
+			if message.TenantID == "" {
+				message.TenantID = tenantID
+			}
+		}
+		messages = append(messages, pending...)
+	}
+
+	sort.SliceStable(messages, func(i, j int) bool { return messages[i].NextAttemptAt.Before(messages[j].NextAttemptAt) })
+	if len(messages) > limit {
+		messages = messages[:limit]
+	}
+	return messages, nil
+}
+
+// MarkPublished marca a mensagem como publicada no banco do seu tenant
+func (st *tenantOutboxStore) MarkPublished(ctx context.Context, message *OutboxMessage) error {
+	store, err := st.storeFor(message.TenantID)
+	if err != nil {
+		return err
+	}
+	return store.MarkPublished(ctx, message)
+}
+
+// MarkFailed registra a falha da publicação no banco do seu tenant
+func (st *tenantOutboxStore) MarkFailed(ctx context.Context, message *OutboxMessage, dead bool) error {
+	store, err := st.storeFor(message.TenantID)
+	if err != nil {
+		return err
+	}
+	return store.MarkFailed(ctx, message, dead)
+}
+
+// outboxSettings define o store e as entidades cujas gravações passam pelo outbox
+type outboxSettings struct {
+	store    OutboxStore
+	entities map[string]bool // nil para todas as entidades
+}
+
+// EnableOutbox grava os eventos EntityInserted, EntityModified e EntityDeleted das entidades informadas
+// (ou de todas) no store, na mesma transação da alteração. StartOutboxRelay os publica depois.
+// No modo multi-tenant um TenantOutboxStore (como o SQLOutboxStore) é aberto no banco de cada tenant
+func (s *Server) EnableOutbox(store OutboxStore, entities ...string) {
+	if tenantStore, ok := store.(TenantOutboxStore); ok && s.multiTenantPool != nil {
+		store = &tenantOutboxStore{pool: s.multiTenantPool, base: tenantStore, stores: make(map[DatabaseProvider]OutboxStore)}
+	} else if s.multiTenantPool != nil {
+		s.logger.Printf("⚠️ O store do outbox não implementa TenantOutboxStore: as mensagens de todos os tenants passam por ele")
+	}
+
+	settings := &outboxSettings{store: store}
+	if len(entities) > 0 {
+		settings.entities = make(map[string]bool, len(entities))
+		for _, name := range entities {
+			settings.entities[name] = true
+		}
+	}
+
+	s.outbox.Store(settings)
+}
+
+// StartOutboxRelay inicia o relay do outbox habilitado em EnableOutbox; ele é parado no Shutdown
+func (s *Server) StartOutboxRelay(config OutboxRelayConfig, sinks ...OutboxSink) (*OutboxRelay, error) {
+	settings := s.outbox.Load()
+	if settings == nil {
+		return nil, fmt.Errorf("outbox is not enabled")
+	}
+
+	relay := NewOutboxRelay(settings.store, config, s.logger, sinks...)
+	relay.Start()
+
+	s.relaysMu.Lock()
+	defer s.relaysMu.Unlock()
+	s.outboxRelays = append(s.outboxRelays, relay)
+	return relay, nil
+}
+
+// stopOutboxRelays para os relays iniciados pelo servidor
+func (s *Server) stopOutboxRelays() {
+	s.relaysMu.Lock()
+	relays := s.outboxRelays
+	s.outboxRelays = nil
+	s.relaysMu.Unlock()
+
+	for _, relay := range relays {
+		relay.Stop()
+	}
+}
+
+// outboxStore retorna o store do outbox se as gravações da entidade passam por ele
+func (s *Server) outboxStore(entityName string) OutboxStore {
+	settings := s.outbox.Load()
+	if settings == nil || (settings.entities != nil && !settings.entities[entityName]) {
+		return nil
+	}
+	return settings.store
+}
+
+// appendOutbox grava o evento da entidade no outbox, na transação da gravação
+func (s *Server) appendOutbox(ctx context.Context, eventCtx *EventContext, eventType EventType, keys map[string]interface{}, entity interface{}) error {
+	store := s.outboxStore(eventCtx.EntityName)
+	if store == nil {
+		return nil
+	}
+
+	payload, err := json.Marshal(entity)
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox payload: %w", err)
+	}
+
+	now := time.Now()
+	message := &OutboxMessage{
+		ID:            formatUUID(newSmartGuid()),
+		EntityName:    eventCtx.EntityName,
+		EventType:     eventType,
+		Keys:          cloneValues(keys),
+		Payload:       payload,
+		CreatedAt:     now,
+		NextAttemptAt: now,
+	}
+	if s.multiTenantPool != nil {
+		message.TenantID = contextTenant(eventCtx)
+	}
+	return store.Append(ctx, message)
+}
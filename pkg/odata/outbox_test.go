@@ -0,0 +1,241 @@
+package odata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// outboxTestStore guarda o outbox em memória e registra se cada mensagem foi gravada em uma transação
+type outboxTestStore struct {
+	mu         sync.Mutex
+	messages   []*OutboxMessage
+	status     map[string]string
+	inTx       map[string]bool
+	failAppend bool
+}
+
+func newOutboxTestStore() *outboxTestStore {
+	return &outboxTestStore{status: map[string]string{}, inTx: map[string]bool{}}
+}
+
+func (st *outboxTestStore) Append(ctx context.Context, message *OutboxMessage) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.failAppend {
+		return errors.New("outbox unavailable")
+	}
+	_, st.inTx[message.ID] = TxFromContext(ctx)
+	st.messages = append(st.messages, message)
+	st.status[message.ID] = OutboxStatusPending
+	return nil
+}
+
+func (st *outboxTestStore) Pending(ctx context.Context, limit int) ([]*OutboxMessage, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	var pending []*OutboxMessage
+	for _, message := range st.messages {
+		if st.status[message.ID] == OutboxStatusPending && !message.NextAttemptAt.After(time.Now()) {
+			pending = append(pending, message)
+		}
+	}
+	sort.SliceStable(pending, func(i, j int) bool { return pending[i].NextAttemptAt.Before(pending[j].NextAttemptAt) })
+	if len(pending) > limit {
+		pending = pending[:limit]
+	}
+	return pending, nil
+}
+
+func (st *outboxTestStore) MarkPublished(ctx context.Context, message *OutboxMessage) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.status[message.ID] = OutboxStatusPublished
+	return nil
+}
+
+func (st *outboxTestStore) MarkFailed(ctx context.Context, message *OutboxMessage, dead bool) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.status[message.ID] = OutboxStatusPending
+	if dead {
+		st.status[message.ID] = OutboxStatusDead
+	}
+	return nil
+}
+
+func TestServer_OutboxWritesWithEntityChange(t *testing.T) {
+	server, _, db, _ := newLifecycleTestServer(t)
+	store := newOutboxTestStore()
+	server.EnableOutbox(store, "Notes")
+
+	// Mesmo sem handlers, a gravação e o outbox usam a mesma transação
+	status, _ := sendLifecycleRequest(t, server, "PATCH", "/odata/Notes('n1')", `{"text": "second"}`)
+	require.Equal(t, 200, status)
+	status, _ = sendLifecycleRequest(t, server, "POST", "/odata/Products", `{"id": 1, "name": "pen"}`)
+	require.Equal(t, 201, status)
+	status, _ = sendLifecycleRequest(t, server, "DELETE", "/odata/Notes('n1')", "")
+	require.Equal(t, 204, status)
+
+	require.Len(t, store.messages, 2)
+	modified, deleted := store.messages[0], store.messages[1]
+	assert.Equal(t, "Notes", modified.EntityName)
+	assert.Equal(t, EventEntityModified, modified.EventType)
+	assert.Equal(t, map[string]interface{}{"id": "n1"}, modified.Keys)
+	assert.JSONEq(t, `{"id": "n1", "text": "second"}`, string(modified.Payload))
+	assert.True(t, store.inTx[modified.ID])
+
+	// A entidade removida é lida antes da exclusão para compor a mensagem
+	assert.Equal(t, EventEntityDeleted, deleted.EventType)
+	assert.JSONEq(t, `{"id": "n1", "text": "second"}`, string(deleted.Payload))
+
+	// A falha do outbox desfaz a gravação da entidade
+	server.EnableOutbox(store)
+	store.failAppend = true
+	db.log = nil
+	status, _ = sendLifecycleRequest(t, server, "POST", "/odata/Products", `{"id": 2, "name": "ink"}`)
+	require.Equal(t, 500, status)
+	assert.Equal(t, []string{"BEGIN", "INSERT INTO users", "ROLLBACK"}, db.entries())
+}
+
+// outboxTestTenantStore abre um outboxTestStore por provider
+type outboxTestTenantStore struct {
+	*outboxTestStore
+	mu     sync.Mutex
+	stores map[DatabaseProvider]*outboxTestStore
+}
+
+func (st *outboxTestTenantStore) ForProvider(provider DatabaseProvider) OutboxStore {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	store := newOutboxTestStore()
+	st.stores[provider] = store
+	return store
+}
+
+func TestServer_OutboxMultiTenant(t *testing.T) {
+	providers := map[string]DatabaseProvider{}
+	for _, tenantID := range []string{"default", "acme"} {
+		providers[tenantID] = &MockDatabaseProvider{connection: newFakeDB(t, nil).DB}
+	}
+	server := newMultiTenantTestServer(t, providers)
+	registerDeepInsertTestServices(t, server)
+
+	base := &outboxTestTenantStore{outboxTestStore: newOutboxTestStore(), stores: map[DatabaseProvider]*outboxTestStore{}}
+	server.EnableOutbox(base, "OrderLines")
+
+	for _, tenantID := range []string{"acme", "default", "acme"} {
+		req := httptest.NewRequest("POST", "/odata/OrderLines", strings.NewReader(`{"Product": "chair"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Tenant-ID", tenantID)
+		resp, err := server.GetRouter().Test(req)
+		require.NoError(t, err)
+		require.Equal(t, 201, resp.StatusCode)
+	}
+
+	// Cada tenant grava o outbox no seu banco, na transação da alteração
+	acme, fallback := base.stores[providers["acme"]], base.stores[providers["default"]]
+	require.NotNil(t, acme)
+	require.NotNil(t, fallback)
+	require.Len(t, acme.messages, 2)
+	require.Len(t, fallback.messages, 1)
+	assert.Empty(t, base.messages)
+	assert.Equal(t, "acme", acme.messages[0].TenantID)
+	assert.True(t, acme.inTx[acme.messages[0].ID])
+
+	// Um único relay lê e marca as mensagens de todos os bancos
+	var published []string
+	relay := NewOutboxRelay(server.outbox.Load().store, OutboxRelayConfig{}, log.New(io.Discard, "", 0),
+		OutboxSinkFunc(func(ctx context.Context, message *OutboxMessage) error {
+			published = append(published, message.TenantID)
+			return nil
+		}))
+	processed, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, processed)
+	assert.ElementsMatch(t, []string{"acme", "acme", "default"}, published)
+	for _, store := range []*outboxTestStore{acme, fallback} {
+		for _, message := range store.messages {
+			assert.Equal(t, OutboxStatusPublished, store.status[message.ID])
+		}
+	}
+}
+
+func TestOutboxRelay_RetriesAndDeadMessages(t *testing.T) {
+	store := newOutboxTestStore()
+	for _, id := range []string{"m1", "m2"} {
+		require.NoError(t, store.Append(context.Background(), &OutboxMessage{ID: id, EntityName: "Orders", EventType: EventEntityInserted}))
+	}
+
+	var published []string
+	relay := NewOutboxRelay(store, OutboxRelayConfig{MaxAttempts: 2, InitialBackoff: 5 * time.Millisecond}, log.New(io.Discard, "", 0),
+		OutboxSinkFunc(func(ctx context.Context, message *OutboxMessage) error {
+			if message.ID == "m2" {
+				return errors.New("broker unavailable")
+			}
+			published = append(published, message.ID)
+			return nil
+		}))
+	ctx := context.Background()
+
+	processed, err := relay.RelayOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, processed)
+	assert.Equal(t, []string{"m1"}, published)
+	assert.Equal(t, OutboxStatusPublished, store.status["m1"])
+	assert.Equal(t, "broker unavailable", store.messages[1].LastError)
+
+	// A mensagem com falha aguarda o backoff antes da nova tentativa
+	processed, err = relay.RelayOnce(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, processed)
+
+	time.Sleep(10 * time.Millisecond)
+	processed, err = relay.RelayOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, processed)
+	assert.Equal(t, 2, store.messages[1].Attempts)
+	assert.Equal(t, OutboxStatusDead, store.status["m2"])
+}
+
+func TestSQLOutboxStore(t *testing.T) {
+	provider, db := newConstraintTestProvider(t)
+	store := NewSQLOutboxStore(provider, "")
+	message := &OutboxMessage{
+		ID:         "m1",
+		EntityName: "Notes",
+		EventType:  EventEntityModified,
+		Keys:       map[string]interface{}{"id": "n1"},
+		Payload:    json.RawMessage(`{"id":"n1"}`),
+	}
+
+	err := NewUnitOfWork(provider, nil).Run(context.Background(), func(ctx context.Context) error {
+		return store.Append(ctx, message)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BEGIN", "INSERT INTO users", "COMMIT"}, db.entries())
+	assert.Equal(t, "EntityModified", provider.inserted["EventType"])
+	assert.Equal(t, `{"id":"n1"}`, provider.inserted["EntityKeys"])
+	assert.Equal(t, OutboxStatusPending, provider.inserted["Status"])
+
+	pending, err := store.Pending(context.Background(), 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "u1", pending[0].ID)
+
+	message.Attempts = 3
+	require.NoError(t, store.MarkFailed(context.Background(), message, true))
+	assert.Equal(t, OutboxStatusDead, provider.updated["Status"])
+	assert.Equal(t, int64(3), provider.updated["Attempts"])
+}
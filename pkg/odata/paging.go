@@ -0,0 +1,330 @@
+package odata
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// SkipToken representa o estado de continuação da paginação dirigida pelo servidor.
+// Values guarda os valores de ordenação e chaves do último registro (keyset) e Skip
+// o deslocamento aplicado depois do keyset, usado quando algum valor é nulo.
+type SkipToken struct {
+	Values []interface{} `json:"v,omitempty"`
+	Skip   int           `json:"s,omitempty"`
+}
+
+// EncodeSkipToken codifica o token em um valor opaco para $skiptoken
+func EncodeSkipToken(token *SkipToken) (string, error) {
+	payload, err := json.Marshal(token)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode skip token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(payload), nil
+}
+
+// ParseSkipToken decodifica um valor de $skiptoken
+func ParseSkipToken(raw string) (*SkipToken, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid $skiptoken")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+
+	var token SkipToken
+	if err := decoder.Decode(&token); err != nil {
+		return nil, fmt.Errorf("invalid $skiptoken")
+	}
+	if token.Skip < 0 {
+		return nil, fmt.Errorf("invalid $skiptoken")
+	}
+	return &token, nil
+}
+
+// formatOrderByExpressions converte expressões de ordenação de volta para o formato de $orderby
+func formatOrderByExpressions(expressions []OrderByExpression) string {
+	items := make([]string, 0, len(expressions))
+	for _, expr := range expressions {
+		items = append(items, expr.Property+" "+string(expr.Direction))
+	}
+	return strings.Join(items, ",")
+}
+
+// pagingPlan descreve como a consulta paginada foi preparada
+type pagingPlan struct {
+	pageSize int
+	order    []OrderByExpression
+	keyset   bool         // Se a ordenação permite continuação por keyset
+	previous *SkipToken   // Token recebido na requisição
+	offset   int          // Deslocamento aplicado nesta página (fora do keyset)
+	options  QueryOptions // Opções reescritas para a consulta SQL
+}
+
+// preparePaging reescreve as opções para buscar uma página com um registro extra
+// e aplicar a continuação do $skiptoken
+func (s *BaseEntityService) preparePaging(options QueryOptions) (*pagingPlan, error) {
+	plan := &pagingPlan{options: options, previous: options.SkipToken}
+	requestedTop := GetTopValue(options.Top)
+	paged := options.MaxPageSize > 0 && (requestedTop == 0 || requestedTop > options.MaxPageSize)
+	if !paged && options.SkipToken == nil {
+		return nil, nil
+	}
+
+	order, err := NewODataParser().ParseOrderBy(options.OrderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	// A ordenação é completada com as chaves para ser determinística
+	for _, key := range s.metadata.Keys {
+		if !hasOrderByProperty(order, key) {
+			order = append(order, OrderByExpression{Property: key, Direction: OrderAsc})
+		}
+	}
+
+	plan.keyset = len(order) > 0
+	for i, expr := range order {
+		prop, err := findFilterProperty(s.metadata, expr.Property)
+		if err != nil {
+			return nil, err
+		}
+		if prop.IsNavigation || prop.IsNullable && !prop.IsKey {
+			plan.keyset = false
+		} else {
+			order[i].Property = prop.Name
+		}
+	}
+	plan.order = order
+	plan.options.OrderBy = formatOrderByExpressions(order)
+
+	if token := options.SkipToken; token != nil {
+		if len(token.Values) > 0 {
+			keysetFilter, err := s.buildKeysetFilter(order, token.Values)
+			if err != nil {
+				return nil, err
+			}
+			plan.options.Filter = combineFilters(options.Filter, keysetFilter)
+		}
+		plan.offset = token.Skip
+		if token.Skip > 0 {
+			skip := GoDataSkipQuery(token.Skip)
+			plan.options.Skip = &skip
+		} else {
+			plan.options.Skip = nil
+		}
+	} else {
+		plan.offset = GetSkipValue(options.Skip)
+	}
+
+	if paged {
+		plan.pageSize = options.MaxPageSize
+		top := GoDataTopQuery(options.MaxPageSize + 1)
+		plan.options.Top = &top
+	}
+
+	return plan, nil
+}
+
+// nextSkipToken gera o token da próxima página a partir do último registro retornado
+func (p *pagingPlan) nextSkipToken(last interface{}) (string, error) {
+	token := &SkipToken{}
+
+	entity, ok := last.(*OrderedEntity)
+	if p.keyset && ok {
+		for _, expr := range p.order {
+			value, exists := entity.Get(expr.Property)
+			if !exists || value == nil {
+				token.Values = nil
+				break
+			}
+			token.Values = append(token.Values, value)
+		}
+	}
+
+	// Sem valores de keyset, continua a partir do deslocamento
+	if len(token.Values) == 0 {
+		if p.previous != nil {
+			token.Values = p.previous.Values
+		}
+		token.Skip = p.offset + p.pageSize
+	}
+
+	return EncodeSkipToken(token)
+}
+
+// buildKeysetFilter monta a condição (a > v1) OR (a = v1 AND b > v2) ... para a continuação
+func (s *BaseEntityService) buildKeysetFilter(order []OrderByExpression, values []interface{}) (*ParseNode, error) {
+	if len(values) != len(order) {
+		return nil, fmt.Errorf("invalid $skiptoken for the requested $orderby")
+	}
+
+	typed := make([]interface{}, len(values))
+	for i, expr := range order {
+		prop, err := findFilterProperty(s.metadata, expr.Property)
+		if err != nil {
+			return nil, err
+		}
+		value, err := convertSkipTokenValue(values[i], prop)
+		if err != nil {
+			return nil, err
+		}
+		typed[i] = value
+	}
+
+	var result *ParseNode
+	for i, expr := range order {
+		operator := "gt"
+		if expr.Direction == OrderDesc {
+			operator = "lt"
+		}
+		term := keysetComparison(operator, expr.Property, typed[i])
+		for j := i - 1; j >= 0; j-- {
+			term = keysetLogical("and", keysetComparison("eq", order[j].Property, typed[j]), term)
+		}
+
+		if result == nil {
+			result = term
+		} else {
+			result = keysetLogical("or", result, term)
+		}
+	}
+
+	return result, nil
+}
+
+// keysetComparison cria o nó "propriedade operador valor"
+func keysetComparison(operator, property string, value interface{}) *ParseNode {
+	return &ParseNode{
+		Token: &Token{Type: int(FilterTokenComparison), Value: operator},
+		Children: []*ParseNode{
+			{Token: &Token{Type: int(FilterTokenProperty), Value: property}},
+			// O valor tipado segue em SemanticReference e é enviado como argumento
+			{Token: &Token{Type: int(FilterTokenNumber), Value: fmt.Sprint(value), SemanticReference: value}},
+		},
+	}
+}
+
+// keysetLogical cria um nó lógico entre duas expressões
+func keysetLogical(operator string, left, right *ParseNode) *ParseNode {
+	return &ParseNode{
+		Token:    &Token{Type: int(FilterTokenLogical), Value: operator},
+		Children: []*ParseNode{left, right},
+	}
+}
+
+// combineFilters combina o $filter da requisição com uma condição adicional
+func combineFilters(filter *GoDataFilterQuery, condition *ParseNode) *GoDataFilterQuery {
+	if filter == nil || filter.Tree == nil {
+		return &GoDataFilterQuery{Tree: condition}
+	}
+	return &GoDataFilterQuery{
+		Tree:     keysetLogical("and", filter.Tree, condition),
+		RawValue: filter.RawValue,
+	}
+}
+
+// convertSkipTokenValue converte um valor decodificado do token para o tipo da propriedade
+func convertSkipTokenValue(value interface{}, prop PropertyMetadata) (interface{}, error) {
+	invalid := fmt.Errorf("invalid $skiptoken value for '%s'", prop.Name)
+
+	switch v := value.(type) {
+	case json.Number:
+		if isIntegerType(prop.Type) {
+			parsed, err := v.Int64()
+			if err != nil {
+				return nil, invalid
+			}
+			return parsed, nil
+		}
+		parsed, err := v.Float64()
+		if err != nil {
+			return nil, invalid
+		}
+		return parsed, nil
+	case string:
+		if prop.Type == "time.Time" {
+			parsed, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return nil, invalid
+			}
+			return parsed, nil
+		}
+		return v, nil
+	case bool:
+		return v, nil
+	default:
+		return nil, invalid
+	}
+}
+
+// hasOrderByProperty verifica se a propriedade já faz parte da ordenação
+func hasOrderByProperty(order []OrderByExpression, property string) bool {
+	for _, expr := range order {
+		if strings.EqualFold(expr.Property, property) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveMaxPageSize calcula o tamanho de página efetivo a partir da configuração e do Prefer
+func (s *Server) resolveMaxPageSize(c fiber.Ctx, entityName string) (int, bool) {
+	limit := s.config.MaxPageSize
+	if size, ok := s.config.EntityMaxPageSizes[entityName]; ok {
+		limit = size
+	}
+
+	preferred := preferMaxPageSize(c.Get("Prefer"))
+	if preferred > 0 && (limit <= 0 || preferred <= limit) {
+		return preferred, true
+	}
+	return limit, false
+}
+
+// preferMaxPageSize extrai a preferência odata.maxpagesize do cabeçalho Prefer
+func preferMaxPageSize(prefer string) int {
+	for _, preference := range strings.Split(prefer, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(preference), "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "odata.maxpagesize" || name == "maxpagesize" {
+			size, err := strconv.Atoi(strings.Trim(strings.TrimSpace(value), `"`))
+			if err == nil && size > 0 {
+				return size
+			}
+		}
+	}
+	return 0
+}
+
+// buildNextLink monta o @odata.nextLink preservando as opções da requisição
+func (s *Server) buildNextLink(c fiber.Ctx, skipToken string, returned int) string {
+	values, err := url.ParseQuery(string(c.Request().URI().QueryString()))
+	if err != nil {
+		values = url.Values{}
+	}
+
+	for key := range values {
+		switch strings.ToLower(key) {
+		case "$skiptoken", "$skip":
+			// O deslocamento já está contido no token
+			delete(values, key)
+		case "$top":
+			// O $top original continua valendo para o restante das páginas
+			if top, err := strconv.Atoi(values.Get(key)); err == nil {
+				values.Set(key, strconv.Itoa(top-returned))
+			}
+		}
+	}
+	values.Set("$skiptoken", skipToken)
+
+	return c.BaseURL() + c.Path() + "?" + strings.ReplaceAll(values.Encode(), "%24", "$")
+}
@@ -0,0 +1,177 @@
+package odata
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type PagingTestProduct struct {
+	ID   int64  `json:"id" column:"id" primaryKey:"idGenerator:none"`
+	Name string `json:"name" column:"name"`
+}
+
+// pagingTestProvider registra as opções recebidas na montagem do SELECT
+type pagingTestProvider struct {
+	MockDatabaseProvider
+	options QueryOptions
+}
+
+func (p *pagingTestProvider) BuildSelectQuery(metadata EntityMetadata, options QueryOptions) (string, []interface{}, error) {
+	p.options = options
+	return "SELECT id, name FROM products", nil, nil
+}
+
+func newPagingTestServer(t *testing.T, rows int) (*Server, *pagingTestProvider) {
+	db := newFakeDB(t, func(string) *fakeRows {
+		products := newFakeRows([]string{"id", "name"})
+		for i := 1; i <= rows; i++ {
+			products.rows = append(products.rows, []driver.Value{int64(i), "Product"})
+		}
+		return products
+	})
+
+	provider := &pagingTestProvider{MockDatabaseProvider: MockDatabaseProvider{connection: db.DB}}
+	server := newTestServer(provider, func(config *ServerConfig) { config.MaxPageSize = 2 })
+	require.NoError(t, server.RegisterEntity("Products", PagingTestProduct{}))
+
+	return server, provider
+}
+
+func pagingTestMetadata() EntityMetadata {
+	return EntityMetadata{
+		Name:      "Products",
+		TableName: "products",
+		Keys:      []string{"ID"},
+		Properties: []PropertyMetadata{
+			{Name: "ID", Type: "int64", ColumnName: "id", IsKey: true},
+			{Name: "Name", Type: "string", ColumnName: "name"},
+		},
+	}
+}
+
+func TestSkipToken_EncodeParse(t *testing.T) {
+	raw, err := EncodeSkipToken(&SkipToken{Values: []interface{}{"Chair", int64(7)}, Skip: 4})
+	require.NoError(t, err)
+
+	token, err := ParseSkipToken(raw)
+	require.NoError(t, err)
+	assert.Equal(t, 4, token.Skip)
+	require.Len(t, token.Values, 2)
+	assert.Equal(t, "Chair", token.Values[0])
+	assert.Equal(t, json.Number("7"), token.Values[1])
+
+	_, err = ParseSkipToken("not a token!")
+	assert.Error(t, err)
+
+	_, err = NewODataParser().ParseQueryOptions(url.Values{"$skiptoken": []string{"%%%"}})
+	assert.Error(t, err)
+}
+
+func TestBaseEntityService_PreparePaging(t *testing.T) {
+	service := &BaseEntityService{metadata: pagingTestMetadata()}
+	ctx := context.Background()
+
+	// Primeira página: completa a ordenação com a chave e busca um registro extra
+	plan, err := service.preparePaging(QueryOptions{OrderBy: "Name desc", MaxPageSize: 10})
+	require.NoError(t, err)
+	require.NotNil(t, plan)
+	assert.Equal(t, "Name desc,ID asc", plan.options.OrderBy)
+	assert.Equal(t, 11, GetTopValue(plan.options.Top))
+
+	last := NewOrderedEntity()
+	last.Set("ID", int64(42))
+	last.Set("Name", "Desk")
+	raw, err := plan.nextSkipToken(last)
+	require.NoError(t, err)
+
+	// Próxima página: o token vira uma condição de keyset combinada com o $filter
+	token, err := ParseSkipToken(raw)
+	require.NoError(t, err)
+	filter, err := ParseFilterString(ctx, "ID gt 5")
+	require.NoError(t, err)
+
+	plan, err = service.preparePaging(QueryOptions{OrderBy: "Name desc", Filter: filter, SkipToken: token, MaxPageSize: 10})
+	require.NoError(t, err)
+	assert.Nil(t, plan.options.Skip)
+
+	sql, args, err := NewQueryBuilder("mysql").BuildWhereClause(ctx, plan.options.Filter.Tree, service.metadata)
+	require.NoError(t, err)
+	assert.Equal(t, "((id > :param1) AND ((name < :param2) OR ((name = :param3) AND (id > :param4))))", sql)
+	assert.Len(t, args, 4)
+
+	// $top menor que a página não precisa de paginação
+	top := GoDataTopQuery(5)
+	plan, err = service.preparePaging(QueryOptions{Top: &top, MaxPageSize: 10})
+	require.NoError(t, err)
+	assert.Nil(t, plan)
+
+	// Tokens que não correspondem à ordenação são rejeitados
+	_, err = service.preparePaging(QueryOptions{SkipToken: &SkipToken{Values: []interface{}{json.Number("1")}}, OrderBy: "Name", MaxPageSize: 10})
+	assert.Error(t, err)
+}
+
+func TestServer_ServerDrivenPaging(t *testing.T) {
+	server, provider := newPagingTestServer(t, 3)
+
+	resp, err := server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Products?$filter=ID%20gt%200&$top=10", nil))
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+
+	var body struct {
+		Value    []map[string]interface{} `json:"value"`
+		NextLink string                   `json:"@odata.nextLink"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Len(t, body.Value, 2)
+	assert.Equal(t, 3, GetTopValue(provider.options.Top))
+	assert.Equal(t, "id asc", provider.options.OrderBy)
+
+	next, err := url.Parse(body.NextLink)
+	require.NoError(t, err)
+	assert.Equal(t, "/odata/Products", next.Path)
+	assert.Equal(t, "ID gt 0", next.Query().Get("$filter"))
+	assert.Equal(t, "8", next.Query().Get("$top"))
+	require.NotEmpty(t, next.Query().Get("$skiptoken"))
+
+	// Seguindo o nextLink, o token é aplicado como keyset
+	resp, err = server.GetRouter().Test(httptest.NewRequest("GET", next.RequestURI(), nil))
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	require.NotNil(t, provider.options.SkipToken)
+	assert.Equal(t, []interface{}{json.Number("2")}, provider.options.SkipToken.Values)
+}
+
+func TestServer_PreferMaxPageSize(t *testing.T) {
+	server, provider := newPagingTestServer(t, 3)
+
+	req := httptest.NewRequest("GET", "/odata/Products", nil)
+	req.Header.Set("Prefer", "odata.maxpagesize=1")
+	resp, err := server.GetRouter().Test(req)
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "odata.maxpagesize=1", resp.Header.Get("Preference-Applied"))
+	assert.Equal(t, 2, GetTopValue(provider.options.Top))
+
+	// Preferências maiores que o limite do servidor são ignoradas
+	req = httptest.NewRequest("GET", "/odata/Products", nil)
+	req.Header.Set("Prefer", "odata.maxpagesize=50")
+	resp, err = server.GetRouter().Test(req)
+	require.NoError(t, err)
+	assert.Empty(t, resp.Header.Get("Preference-Applied"))
+	assert.Equal(t, 3, GetTopValue(provider.options.Top))
+
+	// Resultados que cabem na página não têm nextLink
+	server, _ = newPagingTestServer(t, 2)
+	resp, err = server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Products", nil))
+	require.NoError(t, err)
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.NotContains(t, body, "@odata.nextLink")
+}
@@ -31,6 +31,7 @@ func GetGlobalParser() *ODataParser {
 				"$search":      true,
 				"$format":      true,
 				"$apply":       true,
+				"$skiptoken":   true,
 				"$inlinecount": true,
 			},
 		}
@@ -179,6 +180,24 @@ func (p *ODataParser) ParseQueryOptionsWithConfig(values url.Values, config Comp
 		options.Search = &SearchOption{RawQuery: searchStr}
 	}
 
+	// Parse $apply (case insensitive)
+	if applyStr := p.getCaseInsensitiveValue(values, "$apply"); applyStr != "" {
+		applyOption, err := ParseApplyString(context.Background(), applyStr)
+		if err != nil {
+			return options, fmt.Errorf("invalid $apply: %w", err)
+		}
+		options.Apply = applyOption
+	}
+
+	// Parse $skiptoken (case insensitive)
+	if skipTokenStr := p.getCaseInsensitiveValue(values, "$skiptoken"); skipTokenStr != "" {
+		skipToken, err := ParseSkipToken(skipTokenStr)
+		if err != nil {
+			return options, err
+		}
+		options.SkipToken = skipToken
+	}
+
 	return options, nil
 }
 
@@ -0,0 +1,266 @@
+package odata
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// ReadOnlyPropertyMode define o tratamento de propriedades somente leitura enviadas no corpo
+type ReadOnlyPropertyMode string
+
+const (
+	ReadOnlyPropertiesIgnore ReadOnlyPropertyMode = "ignore" // Descarta as propriedades somente leitura
+	ReadOnlyPropertiesReject ReadOnlyPropertyMode = "reject" // Rejeita a requisição com 400
+)
+
+// odataBindSuffix é o sufixo da anotação que vincula entidades existentes no corpo (Customer@odata.bind)
+const odataBindSuffix = "@odata.bind"
+
+// isReadOnlyProperty verifica se a propriedade é calculada pelo servidor: chaves geradas na inserção,
+// propriedades NoInsert/NoUpdate e a coluna de versão do ETag
+func isReadOnlyProperty(metadata EntityMetadata, prop PropertyMetadata, insert bool) bool {
+	if prop.IsKey {
+		return insert && isGeneratedKey(prop)
+	}
+	if (insert && prop.HasPropFlag(PropNoInsert)) || (!insert && prop.HasPropFlag(PropNoUpdate)) {
+		return true
+	}
+	if metadata.ETag != nil && metadata.ETag.Mode == ETagModeVersion {
+		for _, name := range metadata.ETag.Properties {
+			if name == prop.Name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sanitizeReadOnlyProperties remove ou rejeita as propriedades somente leitura do corpo. keys são as
+// chaves da URL (PUT/PATCH e upsert): chaves no corpo devem coincidir com elas
+func (s *Server) sanitizeReadOnlyProperties(metadata EntityMetadata, data, keys map[string]interface{}, insert bool) error {
+	reject := s.config.ReadOnlyProperties == ReadOnlyPropertiesReject
+
+	for _, prop := range metadata.Properties {
+		value, exists := data[prop.Name]
+		if !exists || prop.IsNavigation {
+			continue
+		}
+
+		if prop.IsKey && keys != nil {
+			if fmt.Sprint(value) != fmt.Sprint(keys[prop.Name]) {
+				if reject {
+					return fmt.Errorf("key property '%s' cannot be changed", prop.Name)
+				}
+				delete(data, prop.Name)
+			} else if !insert {
+				delete(data, prop.Name)
+			}
+			continue
+		}
+
+		if isReadOnlyProperty(metadata, prop, insert) {
+			if reject {
+				return fmt.Errorf("property '%s' is read-only", prop.Name)
+			}
+			delete(data, prop.Name)
+		}
+	}
+	return nil
+}
+
+// replacementPayload completa o corpo de um PUT: as propriedades omitidas voltam a null ou ao valor zero do tipo.
+// Propriedades com default no banco e não anuláveis mantêm o valor atual
+func replacementPayload(metadata EntityMetadata, data map[string]interface{}) map[string]interface{} {
+	for _, prop := range metadata.Properties {
+		if prop.IsNavigation || prop.IsKey || isReadOnlyProperty(metadata, prop, false) {
+			continue
+		}
+		if _, exists := data[prop.Name]; exists {
+			continue
+		}
+		if prop.IsNullable {
+			data[prop.Name] = nil
+		} else if !prop.HasDefault {
+			data[prop.Name] = zeroPropertyValue(prop)
+		}
+	}
+	return data
+}
+
+// zeroPropertyValue retorna o valor zero do tipo da propriedade
+func zeroPropertyValue(prop PropertyMetadata) interface{} {
+	switch prop.Type {
+	case "string":
+		return ""
+	case "int32", "int64":
+		return int64(0)
+	case "float32", "float64":
+		return float64(0)
+	case "bool":
+		return false
+	case "time.Time":
+		return time.Time{}
+	}
+	return nil
+}
+
+// entityBinding é um vínculo @odata.bind aplicado depois da gravação da entidade de origem
+type entityBinding struct {
+	link       *deepInsertLink
+	entityName string
+	service    EntityService
+	entities   []*OrderedEntity
+}
+
+// resolveBindings lê as anotações @odata.bind do corpo e carrega as entidades referenciadas.
+// Navegações N:1 viram a chave estrangeira no próprio corpo; as demais são devolvidas para applyBindings
+func (s *Server) resolveBindings(ctx context.Context, metadata EntityMetadata, data map[string]interface{}) ([]*entityBinding, error) {
+	var bindings []*entityBinding
+
+	for name, value := range data {
+		if !strings.HasSuffix(name, odataBindSuffix) {
+			continue
+		}
+		delete(data, name)
+
+		navigation := strings.TrimSuffix(name, odataBindSuffix)
+		prop, exists := navigationProperty(metadata, navigation)
+		if !exists {
+			return nil, &resourcePathError{fiber.StatusBadRequest, "InvalidReference", fmt.Sprintf("'%s' is not a navigation property", navigation)}
+		}
+		if _, nested := data[prop.Name]; nested {
+			return nil, &resourcePathError{fiber.StatusBadRequest, "InvalidReference", fmt.Sprintf("navigation property '%s' cannot be bound and nested at the same time", prop.Name)}
+		}
+
+		var ids []interface{}
+		switch v := value.(type) {
+		case string:
+			if prop.IsCollection {
+				return nil, &resourcePathError{fiber.StatusBadRequest, "InvalidReference", fmt.Sprintf("'%s' expects an array of entity ids", name)}
+			}
+			ids = []interface{}{v}
+		case []interface{}:
+			if !prop.IsCollection {
+				return nil, &resourcePathError{fiber.StatusBadRequest, "InvalidReference", fmt.Sprintf("'%s' expects a single entity id", name)}
+			}
+			ids = v
+		default:
+			return nil, &resourcePathError{fiber.StatusBadRequest, "InvalidReference", fmt.Sprintf("invalid value for '%s'", name)}
+		}
+
+		link, err := s.buildDeepInsertLink(metadata, prop)
+		if err != nil {
+			return nil, &resourcePathError{fiber.StatusBadRequest, "InvalidReference", err.Error()}
+		}
+		relatedName, relatedService, _ := s.findEntitySet(relatedEntityType(prop))
+
+		binding := &entityBinding{link: link, entityName: relatedName, service: relatedService}
+		for _, item := range ids {
+			id, ok := item.(string)
+			if !ok {
+				return nil, &resourcePathError{fiber.StatusBadRequest, "InvalidReference", fmt.Sprintf("invalid entity id in '%s'", name)}
+			}
+			entity, err := s.loadBoundEntity(ctx, relatedName, relatedService, id)
+			if err != nil {
+				return nil, err
+			}
+			binding.entities = append(binding.entities, entity)
+		}
+
+		// N:1: a origem guarda a chave estrangeira, que passa a fazer parte do corpo
+		if link.joinTable == nil && isPrincipalNavigation(metadata, relatedService.GetMetadata(), link) {
+			value, _ := binding.entities[0].Get(link.keyProperty)
+			data[link.parentProperty] = value
+			continue
+		}
+		bindings = append(bindings, binding)
+	}
+
+	return bindings, nil
+}
+
+// loadBoundEntity carrega a entidade apontada por um @odata.bind
+func (s *Server) loadBoundEntity(ctx context.Context, entityName string, service EntityService, id string) (*OrderedEntity, error) {
+	key, err := s.parseReferenceID(id, entityName)
+	if err != nil {
+		return nil, err
+	}
+
+	target := &navigationTarget{entityName: entityName, service: service}
+	if err := s.navigateToKey(target, key); err != nil {
+		return nil, err
+	}
+
+	entity, err := s.loadNavigationEntity(ctx, target)
+	if err != nil {
+		if pathErr, ok := err.(*resourcePathError); ok && pathErr.status == fiber.StatusNotFound {
+			return nil, &resourcePathError{fiber.StatusBadRequest, "InvalidReference", fmt.Sprintf("entity '%s' not found", id)}
+		}
+		return nil, err
+	}
+	return entity, nil
+}
+
+// navigationProperty localiza a propriedade de navegação pelo nome
+func navigationProperty(metadata EntityMetadata, name string) (PropertyMetadata, bool) {
+	for _, prop := range metadata.Properties {
+		if prop.IsNavigation && prop.Name == name {
+			return prop, true
+		}
+	}
+	return PropertyMetadata{}, false
+}
+
+// writeWithBindings grava a entidade e aplica os vínculos @odata.bind na mesma transação
+func (s *Server) writeWithBindings(ctx context.Context, provider DatabaseProvider, service EntityService, bindings []*entityBinding, write func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	if len(bindings) == 0 {
+		return write(ctx)
+	}
+
+	return s.runInTransaction(ctx, provider, func(ctx context.Context) (interface{}, error) {
+		entity, err := write(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.applyBindings(ctx, service, entity, bindings); err != nil {
+			return nil, err
+		}
+		return entity, nil
+	})
+}
+
+// applyBindings vincula as entidades referenciadas à entidade gravada: 1:N altera a chave estrangeira
+// da entidade relacionada e N:N insere na tabela de junção. Os vínculos existentes são mantidos
+func (s *Server) applyBindings(ctx context.Context, service EntityService, parent interface{}, bindings []*entityBinding) error {
+	for _, binding := range bindings {
+		link := binding.link
+		parentValue := entityValue(parent, link.parentProperty)
+		if parentValue == nil {
+			return fmt.Errorf("entity has no value for '%s' to bind '%s'", link.parentProperty, link.navigation.Name)
+		}
+
+		for _, entity := range binding.entities {
+			if link.joinTable != nil {
+				references, ok := service.(ReferenceService)
+				if !ok {
+					return fmt.Errorf("'%s' does not support join table references", service.GetMetadata().Name)
+				}
+				value, _ := entity.Get(link.keyProperty)
+				if err := references.LinkEntities(ctx, link.navigation.Name, parentValue, value); err != nil {
+					return err
+				}
+				continue
+			}
+
+			keys := entityKeyValues(binding.service.GetMetadata(), entity)
+			if _, err := s.modifyEntity(ctx, binding.entityName, binding.service, keys, map[string]interface{}{link.keyProperty: parentValue}, entity); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package odata
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sendPayload(t *testing.T, server *Server, method, path, body string) int {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := server.GetRouter().Test(req)
+	require.NoError(t, err)
+	return resp.StatusCode
+}
+
+func TestServer_PutReplacesEntity(t *testing.T) {
+	server, services, _ := newDeepInsertTestServer(t)
+	services.lines.seed(map[string]interface{}{"ID": int64(1), "OrderID": int64(10), "Product": "chair"})
+
+	// PATCH altera apenas o que foi enviado
+	require.Equal(t, 200, sendPayload(t, server, "PATCH", "/odata/OrderLines(1)", `{"Product": "sofa"}`))
+	assert.Equal(t, map[string]interface{}{"Product": "sofa"}, services.lines.updated[0])
+	assert.EqualValues(t, 10, entityValue(services.lines.find(1), "OrderID"))
+
+	// PUT substitui a entidade: as propriedades omitidas voltam ao valor zero
+	require.Equal(t, 200, sendPayload(t, server, "PUT", "/odata/OrderLines(1)", `{"Product": "rug"}`))
+	assert.Equal(t, map[string]interface{}{"Product": "rug", "OrderID": int64(0)}, services.lines.updated[1])
+}
+
+func TestServer_PutUpsert(t *testing.T) {
+	server, services, _ := newDeepInsertTestServer(t)
+
+	assert.Equal(t, 404, sendPayload(t, server, "PUT", "/odata/OrderLines(77)", `{"Product": "lamp"}`))
+
+	server.config.EnableUpsert = true
+	require.Equal(t, 201, sendPayload(t, server, "PUT", "/odata/OrderLines(77)", `{"Product": "lamp"}`))
+	require.Len(t, services.lines.created, 1)
+	assert.EqualValues(t, 77, services.lines.created[0]["ID"])
+	assert.Equal(t, "lamp", services.lines.created[0]["Product"])
+}
+
+func TestServer_ReadOnlyProperties(t *testing.T) {
+	server, services, _ := newDeepInsertTestServer(t)
+	services.lines.seed(map[string]interface{}{"ID": int64(1), "OrderID": int64(10), "Product": "chair"})
+
+	// Por padrão as chaves e campos calculados são descartados
+	require.Equal(t, 200, sendPayload(t, server, "PATCH", "/odata/OrderLines(1)", `{"ID": 2, "Product": "sofa"}`))
+	assert.Equal(t, map[string]interface{}{"Product": "sofa"}, services.lines.updated[0])
+	require.Equal(t, 201, sendPayload(t, server, "POST", "/odata/OrderLines", `{"ID": 5, "Product": "rug"}`))
+	assert.NotContains(t, services.lines.created[0], "ID")
+
+	server.config.ReadOnlyProperties = ReadOnlyPropertiesReject
+	assert.Equal(t, 400, sendPayload(t, server, "PATCH", "/odata/OrderLines(1)", `{"ID": 2}`))
+	assert.Equal(t, 200, sendPayload(t, server, "PATCH", "/odata/OrderLines(1)", `{"ID": 1, "Product": "desk"}`))
+	assert.Equal(t, 400, sendPayload(t, server, "POST", "/odata/OrderLines", `{"ID": 5, "Product": "rug"}`))
+	assert.Len(t, services.lines.created, 1)
+}
+
+func TestServer_ODataBind(t *testing.T) {
+	t.Run("binds existing entities", func(t *testing.T) {
+		server, services, db := newDeepInsertTestServer(t)
+		services.customers.seed(map[string]interface{}{"ID": int64(1), "Name": "Ann"})
+		services.lines.seed(map[string]interface{}{"ID": int64(7), "Product": "chair"})
+
+		body := `{"Customer@odata.bind": "Customers(1)", "Lines@odata.bind": ["OrderLines(7)"]}`
+		require.Equal(t, 201, sendPayload(t, server, "POST", "/odata/Orders", body))
+
+		require.Len(t, services.orders.created, 1)
+		assert.EqualValues(t, 1, services.orders.created[0]["CustomerID"])
+		assert.NotContains(t, services.orders.created[0], "Customer@odata.bind")
+		assert.Empty(t, services.customers.created)
+		assert.EqualValues(t, 101, entityValue(services.lines.find(7), "OrderID"))
+		assert.Equal(t, 1, db.commits)
+
+		// Em atualizações o vínculo N:1 troca a chave estrangeira
+		services.customers.seed(map[string]interface{}{"ID": int64(2), "Name": "Bob"})
+		require.Equal(t, 200, sendPayload(t, server, "PATCH", "/odata/Orders(101)", `{"Customer@odata.bind": "/odata/Customers(2)"}`))
+		assert.EqualValues(t, 2, entityValue(services.orders.find(101), "CustomerID"))
+	})
+
+	t.Run("uses the provider of the entity set", func(t *testing.T) {
+		server, services, db := newDeepInsertTestServer(t)
+		services.lines.seed(map[string]interface{}{"ID": int64(7), "Product": "chair"})
+		orders, ordersDB := newConstraintTestProvider(t)
+		ordersDB.lastInsertID = 5
+		metadata, err := MapEntityFromStruct(DeepInsertTestOrder{})
+		require.NoError(t, err)
+		require.NoError(t, server.RegisterEntityWithService("Orders", NewBaseEntityService(orders, metadata, server)))
+
+		// A transação do vínculo é aberta no banco de Orders, não no provider padrão
+		require.Equal(t, 201, sendPayload(t, server, "POST", "/odata/Orders", `{"Lines@odata.bind": ["OrderLines(7)"]}`))
+		assert.Equal(t, []string{"BEGIN", "INSERT INTO users", "COMMIT"}, ordersDB.entries())
+		assert.Empty(t, db.entries())
+	})
+
+	t.Run("rejects invalid references", func(t *testing.T) {
+		server, services, _ := newDeepInsertTestServer(t)
+
+		assert.Equal(t, 400, sendPayload(t, server, "POST", "/odata/Orders", `{"Customer@odata.bind": "Customers(9)"}`))
+		assert.Equal(t, 400, sendPayload(t, server, "POST", "/odata/Orders", `{"Customer@odata.bind": "OrderLines(1)"}`))
+		assert.Equal(t, 400, sendPayload(t, server, "POST", "/odata/Orders", `{"Lines@odata.bind": "OrderLines(1)"}`))
+		assert.Equal(t, 400, sendPayload(t, server, "POST", "/odata/Orders", `{"Missing@odata.bind": "Customers(1)"}`))
+		assert.Empty(t, services.orders.created)
+	})
+}
@@ -0,0 +1,258 @@
+package odata
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// Valores da preferência return
+const (
+	ReturnMinimal        = "minimal"
+	ReturnRepresentation = "representation"
+)
+
+// preferences representa as preferências do cabeçalho Prefer tratadas pelo servidor
+type preferences struct {
+	returnMode            string // minimal ou representation
+	respondAsync          bool
+	includeAnnotations    string // Valor de odata.include-annotations
+	hasIncludeAnnotations bool
+}
+
+// parsePreferences lê as preferências do cabeçalho Prefer
+func parsePreferences(prefer string) preferences {
+	var prefs preferences
+	for _, preference := range splitPreferences(prefer) {
+		name, value, _ := strings.Cut(preference, "=")
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "return", "odata.return":
+			if value = strings.ToLower(value); value == ReturnMinimal || value == ReturnRepresentation {
+				prefs.returnMode = value
+			}
+		case "respond-async":
+			prefs.respondAsync = true
+		case "odata.include-annotations", "include-annotations":
+			prefs.includeAnnotations = value
+			prefs.hasIncludeAnnotations = true
+		}
+	}
+	return prefs
+}
+
+// splitPreferences separa as preferências por vírgula, respeitando valores entre aspas
+func splitPreferences(prefer string) []string {
+	var parts []string
+	var current strings.Builder
+	quoted := false
+
+	for _, r := range prefer {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			current.WriteRune(r)
+		case r == ',' && !quoted:
+			parts = append(parts, strings.TrimSpace(current.String()))
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if part := strings.TrimSpace(current.String()); part != "" {
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// addPreferenceApplied acrescenta uma preferência aplicada ao cabeçalho Preference-Applied
+func addPreferenceApplied(c fiber.Ctx, preference string) {
+	if applied := c.GetRespHeader("Preference-Applied"); applied != "" {
+		preference = applied + ", " + preference
+	}
+	c.Set("Preference-Applied", preference)
+}
+
+// writeEntityResult escreve o resultado de uma escrita conforme Prefer: return=minimal responde 204
+// com OData-EntityId; return=representation (padrão) devolve a entidade com o status informado
+func (s *Server) writeEntityResult(c fiber.Ctx, service EntityService, entity interface{}, status int) error {
+	s.setEntityETag(c, service.GetMetadata(), entity)
+
+	entityURL := s.buildEntityURL(c, service, entity)
+	if status == fiber.StatusCreated {
+		c.Set("Location", entityURL)
+	}
+
+	switch parsePreferences(c.Get("Prefer")).returnMode {
+	case ReturnMinimal:
+		addPreferenceApplied(c, "return="+ReturnMinimal)
+		c.Set("OData-EntityId", entityURL)
+		return c.SendStatus(fiber.StatusNoContent)
+	case ReturnRepresentation:
+		addPreferenceApplied(c, "return="+ReturnRepresentation)
+	}
+
+	c.Status(status)
+	return c.JSON(entity)
+}
+
+// preferenceMiddleware trata as preferências que envolvem toda a requisição:
+// respond-async e odata.include-annotations
+func (s *Server) preferenceMiddleware() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		// Na execução em segundo plano o contexto da requisição é o cancelável da requisição assíncrona
+		if ctx, ok := c.RequestCtx().UserValue(asyncContextKey).(context.Context); ok {
+			c.SetContext(ctx)
+		}
+
+		prefs := parsePreferences(c.Get("Prefer"))
+
+		if prefs.respondAsync && s.acceptsAsync(c) {
+			return s.startAsyncRequest(c)
+		}
+
+		if err := c.Next(); err != nil {
+			return err
+		}
+
+		if !prefs.hasIncludeAnnotations {
+			return nil
+		}
+		addPreferenceApplied(c, `odata.include-annotations="`+prefs.includeAnnotations+`"`)
+
+		body := c.Response().Body()
+		if !strings.HasPrefix(c.GetRespHeader(fiber.HeaderContentType), fiber.MIMEApplicationJSON) || len(body) == 0 {
+			return nil
+		}
+		filtered, err := filterAnnotations(body, newAnnotationFilter(prefs.includeAnnotations))
+		if err != nil {
+			return nil
+		}
+		c.Response().SetBodyRaw(filtered)
+		return nil
+	}
+}
+
+// annotationPattern é um padrão de odata.include-annotations (*, Namespace.*, Namespace.Termo, com - para excluir)
+type annotationPattern struct {
+	pattern string
+	exclude bool
+}
+
+// annotationFilter decide quais anotações de instância são mantidas na resposta
+type annotationFilter []annotationPattern
+
+// newAnnotationFilter interpreta o valor de odata.include-annotations
+func newAnnotationFilter(value string) annotationFilter {
+	var filter annotationFilter
+	for _, pattern := range strings.Split(value, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		exclude := strings.HasPrefix(pattern, "-")
+		filter = append(filter, annotationPattern{pattern: strings.TrimPrefix(pattern, "-"), exclude: exclude})
+	}
+	return filter
+}
+
+// allows verifica se a anotação é incluída: vale o padrão mais específico e, no empate, a exclusão
+func (f annotationFilter) allows(term string) bool {
+	// O qualificador (Termo#Qualificador) não participa da comparação
+	term, _, _ = strings.Cut(term, "#")
+
+	best, allowed := -1, false
+	for _, p := range f {
+		specificity := -1
+		switch {
+		case p.pattern == "*":
+			specificity = 0
+		case strings.HasSuffix(p.pattern, ".*") && strings.HasPrefix(term, strings.TrimSuffix(p.pattern, "*")):
+			specificity = 1
+		case p.pattern == term:
+			specificity = 2
+		}
+
+		if specificity > best || (specificity == best && specificity >= 0 && p.exclude) {
+			best, allowed = specificity, !p.exclude
+		}
+	}
+	return allowed
+}
+
+// filterAnnotations remove do JSON as anotações de instância não incluídas, preservando a ordem das
+// propriedades. As informações de controle (@odata.*) são sempre mantidas
+func filterAnnotations(data []byte, filter annotationFilter) ([]byte, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return data, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	decoder.UseNumber()
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if trimmed[0] == '[' {
+		buf.WriteByte('[')
+		for first := true; decoder.More(); first = false {
+			var item json.RawMessage
+			if err := decoder.Decode(&item); err != nil {
+				return nil, err
+			}
+			filtered, err := filterAnnotations(item, filter)
+			if err != nil {
+				return nil, err
+			}
+			if !first {
+				buf.WriteByte(',')
+			}
+			buf.Write(filtered)
+		}
+		buf.WriteByte(']')
+		return buf.Bytes(), nil
+	}
+
+	buf.WriteByte('{')
+	first := true
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := token.(string)
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+
+		if at := strings.LastIndex(key, "@"); at >= 0 {
+			term := key[at+1:]
+			if !strings.HasPrefix(term, "odata.") && !filter.allows(term) {
+				continue
+			}
+		}
+
+		filtered, err := filterAnnotations(value, filter)
+		if err != nil {
+			return nil, err
+		}
+		name, _ := json.Marshal(key)
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(filtered)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,195 @@
+package odata
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePreferences(t *testing.T) {
+	prefs := parsePreferences(`return=minimal, respond-async, odata.include-annotations="Display.*,-Display.Hidden"`)
+	assert.Equal(t, ReturnMinimal, prefs.returnMode)
+	assert.True(t, prefs.respondAsync)
+	assert.True(t, prefs.hasIncludeAnnotations)
+	assert.Equal(t, "Display.*,-Display.Hidden", prefs.includeAnnotations)
+
+	assert.Empty(t, parsePreferences("return=other").returnMode)
+}
+
+func TestFilterAnnotations(t *testing.T) {
+	filter := newAnnotationFilter("Display.*,-Display.Hidden,Core.Description")
+	assert.True(t, filter.allows("Display.Label"))
+	assert.False(t, filter.allows("Display.Hidden"))
+	assert.True(t, filter.allows("Core.Description#short"))
+	assert.False(t, filter.allows("Core.Other"))
+
+	// O padrão mais específico prevalece sobre a exclusão geral
+	assert.True(t, newAnnotationFilter("-*,Core.Description").allows("Core.Description"))
+	assert.False(t, newAnnotationFilter("*,-Core.Description").allows("Core.Description"))
+
+	body := `{"@odata.context":"$metadata#Orders","value":[{"@odata.etag":"W/\"1\"","ID":1,"Name@Core.Other":"x","Name@Display.Label":"Nome","Name":"a"}]}`
+	filtered, err := filterAnnotations([]byte(body), filter)
+	require.NoError(t, err)
+	assert.Equal(t, `{"@odata.context":"$metadata#Orders","value":[{"@odata.etag":"W/\"1\"","ID":1,"Name@Display.Label":"Nome","Name":"a"}]}`, string(filtered))
+}
+
+func TestServer_PreferReturn(t *testing.T) {
+	server, services, _ := newDeepInsertTestServer(t)
+
+	req := httptest.NewRequest("POST", "/odata/OrderLines", strings.NewReader(`{"Product": "chair"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Prefer", "return=minimal")
+	resp, err := server.GetRouter().Test(req)
+	require.NoError(t, err)
+	require.Equal(t, 204, resp.StatusCode)
+	assert.Equal(t, "return=minimal", resp.Header.Get("Preference-Applied"))
+	assert.Contains(t, resp.Header.Get("OData-EntityId"), "/odata/OrderLines(1001)")
+	assert.Equal(t, resp.Header.Get("OData-EntityId"), resp.Header.Get("Location"))
+	require.Len(t, services.lines.created, 1)
+
+	req = httptest.NewRequest("PATCH", "/odata/OrderLines(1001)", strings.NewReader(`{"Product": "sofa"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Prefer", "return=representation")
+	resp, err = server.GetRouter().Test(req)
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "return=representation", resp.Header.Get("Preference-Applied"))
+	body, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(body), `"Product":"sofa"`)
+}
+
+func TestServer_PreferRespondAsync(t *testing.T) {
+	server, services, _ := newDeepInsertTestServer(t)
+	services.lines.seed(map[string]interface{}{"ID": int64(1), "Product": "chair"})
+
+	req := httptest.NewRequest("GET", "/odata/OrderLines", nil)
+	req.Header.Set("Prefer", "respond-async")
+	resp, err := server.GetRouter().Test(req)
+	require.NoError(t, err)
+	require.Equal(t, 202, resp.StatusCode)
+	assert.Equal(t, "respond-async", resp.Header.Get("Preference-Applied"))
+
+	monitor, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(monitor.Path, "/odata/$async/"))
+
+	// O monitor responde 202 até a conclusão e depois devolve a resposta final como application/http
+	var result string
+	for i := 0; i < 100 && result == ""; i++ {
+		resp, err = server.GetRouter().Test(httptest.NewRequest("GET", monitor.Path, nil))
+		require.NoError(t, err)
+		if resp.StatusCode == 200 {
+			raw, _ := io.ReadAll(resp.Body)
+			result = string(raw)
+			assert.Equal(t, "application/http", resp.Header.Get("Content-Type"))
+			break
+		}
+		require.Equal(t, 202, resp.StatusCode)
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, strings.HasPrefix(result, "HTTP/1.1 200 OK\r\n"))
+	assert.Contains(t, result, `"Product":"chair"`)
+
+	resp, err = server.GetRouter().Test(httptest.NewRequest("DELETE", monitor.Path, nil))
+	require.NoError(t, err)
+	assert.Equal(t, 204, resp.StatusCode)
+
+	resp, err = server.GetRouter().Test(httptest.NewRequest("GET", monitor.Path, nil))
+	require.NoError(t, err)
+	assert.Equal(t, 404, resp.StatusCode)
+}
+
+// asyncTestSlowService bloqueia as consultas até o cancelamento do contexto
+type asyncTestSlowService struct {
+	*deepInsertTestService
+	started  chan struct{}
+	canceled chan struct{}
+}
+
+func (s *asyncTestSlowService) Query(ctx context.Context, options QueryOptions) (*ODataResponse, error) {
+	s.started <- struct{}{}
+	<-ctx.Done()
+	close(s.canceled)
+	return nil, ctx.Err()
+}
+
+// startAsync inicia uma consulta assíncrona e retorna o caminho do monitor
+func startAsync(t *testing.T, server *Server, path, token string) (int, string) {
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("Prefer", "respond-async")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := server.GetRouter().Test(req)
+	require.NoError(t, err)
+	monitor, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return resp.StatusCode, monitor.Path
+}
+
+func TestServer_AsyncMonitorOwner(t *testing.T) {
+	server, _ := newJWTTestServer(t, time.Minute)
+	ana, err := server.jwtService.GenerateToken(&UserIdentity{Username: "ana"})
+	require.NoError(t, err)
+	bia, err := server.jwtService.GenerateToken(&UserIdentity{Username: "bia"})
+	require.NoError(t, err)
+
+	status, monitor := startAsync(t, server, "/odata/Notes", ana)
+	require.Equal(t, 202, status)
+
+	monitorStatus := func(method, token string) int {
+		req := httptest.NewRequest(method, monitor, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := server.GetRouter().Test(req)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	// O resultado só é entregue a quem iniciou a requisição
+	assert.Equal(t, 404, monitorStatus("GET", ""))
+	assert.Equal(t, 404, monitorStatus("GET", bia))
+	assert.Equal(t, 404, monitorStatus("DELETE", bia))
+	assert.Contains(t, []int{200, 202}, monitorStatus("GET", ana))
+	assert.Equal(t, 204, monitorStatus("DELETE", ana))
+}
+
+func TestServer_AsyncCancelAndLimit(t *testing.T) {
+	server, _, _ := newDeepInsertTestServer(t)
+	server.asyncJobs = newAsyncJobStore(time.Minute, 1)
+	slow := &asyncTestSlowService{
+		deepInsertTestService: newDeepInsertTestService(t, DeepInsertTestLine{}, 0),
+		started:               make(chan struct{}, 1),
+		canceled:              make(chan struct{}),
+	}
+	require.NoError(t, server.RegisterEntityWithService("Slow", slow))
+
+	status, monitor := startAsync(t, server, "/odata/Slow", "")
+	require.Equal(t, 202, status)
+	<-slow.started
+
+	// Com o limite atingido novas requisições assíncronas são recusadas
+	status, _ = startAsync(t, server, "/odata/Slow", "")
+	assert.Equal(t, 503, status)
+
+	// O DELETE no monitor cancela a consulta em andamento e libera o espaço
+	resp, err := server.GetRouter().Test(httptest.NewRequest("DELETE", monitor, nil))
+	require.NoError(t, err)
+	require.Equal(t, 204, resp.StatusCode)
+	select {
+	case <-slow.canceled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("asynchronous request was not canceled")
+	}
+
+	status, _ = startAsync(t, server, "/odata/OrderLines", "")
+	assert.Equal(t, 202, status)
+}
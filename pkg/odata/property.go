@@ -0,0 +1,222 @@
+package odata
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// defaultBinaryContentType é usado no $value de propriedades binárias sem contentType na tag odata
+const defaultBinaryContentType = "application/octet-stream"
+
+// handleProperty lida com o acesso a uma propriedade individual, como /Products(1)/Name e /Products(1)/Name/$value
+func (s *Server) handleProperty(c fiber.Ctx, target *navigationTarget) error {
+	switch c.Method() {
+	case "GET":
+		return s.handleGetProperty(c, target)
+	case "PUT":
+		return s.handlePutProperty(c, target)
+	case "DELETE":
+		return s.handleDeleteProperty(c, target)
+	default:
+		s.writeError(c, fiber.StatusMethodNotAllowed, "MethodNotAllowed", "Method not allowed")
+		return nil
+	}
+}
+
+// handleGetProperty retorna o valor da propriedade, em JSON ou bruto ($value)
+func (s *Server) handleGetProperty(c fiber.Ctx, target *navigationTarget) error {
+	entity, err := s.loadNavigationEntity(s.requestContext(c), target)
+	if err != nil {
+		return s.writeNavigationError(c, err)
+	}
+	s.setEntityETag(c, target.service.GetMetadata(), entity)
+
+	value, _ := entity.Get(target.property.Name)
+	if value == nil {
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+
+	if !target.rawValue {
+		resource := strings.TrimPrefix(c.Path(), s.config.RoutePrefix+"/")
+		return c.JSON(fiber.Map{
+			"@odata.context": "$metadata#" + resource,
+			"value":          value,
+		})
+	}
+
+	// Propriedades binárias são enviadas como estão, com o content type configurado
+	if target.property.Type == "[]byte" {
+		contentType := target.property.ContentType
+		if contentType == "" {
+			contentType = defaultBinaryContentType
+		}
+		c.Set(fiber.HeaderContentType, contentType)
+
+		switch v := value.(type) {
+		case []byte:
+			return c.Send(v)
+		case string:
+			return c.SendString(v)
+		}
+	}
+
+	c.Set(fiber.HeaderContentType, "text/plain; charset=utf-8")
+	return c.SendString(formatRawValue(value))
+}
+
+// handlePutProperty substitui o valor da propriedade
+func (s *Server) handlePutProperty(c fiber.Ctx, target *navigationTarget) error {
+	var value interface{}
+	var err error
+	if target.rawValue {
+		value, err = s.parseRawPropertyValue(c.Body(), *target.property)
+	} else {
+		value, err = parsePropertyPayload(c.Body(), *target.property)
+	}
+	if err != nil {
+		s.writeError(c, fiber.StatusBadRequest, "InvalidRequest", err.Error())
+		return nil
+	}
+
+	return s.updateProperty(c, target, value)
+}
+
+// handleDeleteProperty atribui null à propriedade
+func (s *Server) handleDeleteProperty(c fiber.Ctx, target *navigationTarget) error {
+	if target.rawValue {
+		s.writeError(c, fiber.StatusMethodNotAllowed, "MethodNotAllowed", "use DELETE on the property to set it to null")
+		return nil
+	}
+	if !target.property.IsNullable {
+		s.writeError(c, fiber.StatusBadRequest, "InvalidRequest", fmt.Sprintf("property '%s' is not nullable", target.property.Name))
+		return nil
+	}
+
+	return s.updateProperty(c, target, nil)
+}
+
+// updateProperty grava o novo valor da propriedade na entidade endereçada
+func (s *Server) updateProperty(c fiber.Ctx, target *navigationTarget, value interface{}) error {
+	if target.property.IsKey {
+		s.writeError(c, fiber.StatusBadRequest, "InvalidRequest", fmt.Sprintf("key property '%s' cannot be modified", target.property.Name))
+		return nil
+	}
+	if isReadOnlyProperty(target.service.GetMetadata(), *target.property, false) {
+		s.writeError(c, fiber.StatusBadRequest, "ReadOnlyProperty", fmt.Sprintf("property '%s' is read-only", target.property.Name))
+		return nil
+	}
+
+	entity, err := s.loadNavigationEntity(s.requestContext(c), target)
+	if err != nil {
+		return s.writeNavigationError(c, err)
+	}
+	keys := entityKeyValues(target.service.GetMetadata(), entity)
+
+	ctx, ok := s.checkIfMatch(c, s.requestContext(c), target.service, keys)
+	if !ok {
+		return nil
+	}
+
+	updatedEntity, err := s.modifyEntity(ctx, s.entitySetNameOf(target.service.GetMetadata()), target.service, keys, map[string]interface{}{target.property.Name: value}, entity)
+	if err != nil {
+		var constraint *PropertyConstraintError
+		var validation *ValidationError
+		if errors.As(err, &validation) {
+			s.writeValidationError(c, validation)
+		} else if errors.As(err, &constraint) {
+			s.writeConstraintError(c, constraint)
+		} else if errors.Is(err, ErrPreconditionFailed) {
+			s.writeError(c, fiber.StatusPreconditionFailed, "PreconditionFailed", "The entity has been modified")
+		} else {
+			s.writeError(c, fiber.StatusInternalServerError, "UpdateError", err.Error())
+		}
+		return nil
+	}
+
+	s.setEntityETag(c, target.service.GetMetadata(), updatedEntity)
+
+	// Por padrão a resposta é vazia; return=representation devolve o novo valor da propriedade
+	switch parsePreferences(c.Get("Prefer")).returnMode {
+	case ReturnMinimal:
+		addPreferenceApplied(c, "return="+ReturnMinimal)
+	case ReturnRepresentation:
+		if updated, ok := updatedEntity.(*OrderedEntity); ok && !target.rawValue {
+			if value, _ := updated.Get(target.property.Name); value != nil {
+				addPreferenceApplied(c, "return="+ReturnRepresentation)
+				resource := strings.TrimPrefix(c.Path(), s.config.RoutePrefix+"/")
+				return c.JSON(fiber.Map{
+					"@odata.context": "$metadata#" + resource,
+					"value":          value,
+				})
+			}
+		}
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// parsePropertyPayload lê o corpo {"value": ...} de um PUT na propriedade
+func parsePropertyPayload(body []byte, prop PropertyMetadata) (interface{}, error) {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid JSON")
+	}
+
+	value, ok := payload["value"]
+	if !ok {
+		return nil, fmt.Errorf("the request body must contain 'value'")
+	}
+	if value == nil {
+		if !prop.IsNullable {
+			return nil, fmt.Errorf("property '%s' is not nullable", prop.Name)
+		}
+		return nil, nil
+	}
+
+	// Edm.Binary é representado em base64 no JSON
+	if prop.Type == "[]byte" {
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("property '%s' expects a base64 string", prop.Name)
+		}
+		if decoded, err := base64.StdEncoding.DecodeString(str); err == nil {
+			return decoded, nil
+		}
+		decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(str, "="))
+		if err != nil {
+			return nil, fmt.Errorf("property '%s' expects a base64 string", prop.Name)
+		}
+		return decoded, nil
+	}
+
+	return value, nil
+}
+
+// parseRawPropertyValue converte o corpo bruto de um PUT em $value no tipo da propriedade
+func (s *Server) parseRawPropertyValue(body []byte, prop PropertyMetadata) (interface{}, error) {
+	switch prop.Type {
+	case "[]byte":
+		return append([]byte(nil), body...), nil
+	case "string":
+		return string(body), nil
+	default:
+		return s.parseKeyValue(strings.TrimSpace(string(body)), prop.Type)
+	}
+}
+
+// formatRawValue formata um valor primitivo para o $value em texto
+func formatRawValue(value interface{}) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
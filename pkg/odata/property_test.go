@@ -0,0 +1,101 @@
+package odata
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type PropertyTestDocument struct {
+	TableName string  `table:"documents"`
+	ID        int64   `json:"ID" column:"id" primaryKey:"idGenerator:none"`
+	Title     string  `json:"Title" column:"title"`
+	Notes     *string `json:"Notes" column:"notes" odata:"null"`
+	Content   []byte  `json:"Content" column:"content" odata:"null; contentType:image/png"`
+}
+
+func newPropertyTestServer(t *testing.T) (*Server, *navigationTestProvider) {
+	server, provider, _ := newNavigationTestServerWithDB(t)
+	require.NoError(t, server.RegisterEntity("Documents", PropertyTestDocument{}))
+	return server, provider
+}
+
+func TestServer_PropertyAccess(t *testing.T) {
+	server, _ := newPropertyTestServer(t)
+
+	resp, err := server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Documents(1)/Title", nil))
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "$metadata#Documents(1)/Title", body["@odata.context"])
+	assert.Equal(t, "Report", body["value"])
+
+	resp, err = server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Documents(1)/Title/$value", nil))
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "text/plain; charset=utf-8", resp.Header.Get("Content-Type"))
+	raw, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "Report", string(raw))
+
+	// Propriedades binárias são enviadas como bytes com o content type da tag
+	resp, err = server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Documents(1)/Content/$value", nil))
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+	raw, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, []byte("\x89PNG"), raw)
+
+	// Valores nulos respondem sem conteúdo
+	resp, err = server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Documents(1)/Notes", nil))
+	require.NoError(t, err)
+	assert.Equal(t, 204, resp.StatusCode)
+
+	resp, err = server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Documents(1)/Missing", nil))
+	require.NoError(t, err)
+	assert.Equal(t, 404, resp.StatusCode)
+
+	resp, err = server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Documents(1)/$value", nil))
+	require.NoError(t, err)
+	assert.Equal(t, 400, resp.StatusCode)
+}
+
+func TestServer_PropertyUpdate(t *testing.T) {
+	server, provider := newPropertyTestServer(t)
+
+	req := httptest.NewRequest("PUT", "/odata/Documents(1)/Title", strings.NewReader(`{"value": "Summary"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := server.GetRouter().Test(req)
+	require.NoError(t, err)
+	require.Equal(t, 204, resp.StatusCode)
+	assert.Equal(t, map[string]interface{}{"Title": "Summary"}, provider.update)
+	assert.EqualValues(t, map[string]interface{}{"ID": int64(1)}, provider.updateKeys)
+
+	req = httptest.NewRequest("PUT", "/odata/Documents(1)/Content/$value", strings.NewReader("\x89PNG2"))
+	req.Header.Set("Content-Type", "image/png")
+	resp, err = server.GetRouter().Test(req)
+	require.NoError(t, err)
+	require.Equal(t, 204, resp.StatusCode)
+	assert.Equal(t, []byte("\x89PNG2"), provider.update["Content"])
+
+	resp, err = server.GetRouter().Test(httptest.NewRequest("DELETE", "/odata/Documents(1)/Notes", nil))
+	require.NoError(t, err)
+	require.Equal(t, 204, resp.StatusCode)
+	assert.Equal(t, map[string]interface{}{"Notes": nil}, provider.update)
+
+	// Propriedades obrigatórias e chaves não podem ser anuladas ou alteradas
+	resp, err = server.GetRouter().Test(httptest.NewRequest("DELETE", "/odata/Documents(1)/Title", nil))
+	require.NoError(t, err)
+	assert.Equal(t, 400, resp.StatusCode)
+
+	req = httptest.NewRequest("PUT", "/odata/Documents(1)/ID", strings.NewReader(`{"value": 2}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = server.GetRouter().Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, 400, resp.StatusCode)
+}
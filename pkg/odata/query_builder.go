@@ -68,6 +68,11 @@ func NewQueryBuilder(dialect string) *QueryBuilder {
 		prepareMap: make(PrepareMap),
 	}
 
+	// Nomes de driver do PostgreSQL usam o mesmo dialeto
+	if qb.dialect == "pgx" || qb.dialect == "postgres" {
+		qb.dialect = "postgresql"
+	}
+
 	// Configura os mapas baseado no dialeto
 	switch qb.dialect {
 	case "mysql":
@@ -222,9 +227,25 @@ func (qb *QueryBuilder) buildNodeExpression(ctx context.Context, node *ParseNode
 
 	switch node.Token.Type {
 	case int(FilterTokenProperty):
+		// Caminhos, $it e propriedades dentro de lambdas dependem do escopo
+		if qb.isScopedProperty(ctx, node) {
+			sql, err := qb.buildScopedPropertyExpression(ctx, node, metadata)
+			return sql, []interface{}{}, err
+		}
+
 		// Propriedade - mapear para nome da coluna
 		return qb.buildPropertyExpression(node, metadata)
 
+	case int(FilterTokenLambda):
+		// any/all - subquery correlacionada
+		var args []interface{}
+		sql, err := qb.buildLambdaExpression(ctx, node, metadata, func(ctx context.Context, body *ParseNode) (string, error) {
+			bodySQL, bodyArgs, err := qb.buildNodeExpression(ctx, body, metadata)
+			args = append(args, bodyArgs...)
+			return bodySQL, err
+		})
+		return sql, args, err
+
 	case int(FilterTokenString):
 		// String literal
 		value := strings.Trim(node.Token.Value, "'")
@@ -273,10 +294,21 @@ func (qb *QueryBuilder) buildNodeExpressionNamed(ctx context.Context, node *Pars
 
 	switch node.Token.Type {
 	case int(FilterTokenProperty):
+		// Caminhos, $it e propriedades dentro de lambdas dependem do escopo
+		if qb.isScopedProperty(ctx, node) {
+			return qb.buildScopedPropertyExpression(ctx, node, metadata)
+		}
+
 		// Propriedade - mapear para nome da coluna
 		sql, _, err := qb.buildPropertyExpression(node, metadata)
 		return sql, err
 
+	case int(FilterTokenLambda):
+		// any/all - subquery correlacionada
+		return qb.buildLambdaExpression(ctx, node, metadata, func(ctx context.Context, body *ParseNode) (string, error) {
+			return qb.buildNodeExpressionNamed(ctx, body, metadata, namedArgs)
+		})
+
 	case int(FilterTokenString):
 		// String literal
 		value := strings.Trim(node.Token.Value, "'")
@@ -524,10 +556,10 @@ func (qb *QueryBuilder) buildFunctionExpression(ctx context.Context, node *Parse
 // BuildSelectClause constrói cláusula SELECT
 func (qb *QueryBuilder) BuildSelectClause(metadata EntityMetadata, selectOptions []string) string {
 	if len(selectOptions) == 0 {
-		// Seleciona todas as colunas não-navegacionais
+		// Seleciona todas as colunas não-navegacionais; Lazy só vem quando citada no $select
 		columns := make([]string, 0)
 		for _, prop := range metadata.Properties {
-			if !prop.IsNavigation {
+			if !prop.IsNavigation && !prop.HasPropFlag(PropLazy) {
 				columnName := prop.ColumnName
 				if columnName == "" {
 					columnName = prop.Name
@@ -665,6 +697,9 @@ func (qb *QueryBuilder) BuildLimitClause(top, skip int) string {
 			return fmt.Sprintf("LIMIT %d OFFSET %d", top, skip)
 		} else if top > 0 {
 			return fmt.Sprintf("LIMIT %d", top)
+		} else if skip > 0 && qb.dialect == "mysql" {
+			// MySQL não aceita OFFSET sem LIMIT
+			return fmt.Sprintf("LIMIT 18446744073709551615 OFFSET %d", skip)
 		} else if skip > 0 {
 			return fmt.Sprintf("OFFSET %d", skip)
 		}
@@ -0,0 +1,100 @@
+package odata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// QueryKind identifica a consulta interceptada pelo evento EntityQuerying
+type QueryKind string
+
+const (
+	QueryKindCollection QueryKind = "collection" // Coleção, inclusive por navegação
+	QueryKindEntity     QueryKind = "entity"     // Entidade única
+	QueryKindCount      QueryKind = "count"      // Segmento /$count
+	QueryKindExpand     QueryKind = "expand"     // Entidades relacionadas de um $expand
+	QueryKindSubscribe  QueryKind = "subscribe"  // Assinatura de alterações em /$subscribe
+)
+
+// EntityQueryingArgs argumentos para evento OnEntityQuerying, disparado antes da consulta.
+// QueryOptions pode ser alterado pelo handler; Cancel recusa a consulta com 403
+type EntityQueryingArgs struct {
+	*BaseEventArgs
+	Kind         QueryKind
+	Keys         map[string]interface{} // Chaves da entidade única (nil nas coleções)
+	QueryOptions QueryOptions
+}
+
+// AddFilter combina a condição com o $filter do cliente usando AND
+func (e *EntityQueryingArgs) AddFilter(condition *ParseNode) {
+	if condition != nil {
+		e.QueryOptions.Filter = combineFilters(e.QueryOptions.Filter, condition)
+	}
+}
+
+// AddFilterExpression interpreta uma expressão na sintaxe do $filter e a combina com o filtro do cliente
+func (e *EntityQueryingArgs) AddFilterExpression(filter string) error {
+	ctx := context.Background()
+	if e.Context != nil && e.Context.Context != nil {
+		ctx = e.Context.Context
+	}
+
+	parsed, err := ParseFilterString(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("invalid filter expression: %w", err)
+	}
+	if parsed != nil {
+		e.AddFilter(parsed.Tree)
+	}
+	return nil
+}
+
+// NewEntityQueryingArgs cria argumentos para evento EntityQuerying
+func NewEntityQueryingArgs(ctx *EventContext, kind QueryKind, keys map[string]interface{}, options QueryOptions) *EntityQueryingArgs {
+	return &EntityQueryingArgs{
+		BaseEventArgs: &BaseEventArgs{
+			Context:    ctx,
+			EventType:  EventEntityQuerying,
+			EntityName: ctx.EntityName,
+			canCancel:  true,
+		},
+		Kind:         kind,
+		Keys:         keys,
+		QueryOptions: options,
+	}
+}
+
+// interceptQuery dispara EntityQuerying antes de uma consulta e aplica as opções alteradas pelos handlers
+func (s *Server) interceptQuery(ctx context.Context, entityName string, kind QueryKind, keys map[string]interface{}, options *QueryOptions) error {
+	if !s.eventManager.HasHandlers(entityName, EventEntityQuerying) {
+		return nil
+	}
+
+	args := NewEntityQueryingArgs(s.eventContext(ctx, entityName), kind, keys, *options)
+	if err := s.eventManager.Emit(args); err != nil {
+		return err
+	}
+	*options = args.QueryOptions
+	return nil
+}
+
+// isQueryVetoed verifica se o erro vem de um handler de EntityQuerying que recusou a consulta
+func isQueryVetoed(err error) (*EventCanceledError, bool) {
+	var canceled *EventCanceledError
+	if errors.As(err, &canceled) && canceled.EventType == EventEntityQuerying {
+		return canceled, true
+	}
+	return nil, false
+}
+
+// writeQueryError responde a falha de uma consulta: 403 quando recusada em EntityQuerying
+func (s *Server) writeQueryError(c fiber.Ctx, code string, err error) {
+	if canceled, ok := isQueryVetoed(err); ok {
+		s.writeError(c, fiber.StatusForbidden, "QueryForbidden", canceled.Reason)
+		return
+	}
+	s.writeError(c, fiber.StatusInternalServerError, code, err.Error())
+}
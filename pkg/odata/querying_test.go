@@ -0,0 +1,92 @@
+package odata
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_EntityQueryingRewritesOptions(t *testing.T) {
+	server, provider := newNavigationTestServer(t)
+
+	var kinds []QueryKind
+	var keys map[string]interface{}
+	server.OnEntityQuerying("Orders", func(args EventArgs) error {
+		querying := args.(*EntityQueryingArgs)
+		kinds = append(kinds, querying.Kind)
+		return querying.AddFilterExpression("CustomerID eq 1")
+	})
+	server.OnEntityQuerying("Customers", func(args EventArgs) error {
+		querying := args.(*EntityQueryingArgs)
+		kinds = append(kinds, querying.Kind)
+		keys = querying.Keys
+		querying.AddFilter(keysetComparison("eq", "Name", "Ann"))
+		return nil
+	})
+
+	resp, err := server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Orders?$filter=ID%20gt%205", nil))
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	require.Len(t, provider.filters, 1)
+	assert.Equal(t, "((id > :param1) AND (customer_id = :param2))", provider.filters[0])
+
+	// Sem $filter do cliente, a condição do handler é o filtro inteiro
+	resp, err = server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Orders", nil))
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "(customer_id = :param1)", provider.filters[1])
+
+	resp, err = server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Customers(1)", nil))
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "((id = :param1) AND (name = :param2))", provider.filters[2])
+	assert.Equal(t, map[string]interface{}{"ID": int64(1)}, keys)
+
+	resp, err = server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Orders/$count", nil))
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "(customer_id = :param1)", provider.filters[3])
+
+	assert.Equal(t, []QueryKind{QueryKindCollection, QueryKindCollection, QueryKindEntity, QueryKindCount}, kinds)
+}
+
+func TestServer_EntityQueryingVeto(t *testing.T) {
+	server, _ := newNavigationTestServer(t)
+
+	server.OnEntityQuerying("Customers", func(args EventArgs) error {
+		if args.(*EntityQueryingArgs).Kind == QueryKindEntity {
+			args.Cancel("customers are private")
+		}
+		return nil
+	})
+	server.OnEntityQuerying("Orders", func(args EventArgs) error {
+		if kind := args.(*EntityQueryingArgs).Kind; kind == QueryKindExpand || kind == QueryKindCount {
+			args.Cancel("orders are restricted")
+		}
+		return nil
+	})
+
+	for _, url := range []string{
+		"/odata/Customers(1)",
+		"/odata/Orders(10)/Customer",
+		"/odata/Orders/$count",
+		"/odata/Customers?$expand=Orders",
+	} {
+		resp, err := server.GetRouter().Test(httptest.NewRequest("GET", url, nil))
+		require.NoError(t, err)
+		require.Equal(t, 403, resp.StatusCode, url)
+
+		var body ODataResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		require.NotNil(t, body.Error, url)
+		assert.Equal(t, "QueryForbidden", body.Error.Code, url)
+	}
+
+	// A coleção continua liberada
+	resp, err := server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Orders", nil))
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+}
@@ -0,0 +1,493 @@
+package odata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// ErrReferenceNotFound indica que o vínculo entre as entidades não existe
+var ErrReferenceNotFound = errors.New("reference not found")
+
+// ReferenceService é implementado pelos serviços capazes de consultar e manter vínculos
+// N:N em tabelas de junção (ManyAssociationMetadata.JoinTable)
+type ReferenceService interface {
+	QueryNavigation(ctx context.Context, entity *OrderedEntity, navigation string, option ExpandOption) ([]any, int64, error)
+	LinkEntities(ctx context.Context, navigation string, parentValue, relatedValue interface{}) error
+	UnlinkEntities(ctx context.Context, navigation string, parentValue, relatedValue interface{}) error
+}
+
+// BuildJoinTableInsert constrói o INSERT que vincula duas entidades na tabela de junção
+func (qb *QueryBuilder) BuildJoinTableInsert(many *ManyAssociationMetadata, parentValue, relatedValue interface{}) (string, []interface{}, error) {
+	if err := validateJoinTable(many); err != nil {
+		return "", nil, err
+	}
+
+	namedArgs := NewNamedArgs(qb.dialect)
+	query := fmt.Sprintf("INSERT INTO %s (%s, %s) VALUES (%s, %s)",
+		many.JoinTable, many.JoinColumn, many.InverseJoinColumn,
+		namedArgs.AddArg(parentValue), namedArgs.AddArg(relatedValue))
+
+	return query, namedArgs.GetArgs(), nil
+}
+
+// BuildJoinTableDelete constrói o DELETE que desfaz o vínculo entre duas entidades na tabela de junção
+func (qb *QueryBuilder) BuildJoinTableDelete(many *ManyAssociationMetadata, parentValue, relatedValue interface{}) (string, []interface{}, error) {
+	if err := validateJoinTable(many); err != nil {
+		return "", nil, err
+	}
+
+	namedArgs := NewNamedArgs(qb.dialect)
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = %s AND %s = %s",
+		many.JoinTable, many.JoinColumn, namedArgs.AddArg(parentValue),
+		many.InverseJoinColumn, namedArgs.AddArg(relatedValue))
+
+	return query, namedArgs.GetArgs(), nil
+}
+
+// validateJoinTable verifica se os metadados da tabela de junção estão completos
+func validateJoinTable(many *ManyAssociationMetadata) error {
+	if many == nil || many.JoinTable == "" {
+		return fmt.Errorf("relationship has no join table")
+	}
+	if many.JoinColumn == "" || many.InverseJoinColumn == "" {
+		return fmt.Errorf("join table '%s' requires joinColumn and inverseJoinColumn", many.JoinTable)
+	}
+	return nil
+}
+
+// QueryNavigation retorna as entidades relacionadas a uma entidade pela propriedade de navegação
+func (s *BaseEntityService) QueryNavigation(ctx context.Context, entity *OrderedEntity, navigation string, option ExpandOption) ([]any, int64, error) {
+	navProp, err := s.findNavigationProperty(navigation)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	option.Property = navProp.Name
+	related, err := s.findRelatedEntitiesWithOrder(ctx, navProp, []any{entity}, option)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find related entities for property %s: %w", navProp.Name, err)
+	}
+
+	key := ""
+	if value, exists := entity.Get(related.parentProperty); exists && value != nil {
+		key = expandKeyValue(value)
+	}
+
+	entities := related.entities[key]
+	if entities == nil {
+		entities = []any{}
+	}
+	return entities, related.counts[key], nil
+}
+
+// LinkEntities vincula duas entidades na tabela de junção da propriedade de navegação
+func (s *BaseEntityService) LinkEntities(ctx context.Context, navigation string, parentValue, relatedValue interface{}) error {
+	navProp, err := s.findNavigationProperty(navigation)
+	if err != nil {
+		return err
+	}
+
+	query, args, err := s.queryBuilder().BuildJoinTableInsert(navProp.ManyAssociation, parentValue, relatedValue)
+	if err != nil {
+		return fmt.Errorf("failed to build link query: %w", err)
+	}
+
+	if _, err := s.executeExec(ctx, query, args); err != nil {
+		return fmt.Errorf("failed to execute link: %w", err)
+	}
+	return nil
+}
+
+// UnlinkEntities remove o vínculo entre duas entidades na tabela de junção da propriedade de navegação
+func (s *BaseEntityService) UnlinkEntities(ctx context.Context, navigation string, parentValue, relatedValue interface{}) error {
+	navProp, err := s.findNavigationProperty(navigation)
+	if err != nil {
+		return err
+	}
+
+	query, args, err := s.queryBuilder().BuildJoinTableDelete(navProp.ManyAssociation, parentValue, relatedValue)
+	if err != nil {
+		return fmt.Errorf("failed to build unlink query: %w", err)
+	}
+
+	result, err := s.executeExec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("failed to execute unlink: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrReferenceNotFound
+	}
+	return nil
+}
+
+// findNavigationProperty localiza a propriedade de navegação pelo nome (case-insensitive)
+func (s *BaseEntityService) findNavigationProperty(name string) (*PropertyMetadata, error) {
+	for i := range s.metadata.Properties {
+		if s.metadata.Properties[i].IsNavigation && strings.EqualFold(s.metadata.Properties[i].Name, name) {
+			return &s.metadata.Properties[i], nil
+		}
+	}
+	return nil, fmt.Errorf("navigation property %s not found", name)
+}
+
+// handleReference lida com os vínculos de uma navegação ($ref)
+func (s *Server) handleReference(c fiber.Ctx, target *navigationTarget) error {
+	if c.Method() == "GET" {
+		return s.handleGetReference(c, target)
+	}
+
+	if target.navigation == nil {
+		s.writeError(c, fiber.StatusBadRequest, "InvalidPath", "$ref can only be modified through a navigation property")
+		return nil
+	}
+
+	// O vínculo também altera a entidade de origem
+	if err := s.checkEntityReadOnly(target.parentEntityName, c.Method()); err != nil {
+		return err
+	}
+
+	switch c.Method() {
+	case "PUT":
+		return s.handleSetReference(c, target)
+	case "POST":
+		return s.handleAddReference(c, target)
+	case "DELETE":
+		return s.handleDeleteReference(c, target)
+	default:
+		s.writeError(c, fiber.StatusMethodNotAllowed, "MethodNotAllowed", "Method not allowed")
+		return nil
+	}
+}
+
+// handleGetReference retorna o @odata.id das entidades vinculadas
+func (s *Server) handleGetReference(c fiber.Ctx, target *navigationTarget) error {
+	ctx := s.requestContext(c)
+
+	if target.single {
+		entity, err := s.loadNavigationEntity(ctx, target)
+		if err != nil {
+			var pathErr *resourcePathError
+			if !target.keyed && errors.As(err, &pathErr) && pathErr.status == fiber.StatusNotFound {
+				return c.SendStatus(fiber.StatusNoContent)
+			}
+			return s.writeNavigationError(c, err)
+		}
+		return c.JSON(fiber.Map{
+			"@odata.context": "$metadata#$ref",
+			"@odata.id":      s.buildEntityURL(c, target.service, entity),
+		})
+	}
+
+	entities, err := s.navigationEntities(c, target)
+	if err != nil {
+		return s.writeNavigationError(c, err)
+	}
+
+	references := make([]fiber.Map, 0, len(entities))
+	for _, entity := range entities {
+		references = append(references, fiber.Map{"@odata.id": s.buildEntityURL(c, target.service, entity)})
+	}
+	return c.JSON(fiber.Map{
+		"@odata.context": "$metadata#Collection($ref)",
+		"value":          references,
+	})
+}
+
+// handleSetReference lida com PUT em uma navegação simples, apontando a chave estrangeira para outra entidade
+func (s *Server) handleSetReference(c fiber.Ctx, target *navigationTarget) error {
+	if target.navigation.IsCollection {
+		s.writeError(c, fiber.StatusMethodNotAllowed, "MethodNotAllowed", "use POST to add references to a collection")
+		return nil
+	}
+
+	referenced, err := s.readReference(c, target)
+	if err != nil {
+		return s.writeNavigationError(c, err)
+	}
+
+	value, _ := referenced.Get(target.keyProperty)
+	return s.updateParentReference(c, target, value)
+}
+
+// handleAddReference lida com POST em uma navegação de coleção, vinculando uma entidade existente
+func (s *Server) handleAddReference(c fiber.Ctx, target *navigationTarget) error {
+	if !target.navigation.IsCollection || target.keyed {
+		s.writeError(c, fiber.StatusMethodNotAllowed, "MethodNotAllowed", "use PUT to set a single-valued reference")
+		return nil
+	}
+	if target.parentValue == nil {
+		s.writeError(c, fiber.StatusBadRequest, "InvalidPath", "the parent entity has no value for the relationship")
+		return nil
+	}
+
+	referenced, err := s.readReference(c, target)
+	if err != nil {
+		return s.writeNavigationError(c, err)
+	}
+
+	ctx := s.requestContext(c)
+	if target.joinTable {
+		relatedValue, _ := referenced.Get(expandKeyProperty(target.service.GetMetadata()))
+		if err := s.linkEntities(ctx, target, relatedValue); err != nil {
+			s.writeError(c, fiber.StatusInternalServerError, "ReferenceError", err.Error())
+			return nil
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+
+	// 1:N: a chave estrangeira fica na entidade referenciada
+	keys := entityKeyValues(target.service.GetMetadata(), referenced)
+	if _, err := s.modifyEntity(ctx, target.entityName, target.service, keys, map[string]interface{}{target.keyProperty: target.parentValue}, referenced); err != nil {
+		s.writeError(c, fiber.StatusInternalServerError, "ReferenceError", err.Error())
+		return nil
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// handleDeleteReference desfaz o vínculo de uma navegação simples ou de um membro da coleção
+func (s *Server) handleDeleteReference(c fiber.Ctx, target *navigationTarget) error {
+	ctx := s.requestContext(c)
+
+	if !target.navigation.IsCollection {
+		return s.updateParentReference(c, target, nil)
+	}
+
+	// O membro vem da chave no caminho ou do parâmetro $id
+	if !target.keyed {
+		id := c.Query("$id")
+		if id == "" {
+			s.writeError(c, fiber.StatusBadRequest, "InvalidReference", "$id is required to remove a reference from a collection")
+			return nil
+		}
+		key, err := s.parseReferenceID(id, target.entityName)
+		if err != nil {
+			return s.writeNavigationError(c, err)
+		}
+		if err := s.navigateToKey(target, key); err != nil {
+			return s.writeNavigationError(c, err)
+		}
+	}
+
+	if target.parentValue == nil {
+		s.writeError(c, fiber.StatusNotFound, "ReferenceNotFound", "Reference not found")
+		return nil
+	}
+
+	if target.joinTable {
+		relatedValue := target.keys[expandKeyProperty(target.service.GetMetadata())]
+		if err := s.unlinkEntities(ctx, target, relatedValue); err != nil {
+			if errors.Is(err, ErrReferenceNotFound) {
+				s.writeError(c, fiber.StatusNotFound, "ReferenceNotFound", "Reference not found")
+				return nil
+			}
+			s.writeError(c, fiber.StatusInternalServerError, "ReferenceError", err.Error())
+			return nil
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+
+	// 1:N: o membro precisa estar vinculado à entidade de origem
+	member, err := s.loadNavigationEntity(ctx, target)
+	if err != nil {
+		return s.writeNavigationError(c, err)
+	}
+	if prop, err := findFilterProperty(target.service.GetMetadata(), target.keyProperty); err == nil && !prop.IsNullable {
+		s.writeError(c, fiber.StatusBadRequest, "InvalidReference", fmt.Sprintf("property '%s' is required and cannot be unlinked", prop.Name))
+		return nil
+	}
+
+	keys := entityKeyValues(target.service.GetMetadata(), member)
+	if _, err := s.modifyEntity(ctx, target.entityName, target.service, keys, map[string]interface{}{target.keyProperty: nil}, member); err != nil {
+		s.writeError(c, fiber.StatusInternalServerError, "ReferenceError", err.Error())
+		return nil
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// updateParentReference altera a chave estrangeira de uma navegação simples na entidade de origem
+func (s *Server) updateParentReference(c fiber.Ctx, target *navigationTarget, value interface{}) error {
+	if target.keyed {
+		s.writeError(c, fiber.StatusBadRequest, "InvalidPath", "single-valued references do not accept a key")
+		return nil
+	}
+
+	metadata := target.parentService.GetMetadata()
+	if value == nil {
+		if prop, err := findFilterProperty(metadata, target.parentProperty); err == nil && !prop.IsNullable {
+			s.writeError(c, fiber.StatusBadRequest, "InvalidReference", fmt.Sprintf("property '%s' is required and cannot be unlinked", prop.Name))
+			return nil
+		}
+	}
+
+	keys := entityKeyValues(metadata, target.parent)
+	if _, err := s.modifyEntity(s.requestContext(c), target.parentEntityName, target.parentService, keys, map[string]interface{}{target.parentProperty: value}, target.parent); err != nil {
+		s.writeError(c, fiber.StatusInternalServerError, "ReferenceError", err.Error())
+		return nil
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// readReference lê o corpo {"@odata.id": "..."} e carrega a entidade referenciada
+func (s *Server) readReference(c fiber.Ctx, target *navigationTarget) (*OrderedEntity, error) {
+	var body map[string]interface{}
+	if err := c.Bind().Body(&body); err != nil {
+		return nil, &resourcePathError{fiber.StatusBadRequest, "InvalidRequest", "Invalid JSON"}
+	}
+
+	id, _ := body["@odata.id"].(string)
+	if id == "" {
+		return nil, &resourcePathError{fiber.StatusBadRequest, "InvalidReference", "@odata.id is required"}
+	}
+
+	key, err := s.parseReferenceID(id, target.entityName)
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := &navigationTarget{entityName: target.entityName, service: target.service}
+	if err := s.navigateToKey(referenced, key); err != nil {
+		return nil, err
+	}
+	return s.loadNavigationEntity(s.requestContext(c), referenced)
+}
+
+// parseReferenceID extrai a chave de um @odata.id, absoluto ou relativo, do entity set esperado
+func (s *Server) parseReferenceID(id, entityName string) (string, error) {
+	path := id
+	if parsed, err := url.Parse(id); err == nil && parsed.Path != "" {
+		path = parsed.Path
+	}
+	path = strings.TrimPrefix(path, s.config.RoutePrefix)
+
+	segments, err := ParseResourcePath(path)
+	if err != nil || len(segments) != 1 || !segments[0].HasKey {
+		return "", &resourcePathError{fiber.StatusBadRequest, "InvalidReference", fmt.Sprintf("invalid entity id '%s'", id)}
+	}
+	if segments[0].Name != entityName {
+		return "", &resourcePathError{fiber.StatusBadRequest, "InvalidReference", fmt.Sprintf("entity id '%s' does not belong to '%s'", id, entityName)}
+	}
+	return segments[0].Key, nil
+}
+
+// navigationEntities consulta as entidades de uma navegação de coleção aplicando as opções da requisição
+func (s *Server) navigationEntities(c fiber.Ctx, target *navigationTarget) ([]any, error) {
+	options, err := s.parseQueryOptions(c)
+	if err != nil {
+		return nil, &resourcePathError{fiber.StatusBadRequest, "InvalidQuery", err.Error()}
+	}
+
+	if target.joinTable {
+		entities, _, err := s.queryJoinTable(c, target, options, false)
+		return entities, err
+	}
+
+	if target.condition == nil {
+		return []any{}, nil
+	}
+	options.Filter = combineFilters(options.Filter, target.condition)
+
+	response, err := target.service.Query(s.requestContext(c), options)
+	if err != nil {
+		return nil, &resourcePathError{fiber.StatusInternalServerError, "QueryError", err.Error()}
+	}
+	entities, _ := response.Value.([]any)
+	return entities, nil
+}
+
+// queryJoinTable consulta uma navegação N:N pela tabela de junção
+func (s *Server) queryJoinTable(c fiber.Ctx, target *navigationTarget, options QueryOptions, count bool) ([]any, int64, error) {
+	references, ok := target.parentService.(ReferenceService)
+	if !ok {
+		return nil, 0, &resourcePathError{fiber.StatusNotImplemented, "NotImplemented", fmt.Sprintf("'%s' does not support join table navigation", target.parentEntityName)}
+	}
+
+	option := ExpandOption{
+		Filter:  c.Query("$filter"),
+		OrderBy: c.Query("$orderby"),
+		Top:     GetTopValue(options.Top),
+		Skip:    GetSkipValue(options.Skip),
+		Count:   count,
+	}
+
+	entities, total, err := references.QueryNavigation(s.requestContext(c), target.parent, target.navigation.Name, option)
+	if err != nil {
+		return nil, 0, &resourcePathError{fiber.StatusInternalServerError, "QueryError", err.Error()}
+	}
+	return entities, total, nil
+}
+
+// isJoinTableMember verifica se a entidade endereçada pela chave está vinculada à origem pela tabela de junção
+func (s *Server) isJoinTableMember(ctx context.Context, target *navigationTarget) (bool, error) {
+	references, ok := target.parentService.(ReferenceService)
+	if !ok {
+		return false, &resourcePathError{fiber.StatusNotImplemented, "NotImplemented", fmt.Sprintf("'%s' does not support join table navigation", target.parentEntityName)}
+	}
+
+	metadata := target.service.GetMetadata()
+	var terms []string
+	for _, name := range keyPropertyNames(metadata) {
+		terms = append(terms, fmt.Sprintf("%s eq %s", name, formatKeyLiteral(target.keys[name])))
+	}
+
+	entities, _, err := references.QueryNavigation(ctx, target.parent, target.navigation.Name, ExpandOption{Filter: strings.Join(terms, " and ")})
+	if err != nil {
+		return false, &resourcePathError{fiber.StatusInternalServerError, "QueryError", err.Error()}
+	}
+	for _, item := range entities {
+		entity, ok := item.(*OrderedEntity)
+		if !ok {
+			continue
+		}
+		member := true
+		for name, value := range entityKeyValues(metadata, entity) {
+			if expandKeyValue(value) != expandKeyValue(target.keys[name]) {
+				member = false
+			}
+		}
+		if member {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// linkEntities vincula a entidade relacionada à entidade de origem pela tabela de junção
+func (s *Server) linkEntities(ctx context.Context, target *navigationTarget, relatedValue interface{}) error {
+	references, ok := target.parentService.(ReferenceService)
+	if !ok {
+		return fmt.Errorf("'%s' does not support join table references", target.parentEntityName)
+	}
+	return references.LinkEntities(ctx, target.navigation.Name, target.parentValue, relatedValue)
+}
+
+// unlinkEntities remove o vínculo da entidade relacionada na tabela de junção
+func (s *Server) unlinkEntities(ctx context.Context, target *navigationTarget, relatedValue interface{}) error {
+	references, ok := target.parentService.(ReferenceService)
+	if !ok {
+		return fmt.Errorf("'%s' does not support join table references", target.parentEntityName)
+	}
+	return references.UnlinkEntities(ctx, target.navigation.Name, target.parentValue, relatedValue)
+}
+
+// entityKeyValues extrai os valores das chaves da entidade
+func entityKeyValues(metadata EntityMetadata, entity *OrderedEntity) map[string]interface{} {
+	keys := make(map[string]interface{})
+	for _, prop := range metadata.Properties {
+		if prop.IsKey {
+			if value, ok := entity.Get(prop.Name); ok {
+				keys[prop.Name] = value
+			}
+		}
+	}
+	return keys
+}
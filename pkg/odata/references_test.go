@@ -0,0 +1,159 @@
+package odata
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ReferenceTestProduct struct {
+	TableName string             `table:"products"`
+	ID        int64              `json:"ID" column:"id" primaryKey:"idGenerator:none"`
+	Name      string             `json:"Name" column:"name"`
+	Tags      []ReferenceTestTag `json:"Tags" manyAssociation:"references:id; joinTable:product_tags; joinColumn:product_id; inverseJoinColumn:tag_id"`
+}
+
+type ReferenceTestTag struct {
+	TableName string `table:"tags"`
+	ID        int64  `json:"ID" column:"id" primaryKey:"idGenerator:none"`
+	Label     string `json:"Label" column:"label"`
+}
+
+func newReferenceTestServer(t *testing.T) (*Server, *navigationTestProvider, *fakeDB) {
+	server, provider, db := newNavigationTestServerWithDB(t)
+	require.NoError(t, server.RegisterEntity("Products", ReferenceTestProduct{}))
+	require.NoError(t, server.RegisterEntity("Tags", ReferenceTestTag{}))
+	return server, provider, db
+}
+
+func TestQueryBuilder_BuildJoinTable(t *testing.T) {
+	many := &ManyAssociationMetadata{JoinTable: "product_tags", JoinColumn: "product_id", InverseJoinColumn: "tag_id"}
+	qb := NewQueryBuilder("postgresql")
+
+	query, args, err := qb.BuildJoinTableInsert(many, int64(1), int64(3))
+	require.NoError(t, err)
+	assert.Equal(t, "INSERT INTO product_tags (product_id, tag_id) VALUES (:param1, :param2)", query)
+	assert.Len(t, args, 2)
+
+	query, args, err = qb.BuildJoinTableDelete(many, int64(1), int64(3))
+	require.NoError(t, err)
+	assert.Equal(t, "DELETE FROM product_tags WHERE product_id = :param1 AND tag_id = :param2", query)
+	assert.Len(t, args, 2)
+
+	_, _, err = qb.BuildJoinTableInsert(&ManyAssociationMetadata{JoinTable: "product_tags"}, 1, 3)
+	assert.Error(t, err)
+}
+
+func TestServer_References(t *testing.T) {
+	t.Run("get single and collection", func(t *testing.T) {
+		server, _, _ := newReferenceTestServer(t)
+
+		resp, err := server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Orders(10)/Customer/$ref", nil))
+		require.NoError(t, err)
+		require.Equal(t, 200, resp.StatusCode)
+
+		var single map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&single))
+		assert.Equal(t, "$metadata#$ref", single["@odata.context"])
+		assert.True(t, strings.HasSuffix(single["@odata.id"].(string), "/odata/Customers(1)"))
+
+		resp, err = server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Products(1)/Tags/$ref", nil))
+		require.NoError(t, err)
+		require.Equal(t, 200, resp.StatusCode)
+
+		var collection struct {
+			Value []map[string]string `json:"value"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&collection))
+		require.Len(t, collection.Value, 1)
+		assert.True(t, strings.HasSuffix(collection.Value[0]["@odata.id"], "/odata/Tags(3)"))
+	})
+
+	t.Run("join table member", func(t *testing.T) {
+		server, _, _ := newReferenceTestServer(t)
+
+		resp, err := server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Products(1)/Tags(3)/$ref", nil))
+		require.NoError(t, err)
+		require.Equal(t, 200, resp.StatusCode)
+
+		// Uma tag existente, mas sem vínculo com o produto, não é membro da navegação
+		resp, err = server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Products(1)/Tags(5)/$ref", nil))
+		require.NoError(t, err)
+		assert.Equal(t, 404, resp.StatusCode)
+	})
+
+	t.Run("set single-valued reference", func(t *testing.T) {
+		server, provider, _ := newReferenceTestServer(t)
+
+		req := httptest.NewRequest("PUT", "/odata/Orders(10)/Customer/$ref", strings.NewReader(`{"@odata.id": "http://example.com/odata/Customers(1)"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.GetRouter().Test(req)
+		require.NoError(t, err)
+		require.Equal(t, 204, resp.StatusCode)
+		assert.EqualValues(t, map[string]interface{}{"ID": int64(10)}, provider.updateKeys)
+		assert.EqualValues(t, 1, provider.update["CustomerID"])
+
+		// A chave estrangeira obrigatória não pode ser removida
+		resp, err = server.GetRouter().Test(httptest.NewRequest("DELETE", "/odata/Orders(10)/Customer/$ref", nil))
+		require.NoError(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+	})
+
+	t.Run("add to one-to-many", func(t *testing.T) {
+		server, provider, _ := newReferenceTestServer(t)
+
+		req := httptest.NewRequest("POST", "/odata/Customers(1)/Orders/$ref", strings.NewReader(`{"@odata.id": "Orders(10)"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.GetRouter().Test(req)
+		require.NoError(t, err)
+		require.Equal(t, 204, resp.StatusCode)
+		assert.EqualValues(t, map[string]interface{}{"ID": int64(10)}, provider.updateKeys)
+		assert.EqualValues(t, 1, provider.update["CustomerID"])
+
+		// Entidades de outro entity set são rejeitadas
+		req = httptest.NewRequest("POST", "/odata/Customers(1)/Orders/$ref", strings.NewReader(`{"@odata.id": "Customers(2)"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err = server.GetRouter().Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+	})
+
+	t.Run("many-to-many through join table", func(t *testing.T) {
+		server, _, db := newReferenceTestServer(t)
+
+		req := httptest.NewRequest("POST", "/odata/Products(1)/Tags/$ref", strings.NewReader(`{"@odata.id": "Tags(3)"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.GetRouter().Test(req)
+		require.NoError(t, err)
+		require.Equal(t, 204, resp.StatusCode)
+
+		resp, err = server.GetRouter().Test(httptest.NewRequest("DELETE", "/odata/Products(1)/Tags(3)/$ref", nil))
+		require.NoError(t, err)
+		require.Equal(t, 204, resp.StatusCode)
+
+		resp, err = server.GetRouter().Test(httptest.NewRequest("DELETE", "/odata/Products(1)/Tags/$ref?$id=Tags(3)", nil))
+		require.NoError(t, err)
+		require.Equal(t, 204, resp.StatusCode)
+
+		require.Len(t, db.execs, 3)
+		assert.True(t, strings.HasPrefix(db.execs[0], "INSERT INTO product_tags (product_id, tag_id)"))
+		assert.True(t, strings.HasPrefix(db.execs[1], "DELETE FROM product_tags WHERE product_id ="))
+		assert.Equal(t, db.execs[1], db.execs[2])
+
+		// A navegação N:N também pode ser consultada diretamente
+		resp, err = server.GetRouter().Test(httptest.NewRequest("GET", "/odata/Products(1)/Tags", nil))
+		require.NoError(t, err)
+		require.Equal(t, 200, resp.StatusCode)
+
+		var body struct {
+			Value []map[string]interface{} `json:"value"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		require.Len(t, body.Value, 1)
+		assert.Equal(t, "sale", body.Value[0]["Label"])
+	})
+}
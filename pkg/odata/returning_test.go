@@ -0,0 +1,87 @@
+package odata
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// returningTestProvider devolve a linha gravada como o banco devolveria, com valores preenchidos por ele
+type returningTestProvider struct {
+	MockDatabaseProvider
+	selects int
+	row     map[string]interface{}
+	data    map[string]interface{}
+	keys    map[string]interface{}
+}
+
+func (p *returningTestProvider) BuildSelectQuery(metadata EntityMetadata, options QueryOptions) (string, []interface{}, error) {
+	p.selects++
+	return "SELECT tokens", nil, nil
+}
+
+func (p *returningTestProvider) InsertReturning(ctx context.Context, exec SQLExecutor, entity EntityMetadata, data map[string]interface{}) (map[string]interface{}, error) {
+	p.data = data
+	return p.row, nil
+}
+
+func (p *returningTestProvider) UpdateReturning(ctx context.Context, exec SQLExecutor, entity EntityMetadata, data map[string]interface{}, keyValues map[string]interface{}) (map[string]interface{}, error) {
+	p.data, p.keys = data, keyValues
+	return p.row, nil
+}
+
+func newReturningTestServer(t *testing.T) (*Server, *returningTestProvider) {
+	db := newFakeDB(t, func(string) *fakeRows {
+		return newFakeRows([]string{"id", "email"}, []driver.Value{"u1", "ana@example.com"})
+	})
+	provider := &returningTestProvider{MockDatabaseProvider: MockDatabaseProvider{connection: db.DB}}
+	server := newTestServer(provider)
+	require.NoError(t, server.RegisterEntity("Tokens", IDGeneratorTestToken{}))
+
+	return server, provider
+}
+
+func TestBaseEntityService_ReturningProvider(t *testing.T) {
+	server, provider := newReturningTestServer(t)
+	service := server.entities["Tokens"].(*BaseEntityService)
+	ctx := context.Background()
+
+	provider.row = map[string]interface{}{"id": "from-db", "email": []byte("trigger@example.com")}
+	created, err := service.Create(ctx, map[string]interface{}{"email": "ana@example.com"})
+	require.NoError(t, err)
+	entity := created.(*OrderedEntity)
+	id, _ := entity.Get("id")
+	email, _ := entity.Get("email")
+	assert.Equal(t, "from-db", id)
+	assert.Equal(t, "trigger@example.com", email)
+	assert.Zero(t, provider.selects, "the inserted row must not be read again")
+
+	updated, err := service.Update(ctx, map[string]interface{}{"id": "from-db"}, map[string]interface{}{"email": "new@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"id": "from-db"}, provider.keys)
+	email, _ = updated.(*OrderedEntity).Get("email")
+	assert.Equal(t, "trigger@example.com", email)
+	assert.Zero(t, provider.selects)
+
+	// Nenhuma linha devolvida: a entidade não existe
+	provider.row = nil
+	_, err = service.Update(ctx, map[string]interface{}{"id": "missing"}, map[string]interface{}{"email": "new@example.com"})
+	assert.EqualError(t, err, "no rows updated")
+}
+
+func TestServer_ReturningProviderLocation(t *testing.T) {
+	server, provider := newReturningTestServer(t)
+	provider.row = map[string]interface{}{"id": "from-db", "email": "ana@example.com"}
+
+	req := httptest.NewRequest("POST", "/odata/Tokens", strings.NewReader(`{"email": "ana@example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := server.GetRouter().Test(req)
+	require.NoError(t, err)
+	require.Equal(t, 201, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Location"), "/odata/Tokens('from-db')")
+}
@@ -3,6 +3,8 @@ package odata
 import (
 	"context"
 	"crypto/tls"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"net/url"
@@ -12,6 +14,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -74,28 +77,50 @@ type ServerConfig struct {
 	EnableJWT   bool
 	JWTConfig   *JWTConfig
 	RequireAuth bool // Se true, todas as rotas requerem autenticação por padrão
+
+	// Configurações de paginação dirigida pelo servidor
+	MaxPageSize        int            // Tamanho máximo de página das coleções (0 = sem limite)
+	EntityMaxPageSizes map[string]int // Tamanho máximo de página por entity set (sobrepõe MaxPageSize)
+
+	// Configurações de escrita
+	ReadOnlyProperties ReadOnlyPropertyMode // Tratamento de chaves e campos calculados no corpo (padrão: ignore)
+	EnableUpsert       bool                 // Se true, PUT em uma chave inexistente cria a entidade
+
+	// Configurações de transação
+	TransactionIsolation sql.IsolationLevel // Isolamento das transações abertas pelo servidor (changesets, deep insert, handlers)
+
+	// Configurações de requisições assíncronas (Prefer: respond-async)
+	AsyncJobTTL  time.Duration // Tempo que o resultado fica disponível no monitor depois de concluído
+	AsyncMaxJobs int           // Limite de requisições assíncronas guardadas ao mesmo tempo (padrão: 1000)
+
+	// Configurações dos handlers de eventos assíncronos
+	AsyncEvents AsyncEventConfig // Pool de workers, novas tentativas e dead-letter
 }
 
 // DefaultServerConfig retorna uma configuração padrão do servidor
 func DefaultServerConfig() *ServerConfig {
 	return &ServerConfig{
-		Name:              "godata-service",
-		DisplayName:       "GoData OData Service",
-		Description:       "Serviço GoData OData v4 para APIs RESTful",
-		Host:              "localhost",
-		Port:              8080,
-		EnableCORS:        true,
-		AllowedOrigins:    []string{"*"},
-		AllowedMethods:    []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowedHeaders:    []string{"*"},
-		ExposedHeaders:    []string{"OData-Version", "Content-Type"},
-		AllowCredentials:  false,
-		EnableLogging:     true,
-		LogLevel:          "INFO",
-		EnableCompression: false,            // Desabilitado por padrão para evitar problemas
-		MaxRequestSize:    10 * 1024 * 1024, // 10MB
-		ShutdownTimeout:   30 * time.Second,
-		RoutePrefix:       "/odata",
+		Name:               "godata-service",
+		DisplayName:        "GoData OData Service",
+		Description:        "Serviço GoData OData v4 para APIs RESTful",
+		Host:               "localhost",
+		Port:               8080,
+		EnableCORS:         true,
+		AllowedOrigins:     []string{"*"},
+		AllowedMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:     []string{"*"},
+		ExposedHeaders:     []string{"OData-Version", "Content-Type", "Location", "OData-EntityId", "Preference-Applied"},
+		AllowCredentials:   false,
+		EnableLogging:      true,
+		LogLevel:           "INFO",
+		EnableCompression:  false,            // Desabilitado por padrão para evitar problemas
+		MaxRequestSize:     10 * 1024 * 1024, // 10MB
+		ShutdownTimeout:    30 * time.Second,
+		RoutePrefix:        "/odata",
+		ReadOnlyProperties: ReadOnlyPropertiesIgnore,
+		AsyncJobTTL:        10 * time.Minute,
+		AsyncMaxJobs:       1000,
+		AsyncEvents:        DefaultAsyncEventConfig(),
 	}
 }
 
@@ -114,8 +139,15 @@ type Server struct {
 	mu                sync.RWMutex
 	running           bool
 	jwtService        *JWTService
-	entityAuth        map[string]EntityAuthConfig // Configurações de autenticação por entidade
-	eventManager      *EntityEventManager         // Gerenciador de eventos de entidade
+	entityAuth        map[string]EntityAuthConfig    // Configurações de autenticação por entidade
+	eventManager      *EntityEventManager            // Gerenciador de eventos de entidade
+	operations        []*Operation                   // Ações e funções registradas
+	asyncJobs         *asyncJobStore                 // Requisições assíncronas (Prefer: respond-async)
+	outbox            atomic.Pointer[outboxSettings] // Outbox transacional dos eventos de gravação
+	outboxRelays      []*OutboxRelay                 // Relays iniciados por StartOutboxRelay
+	relaysMu          sync.Mutex
+	webhooks          atomic.Pointer[WebhookManager] // Webhooks habilitados por EnableWebhooks
+	changeFeed        atomic.Pointer[changeFeed]     // Assinantes de /$subscribe
 
 	// Campos para gerenciamento de serviço
 	serviceLogger service.Logger
@@ -186,6 +218,8 @@ func newMultiTenantServer(multiTenantConfig *MultiTenantConfig) *Server {
 		entityAuth:        make(map[string]EntityAuthConfig),
 		eventManager:      NewEntityEventManager(logger),
 	}
+	server.asyncJobs = newAsyncJobStore(server.config.AsyncJobTTL, server.config.AsyncMaxJobs)
+	server.eventManager.SetAsyncConfig(server.config.AsyncEvents)
 
 	// Inicializa pool multi-tenant
 	server.multiTenantPool = NewMultiTenantProviderPool(multiTenantConfig, logger)
@@ -231,7 +265,9 @@ func newServerWithConfig(provider DatabaseProvider, config *ServerConfig) *Serve
 		logger:       logger,
 		entityAuth:   make(map[string]EntityAuthConfig),
 		eventManager: NewEntityEventManager(logger),
+		asyncJobs:    newAsyncJobStore(config.AsyncJobTTL, config.AsyncMaxJobs),
 	}
+	server.eventManager.SetAsyncConfig(config.AsyncEvents)
 
 	// Configurar JWT se habilitado
 	if config.EnableJWT {
@@ -297,12 +333,26 @@ func (s *Server) setupMultiTenantMiddlewares() {
 func (s *Server) setupBaseRoutes() {
 	prefix := s.config.RoutePrefix
 
+	// Preferências que envolvem toda a requisição (respond-async, odata.include-annotations)
+	s.router.Use(prefix, s.preferenceMiddleware())
+
+	// Monitor das requisições assíncronas
+	s.router.Get(prefix+"/"+asyncMonitorSegment+"/:id", s.handleAsyncMonitor)
+	s.router.Delete(prefix+"/"+asyncMonitorSegment+"/:id", s.handleAsyncMonitor)
+
 	// Rota para metadados
 	s.router.Get(prefix+"/$metadata", s.handleMetadata)
 
 	// Rota para service document
 	s.router.Get(prefix+"/", s.handleServiceDocument)
 
+	// Rota para requisições em lote ($batch)
+	s.router.Post(prefix+"/$batch", s.handleBatch)
+
+	// Rotas para ações e funções não vinculadas, como /Default.TopSellers(count=5)
+	s.addRoute("GET", prefix+"/"+csdlNamespace+".*", s.OptionalAuthMiddleware(), s.handleUnboundOperation)
+	s.addRoute("POST", prefix+"/"+csdlNamespace+".*", s.OptionalAuthMiddleware(), s.handleUnboundOperation)
+
 	// Rota para health check
 	s.router.Get("/health", s.handleHealth)
 
@@ -395,36 +445,60 @@ func (s *Server) setupEntityRoutes(entityName string) {
 
 	// Rota para coleção de entidades (GET, POST)
 	getHandlers := append(middlewares, s.handleEntityCollection)
-	s.router.Get(prefix+"/"+entityName, getHandlers[0], getHandlers[1:]...)
+	s.addRoute("GET", prefix+"/"+entityName, getHandlers...)
 
 	postHandlers := append(middlewares, s.CheckEntityReadOnly(entityName, "POST"), s.handleEntityCollection)
-	s.router.Post(prefix+"/"+entityName, postHandlers[0], postHandlers[1:]...)
+	s.addRoute("POST", prefix+"/"+entityName, postHandlers...)
 
 	// Rota para entidade individual (GET, PUT, PATCH, DELETE)
 	// Usando padrão wildcard para capturar URLs como /odata/FabTarefa(53)
 	getByIdHandlers := append(middlewares, s.handleEntityById)
-	s.router.Get(prefix+"/"+entityName+"(*)", getByIdHandlers[0], getByIdHandlers[1:]...)
+	s.addRoute("GET", prefix+"/"+entityName+"(*)", getByIdHandlers...)
 
 	putHandlers := append(middlewares, s.CheckEntityReadOnly(entityName, "PUT"), s.handleEntityById)
-	s.router.Put(prefix+"/"+entityName+"(*)", putHandlers[0], putHandlers[1:]...)
+	s.addRoute("PUT", prefix+"/"+entityName+"(*)", putHandlers...)
 
 	patchHandlers := append(middlewares, s.CheckEntityReadOnly(entityName, "PATCH"), s.handleEntityById)
-	s.router.Patch(prefix+"/"+entityName+"(*)", patchHandlers[0], patchHandlers[1:]...)
+	s.addRoute("PATCH", prefix+"/"+entityName+"(*)", patchHandlers...)
 
 	deleteHandlers := append(middlewares, s.CheckEntityReadOnly(entityName, "DELETE"), s.handleEntityById)
-	s.router.Delete(prefix+"/"+entityName+"(*)", deleteHandlers[0], deleteHandlers[1:]...)
+	s.addRoute("DELETE", prefix+"/"+entityName+"(*)", deleteHandlers...)
 
 	// Rota para count da coleção
 	countHandlers := append(middlewares, s.handleEntityCount)
-	s.router.Get(prefix+"/"+entityName+"/$count", countHandlers[0], countHandlers[1:]...)
+	s.addRoute("GET", prefix+"/"+entityName+"/$count", countHandlers...)
+
+	// Rota para o stream de alterações da coleção (SSE ou WebSocket)
+	subscribeHandlers := append(middlewares, s.handleEntitySubscribe)
+	s.addRoute("GET", prefix+"/"+entityName+"/"+subscribeSegment, subscribeHandlers...)
+
+	// Rotas para operações vinculadas à coleção, como /Orders/Default.Recalculate
+	operationHandlers := append(middlewares, s.handleCollectionOperation)
+	s.addRoute("GET", prefix+"/"+entityName+"/*", operationHandlers...)
+	s.addRoute("POST", prefix+"/"+entityName+"/*", operationHandlers...)
+
+	// Rotas para caminhos de navegação, como /Orders(1)/Items
+	// O acesso e o modo apenas leitura da entidade alvo são verificados no handler
+	navigationHandlers := append(middlewares, s.handleNavigationPath)
+	for _, method := range []string{"GET", "POST", "PUT", "PATCH", "DELETE"} {
+		s.addRoute(method, prefix+"/"+entityName+"(*)/*", navigationHandlers...)
+	}
 
 	// Rota OPTIONS para CORS se habilitado
 	if s.config.EnableCORS {
 		s.router.Options(prefix+"/"+entityName, s.handleOptions)
 		s.router.Options(prefix+"/"+entityName+"(*)", s.handleOptions)
+		s.router.Options(prefix+"/"+entityName+"(*)/*", s.handleOptions)
 	}
 }
 
+// addRoute registra a rota com os handlers na ordem de execução
+// No Fiber v3 o primeiro argumento é o handler final e os middlewares vêm depois, por isso o último handler é separado
+func (s *Server) addRoute(method, path string, handlers ...fiber.Handler) {
+	last := len(handlers) - 1
+	s.router.Add([]string{method}, path, handlers[last], handlers[:last]...)
+}
+
 // Start inicia o servidor HTTP
 // Detecta automaticamente se deve executar como serviço ou normalmente
 func (s *Server) Start() error {
@@ -523,15 +597,18 @@ func (s *Server) setupGracefulShutdown(ctx context.Context) {
 // Shutdown para o servidor gracefully
 func (s *Server) Shutdown() error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	if !s.running {
+		s.mu.Unlock()
 		return fmt.Errorf("servidor não está rodando")
 	}
 
 	if s.httpServer == nil {
+		s.mu.Unlock()
 		return fmt.Errorf("servidor HTTP não inicializado")
 	}
+	httpServer := s.httpServer
+	// O lock é liberado enquanto as requisições e os handlers em andamento terminam, já que eles consultam as entidades
+	s.mu.Unlock()
 
 	s.logger.Printf("Parando servidor...")
 
@@ -539,12 +616,28 @@ func (s *Server) Shutdown() error {
 	ctx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
 	defer cancel()
 
+	// Encerra os streams de /$subscribe, que de outra forma manteriam as conexões abertas
+	if feed := s.changeFeed.Load(); feed != nil {
+		feed.close()
+	}
+
 	// Shutdown graceful
-	if err := s.httpServer.ShutdownWithContext(ctx); err != nil {
+	if err := httpServer.ShutdownWithContext(ctx); err != nil {
 		s.logger.Printf("Erro durante shutdown: %v", err)
 		return err
 	}
 
+	// Para os relays do outbox e aguarda os handlers assíncronos em andamento
+	s.stopOutboxRelays()
+	if err := s.eventManager.Close(ctx); err != nil {
+		s.logger.Printf("⚠️ Handlers assíncronos não concluídos no shutdown: %v", err)
+	}
+	if webhooks := s.webhooks.Load(); webhooks != nil {
+		if err := webhooks.Close(ctx); err != nil {
+			s.logger.Printf("⚠️ Entregas de webhook não concluídas no shutdown: %v", err)
+		}
+	}
+
 	// Fechar provider se necessário
 	if s.provider != nil {
 		if err := s.provider.Close(); err != nil {
@@ -552,7 +645,9 @@ func (s *Server) Shutdown() error {
 		}
 	}
 
+	s.mu.Lock()
 	s.running = false
+	s.mu.Unlock()
 	s.logger.Printf("Servidor parado com sucesso")
 	return nil
 }
@@ -629,11 +724,24 @@ func (s *Server) GetEntities() map[string]EntityService {
 	return entities
 }
 
+// entityService retorna o serviço registrado para o entity set
+func (s *Server) entityService(name string) (EntityService, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	service, exists := s.entities[name]
+	return service, exists
+}
+
 // GetEventManager retorna o gerenciador de eventos
 func (s *Server) GetEventManager() *EntityEventManager {
 	return s.eventManager
 }
 
+// OnEntityQuerying registra um handler para o evento EntityQuerying, disparado antes da consulta
+func (s *Server) OnEntityQuerying(entityName string, handler func(args EventArgs) error) {
+	s.eventManager.SubscribeFunc(EventEntityQuerying, entityName, handler)
+}
+
 // OnEntityGet registra um handler para o evento EntityGet
 func (s *Server) OnEntityGet(entityName string, handler func(args EventArgs) error) {
 	s.eventManager.SubscribeFunc(EventEntityGet, entityName, handler)
@@ -674,11 +782,26 @@ func (s *Server) OnEntityDeleted(entityName string, handler func(args EventArgs)
 	s.eventManager.SubscribeFunc(EventEntityDeleted, entityName, handler)
 }
 
+// OnEntityValidating registra um validador customizado para o evento EntityValidating
+func (s *Server) OnEntityValidating(entityName string, handler func(args EventArgs) error) {
+	s.eventManager.SubscribeFunc(EventEntityValidating, entityName, handler)
+}
+
+// OnEntityValidated registra um handler para o evento EntityValidated
+func (s *Server) OnEntityValidated(entityName string, handler func(args EventArgs) error) {
+	s.eventManager.SubscribeFunc(EventEntityValidated, entityName, handler)
+}
+
 // OnEntityError registra um handler para o evento EntityError
 func (s *Server) OnEntityError(entityName string, handler func(args EventArgs) error) {
 	s.eventManager.SubscribeFunc(EventEntityError, entityName, handler)
 }
 
+// OnEntityQueryingGlobal registra um handler global para o evento EntityQuerying
+func (s *Server) OnEntityQueryingGlobal(handler func(args EventArgs) error) {
+	s.eventManager.SubscribeGlobalFunc(EventEntityQuerying, handler)
+}
+
 // OnEntityGetGlobal registra um handler global para o evento EntityGet
 func (s *Server) OnEntityGetGlobal(handler func(args EventArgs) error) {
 	s.eventManager.SubscribeGlobalFunc(EventEntityGet, handler)
@@ -719,11 +842,33 @@ func (s *Server) OnEntityDeletedGlobal(handler func(args EventArgs) error) {
 	s.eventManager.SubscribeGlobalFunc(EventEntityDeleted, handler)
 }
 
+// OnEntityValidatingGlobal registra um validador customizado global para o evento EntityValidating
+func (s *Server) OnEntityValidatingGlobal(handler func(args EventArgs) error) {
+	s.eventManager.SubscribeGlobalFunc(EventEntityValidating, handler)
+}
+
+// OnEntityValidatedGlobal registra um handler global para o evento EntityValidated
+func (s *Server) OnEntityValidatedGlobal(handler func(args EventArgs) error) {
+	s.eventManager.SubscribeGlobalFunc(EventEntityValidated, handler)
+}
+
 // OnEntityErrorGlobal registra um handler global para o evento EntityError
 func (s *Server) OnEntityErrorGlobal(handler func(args EventArgs) error) {
 	s.eventManager.SubscribeGlobalFunc(EventEntityError, handler)
 }
 
+// OnEntityEventAsync registra um handler executado em segundo plano, depois da confirmação da operação.
+// Aceita os eventos que não podem ser cancelados: EntityGet, EntityList, EntityInserted, EntityModified,
+// EntityDeleted e EntityError
+func (s *Server) OnEntityEventAsync(eventType EventType, entityName string, handler func(args EventArgs) error) error {
+	return s.eventManager.SubscribeAsync(eventType, entityName, EventHandlerFunc(handler))
+}
+
+// OnEntityEventAsyncGlobal registra um handler global executado em segundo plano
+func (s *Server) OnEntityEventAsyncGlobal(eventType EventType, handler func(args EventArgs) error) error {
+	return s.eventManager.SubscribeGlobalAsync(eventType, EventHandlerFunc(handler))
+}
+
 // Health check handler
 func (s *Server) handleHealth(c fiber.Ctx) error {
 	health := map[string]interface{}{
@@ -839,6 +984,11 @@ func (s *Server) handleEntityById(c fiber.Ctx) error {
 		return s.handleEntityCollection(c)
 	}
 
+	// Caminhos como /Orders(1)/Items(3) também terminam com parênteses
+	if isNavigationPath(strings.TrimPrefix(path, s.config.RoutePrefix+"/")) {
+		return s.handleNavigationPath(c)
+	}
+
 	// Extrai as chaves da URL
 	keys, err := s.extractKeys(path, service.GetMetadata())
 	if err != nil {
@@ -866,12 +1016,6 @@ func (s *Server) handleEntityById(c fiber.Ctx) error {
 
 // handleGetCollection lida com GET na coleção de entidades
 func (s *Server) handleGetCollection(c fiber.Ctx, service EntityService) error {
-	// Cria contexto com referência ao Fiber Context para multi-tenant
-	ctx := context.WithValue(c.Context(), FiberContextKey, c)
-
-	// Extrai o nome da entidade
-	entityName := s.extractEntityName(c.Path())
-
 	// Parse centralizado das opções de consulta
 	options, err := s.parseQueryOptions(c)
 	if err != nil {
@@ -879,13 +1023,45 @@ func (s *Server) handleGetCollection(c fiber.Ctx, service EntityService) error {
 		return nil
 	}
 
+	return s.writeCollection(c, service, options)
+}
+
+// writeCollection executa a consulta da coleção e escreve a resposta, com paginação dirigida pelo servidor
+func (s *Server) writeCollection(c fiber.Ctx, service EntityService, options QueryOptions) error {
+	// Cria contexto com referência ao Fiber Context para multi-tenant
+	ctx := s.requestContext(c)
+
+	// Extrai o nome da entidade
+	entityName := s.requestEntityName(c)
+
+	// Paginação dirigida pelo servidor (configuração e Prefer: odata.maxpagesize)
+	if options.Apply == nil {
+		pageSize, preferred := s.resolveMaxPageSize(c, entityName)
+		options.MaxPageSize = pageSize
+		if preferred {
+			addPreferenceApplied(c, fmt.Sprintf("odata.maxpagesize=%d", pageSize))
+		}
+	}
+
 	// Executa consulta centralizada com eventos
-	response, err := s.handleEntityQueryWithEvents(ctx, service, options, entityName, true)
+	response, err := s.handleEntityQueryWithEvents(ctx, service, options, entityName, nil)
 	if err != nil {
-		s.writeError(c, fiber.StatusInternalServerError, "QueryError", err.Error())
+		s.writeQueryError(c, "QueryError", err)
 		return nil
 	}
 
+	// Link para a próxima página quando o resultado foi truncado
+	if response.NextSkipToken != "" {
+		returned := 0
+		if results, ok := response.Value.([]any); ok {
+			returned = len(results)
+		}
+		response.NextLink = s.buildNextLink(c, response.NextSkipToken, returned)
+	}
+
+	// Anota cada entidade com o ETag
+	s.applyCollectionETags(response, service.GetMetadata())
+
 	// Constrói resposta OData centralizada
 	odataResponse := s.buildODataResponse(response, true, service.GetMetadata())
 
@@ -902,10 +1078,10 @@ func (s *Server) handleGetEntity(c fiber.Ctx, service EntityService, keys map[st
 	}
 
 	// Cria contexto com referência ao Fiber Context para multi-tenant
-	ctx := context.WithValue(c.Context(), FiberContextKey, c)
+	ctx := s.requestContext(c)
 
 	// Extrai o nome da entidade
-	entityName := s.extractEntityName(c.Path())
+	entityName := s.requestEntityName(c)
 
 	// Parse das opções de consulta da URL (caso existam)
 	options, err := s.parseQueryOptions(c)
@@ -948,9 +1124,9 @@ func (s *Server) handleGetEntity(c fiber.Ctx, service EntityService, keys map[st
 	options.Filter = keyFilter
 
 	// Executa consulta centralizada com eventos
-	response, err := s.handleEntityQueryWithEvents(ctx, service, options, entityName, false)
+	response, err := s.handleEntityQueryWithEvents(ctx, service, options, entityName, keys)
 	if err != nil {
-		s.writeError(c, fiber.StatusInternalServerError, "QueryError", err.Error())
+		s.writeQueryError(c, "QueryError", err)
 		return nil
 	}
 
@@ -969,17 +1145,30 @@ func (s *Server) handleGetEntity(c fiber.Ctx, service EntityService, keys map[st
 
 	s.logger.Printf("✅ handleGetEntity - Entity retrieved successfully")
 
-	// Dispara evento OnEntityGet específico com as chaves reais
-	eventCtx := createEventContext(c, entityName)
+	// ETag da entidade e suporte a If-None-Match
+	metadata := service.GetMetadata()
+	var etag string
+	var hasETag bool
 	if results, ok := response.Value.([]interface{}); ok && len(results) > 0 {
-		args := NewEntityGetArgs(eventCtx, keys, results[0])
-		if err := s.eventManager.Emit(args); err != nil {
-			s.logger.Printf("❌ Erro no evento OnEntityGet: %v", err)
+		etag, hasETag = ComputeETag(metadata, results[0])
+	}
+	if hasETag {
+		c.Set(fiber.HeaderETag, etag)
+		if ifNoneMatch := c.Get(fiber.HeaderIfNoneMatch); ifNoneMatch != "" && etagMatches(ifNoneMatch, etag) {
+			return c.SendStatus(fiber.StatusNotModified)
 		}
 	}
 
 	// Constrói resposta OData centralizada
-	odataResponse := s.buildODataResponse(response, false, service.GetMetadata())
+	odataResponse := s.buildODataResponse(response, false, metadata)
+	if hasETag {
+		switch r := odataResponse.(type) {
+		case *OrderedEntityResponse:
+			r.ETag = etag
+		case map[string]interface{}:
+			r["@odata.etag"] = etag
+		}
+	}
 
 	return c.JSON(odataResponse)
 }
@@ -992,18 +1181,65 @@ func (s *Server) handleCreateEntity(c fiber.Ctx, service EntityService) error {
 		return nil
 	}
 
-	createdEntity, err := service.Create(c.Context(), entity)
+	return s.createEntity(c, service, entity, nil)
+}
+
+// createEntity cria a entidade e escreve a resposta 201 com Location e ETag.
+// keys são as chaves da URL quando a criação vem de um upsert (PUT)
+func (s *Server) createEntity(c fiber.Ctx, service EntityService, entity, keys map[string]interface{}) error {
+	metadata := service.GetMetadata()
+	ctx := s.requestContext(c)
+	provider := s.serviceProvider(c, service)
+
+	if err := s.sanitizeReadOnlyProperties(metadata, entity, keys, true); err != nil {
+		s.writeError(c, fiber.StatusBadRequest, "ReadOnlyProperty", err.Error())
+		return nil
+	}
+	for name, value := range keys {
+		entity[name] = value
+	}
+
+	bindings, err := s.resolveBindings(ctx, metadata, entity)
 	if err != nil {
-		s.writeError(c, fiber.StatusInternalServerError, "CreateError", err.Error())
+		return s.writeNavigationError(c, err)
+	}
+
+	// Entidades aninhadas em navegações são criadas na mesma transação (deep insert)
+	var plan *deepInsertNode
+	if hasNestedEntities(metadata, entity) {
+		if plan, err = s.buildDeepInsertPlan(s.entitySetNameOf(metadata), service, entity); err != nil {
+			s.writeError(c, fiber.StatusBadRequest, "InvalidRequest", err.Error())
+			return nil
+		}
+	}
+
+	createdEntity, err := s.writeWithBindings(ctx, provider, service, bindings, func(ctx context.Context) (interface{}, error) {
+		if plan != nil {
+			return s.deepInsert(ctx, provider, plan)
+		}
+		return s.insertEntity(ctx, s.entitySetNameOf(metadata), service, entity)
+	})
+	if err != nil {
+		var canceled *EventCanceledError
+		var constraint *PropertyConstraintError
+		var validation *ValidationError
+		if errors.As(err, &canceled) {
+			s.writeError(c, fiber.StatusBadRequest, "EventCanceled", canceled.Error())
+		} else if errors.As(err, &validation) {
+			s.writeValidationError(c, validation)
+		} else if errors.As(err, &constraint) {
+			s.writeConstraintError(c, constraint)
+		} else {
+			s.writeError(c, fiber.StatusInternalServerError, "CreateError", err.Error())
+		}
 		return nil
 	}
 
-	c.Set("Location", s.buildEntityURL(c, service, createdEntity))
-	c.Status(fiber.StatusCreated)
-	return c.JSON(createdEntity)
+	return s.writeEntityResult(c, service, createdEntity, fiber.StatusCreated)
 }
 
-// handleUpdateEntity lida com PUT/PATCH para atualizar uma entidade
+// handleUpdateEntity lida com PUT/PATCH para atualizar uma entidade. PATCH altera apenas as propriedades
+// enviadas; PUT substitui a entidade, e as propriedades omitidas voltam ao valor padrão
 func (s *Server) handleUpdateEntity(c fiber.Ctx, service EntityService, keys map[string]interface{}) error {
 	var entity map[string]interface{}
 	if err := c.Bind().Body(&entity); err != nil {
@@ -1011,9 +1247,63 @@ func (s *Server) handleUpdateEntity(c fiber.Ctx, service EntityService, keys map
 		return nil
 	}
 
-	updatedEntity, err := service.Update(c.Context(), keys, entity)
+	metadata := service.GetMetadata()
+	replace := c.Method() == fiber.MethodPut
+
+	// Upsert: PUT sem If-Match em uma chave inexistente cria a entidade
+	if replace && s.config.EnableUpsert && c.Get(fiber.HeaderIfMatch) == "" {
+		if _, err := service.Get(s.requestContext(c), keys); err != nil && strings.Contains(err.Error(), "not found") {
+			return s.createEntity(c, service, entity, keys)
+		}
+	}
+
+	if err := s.sanitizeReadOnlyProperties(metadata, entity, keys, false); err != nil {
+		s.writeError(c, fiber.StatusBadRequest, "ReadOnlyProperty", err.Error())
+		return nil
+	}
+
+	ctx, ok := s.checkIfMatch(c, s.requestContext(c), service, keys)
+	if !ok {
+		return nil
+	}
+
+	bindings, err := s.resolveBindings(ctx, metadata, entity)
+	if err != nil {
+		return s.writeNavigationError(c, err)
+	}
+	if replace {
+		entity = replacementPayload(metadata, entity)
+	}
+
+	// Entidades aninhadas em navegações com cascade SaveUpdate são gravadas na mesma transação (deep update)
+	deep := hasNestedEntities(metadata, entity)
+	if deep {
+		if err := s.validateDeepUpdate(metadata, entity); err != nil {
+			s.writeError(c, fiber.StatusBadRequest, "InvalidRequest", err.Error())
+			return nil
+		}
+	}
+
+	provider := s.serviceProvider(c, service)
+	updatedEntity, err := s.writeWithBindings(ctx, provider, service, bindings, func(ctx context.Context) (interface{}, error) {
+		if deep {
+			return s.deepUpdate(ctx, provider, service, keys, entity)
+		}
+		return s.modifyEntity(ctx, s.entitySetNameOf(metadata), service, keys, entity, nil)
+	})
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
+		var canceled *EventCanceledError
+		var constraint *PropertyConstraintError
+		var validation *ValidationError
+		if errors.As(err, &canceled) {
+			s.writeError(c, fiber.StatusBadRequest, "EventCanceled", canceled.Error())
+		} else if errors.As(err, &validation) {
+			s.writeValidationError(c, validation)
+		} else if errors.As(err, &constraint) {
+			s.writeConstraintError(c, constraint)
+		} else if errors.Is(err, ErrPreconditionFailed) {
+			s.writeError(c, fiber.StatusPreconditionFailed, "PreconditionFailed", "The entity has been modified")
+		} else if strings.Contains(err.Error(), "not found") {
 			s.writeError(c, fiber.StatusNotFound, "EntityNotFound", err.Error())
 		} else {
 			s.writeError(c, fiber.StatusInternalServerError, "UpdateError", err.Error())
@@ -1021,14 +1311,30 @@ func (s *Server) handleUpdateEntity(c fiber.Ctx, service EntityService, keys map
 		return nil
 	}
 
-	return c.JSON(updatedEntity)
+	return s.writeEntityResult(c, service, updatedEntity, fiber.StatusOK)
 }
 
 // handleDeleteEntity lida com DELETE para remover uma entidade
 func (s *Server) handleDeleteEntity(c fiber.Ctx, service EntityService, keys map[string]interface{}) error {
-	err := service.Delete(c.Context(), keys)
+	ctx, ok := s.checkIfMatch(c, s.requestContext(c), service, keys)
+	if !ok {
+		return nil
+	}
+
+	var err error
+	if hasCascadeRemove(service.GetMetadata()) {
+		// As entidades relacionadas com cascade Remove são removidas na mesma transação
+		err = s.cascadeDelete(ctx, s.serviceProvider(c, service), service, keys)
+	} else {
+		err = s.removeEntity(ctx, s.entitySetNameOf(service.GetMetadata()), service, keys, nil, false)
+	}
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
+		var canceled *EventCanceledError
+		if errors.As(err, &canceled) {
+			s.writeError(c, fiber.StatusBadRequest, "EventCanceled", canceled.Error())
+		} else if errors.Is(err, ErrPreconditionFailed) {
+			s.writeError(c, fiber.StatusPreconditionFailed, "PreconditionFailed", "The entity has been modified")
+		} else if strings.Contains(err.Error(), "not found") {
 			s.writeError(c, fiber.StatusNotFound, "EntityNotFound", err.Error())
 		} else {
 			s.writeError(c, fiber.StatusInternalServerError, "DeleteError", err.Error())
@@ -1039,12 +1345,6 @@ func (s *Server) handleDeleteEntity(c fiber.Ctx, service EntityService, keys map
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
-// handleMetadata lida com GET dos metadados
-func (s *Server) handleMetadata(c fiber.Ctx) error {
-	metadata := s.buildMetadataJSON()
-	return c.JSON(metadata)
-}
-
 // handleServiceDocument lida com GET do documento de serviço
 func (s *Server) handleServiceDocument(c fiber.Ctx) error {
 	serviceDoc := map[string]interface{}{
@@ -1072,9 +1372,9 @@ func (s *Server) handleEntityCount(c fiber.Ctx) error {
 	}
 
 	// Obtém a contagem usando o método centralizado
-	count, err := s.getEntityCount(c.Context(), service, options)
+	count, err := s.getEntityCount(s.requestContext(c), service, options)
 	if err != nil {
-		s.writeError(c, fiber.StatusInternalServerError, "CountError", err.Error())
+		s.writeQueryError(c, "CountError", err)
 		return nil
 	}
 
@@ -1091,6 +1391,9 @@ func (s *Server) getEntityCount(ctx context.Context, service EntityService, opti
 		Filter: options.Filter,
 		Search: options.Search,
 	}
+	if err := s.interceptQuery(ctx, s.entitySetNameOf(service.GetMetadata()), QueryKindCount, nil, &countOptions); err != nil {
+		return 0, err
+	}
 
 	// Executa a consulta para contagem
 	response, err := service.Query(ctx, countOptions)
@@ -1115,6 +1418,14 @@ func (s *Server) getEntityCount(ctx context.Context, service EntityService, opti
 	return 0, nil
 }
 
+// requestEntityName retorna o entity set alvo da requisição, considerando caminhos de navegação
+func (s *Server) requestEntityName(c fiber.Ctx) string {
+	if entityName, ok := c.Locals(entitySetLocalKey).(string); ok && entityName != "" {
+		return entityName
+	}
+	return s.extractEntityName(c.Path())
+}
+
 // extractEntityName extrai o nome da entidade da URL
 func (s *Server) extractEntityName(path string) string {
 	// Remove o prefixo da rota
@@ -1128,8 +1439,9 @@ func (s *Server) extractEntityName(path string) string {
 		path = path[:idx]
 	}
 
-	// Remove $count se presente
+	// Remove $count e $subscribe se presentes
 	path = strings.TrimSuffix(path, "/$count")
+	path = strings.TrimSuffix(path, "/"+subscribeSegment)
 
 	return path
 }
@@ -1270,15 +1582,20 @@ func (s *Server) buildEntityURL(c fiber.Ctx, service EntityService, entity inter
 
 	// Encontra as chaves primárias
 	var keyValues []string
-	entityMap, ok := entity.(map[string]interface{})
-	if !ok {
+	var entityMap map[string]interface{}
+	switch e := entity.(type) {
+	case map[string]interface{}:
+		entityMap = e
+	case *OrderedEntity:
+		entityMap = e.ToMap()
+	default:
 		return ""
 	}
 
 	for _, prop := range metadata.Properties {
 		if prop.IsKey {
 			if value, exists := entityMap[prop.Name]; exists {
-				keyValues = append(keyValues, fmt.Sprintf("%v", value))
+				keyValues = append(keyValues, formatKeyLiteral(value))
 			}
 		}
 	}
@@ -1292,7 +1609,7 @@ func (s *Server) buildEntityURL(c fiber.Ctx, service EntityService, entity inter
 		scheme = "https"
 	}
 
-	baseURL := fmt.Sprintf("%s://%s%s/%s", scheme, c.Hostname(), s.config.RoutePrefix, metadata.Name)
+	baseURL := fmt.Sprintf("%s://%s%s/%s", scheme, c.Hostname(), s.config.RoutePrefix, s.entitySetNameOf(metadata))
 
 	if len(keyValues) == 1 {
 		return fmt.Sprintf("%s(%s)", baseURL, keyValues[0])
@@ -1311,62 +1628,6 @@ func (s *Server) buildEntityURL(c fiber.Ctx, service EntityService, entity inter
 	return fmt.Sprintf("%s(%s)", baseURL, strings.Join(keyPairs, ","))
 }
 
-// buildMetadataJSON constrói os metadados em formato JSON
-func (s *Server) buildMetadataJSON() MetadataResponse {
-	metadata := MetadataResponse{
-		Context: "$metadata",
-		Version: "4.0",
-	}
-
-	// Adiciona as entidades
-	var entities []EntityTypeMetadata
-	var entitySets []EntitySetMetadata
-
-	for name, service := range s.entities {
-		entityMetadata := service.GetMetadata()
-
-		// Constrói as propriedades
-		var properties []PropertyTypeMetadata
-		for _, prop := range entityMetadata.Properties {
-			property := PropertyTypeMetadata{
-				Name:       prop.Name,
-				Type:       s.mapODataType(prop.Type),
-				Nullable:   prop.IsNullable,
-				IsKey:      prop.IsKey,
-				HasDefault: prop.HasDefault,
-				MaxLength:  prop.MaxLength,
-			}
-
-			properties = append(properties, property)
-		}
-
-		// Entidade
-		entity := EntityTypeMetadata{
-			Name:       name,
-			Namespace:  "Default",
-			Keys:       s.getEntityKeys(entityMetadata),
-			Properties: properties,
-		}
-
-		entities = append(entities, entity)
-
-		// Entity Set
-		entitySet := EntitySetMetadata{
-			Name:       name,
-			EntityType: "Default." + name,
-			Kind:       "EntitySet",
-			URL:        name,
-		}
-
-		entitySets = append(entitySets, entitySet)
-	}
-
-	metadata.Entities = entities
-	metadata.EntitySets = entitySets
-
-	return metadata
-}
-
 // getEntityKeys retorna as chaves primárias de uma entidade
 func (s *Server) getEntityKeys(metadata EntityMetadata) []string {
 	var keys []string
@@ -1503,8 +1764,20 @@ func (s *Server) executeEntityQuery(ctx context.Context, service EntityService,
 	return response, nil
 }
 
-// handleEntityQueryWithEvents executa consulta e dispara eventos apropriados
-func (s *Server) handleEntityQueryWithEvents(ctx context.Context, service EntityService, options QueryOptions, entityName string, isCollection bool) (*ODataResponse, error) {
+// handleEntityQueryWithEvents executa consulta e dispara eventos apropriados. keys identifica a entidade
+// única consultada e é nil nas coleções
+func (s *Server) handleEntityQueryWithEvents(ctx context.Context, service EntityService, options QueryOptions, entityName string, keys map[string]interface{}) (*ODataResponse, error) {
+	isCollection := keys == nil
+
+	// EntityQuerying pode alterar as opções ou recusar a consulta antes da execução
+	kind := QueryKindCollection
+	if !isCollection {
+		kind = QueryKindEntity
+	}
+	if err := s.interceptQuery(ctx, entityName, kind, keys, &options); err != nil {
+		return nil, err
+	}
+
 	// Executa a consulta
 	response, err := s.executeEntityQuery(ctx, service, options, entityName)
 	if err != nil {
@@ -1544,13 +1817,6 @@ func (s *Server) handleEntityQueryWithEvents(ctx context.Context, service Entity
 			} else {
 				// Para entidades específicas, dispara evento OnEntityGet
 				if results, ok := response.Value.([]interface{}); ok && len(results) > 0 {
-					// Extrai chaves da URL para o evento
-					keys := make(map[string]interface{})
-					if options.Filter != nil {
-						// Tenta extrair chaves do filtro (implementação básica)
-						keys["extracted_from_filter"] = options.Filter.RawValue
-					}
-
 					args := NewEntityGetArgs(eventCtx, keys, results[0])
 					if err := s.eventManager.Emit(args); err != nil {
 						s.logger.Printf("❌ Erro no evento OnEntityGet: %v", err)
@@ -1647,6 +1913,19 @@ func (s *Server) writeError(c fiber.Ctx, statusCode int, code, message string) {
 	c.JSON(errorResponse)
 }
 
+// requestContext cria o contexto da requisição com referência ao Fiber Context
+// e à transação ativa (quando a requisição faz parte de um changeset)
+func (s *Server) requestContext(c fiber.Ctx) context.Context {
+	ctx := context.WithValue(c.Context(), FiberContextKey, c)
+	if tx, ok := c.Locals(TxContextKey).(*sql.Tx); ok && tx != nil {
+		ctx = context.WithValue(ctx, TxContextKey, tx)
+		if callbacks, ok := c.Locals(txCallbacksContextKey).(*txCallbacks); ok {
+			ctx = withTxCallbacks(ctx, callbacks)
+		}
+	}
+	return ctx
+}
+
 // getCurrentProvider retorna o provider para o tenant atual
 func (s *Server) getCurrentProvider(c fiber.Ctx) DatabaseProvider {
 	if s.multiTenantPool == nil {
@@ -1657,6 +1936,14 @@ func (s *Server) getCurrentProvider(c fiber.Ctx) DatabaseProvider {
 	return s.multiTenantPool.GetProvider(tenantID)
 }
 
+// serviceProvider retorna o provider em que o serviço grava: o do próprio BaseEntityService ou o do tenant atual
+func (s *Server) serviceProvider(c fiber.Ctx, service EntityService) DatabaseProvider {
+	if base, ok := service.(*BaseEntityService); ok && base.provider != nil {
+		return base.provider
+	}
+	return s.getCurrentProvider(c)
+}
+
 // handleTenantList lista todos os tenants disponíveis
 func (s *Server) handleTenantList(c fiber.Ctx) error {
 	if s.multiTenantPool == nil {
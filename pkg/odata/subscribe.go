@@ -0,0 +1,603 @@
+package odata
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// subscribeSegment é o segmento que abre o stream de alterações de uma coleção, como /Orders/$subscribe
+const subscribeSegment = "$subscribe"
+
+const (
+	changeStreamBuffer    = 256              // Notificações pendentes por assinante antes de o stream ser encerrado
+	changeStreamHeartbeat = 30 * time.Second // Intervalo do keep-alive (comentário SSE ou ping WebSocket)
+	changeStreamRetry     = 3000             // Espera, em ms, sugerida ao EventSource para reconectar
+	webSocketWriteTimeout = 10 * time.Second
+	webSocketMaxFrame     = 64 << 10 // Maior frame aceito do cliente, que só envia controle
+)
+
+// Tipos de alteração enviados por /$subscribe
+const (
+	ChangeCreated = "created"
+	ChangeUpdated = "updated"
+	ChangeDeleted = "deleted"
+)
+
+// ChangeNotification é a mensagem enviada aos assinantes de /$subscribe
+type ChangeNotification struct {
+	ID        string                 `json:"id"`
+	Event     string                 `json:"event"` // created, updated ou deleted
+	EntitySet string                 `json:"entitySet"`
+	Keys      map[string]interface{} `json:"keys,omitempty"`
+	// Reason acompanha deleted: "deleted" para a exclusão e "changed" quando a entidade alterada
+	// deixou de atender ao $filter do assinante
+	Reason    string      `json:"reason,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data,omitempty"`
+}
+
+// changeMessage é uma notificação já serializada
+type changeMessage struct {
+	id    string
+	event string
+	body  []byte
+}
+
+// changeSubscriber é uma conexão aberta em /$subscribe
+type changeSubscriber struct {
+	entityName string
+	tenantID   string
+	filter     *GoDataFilterQuery
+	expiresAt  time.Time // Expiração do token JWT, quando houver
+	messages   chan *changeMessage
+	done       chan struct{}
+	once       sync.Once
+	closeCode  uint16 // Código de fechamento WebSocket
+	reason     string
+	expiry     *time.Timer
+}
+
+// close encerra o stream do assinante; apenas o primeiro motivo é mantido
+func (sub *changeSubscriber) close(code uint16, reason string) {
+	sub.once.Do(func() {
+		sub.closeCode, sub.reason = code, reason
+		close(sub.done)
+	})
+}
+
+// send entrega a notificação sem bloquear a gravação; um assinante que não acompanha o ritmo é desconectado
+func (sub *changeSubscriber) send(message *changeMessage) {
+	select {
+	case <-sub.done:
+	case sub.messages <- message:
+	default:
+		sub.close(webSocketClosePolicy, "subscriber is too slow")
+	}
+}
+
+// changeFeed distribui os eventos de gravação aos assinantes de /$subscribe
+type changeFeed struct {
+	server      *Server
+	mu          sync.RWMutex
+	subscribers map[string]map[*changeSubscriber]struct{}
+	closed      bool
+	hookMu      sync.Mutex // Separado de mu: os handlers rodam com o lock do gerenciador de eventos
+	hooked      map[string]bool
+}
+
+// changes retorna o distribuidor de alterações, criado no primeiro /$subscribe
+func (s *Server) changes() *changeFeed {
+	if feed := s.changeFeed.Load(); feed != nil {
+		return feed
+	}
+	s.changeFeed.CompareAndSwap(nil, &changeFeed{
+		server:      s,
+		subscribers: make(map[string]map[*changeSubscriber]struct{}),
+		hooked:      make(map[string]bool),
+	})
+	return s.changeFeed.Load()
+}
+
+// subscribe registra o assinante e, uma única vez por entidade, os handlers dos eventos de gravação
+func (f *changeFeed) subscribe(sub *changeSubscriber) error {
+	f.hookMu.Lock()
+	if !f.hooked[sub.entityName] {
+		for _, eventType := range []EventType{EventEntityInserted, EventEntityModified, EventEntityDeleted} {
+			f.server.eventManager.Subscribe(eventType, sub.entityName, EventHandlerFunc(f.handle))
+		}
+		f.hooked[sub.entityName] = true
+	}
+	f.hookMu.Unlock()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return fmt.Errorf("server is shutting down")
+	}
+	if f.subscribers[sub.entityName] == nil {
+		f.subscribers[sub.entityName] = make(map[*changeSubscriber]struct{})
+	}
+	f.subscribers[sub.entityName][sub] = struct{}{}
+
+	if !sub.expiresAt.IsZero() {
+		sub.expiry = time.AfterFunc(time.Until(sub.expiresAt), func() {
+			sub.close(webSocketClosePolicy, "token expired")
+		})
+	}
+	return nil
+}
+
+// unsubscribe remove o assinante
+func (f *changeFeed) unsubscribe(sub *changeSubscriber) {
+	sub.close(webSocketCloseNormal, "stream closed")
+	if sub.expiry != nil {
+		sub.expiry.Stop()
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.subscribers[sub.entityName], sub)
+}
+
+// close encerra todos os streams abertos e recusa novos assinantes
+func (f *changeFeed) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	for _, subscribers := range f.subscribers {
+		for sub := range subscribers {
+			sub.close(webSocketCloseGoingAway, "server is shutting down")
+		}
+	}
+}
+
+// handle avalia o filtro de cada assinante durante o evento e entrega as notificações após a confirmação
+// da transação, na ordem das gravações; alterações revertidas não são enviadas
+func (f *changeFeed) handle(args EventArgs) error {
+	entityName := args.GetEntityName()
+	f.mu.RLock()
+	subscribers := make([]*changeSubscriber, 0, len(f.subscribers[entityName]))
+	for sub := range f.subscribers[entityName] {
+		subscribers = append(subscribers, sub)
+	}
+	f.mu.RUnlock()
+	if len(subscribers) == 0 {
+		return nil
+	}
+
+	service, exists := f.server.entityService(entityName)
+	if !exists {
+		return nil
+	}
+	metadata := service.GetMetadata()
+
+	var entity interface{}
+	var keys map[string]interface{}
+	switch a := args.(type) {
+	case *EntityInsertedArgs:
+		entity = a.CreatedEntity
+		keys = make(map[string]interface{})
+		for _, name := range keyPropertyNames(metadata) {
+			keys[name] = entityValue(entity, name)
+		}
+	case *EntityModifiedArgs:
+		entity, keys = a.UpdatedEntity, a.Keys
+	case *EntityDeletedArgs:
+		entity, keys = a.DeletedEntity, a.Keys
+	default:
+		return nil
+	}
+
+	eventCtx := args.GetContext()
+	tenantID := ""
+	if eventCtx != nil && f.server.multiTenantPool != nil {
+		tenantID = contextTenant(eventCtx)
+	}
+	matcher := newEventFilterMatcher(metadata)
+	notification := ChangeNotification{
+		ID:        formatUUID(newSmartGuid()),
+		EntitySet: entityName,
+		Keys:      keys,
+		Timestamp: time.Now().UTC(),
+	}
+
+	type delivery struct {
+		sub     *changeSubscriber
+		message *changeMessage
+	}
+	var deliveries []delivery
+	encoded := make(map[string]*changeMessage)
+	for _, sub := range subscribers {
+		if sub.tenantID != tenantID {
+			continue
+		}
+
+		event, reason, data := "", "", entity
+		matches := matcher.matches(entity, sub.filter)
+		switch a := args.(type) {
+		case *EntityInsertedArgs:
+			if matches {
+				event = ChangeCreated
+			}
+		case *EntityModifiedArgs:
+			if matches {
+				event = ChangeUpdated
+			} else if a.OriginalEntity != nil && matcher.matches(a.OriginalEntity, sub.filter) {
+				event, reason, data = ChangeDeleted, "changed", nil
+			}
+		case *EntityDeletedArgs:
+			if matches {
+				event, reason = ChangeDeleted, "deleted"
+			}
+		}
+		if event == "" {
+			continue
+		}
+
+		message, exists := encoded[event+reason]
+		if !exists {
+			notification.Event, notification.Reason, notification.Data = event, reason, data
+			body, err := json.Marshal(notification)
+			if err != nil {
+				f.server.logger.Printf("⚠️ Erro ao serializar a notificação de %s: %v", entityName, err)
+				return nil
+			}
+			message = &changeMessage{id: notification.ID, event: event, body: body}
+			encoded[event+reason] = message
+		}
+		deliveries = append(deliveries, delivery{sub: sub, message: message})
+	}
+
+	if len(deliveries) > 0 {
+		ctx := context.Background()
+		if eventCtx != nil && eventCtx.Context != nil {
+			ctx = eventCtx.Context
+		}
+		AfterCommit(ctx, func() {
+			for _, d := range deliveries {
+				d.sub.send(d.message)
+			}
+		})
+	}
+	return nil
+}
+
+// handleEntitySubscribe abre o stream de alterações da coleção: Server-Sent Events por padrão
+// ou WebSocket quando a requisição pede o upgrade. O $filter do assinante passa pelo EntityQuerying
+func (s *Server) handleEntitySubscribe(c fiber.Ctx) error {
+	entityName := s.extractEntityName(c.Path())
+	if _, exists := s.entityService(entityName); !exists {
+		s.writeError(c, fiber.StatusNotFound, "EntityNotFound", fmt.Sprintf("Entity '%s' not found", entityName))
+		return nil
+	}
+
+	ctx := s.requestContext(c)
+	filter, err := ParseFilterString(ctx, c.Query("$filter"))
+	if err != nil {
+		s.writeError(c, fiber.StatusBadRequest, "InvalidQuery", err.Error())
+		return nil
+	}
+	options := QueryOptions{Filter: filter}
+	if err := s.interceptQuery(ctx, entityName, QueryKindSubscribe, nil, &options); err != nil {
+		s.writeQueryError(c, "SubscribeError", err)
+		return nil
+	}
+
+	webSocket := isWebSocketUpgrade(c)
+	key := c.Get("Sec-WebSocket-Key")
+	if webSocket && (key == "" || c.Get("Sec-WebSocket-Version") != "13") {
+		c.Set("Sec-WebSocket-Version", "13")
+		s.writeError(c, fiber.StatusBadRequest, "InvalidUpgrade", "WebSocket upgrade requires Sec-WebSocket-Key and Sec-WebSocket-Version 13")
+		return nil
+	}
+	// O handshake WebSocket não é protegido pelo CORS: sem esta verificação, qualquer página poderia abrir o
+	// stream com os cookies do usuário
+	if webSocket && !s.isWebSocketOriginAllowed(c) {
+		s.writeError(c, fiber.StatusForbidden, "OriginNotAllowed", fmt.Sprintf("Origin '%s' is not allowed", c.Get(fiber.HeaderOrigin)))
+		return nil
+	}
+
+	tenantID := ""
+	if s.multiTenantPool != nil {
+		tenantID = GetCurrentTenant(c)
+	}
+	sub := &changeSubscriber{
+		entityName: entityName,
+		tenantID:   tenantID,
+		filter:     options.Filter,
+		expiresAt:  s.tokenExpiry(c),
+		messages:   make(chan *changeMessage, changeStreamBuffer),
+		done:       make(chan struct{}),
+	}
+	feed := s.changes()
+	if err := feed.subscribe(sub); err != nil {
+		s.writeError(c, fiber.StatusServiceUnavailable, "SubscribeError", err.Error())
+		return nil
+	}
+
+	// O Fiber Context é reciclado quando o handler retorna: os streams usam apenas o assinante
+	if webSocket {
+		c.Set(fiber.HeaderUpgrade, "websocket")
+		c.Set(fiber.HeaderConnection, "Upgrade")
+		c.Set("Sec-WebSocket-Accept", webSocketAccept(key))
+		c.Status(fiber.StatusSwitchingProtocols)
+		c.RequestCtx().Hijack(func(conn net.Conn) {
+			feed.serveWebSocket(conn, sub)
+		})
+		return nil
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set("X-Accel-Buffering", "no")
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		feed.serveEvents(w, sub)
+	})
+}
+
+// tokenExpiry retorna a expiração do token JWT da requisição, que também encerra o stream
+func (s *Server) tokenExpiry(c fiber.Ctx) time.Time {
+	if s.jwtService == nil || GetCurrentUser(c) == nil {
+		return time.Time{}
+	}
+	claims, err := s.jwtService.ValidateToken(extractToken(c))
+	if err != nil || claims.ExpiresAt == nil {
+		return time.Time{}
+	}
+	return claims.ExpiresAt.Time
+}
+
+// serveEvents escreve as notificações como Server-Sent Events até o cliente desconectar ou o stream ser encerrado
+func (f *changeFeed) serveEvents(w *bufio.Writer, sub *changeSubscriber) {
+	defer f.unsubscribe(sub)
+
+	fmt.Fprintf(w, "retry: %d\n\n", changeStreamRetry)
+	if err := w.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(changeStreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case message := <-sub.messages:
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", message.id, message.event, message.body)
+		case <-heartbeat.C:
+			w.WriteString(": keep-alive\n\n")
+		case <-sub.done:
+			reason, _ := json.Marshal(map[string]string{"reason": sub.reason})
+			fmt.Fprintf(w, "event: close\ndata: %s\n\n", reason)
+			_ = w.Flush()
+			return
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// Opcodes e códigos de fechamento do protocolo WebSocket (RFC 6455)
+const (
+	webSocketOpContinuation = 0x0
+	webSocketOpText         = 0x1
+	webSocketOpBinary       = 0x2
+	webSocketOpClose        = 0x8
+	webSocketOpPing         = 0x9
+	webSocketOpPong         = 0xA
+
+	webSocketCloseNormal    = 1000
+	webSocketCloseGoingAway = 1001
+	webSocketCloseProtocol  = 1002
+	webSocketClosePolicy    = 1008
+
+	webSocketMaxControl = 125 // Maior payload de um frame de controle
+)
+
+// errWebSocketProtocol indica um frame que viola o protocolo; a conexão é encerrada com o código 1002
+var errWebSocketProtocol = errors.New("websocket protocol error")
+
+// serveWebSocket envia as notificações como mensagens de texto na conexão WebSocket. As mensagens do
+// cliente, inclusive as fragmentadas, são ignoradas; apenas ping e close são atendidos
+func (f *changeFeed) serveWebSocket(conn net.Conn, sub *changeSubscriber) {
+	defer conn.Close()
+	defer f.unsubscribe(sub)
+
+	var writeMu sync.Mutex
+	write := func(opcode byte, payload []byte) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		_ = conn.SetWriteDeadline(time.Now().Add(webSocketWriteTimeout))
+		return writeWebSocketFrame(conn, opcode, payload)
+	}
+
+	go func() {
+		reader := bufio.NewReader(conn)
+		fragmented := false // Se uma mensagem fragmentada aguarda o frame final
+		for {
+			fin, opcode, payload, err := readWebSocketFrame(reader)
+			if err == nil && opcode < webSocketOpClose {
+				// Uma continuação só é válida dentro de uma mensagem fragmentada, e uma nova mensagem só depois dela
+				if (opcode == webSocketOpContinuation) != fragmented {
+					err = fmt.Errorf("%w: unexpected %s frame", errWebSocketProtocol, webSocketFrameKind(opcode))
+				} else {
+					fragmented = !fin
+				}
+			}
+			if errors.Is(err, errWebSocketProtocol) {
+				sub.close(webSocketCloseProtocol, err.Error())
+				return
+			}
+			if err != nil {
+				sub.close(webSocketClosePolicy, "connection closed")
+				return
+			}
+			switch opcode {
+			case webSocketOpClose:
+				sub.close(webSocketCloseNormal, "closed by client")
+				return
+			case webSocketOpPing:
+				_ = write(webSocketOpPong, payload)
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(changeStreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		var err error
+		select {
+		case message := <-sub.messages:
+			err = write(webSocketOpText, message.body)
+		case <-heartbeat.C:
+			err = write(webSocketOpPing, nil)
+		case <-sub.done:
+			payload := binary.BigEndian.AppendUint16(nil, sub.closeCode)
+			_ = write(webSocketOpClose, append(payload, sub.reason...))
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// isWebSocketUpgrade verifica se a requisição pede o upgrade para WebSocket
+func isWebSocketUpgrade(c fiber.Ctx) bool {
+	if !strings.EqualFold(c.Get(fiber.HeaderUpgrade), "websocket") {
+		return false
+	}
+	for _, token := range strings.Split(c.Get(fiber.HeaderConnection), ",") {
+		if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+			return true
+		}
+	}
+	return false
+}
+
+// webSocketAccept calcula o Sec-WebSocket-Accept da resposta ao handshake
+func webSocketAccept(key string) string {
+	hash := sha1.Sum([]byte(key + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// writeWebSocketFrame escreve um frame final, sem máscara, como exigido do servidor
+func writeWebSocketFrame(w io.Writer, opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	switch length := len(payload); {
+	case length < 126:
+		header = append(header, byte(length))
+	case length <= 0xFFFF:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// isWebSocketOriginAllowed aceita clientes sem Origin (fora do navegador), a mesma origem do servidor e as
+// origens listadas explicitamente em AllowedOrigins; o curinga "*" do CORS não vale para o WebSocket
+func (s *Server) isWebSocketOriginAllowed(c fiber.Ctx) bool {
+	origin := c.Get(fiber.HeaderOrigin)
+	if origin == "" {
+		return true
+	}
+	if parsed, err := url.Parse(origin); err == nil && parsed.Host != "" && strings.EqualFold(parsed.Host, c.Host()) {
+		return true
+	}
+	for _, allowed := range s.config.AllowedOrigins {
+		if allowed != "*" && strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// webSocketFrameKind descreve o opcode nas mensagens de erro
+func webSocketFrameKind(opcode byte) string {
+	if opcode == webSocketOpContinuation {
+		return "continuation"
+	}
+	return "data"
+}
+
+// readWebSocketFrame lê um frame do cliente, que deve vir com máscara. Frames de controle não podem ser
+// fragmentados nem passar de 125 bytes, e sem extensões negociadas os bits RSV devem ser zero
+func readWebSocketFrame(r *bufio.Reader) (bool, byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin := header[0]&0x80 != 0
+	opcode := header[0] & 0x0F
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, fmt.Errorf("%w: reserved bits must be zero", errWebSocketProtocol)
+	}
+	switch opcode {
+	case webSocketOpContinuation, webSocketOpText, webSocketOpBinary:
+	case webSocketOpClose, webSocketOpPing, webSocketOpPong:
+		if !fin {
+			return false, 0, nil, fmt.Errorf("%w: control frames must not be fragmented", errWebSocketProtocol)
+		}
+	default:
+		return false, 0, nil, fmt.Errorf("%w: unknown opcode %d", errWebSocketProtocol, opcode)
+	}
+	if header[1]&0x80 == 0 {
+		return false, 0, nil, fmt.Errorf("%w: client frames must be masked", errWebSocketProtocol)
+	}
+
+	length := uint64(header[1] & 0x7F)
+	if opcode >= webSocketOpClose && length > webSocketMaxControl {
+		return false, 0, nil, fmt.Errorf("%w: control frame payload exceeds %d bytes", errWebSocketProtocol, webSocketMaxControl)
+	}
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(r, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(r, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	if length > webSocketMaxFrame {
+		return false, 0, nil, fmt.Errorf("%w: frame too large: %d bytes", errWebSocketProtocol, length)
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(r, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
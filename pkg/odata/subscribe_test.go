@@ -0,0 +1,356 @@
+package odata
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listenSubscribeTestServer atende o servidor em uma porta local, já que os streams não passam pelo app.Test
+func listenSubscribeTestServer(t *testing.T, server *Server) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = server.GetRouter().Listener(ln, fiber.ListenConfig{DisableStartupMessage: true}) }()
+	t.Cleanup(func() {
+		server.changes().close()
+		_ = server.GetRouter().ShutdownWithTimeout(time.Second)
+	})
+	return ln.Addr().String()
+}
+
+type sseTestEvent struct {
+	event string
+	data  ChangeNotification
+}
+
+// readSSEEvents interpreta o stream e envia os eventos nomeados pelo canal
+func readSSEEvents(body io.Reader) <-chan sseTestEvent {
+	events := make(chan sseTestEvent, 16)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(body)
+		var current sseTestEvent
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if current.event != "" {
+					events <- current
+				}
+				current = sseTestEvent{}
+			case strings.HasPrefix(line, "event: "):
+				current.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				_ = json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &current.data)
+			}
+		}
+	}()
+	return events
+}
+
+func nextSSEEvent(t *testing.T, events <-chan sseTestEvent) sseTestEvent {
+	select {
+	case event, ok := <-events:
+		require.True(t, ok, "stream closed")
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for server-sent event")
+	}
+	return sseTestEvent{}
+}
+
+func TestServer_SubscribeServerSentEvents(t *testing.T) {
+	server, _, _, _ := newLifecycleTestServer(t)
+	addr := listenSubscribeTestServer(t, server)
+
+	status, _ := sendLifecycleRequest(t, server, "GET", "/odata/Notes/$subscribe?$filter="+url.QueryEscape("text eq"), "")
+	assert.Equal(t, 400, status)
+
+	resp, err := http.Get("http://" + addr + "/odata/Notes/$subscribe?$filter=" + url.QueryEscape("text eq 'urgent'"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	events := readSSEEvents(resp.Body)
+
+	for _, request := range []struct{ method, url, body string }{
+		{"POST", "/odata/Notes", `{"id": "n2", "text": "urgent"}`},
+		{"POST", "/odata/Notes", `{"id": "n3", "text": "later"}`},
+		{"PATCH", "/odata/Notes('n2')", `{"text": "later"}`},
+		{"PATCH", "/odata/Notes('n3')", `{"text": "urgent"}`},
+	} {
+		status, _ := sendLifecycleRequest(t, server, request.method, request.url, request.body)
+		require.Less(t, status, 300, request.url)
+	}
+
+	// Uma gravação revertida não é notificada
+	err = server.RunInTransaction(context.Background(), func(ctx context.Context) error {
+		if _, err := server.insertEntity(ctx, "Notes", server.entities["Notes"], map[string]interface{}{"id": "n4", "text": "urgent"}); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	require.EqualError(t, err, "rollback")
+
+	status, _ = sendLifecycleRequest(t, server, "DELETE", "/odata/Notes('n3')", "")
+	require.Equal(t, 204, status)
+
+	created := nextSSEEvent(t, events)
+	assert.Equal(t, ChangeCreated, created.event)
+	assert.Equal(t, "Notes", created.data.EntitySet)
+	assert.Equal(t, map[string]interface{}{"id": "n2"}, created.data.Keys)
+	assert.Equal(t, map[string]interface{}{"id": "n2", "text": "urgent"}, created.data.Data)
+
+	// A nota que deixou de atender ao filtro sai do conjunto do assinante
+	left := nextSSEEvent(t, events)
+	assert.Equal(t, ChangeDeleted, left.event)
+	assert.Equal(t, "changed", left.data.Reason)
+	assert.Equal(t, map[string]interface{}{"id": "n2"}, left.data.Keys)
+	assert.Nil(t, left.data.Data)
+
+	updated := nextSSEEvent(t, events)
+	assert.Equal(t, ChangeUpdated, updated.event)
+	assert.Equal(t, map[string]interface{}{"id": "n3"}, updated.data.Keys)
+
+	deleted := nextSSEEvent(t, events)
+	assert.Equal(t, ChangeDeleted, deleted.event)
+	assert.Equal(t, "deleted", deleted.data.Reason)
+	assert.Equal(t, map[string]interface{}{"id": "n3"}, deleted.data.Keys)
+
+	server.changes().close()
+	assert.Equal(t, "close", nextSSEEvent(t, events).event)
+}
+
+// dialChangeWebSocket faz o handshake WebSocket com um cliente mínimo
+func dialChangeWebSocket(t *testing.T, addr, path, token string) (net.Conn, *bufio.Reader) {
+	conn, reader, resp := webSocketHandshake(t, addr, path, "Authorization: Bearer "+token)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	require.Equal(t, webSocketAccept(testWebSocketKey), resp.Header.Get("Sec-WebSocket-Accept"))
+	return conn, reader
+}
+
+var testWebSocketKey = base64.StdEncoding.EncodeToString([]byte("godata-subscribe"))
+
+// webSocketHandshake envia o pedido de upgrade com o cabeçalho adicional informado e lê a resposta
+func webSocketHandshake(t *testing.T, addr, path, header string) (net.Conn, *bufio.Reader, *http.Response) {
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n%s\r\n\r\n", path, addr, testWebSocketKey, header)
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	return conn, reader, resp
+}
+
+// writeTestFrame envia um frame do cliente, mascarado; first traz o FIN e o opcode
+func writeTestFrame(t *testing.T, conn net.Conn, first byte, payload []byte) {
+	frame := []byte{first}
+	if len(payload) < 126 {
+		frame = append(frame, 0x80|byte(len(payload)))
+	} else {
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	}
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	_, err := conn.Write(frame)
+	require.NoError(t, err)
+}
+
+// readTestFrame lê um frame do servidor, que vem sem máscara
+func readTestFrame(t *testing.T, conn net.Conn, reader *bufio.Reader) (byte, []byte) {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	header := make([]byte, 2)
+	_, err := io.ReadFull(reader, header)
+	require.NoError(t, err)
+
+	length := int(header[1] & 0x7F)
+	if length == 126 {
+		extended := make([]byte, 2)
+		_, err = io.ReadFull(reader, extended)
+		require.NoError(t, err)
+		length = int(binary.BigEndian.Uint16(extended))
+	}
+	payload := make([]byte, length)
+	_, err = io.ReadFull(reader, payload)
+	require.NoError(t, err)
+	return header[0] & 0x0F, payload
+}
+
+func TestServer_SubscribeWebSocketWithAuth(t *testing.T) {
+	server, _ := newJWTTestServer(t, 2*time.Second)
+	server.SetEntityAuth("Notes", EntityAuthConfig{RequireAuth: true, RequiredRoles: []string{"dashboard"}})
+
+	// O EntityQuerying restringe o que o usuário pode acompanhar
+	server.OnEntityQuerying("Notes", func(args EventArgs) error {
+		querying := args.(*EntityQueryingArgs)
+		if querying.Kind == QueryKindSubscribe {
+			return querying.AddFilterExpression("text ne 'secret'")
+		}
+		return nil
+	})
+	addr := listenSubscribeTestServer(t, server)
+
+	viewer, err := server.jwtService.GenerateToken(&UserIdentity{Username: "ana", Roles: []string{"viewer"}})
+	require.NoError(t, err)
+	for token, expected := range map[string]int{"": 401, viewer: 403} {
+		req := httptest.NewRequest("GET", "/odata/Notes/$subscribe", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := server.GetRouter().Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, expected, resp.StatusCode)
+	}
+
+	token, err := server.jwtService.GenerateToken(&UserIdentity{Username: "bia", Roles: []string{"dashboard"}})
+	require.NoError(t, err)
+	conn, reader := dialChangeWebSocket(t, addr, "/odata/Notes/$subscribe", token)
+
+	service := server.entities["Notes"]
+	_, err = server.insertEntity(context.Background(), "Notes", service, map[string]interface{}{"id": "n1", "text": "secret"})
+	require.NoError(t, err)
+	_, err = server.insertEntity(context.Background(), "Notes", service, map[string]interface{}{"id": "n2", "text": "hello"})
+	require.NoError(t, err)
+
+	opcode, payload := readTestFrame(t, conn, reader)
+	require.Equal(t, byte(webSocketOpText), opcode)
+	var notification ChangeNotification
+	require.NoError(t, json.Unmarshal(payload, &notification))
+	assert.Equal(t, ChangeCreated, notification.Event)
+	assert.Equal(t, map[string]interface{}{"id": "n2"}, notification.Keys)
+
+	// A expiração do token encerra o stream
+	opcode, payload = readTestFrame(t, conn, reader)
+	require.Equal(t, byte(webSocketOpClose), opcode)
+	assert.Equal(t, uint16(webSocketClosePolicy), binary.BigEndian.Uint16(payload))
+	assert.Equal(t, "token expired", string(payload[2:]))
+}
+
+func TestServer_SubscribeWebSocketOrigin(t *testing.T) {
+	server, _, _, _ := newLifecycleTestServer(t)
+	server.config.AllowedOrigins = []string{"*", "https://app.example"}
+	addr := listenSubscribeTestServer(t, server)
+
+	// O curinga do CORS não libera o WebSocket para outras origens
+	_, _, resp := webSocketHandshake(t, addr, "/odata/Notes/$subscribe", "Origin: https://evil.example")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	for _, origin := range []string{"http://" + addr, "https://app.example"} {
+		_, _, resp := webSocketHandshake(t, addr, "/odata/Notes/$subscribe", "Origin: "+origin)
+		assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode, origin)
+	}
+}
+
+func TestServer_SubscribeWebSocketProtocol(t *testing.T) {
+	server, _, _, _ := newLifecycleTestServer(t)
+	addr := listenSubscribeTestServer(t, server)
+
+	t.Run("fragmented messages are ignored", func(t *testing.T) {
+		conn, reader := dialChangeWebSocket(t, addr, "/odata/Notes/$subscribe", "")
+		writeTestFrame(t, conn, webSocketOpText, []byte("hel"))
+		// Frames de controle podem vir entre os fragmentos
+		writeTestFrame(t, conn, 0x80|webSocketOpPing, []byte("a"))
+		writeTestFrame(t, conn, 0x80|webSocketOpContinuation, []byte("lo"))
+		writeTestFrame(t, conn, 0x80|webSocketOpPing, []byte("b"))
+
+		for _, expected := range []string{"a", "b"} {
+			opcode, payload := readTestFrame(t, conn, reader)
+			assert.Equal(t, byte(webSocketOpPong), opcode)
+			assert.Equal(t, expected, string(payload))
+		}
+	})
+
+	for name, frame := range map[string]struct {
+		first   byte
+		payload []byte
+	}{
+		"control frame over 125 bytes": {0x80 | webSocketOpPing, make([]byte, 126)},
+		"fragmented control frame":     {webSocketOpPing, nil},
+		"unexpected continuation":      {0x80 | webSocketOpContinuation, []byte("x")},
+		"reserved bits":                {0xC0 | webSocketOpText, []byte("x")},
+		"unknown opcode":               {0x80 | 0x3, nil},
+	} {
+		t.Run(name, func(t *testing.T) {
+			conn, reader := dialChangeWebSocket(t, addr, "/odata/Notes/$subscribe", "")
+			writeTestFrame(t, conn, frame.first, frame.payload)
+
+			opcode, payload := readTestFrame(t, conn, reader)
+			require.Equal(t, byte(webSocketOpClose), opcode)
+			assert.Equal(t, uint16(webSocketCloseProtocol), binary.BigEndian.Uint16(payload))
+		})
+	}
+
+	t.Run("new message inside a fragmented one", func(t *testing.T) {
+		conn, reader := dialChangeWebSocket(t, addr, "/odata/Notes/$subscribe", "")
+		writeTestFrame(t, conn, webSocketOpText, []byte("hel"))
+		writeTestFrame(t, conn, 0x80|webSocketOpText, []byte("lo"))
+
+		opcode, payload := readTestFrame(t, conn, reader)
+		require.Equal(t, byte(webSocketOpClose), opcode)
+		assert.Equal(t, uint16(webSocketCloseProtocol), binary.BigEndian.Uint16(payload))
+	})
+}
+
+func TestServer_SubscribeMultiTenant(t *testing.T) {
+	db := newFakeDB(t, nil)
+	server := newMultiTenantTestServer(t, map[string]DatabaseProvider{
+		"default": &MockDatabaseProvider{connection: db.DB},
+		"acme":    &MockDatabaseProvider{connection: db.DB},
+		"globex":  &MockDatabaseProvider{connection: db.DB},
+	})
+	require.NoError(t, server.RegisterEntityWithService("Notes", &lifecycleTestNotes{notes: map[string]map[string]interface{}{}}))
+	addr := listenSubscribeTestServer(t, server)
+
+	subscribe := func(tenantID string) <-chan sseTestEvent {
+		req, err := http.NewRequest("GET", "http://"+addr+"/odata/Notes/$subscribe", nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Tenant-ID", tenantID)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		require.Equal(t, 200, resp.StatusCode)
+		return readSSEEvents(resp.Body)
+	}
+	acme := subscribe("acme")
+	globex := subscribe("globex")
+
+	insert := func(tenantID, id string) {
+		req := httptest.NewRequest("POST", "/odata/Notes", strings.NewReader(`{"id": "`+id+`", "text": "hello"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Tenant-ID", tenantID)
+		resp, err := server.GetRouter().Test(req)
+		require.NoError(t, err)
+		require.Equal(t, 201, resp.StatusCode)
+	}
+	insert("acme", "a1")
+	insert("globex", "g1")
+
+	// Cada assinante recebe apenas as gravações do seu tenant; a ordem garante que a1 não chegou ao globex
+	assert.Equal(t, map[string]interface{}{"id": "a1"}, nextSSEEvent(t, acme).data.Keys)
+	assert.Equal(t, map[string]interface{}{"id": "g1"}, nextSSEEvent(t, globex).data.Keys)
+
+	insert("acme", "a2")
+	assert.Equal(t, map[string]interface{}{"id": "a2"}, nextSSEEvent(t, acme).data.Keys)
+}
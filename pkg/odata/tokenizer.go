@@ -53,6 +53,8 @@ const (
 	FilterTokenDuration
 	FilterTokenGeographyPoint
 	FilterTokenGeometryPoint
+	FilterTokenLambda         // Operador lambda sobre coleção de navegação (Orders/any, Orders/all)
+	FilterTokenLambdaVariable // Variável de alcance do lambda (o:)
 )
 
 // GetGlobalFilterTokenizer retorna o tokenizer global para filtros
@@ -79,6 +81,16 @@ func createFilterTokenizer() *Tokenizer {
 	// Funções (lista completa de funções OData)
 	t.Add(`^(?i)\b(contains|startswith|endswith|length|indexof|substring|tolower|toupper|trim|concat|year|month|day|hour|minute|second|now|date|time|round|floor|ceiling|cast|isof)\b`, int(FilterTokenFunction))
 
+	// Operadores lambda: caminho da coleção seguido de /any ou /all
+	t.Add(`^(?i)(\$it|[a-zA-Z_][a-zA-Z0-9_]*)(/[a-zA-Z_][a-zA-Z0-9_]*)*/(any|all)\b`, int(FilterTokenLambda))
+
+	// Variável de alcance do lambda (o: ...)
+	t.Add(`^[a-zA-Z_][a-zA-Z0-9_]*\s*:`, int(FilterTokenLambdaVariable))
+
+	// Caminhos de propriedade com segmentos (o/Total, $it/Name)
+	t.Add(`^(\$it|[a-zA-Z_][a-zA-Z0-9_]*)(/[a-zA-Z_][a-zA-Z0-9_]*)+`, int(FilterTokenProperty))
+	t.Add(`^\$it\b`, int(FilterTokenProperty))
+
 	// Parênteses
 	t.Add(`^\(`, int(FilterTokenOpenParen))
 	t.Add(`^\)`, int(FilterTokenCloseParen))
@@ -0,0 +1,250 @@
+package odata
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// savepointCounter gera nomes únicos para os savepoints das unidades de trabalho aninhadas
+var savepointCounter atomic.Int64
+
+// WithTx associa uma transação ao contexto. As operações do BaseEntityService e os handlers de eventos
+// que recebem esse contexto executam os comandos nela
+func WithTx(ctx context.Context, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, TxContextKey, tx)
+}
+
+// TxFromContext retorna a transação associada ao contexto
+func TxFromContext(ctx context.Context) (*sql.Tx, bool) {
+	tx, ok := ctx.Value(TxContextKey).(*sql.Tx)
+	return tx, ok && tx != nil
+}
+
+// txCallbacksContextKeyType define a chave das funções agendadas para depois da confirmação da transação
+type txCallbacksContextKeyType struct{}
+
+var txCallbacksContextKey = txCallbacksContextKeyType{}
+
+// txCallbacks guarda as funções agendadas com AfterCommit em uma transação ou savepoint
+type txCallbacks struct {
+	mu  sync.Mutex
+	fns []func()
+}
+
+func (t *txCallbacks) add(fns ...func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.fns = append(t.fns, fns...)
+}
+
+func (t *txCallbacks) take() []func() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fns := t.fns
+	t.fns = nil
+	return fns
+}
+
+// withTxCallbacks associa ao contexto a lista de funções da transação ou savepoint
+func withTxCallbacks(ctx context.Context, callbacks *txCallbacks) context.Context {
+	return context.WithValue(ctx, txCallbacksContextKey, callbacks)
+}
+
+// AfterCommit agenda fn para depois da confirmação da transação do contexto e a descarta se a transação, ou o
+// savepoint em que foi agendada, for revertida. Sem transação aberta por uma UnitOfWork, fn executa imediatamente
+func AfterCommit(ctx context.Context, fn func()) {
+	if callbacks, ok := ctx.Value(txCallbacksContextKey).(*txCallbacks); ok && callbacks != nil {
+		if _, inTx := TxFromContext(ctx); inTx {
+			callbacks.add(fn)
+			return
+		}
+	}
+	fn()
+}
+
+// UnitOfWork executa um conjunto de operações em uma única transação do provider
+type UnitOfWork struct {
+	provider DatabaseProvider
+	options  *sql.TxOptions
+}
+
+// NewUnitOfWork cria uma unidade de trabalho; options define o nível de isolamento e se é somente leitura
+func NewUnitOfWork(provider DatabaseProvider, options *sql.TxOptions) *UnitOfWork {
+	return &UnitOfWork{provider: provider, options: options}
+}
+
+// Run executa fn em uma transação, confirmada ao final ou revertida se fn retornar erro ou entrar em pânico.
+// Se o contexto já possui uma transação, fn roda em um savepoint: o erro desfaz apenas as suas alterações
+func (u *UnitOfWork) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	return u.run(ctx, fn, true)
+}
+
+// run executa fn na transação; com uma transação já aberta no contexto, savepoint define se fn é isolada
+// em um savepoint ou simplesmente participa da transação externa
+func (u *UnitOfWork) run(ctx context.Context, fn func(ctx context.Context) error, savepoint bool) error {
+	if tx, ok := TxFromContext(ctx); ok {
+		if !savepoint {
+			return fn(ctx)
+		}
+		return u.runInSavepoint(ctx, tx, fn)
+	}
+
+	if u.provider == nil || u.provider.GetConnection() == nil {
+		return fmt.Errorf("database connection is nil - cannot start transaction")
+	}
+
+	tx, err := u.provider.GetConnection().BeginTx(ctx, u.options)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if panicValue := recover(); panicValue != nil {
+			tx.Rollback()
+			panic(panicValue)
+		}
+	}()
+
+	callbacks := &txCallbacks{}
+	if err := fn(withTxCallbacks(WithTx(ctx, tx), callbacks)); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rollbackErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	for _, callback := range callbacks.take() {
+		callback()
+	}
+	return nil
+}
+
+// runInSavepoint executa fn em um savepoint da transação externa
+func (u *UnitOfWork) runInSavepoint(ctx context.Context, tx *sql.Tx, fn func(ctx context.Context) error) error {
+	name := fmt.Sprintf("godata_sp_%d", savepointCounter.Add(1))
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	rollback := func() error {
+		_, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+		return err
+	}
+
+	defer func() {
+		if panicValue := recover(); panicValue != nil {
+			rollback()
+			panic(panicValue)
+		}
+	}()
+
+	// As funções agendadas no savepoint só passam para a transação externa se ele não for revertido
+	callbacks := &txCallbacks{}
+	if err := fn(withTxCallbacks(ctx, callbacks)); err != nil {
+		if rollbackErr := rollback(); rollbackErr != nil {
+			return fmt.Errorf("%w (rollback to savepoint failed: %v)", err, rollbackErr)
+		}
+		return err
+	}
+
+	// O Oracle não possui RELEASE SAVEPOINT; o savepoint é descartado no fim da transação
+	if u.provider == nil || u.provider.GetDriverName() != "oracle" {
+		if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+			return fmt.Errorf("failed to release savepoint: %w", err)
+		}
+	}
+
+	AfterCommit(ctx, func() {
+		for _, callback := range callbacks.take() {
+			callback()
+		}
+	})
+	return nil
+}
+
+// txOptions retorna as opções das transações abertas pelo servidor conforme a configuração
+func (s *Server) txOptions() *sql.TxOptions {
+	if s.config.TransactionIsolation == sql.LevelDefault {
+		return nil
+	}
+	return &sql.TxOptions{Isolation: s.config.TransactionIsolation}
+}
+
+// RunInTransaction executa fn em uma unidade de trabalho no provider do tenant da requisição (quando o
+// contexto vem de um handler), ou no provider padrão, com o isolamento configurado. Dentro de uma transação,
+// usa um savepoint. Entidades registradas com um provider próprio não participam dessa transação; para elas
+// use RunInEntityTransaction
+func (s *Server) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return NewUnitOfWork(s.contextProvider(ctx), s.txOptions()).Run(ctx, fn)
+}
+
+// RunInEntityTransaction é como RunInTransaction, mas abre a unidade de trabalho no provider em que a entidade
+// grava: o provider próprio do serviço ou, sem ele, o do tenant da requisição
+func (s *Server) RunInEntityTransaction(ctx context.Context, entityName string, fn func(ctx context.Context) error) error {
+	service, exists := s.entityService(entityName)
+	if !exists {
+		return fmt.Errorf("entity '%s' is not registered", entityName)
+	}
+	return NewUnitOfWork(s.contextServiceProvider(ctx, service), s.txOptions()).Run(ctx, fn)
+}
+
+// contextProvider retorna o provider do tenant da requisição (ou do TenantContextKey) associada ao contexto,
+// ou o provider padrão
+func (s *Server) contextProvider(ctx context.Context) DatabaseProvider {
+	if c, ok := ctx.Value(FiberContextKey).(fiber.Ctx); ok && c != nil {
+		return s.getCurrentProvider(c)
+	}
+	if tenantID, ok := ctx.Value(TenantContextKey).(string); ok && s.multiTenantPool != nil {
+		return s.multiTenantPool.GetProvider(tenantID)
+	}
+	return s.provider
+}
+
+// contextServiceProvider é a versão de serviceProvider para um contexto: retorna o provider em que o serviço
+// grava, ou o provider do contexto quando o serviço não tem um próprio
+func (s *Server) contextServiceProvider(ctx context.Context, service EntityService) DatabaseProvider {
+	if base, ok := service.(*BaseEntityService); ok && base.provider != nil {
+		return base.provider
+	}
+	return s.contextProvider(ctx)
+}
+
+// runInTransaction executa fn na transação do contexto (changeset do $batch) ou em uma nova transação do provider
+func (s *Server) runInTransaction(ctx context.Context, provider DatabaseProvider, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	var result interface{}
+	err := NewUnitOfWork(provider, s.txOptions()).run(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = fn(ctx)
+		return err
+	}, false)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// hasWriteHandlers indica se há handlers de escrita ou outbox para a entidade; nesse caso a gravação, os handlers
+// e o outbox rodam na mesma transação, e o erro de qualquer um deles desfaz a operação
+func (s *Server) hasWriteHandlers(entityName string) bool {
+	return s.outboxStore(entityName) != nil || s.eventManager.HasHandlers(entityName,
+		EventEntityInserting, EventEntityInserted,
+		EventEntityModifying, EventEntityModified,
+		EventEntityDeleting, EventEntityDeleted)
+}
+
+// Tx retorna a transação da operação que disparou o evento, para que o handler grave na mesma unidade de trabalho
+func (e *EventContext) Tx() (*sql.Tx, bool) {
+	if e == nil || e.Context == nil {
+		return nil, false
+	}
+	return TxFromContext(e.Context)
+}
@@ -0,0 +1,131 @@
+package odata
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitOfWork_Run(t *testing.T) {
+	provider, db := newConstraintTestProvider(t)
+	uow := NewUnitOfWork(provider, &sql.TxOptions{Isolation: sql.LevelSerializable})
+
+	err := uow.Run(context.Background(), func(ctx context.Context) error {
+		tx, ok := TxFromContext(ctx)
+		require.True(t, ok)
+		_, err := tx.ExecContext(ctx, "INSERT INTO audit")
+		return err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BEGIN Serializable", "INSERT INTO audit", "COMMIT"}, db.entries())
+
+	db.log = nil
+	failure := errors.New("handler failed")
+	err = uow.Run(context.Background(), func(ctx context.Context) error { return failure })
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, []string{"BEGIN Serializable", "ROLLBACK"}, db.entries())
+
+	db.log = nil
+	assert.Panics(t, func() {
+		uow.Run(context.Background(), func(ctx context.Context) error { panic("boom") })
+	})
+	assert.Equal(t, []string{"BEGIN Serializable", "ROLLBACK"}, db.entries())
+}
+
+func TestUnitOfWork_Savepoints(t *testing.T) {
+	provider, db := newConstraintTestProvider(t)
+	uow := NewUnitOfWork(provider, nil)
+
+	err := uow.Run(context.Background(), func(ctx context.Context) error {
+		// O erro da unidade aninhada desfaz apenas o seu savepoint
+		inner := uow.Run(ctx, func(ctx context.Context) error { return errors.New("inner failed") })
+		assert.EqualError(t, inner, "inner failed")
+
+		return uow.Run(ctx, func(ctx context.Context) error { return nil })
+	})
+	require.NoError(t, err)
+
+	entries := db.entries()
+	require.Len(t, entries, 6)
+	assert.Equal(t, "BEGIN", entries[0])
+	assert.Regexp(t, `^SAVEPOINT godata_sp_\d+$`, entries[1])
+	assert.Equal(t, "ROLLBACK TO "+entries[1], entries[2])
+	assert.Regexp(t, `^SAVEPOINT godata_sp_\d+$`, entries[3])
+	assert.Equal(t, "RELEASE "+entries[3], entries[4])
+	assert.Equal(t, "COMMIT", entries[5])
+}
+
+func TestServer_RunInTransaction(t *testing.T) {
+	provider, db := newConstraintTestProvider(t)
+	server := newTestServer(provider, func(config *ServerConfig) { config.TransactionIsolation = sql.LevelRepeatableRead })
+	require.NoError(t, server.RegisterEntity("Users", ConstraintTestUser{}))
+	service := server.entities["Users"].(*BaseEntityService)
+
+	// As operações do serviço usam a transação do contexto
+	err := server.RunInTransaction(context.Background(), func(ctx context.Context) error {
+		if _, err := service.Update(ctx, map[string]interface{}{"id": "u1"}, map[string]interface{}{"bio": "hi"}); err != nil {
+			return err
+		}
+		return errors.New("rollback everything")
+	})
+	assert.EqualError(t, err, "rollback everything")
+	assert.Equal(t, []string{"BEGIN Repeatable Read", "UPDATE users", "ROLLBACK"}, db.entries())
+}
+
+func TestServer_RunInEntityTransaction(t *testing.T) {
+	server, _, db, _ := newLifecycleTestServer(t)
+	archive, archiveDB := newConstraintTestProvider(t)
+	metadata, err := NewEntityMapper().MapEntity(ConstraintTestUser{})
+	require.NoError(t, err)
+	service := NewBaseEntityService(archive, metadata, server)
+	require.NoError(t, server.RegisterEntityWithService("Archive", service))
+
+	// A unidade de trabalho é aberta no banco da entidade, não no provider padrão
+	err = server.RunInEntityTransaction(context.Background(), "Archive", func(ctx context.Context) error {
+		_, err := service.Update(ctx, map[string]interface{}{"id": "u1"}, map[string]interface{}{"bio": "hi"})
+		return err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BEGIN", "UPDATE users", "COMMIT"}, archiveDB.entries())
+	assert.Empty(t, db.entries())
+
+	err = server.RunInEntityTransaction(context.Background(), "Invoices", func(ctx context.Context) error { return nil })
+	assert.EqualError(t, err, "entity 'Invoices' is not registered")
+}
+
+func TestAfterCommit(t *testing.T) {
+	provider, db := newConstraintTestProvider(t)
+	uow := NewUnitOfWork(provider, nil)
+
+	var ran []string
+	record := func(name string) func() {
+		return func() { ran = append(ran, name+" after "+db.entries()[len(db.entries())-1]) }
+	}
+
+	err := uow.Run(context.Background(), func(ctx context.Context) error {
+		AfterCommit(ctx, record("outer"))
+
+		// As funções de um savepoint revertido são descartadas
+		_ = uow.Run(ctx, func(ctx context.Context) error {
+			AfterCommit(ctx, record("reverted"))
+			return errors.New("inner failed")
+		})
+		require.NoError(t, uow.Run(ctx, func(ctx context.Context) error {
+			AfterCommit(ctx, record("nested"))
+			return nil
+		}))
+
+		assert.Empty(t, ran)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"outer after COMMIT", "nested after COMMIT"}, ran)
+
+	// Sem transação a função executa imediatamente
+	AfterCommit(context.Background(), func() { ran = append(ran, "now") })
+	assert.Equal(t, "now", ran[2])
+}
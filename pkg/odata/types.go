@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
 )
@@ -16,6 +17,8 @@ type ODataResponse struct {
 	NextLink string      `json:"@odata.nextLink,omitempty"`
 	Value    interface{} `json:"value"`
 	Error    *ODataError `json:"error,omitempty"`
+
+	NextSkipToken string `json:"-"` // Token da próxima página, usado para montar o NextLink
 }
 
 // ODataError representa um erro OData
@@ -56,6 +59,10 @@ type QueryOptions struct {
 	Count   *GoDataCountQuery
 	Compute *ComputeOption
 	Search  *SearchOption
+	Apply   *ApplyOption
+
+	SkipToken   *SkipToken // Continuação da paginação dirigida pelo servidor ($skiptoken)
+	MaxPageSize int        // Tamanho máximo de página definido pelo servidor (0 = sem limite)
 }
 
 // EntityMetadata representa os metadados de uma entidade
@@ -65,6 +72,21 @@ type EntityMetadata struct {
 	Schema     string // Schema da tabela
 	Properties []PropertyMetadata
 	Keys       []string
+	ETag       *ETagMetadata // Token de concorrência otimista (nil quando a entidade não usa ETag)
+}
+
+// ETagMode representa a forma de cálculo do ETag de uma entidade
+type ETagMode string
+
+const (
+	ETagModeVersion ETagMode = "version" // Coluna de versão incrementada a cada UPDATE
+	ETagModeHash    ETagMode = "hash"    // Hash dos valores das propriedades selecionadas
+)
+
+// ETagMetadata representa os metadados do token de concorrência de uma entidade
+type ETagMetadata struct {
+	Mode       ETagMode
+	Properties []string // Propriedades que compõem o token
 }
 
 // PropertyMetadata representa os metadados de uma propriedade
@@ -90,6 +112,12 @@ type PropertyMetadata struct {
 	Schema          string                   // Schema da tabela
 	Association     *AssociationMetadata     // Para associações simples
 	ManyAssociation *ManyAssociationMetadata // Para associações múltiplas
+	ContentType     string                   // Content type do $value de propriedades binárias
+	// Regras de validação declaradas na tag odata (min, max, pattern, enum)
+	Minimum    *float64
+	Maximum    *float64
+	Pattern    *regexp.Regexp
+	EnumValues []string
 }
 
 // RelationshipMetadata representa os metadados de um relacionamento
@@ -132,6 +160,64 @@ const (
 	IDGeneratorSmartGuid IDGeneratorType = "smartGuid"
 )
 
+// CascadeType representa as flags da tag cascade
+type CascadeType string
+
+const (
+	CascadeSaveUpdate   CascadeType = "SaveUpdate"   // Permite criar e atualizar as entidades aninhadas no corpo
+	CascadeRemove       CascadeType = "Remove"       // Remove as entidades relacionadas junto com a entidade
+	CascadeRefresh      CascadeType = "Refresh"      // Recarrega as entidades relacionadas
+	CascadeRemoveOrphan CascadeType = "RemoveOrphan" // Remove as entidades que deixaram a coleção em um deep update
+)
+
+// HasCascade verifica se a propriedade de navegação possui a flag de cascade
+func (p PropertyMetadata) HasCascade(flag CascadeType) bool {
+	flags := append([]string(nil), p.CascadeFlags...)
+	if p.Association != nil {
+		flags = append(flags, p.Association.CascadeFlags...)
+	}
+	if p.ManyAssociation != nil {
+		flags = append(flags, p.ManyAssociation.CascadeFlags...)
+	}
+	for _, f := range flags {
+		if strings.EqualFold(f, string(flag)) {
+			return true
+		}
+	}
+	return false
+}
+
+// PropFlag representa as flags da tag prop
+type PropFlag string
+
+const (
+	PropRequired PropFlag = "Required" // Valor obrigatório: não pode ser omitido na inserção nem receber null
+	PropNoInsert PropFlag = "NoInsert" // Não é gravada no INSERT (valor do banco, trigger ou coluna calculada)
+	PropNoUpdate PropFlag = "NoUpdate" // Não é gravada no UPDATE (imutável depois da inserção)
+	PropLazy     PropFlag = "Lazy"     // Carregada apenas quando informada no $select
+	PropUnique   PropFlag = "Unique"   // Valor único entre as entidades do conjunto
+)
+
+// HasPropFlag verifica se a propriedade possui a flag da tag prop
+func (p PropertyMetadata) HasPropFlag(flag PropFlag) bool {
+	for _, f := range p.PropFlags {
+		if strings.EqualFold(f, string(flag)) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsInsertable indica se a propriedade é gravada no INSERT
+func (p PropertyMetadata) IsInsertable() bool {
+	return !p.IsNavigation && !p.HasPropFlag(PropNoInsert)
+}
+
+// IsUpdatable indica se a propriedade é gravada no UPDATE
+func (p PropertyMetadata) IsUpdatable() bool {
+	return !p.IsNavigation && !p.IsKey && !p.HasPropFlag(PropNoUpdate)
+}
+
 // DatabaseProvider interface para os providers de banco
 type DatabaseProvider interface {
 	Connect(connectionString string) error
@@ -148,6 +234,14 @@ type DatabaseProvider interface {
 	FormatDateTime(t time.Time) string
 }
 
+// ReturningProvider é a capacidade opcional dos providers de devolver a linha gravada pelo INSERT/UPDATE,
+// com os valores preenchidos pelo banco (identity, defaults, triggers), sem um SELECT adicional do serviço.
+// A linha é devolvida como coluna → valor; nil indica que nenhuma linha foi afetada
+type ReturningProvider interface {
+	InsertReturning(ctx context.Context, exec SQLExecutor, entity EntityMetadata, data map[string]interface{}) (map[string]interface{}, error)
+	UpdateReturning(ctx context.Context, exec SQLExecutor, entity EntityMetadata, data map[string]interface{}, keyValues map[string]interface{}) (map[string]interface{}, error)
+}
+
 // EntityService interface para serviços de entidade
 type EntityService interface {
 	GetMetadata() EntityMetadata
@@ -297,6 +391,7 @@ type EntityContainerMetadata struct {
 
 // OrderedEntity representa uma entidade com propriedades ordenadas
 type OrderedEntity struct {
+	ETag            string            `json:"-"`
 	Properties      []OrderedProperty `json:"-"`
 	NavigationLinks []NavigationLink  `json:"-"`
 	data            map[string]interface{}
@@ -344,6 +439,17 @@ func (e *OrderedEntity) Set(name string, value interface{}) {
 	e.data[name] = value
 }
 
+// Remove remove uma propriedade mantendo a ordem das demais
+func (e *OrderedEntity) Remove(name string) {
+	for i, prop := range e.Properties {
+		if prop.Name == name {
+			e.Properties = append(e.Properties[:i], e.Properties[i+1:]...)
+			break
+		}
+	}
+	delete(e.data, name)
+}
+
 // SetNavigationProperty adiciona uma propriedade de navegação como link
 func (e *OrderedEntity) SetNavigationProperty(name string, navigationURL string) {
 	// Verifica se o navigation link já existe
@@ -379,6 +485,15 @@ func (e *OrderedEntity) MarshalJSON() ([]byte, error) {
 	// Constrói o JSON mantendo a ordem das propriedades
 	var pairs []string
 
+	// Adiciona o ETag antes das propriedades
+	if e.ETag != "" {
+		etagJSON, err := json.Marshal(e.ETag)
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, fmt.Sprintf(`"@odata.etag":%s`, string(etagJSON)))
+	}
+
 	// Adiciona propriedades normais
 	for _, prop := range e.Properties {
 		// Serializa o valor
@@ -420,7 +535,11 @@ func (e *OrderedEntity) UnmarshalJSON(data []byte) error {
 
 	// Adiciona as propriedades na ordem que aparecem no JSON
 	for key, value := range temp {
-		if strings.HasSuffix(key, "@odata.navigationLink") {
+		if key == "@odata.etag" {
+			if etag, ok := value.(string); ok {
+				e.ETag = etag
+			}
+		} else if strings.HasSuffix(key, "@odata.navigationLink") {
 			// É um navigation link
 			propName := strings.TrimSuffix(key, "@odata.navigationLink")
 			e.SetNavigationProperty(propName, value.(string))
@@ -436,6 +555,7 @@ func (e *OrderedEntity) UnmarshalJSON(data []byte) error {
 // OrderedEntityResponse representa uma resposta de entidade única mantendo a ordem dos campos
 type OrderedEntityResponse struct {
 	Context         string                   `json:"@odata.context"`
+	ETag            string                   `json:"@odata.etag,omitempty"`
 	Fields          []ResponseField          `json:"-"`
 	NavigationLinks []ResponseNavigationLink `json:"-"`
 	entityMetadata  EntityMetadata           `json:"-"`
@@ -490,6 +610,14 @@ func (r *OrderedEntityResponse) MarshalJSON() ([]byte, error) {
 	}
 	pairs = append(pairs, fmt.Sprintf(`"@odata.context":%s`, string(contextJSON)))
 
+	if r.ETag != "" {
+		etagJSON, err := json.Marshal(r.ETag)
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, fmt.Sprintf(`"@odata.etag":%s`, string(etagJSON)))
+	}
+
 	// Adiciona campos na ordem dos metadados da entidade
 	fieldsMap := make(map[string]interface{})
 	for _, field := range r.Fields {
@@ -33,6 +33,7 @@ var supportedODataKeywords = map[string]bool{
 	"$search":      true,
 	"$compute":     true,
 	"$format":      true,
+	"$skiptoken":   true,
 }
 
 // Configuração de compliance OData otimizada
@@ -537,6 +538,7 @@ func (up *URLParser) ExtractODataSystemParams(values url.Values) map[string]stri
 	systemKeys := []string{
 		"$filter", "$orderby", "$top", "$skip", "$count",
 		"$select", "$expand", "$search", "$compute", "$apply",
+		"$skiptoken",
 	}
 
 	for _, key := range systemKeys {
@@ -0,0 +1,164 @@
+package odata
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// ValidationError reúne as violações das regras de validação de uma entidade, uma por propriedade
+type ValidationError struct {
+	Entity  string
+	Details []ODataErrorDetail
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Details))
+	for _, detail := range e.Details {
+		messages = append(messages, detail.Message)
+	}
+	return fmt.Sprintf("validation failed for %s: %s", e.Entity, strings.Join(messages, "; "))
+}
+
+// validateEntity executa a fase de validação antes dos handlers de gravação, qualquer que seja o EntityService:
+// as regras das tags (required, length, min, max, pattern, enum) e depois os validadores registrados em
+// EntityValidating. keys é nil na inserção
+func (s *Server) validateEntity(eventCtx *EventContext, metadata EntityMetadata, keys, data map[string]any) error {
+	details := validateProperties(metadata, data, keys == nil)
+
+	validating := NewEntityValidatingArgs(eventCtx, keys, data, details)
+	if err := s.eventManager.Emit(validating); err != nil {
+		return err
+	}
+	details = append(validating.Details, untargetedErrors(validating.ValidationErrors, validating.Details)...)
+
+	if err := s.eventManager.Emit(NewEntityValidatedArgs(eventCtx, keys, data, details)); err != nil {
+		return err
+	}
+
+	if len(details) > 0 {
+		return &ValidationError{Entity: metadata.Name, Details: details}
+	}
+	return nil
+}
+
+// validateProperties verifica as regras declaradas nas tags das propriedades presentes nos dados.
+// Na atualização parcial as propriedades omitidas não são validadas, e as que a operação não grava
+// (chaves e NoUpdate na atualização, NoInsert na inserção) são ignoradas
+func validateProperties(metadata EntityMetadata, data map[string]any, insert bool) []ODataErrorDetail {
+	var details []ODataErrorDetail
+	for _, prop := range metadata.Properties {
+		if (insert && !prop.IsInsertable()) || (!insert && !prop.IsUpdatable()) {
+			continue
+		}
+
+		value, exists := data[prop.Name]
+		if !exists || value == nil {
+			if prop.HasPropFlag(PropRequired) && (exists || (insert && isRequiredOnInsert(prop))) {
+				details = append(details, ODataErrorDetail{
+					Code:    "RequiredProperty",
+					Message: (&PropertyConstraintError{Entity: metadata.Name, Property: prop.Name, Constraint: PropRequired}).Error(),
+					Target:  prop.Name,
+				})
+			}
+			continue
+		}
+
+		if detail, ok := validatePropertyValue(prop, value); !ok {
+			details = append(details, detail)
+		}
+	}
+	return details
+}
+
+// untargetedErrors converte as mensagens adicionadas diretamente em ValidationErrors, sem AddError,
+// em detalhes sem propriedade alvo
+func untargetedErrors(validationErrors []string, details []ODataErrorDetail) []ODataErrorDetail {
+	known := make(map[string]int, len(details))
+	for _, detail := range details {
+		known[detail.Message]++
+	}
+
+	var extra []ODataErrorDetail
+	for _, message := range validationErrors {
+		if known[message] > 0 {
+			known[message]--
+			continue
+		}
+		extra = append(extra, ODataErrorDetail{Code: "ValidationError", Message: message})
+	}
+	return extra
+}
+
+// isRequiredOnInsert indica se a propriedade Required precisa de valor na inserção: chaves geradas,
+// colunas com default e NoInsert são preenchidas pelo servidor ou pelo banco
+func isRequiredOnInsert(prop PropertyMetadata) bool {
+	return !prop.HasDefault && prop.IsInsertable() && !isGeneratedKey(prop)
+}
+
+// validatePropertyValue aplica as regras length, min, max, pattern e enum ao valor de uma propriedade
+func validatePropertyValue(prop PropertyMetadata, value any) (ODataErrorDetail, bool) {
+	invalid := func(code, format string, args ...any) (ODataErrorDetail, bool) {
+		return ODataErrorDetail{Code: code, Message: fmt.Sprintf(format, args...), Target: prop.Name}, false
+	}
+
+	if str, ok := value.(string); ok {
+		if prop.MaxLength > 0 && utf8.RuneCountInString(str) > prop.MaxLength {
+			return invalid("MaxLength", "property '%s' exceeds the maximum length of %d", prop.Name, prop.MaxLength)
+		}
+		if prop.Pattern != nil && !prop.Pattern.MatchString(str) {
+			return invalid("Pattern", "property '%s' does not match the pattern %s", prop.Name, prop.Pattern.String())
+		}
+	}
+
+	if number, ok := numericValue(value); ok {
+		if prop.Minimum != nil && number < *prop.Minimum {
+			return invalid("Minimum", "property '%s' must be greater than or equal to %v", prop.Name, *prop.Minimum)
+		}
+		if prop.Maximum != nil && number > *prop.Maximum {
+			return invalid("Maximum", "property '%s' must be less than or equal to %v", prop.Name, *prop.Maximum)
+		}
+	}
+
+	if len(prop.EnumValues) > 0 {
+		text := fmt.Sprint(value)
+		for _, allowed := range prop.EnumValues {
+			if text == allowed {
+				return ODataErrorDetail{}, true
+			}
+		}
+		return invalid("Enum", "property '%s' must be one of: %s", prop.Name, strings.Join(prop.EnumValues, ", "))
+	}
+
+	return ODataErrorDetail{}, true
+}
+
+// numericValue converte valores numéricos (float64 do JSON ou tipos inteiros das structs) para float64
+func numericValue(value any) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// writeValidationError responde 400 com uma entrada em details para cada violação
+func (s *Server) writeValidationError(c fiber.Ctx, err *ValidationError) {
+	c.Set("Content-Type", "application/json")
+	c.Status(fiber.StatusBadRequest)
+	c.JSON(ODataResponse{
+		Error: &ODataError{
+			Code:    "ValidationFailed",
+			Message: err.Error(),
+			Details: err.Details,
+		},
+	})
+}
@@ -0,0 +1,175 @@
+package odata
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ValidationTestProduct struct {
+	ID     int64   `json:"id" column:"id" primaryKey:"idGenerator:none"`
+	Name   string  `json:"name" column:"name" prop:"[required]" odata:"length:10"`
+	Price  float64 `json:"price" column:"price" odata:"min:0;max:1000"`
+	SKU    string  `json:"sku" column:"sku" odata:"pattern:^[A-Z]{3}-[0-9]+$"`
+	Status string  `json:"status" column:"status" odata:"enum:draft|active"`
+}
+
+func newValidationTestServer(t *testing.T) (*Server, *BaseEntityService, *constraintTestProvider) {
+	server, provider := newConstraintTestServer(t)
+	require.NoError(t, server.RegisterEntity("Products", ValidationTestProduct{}))
+	return server, server.entities["Products"].(*BaseEntityService), provider
+}
+
+func TestEntityMapper_ValidationRules(t *testing.T) {
+	metadata, err := NewEntityMapper().MapEntity(ValidationTestProduct{})
+	require.NoError(t, err)
+
+	price := metadata.Properties[2]
+	require.NotNil(t, price.Minimum)
+	require.NotNil(t, price.Maximum)
+	assert.Equal(t, 0.0, *price.Minimum)
+	assert.Equal(t, 1000.0, *price.Maximum)
+	assert.Equal(t, "^[A-Z]{3}-[0-9]+$", metadata.Properties[3].Pattern.String())
+	assert.Equal(t, []string{"draft", "active"}, metadata.Properties[4].EnumValues)
+
+	// pattern é a última regra da tag e pode conter ";"
+	type semicolonPattern struct {
+		Codes string `json:"codes" odata:"length:20; pattern:^[a-z]+(;[a-z]+)*$"`
+	}
+	metadata, err = NewEntityMapper().MapEntity(semicolonPattern{})
+	require.NoError(t, err)
+	assert.Equal(t, 20, metadata.Properties[0].MaxLength)
+	assert.Equal(t, "^[a-z]+(;[a-z]+)*$", metadata.Properties[0].Pattern.String())
+	assert.True(t, metadata.Properties[0].Pattern.MatchString("red;green"))
+
+	type invalidPattern struct {
+		Code string `json:"code" odata:"pattern:[a-"`
+	}
+	_, err = NewEntityMapper().MapEntity(invalidPattern{})
+	assert.Error(t, err)
+
+	type invalidRange struct {
+		Min int `json:"min" odata:"min:zero"`
+		Max int `json:"max" odata:"max:1e"`
+	}
+	_, err = NewEntityMapper().MapEntity(invalidRange{})
+	assert.ErrorContains(t, err, "invalid min")
+}
+
+func TestServer_Validation(t *testing.T) {
+	server, service, provider := newValidationTestServer(t)
+	ctx := context.Background()
+
+	t.Run("valid entity is written", func(t *testing.T) {
+		_, err := server.insertEntity(ctx, "Products", service, map[string]interface{}{"id": 1, "name": "Pen", "price": 10.0, "sku": "PEN-1", "status": "draft"})
+		require.NoError(t, err)
+		assert.Equal(t, "Pen", provider.inserted["name"])
+	})
+
+	t.Run("every violation is reported", func(t *testing.T) {
+		provider.inserted = nil
+		_, err := server.insertEntity(ctx, "Products", service, map[string]interface{}{"id": 2, "name": "Fountain pen", "price": -1.0, "sku": "pen", "status": "deleted"})
+
+		var validation *ValidationError
+		require.ErrorAs(t, err, &validation)
+		assert.Nil(t, provider.inserted)
+
+		codes := map[string]string{}
+		for _, detail := range validation.Details {
+			codes[detail.Target] = detail.Code
+		}
+		assert.Equal(t, map[string]string{"name": "MaxLength", "price": "Minimum", "sku": "Pattern", "status": "Enum"}, codes)
+	})
+
+	t.Run("partial update validates only sent properties", func(t *testing.T) {
+		_, err := server.modifyEntity(ctx, "Products", service, map[string]interface{}{"id": 1}, map[string]interface{}{"price": 500.0}, nil)
+		require.NoError(t, err)
+
+		_, err = server.modifyEntity(ctx, "Products", service, map[string]interface{}{"id": 1}, map[string]interface{}{"price": 5000}, nil)
+		var validation *ValidationError
+		require.ErrorAs(t, err, &validation)
+		assert.Equal(t, "Maximum", validation.Details[0].Code)
+	})
+}
+
+func TestServer_CustomValidators(t *testing.T) {
+	server, service, _ := newValidationTestServer(t)
+	ctx := context.Background()
+
+	var validated *EntityValidatedArgs
+	server.OnEntityValidating("Products", func(args EventArgs) error {
+		validating := args.(*EntityValidatingArgs)
+		if validating.Keys == nil && validating.Data["status"] == "active" && validating.Data["price"] == nil {
+			validating.AddError("price", "active products need a price")
+		}
+		if validating.Data["name"] == "forbidden" {
+			validating.ValidationErrors = append(validating.ValidationErrors, "product is blocked")
+		}
+		return nil
+	})
+	server.OnEntityValidated("Products", func(args EventArgs) error {
+		validated = args.(*EntityValidatedArgs)
+		return nil
+	})
+
+	_, err := server.insertEntity(ctx, "Products", service, map[string]interface{}{"id": 3, "name": "forbidden", "status": "active"})
+	var validation *ValidationError
+	require.ErrorAs(t, err, &validation)
+	require.Len(t, validation.Details, 2)
+	assert.Equal(t, ODataErrorDetail{Code: "ValidationError", Message: "active products need a price", Target: "price"}, validation.Details[0])
+	assert.Equal(t, ODataErrorDetail{Code: "ValidationError", Message: "product is blocked"}, validation.Details[1])
+
+	require.NotNil(t, validated)
+	assert.False(t, validated.IsValid)
+	assert.Len(t, validated.Details, 2)
+
+	_, err = server.insertEntity(ctx, "Products", service, map[string]interface{}{"id": 3, "name": "Pen", "status": "draft"})
+	require.NoError(t, err)
+	assert.True(t, validated.IsValid)
+}
+
+func TestServer_ValidationCustomService(t *testing.T) {
+	server, _, _ := newDeepInsertTestServer(t)
+	goods := newDeepInsertTestService(t, ValidationTestProduct{}, 0)
+	require.NoError(t, server.RegisterEntityWithService("Goods", goods))
+
+	inserting := 0
+	server.OnEntityInserting("Goods", func(args EventArgs) error {
+		inserting++
+		return nil
+	})
+
+	// As regras valem para qualquer EntityService e são verificadas antes dos handlers de gravação
+	require.Equal(t, 400, sendPayload(t, server, "POST", "/odata/Goods", `{"name": "Pen", "price": -5}`))
+	assert.Equal(t, 0, inserting)
+	assert.Empty(t, goods.created)
+
+	require.Equal(t, 201, sendPayload(t, server, "POST", "/odata/Goods", `{"name": "Pen", "price": 5}`))
+	assert.Equal(t, 1, inserting)
+	require.Len(t, goods.created, 1)
+}
+
+func TestServer_ValidationErrorResponse(t *testing.T) {
+	server, _, _ := newValidationTestServer(t)
+
+	req := httptest.NewRequest("POST", "/odata/Products", strings.NewReader(`{"id": 4, "price": 2000, "status": "active"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := server.GetRouter().Test(req)
+	require.NoError(t, err)
+	require.Equal(t, 400, resp.StatusCode)
+
+	var body ODataResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.NotNil(t, body.Error)
+	assert.Equal(t, "ValidationFailed", body.Error.Code)
+	require.Len(t, body.Error.Details, 2)
+	assert.Equal(t, "name", body.Error.Details[0].Target)
+	assert.Equal(t, "RequiredProperty", body.Error.Details[0].Code)
+	assert.Equal(t, "price", body.Error.Details[1].Target)
+	assert.Equal(t, "Maximum", body.Error.Details[1].Code)
+}
@@ -0,0 +1,1267 @@
+package odata
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// Erros das assinaturas de webhook
+var (
+	ErrInvalidWebhook  = errors.New("invalid webhook subscription")
+	ErrWebhookNotFound = errors.New("webhook subscription not found")
+)
+
+// Cabeçalhos enviados em cada entrega de webhook
+const (
+	WebhookEventHeader     = "X-GoData-Event"
+	WebhookDeliveryHeader  = "X-GoData-Delivery"
+	WebhookTimestampHeader = "X-GoData-Timestamp"
+	WebhookSignatureHeader = "X-GoData-Signature"
+)
+
+// webhooksSegment é o segmento das rotas de gerenciamento dos webhooks
+const webhooksSegment = "$webhooks"
+
+// WebhookSubscription é a assinatura de um sistema externo aos eventos de gravação de uma entidade
+type WebhookSubscription struct {
+	ID         string      `json:"ID"`
+	EntityName string      `json:"EntityName"`
+	Events     []EventType `json:"Events"`           // EntityInserted, EntityModified e/ou EntityDeleted
+	URL        string      `json:"URL"`              // Endpoint http(s) que recebe o POST
+	Secret     string      `json:"Secret,omitempty"` // Chave do HMAC; só é devolvida na criação
+	Filter     string      `json:"Filter,omitempty"` // Expressão $filter avaliada sobre a entidade gravada
+	Active     bool        `json:"Active"`
+	TenantID   string      `json:"TenantID,omitempty"` // Com multi-tenant, só recebe os eventos do tenant
+	CreatedAt  time.Time   `json:"CreatedAt"`
+	UpdatedAt  time.Time   `json:"UpdatedAt"`
+}
+
+// WebhookDelivery registra uma tentativa de entrega de um evento a uma assinatura
+type WebhookDelivery struct {
+	ID             string    `json:"ID"`
+	SubscriptionID string    `json:"SubscriptionID"`
+	EventID        string    `json:"EventID"` // Igual em todas as tentativas do mesmo evento
+	EventType      EventType `json:"EventType"`
+	EntityName     string    `json:"EntityName"`
+	Attempt        int       `json:"Attempt"`
+	StatusCode     int       `json:"StatusCode,omitempty"`
+	Success        bool      `json:"Success"`
+	Error          string    `json:"Error,omitempty"`
+	DurationMs     int64     `json:"DurationMs"`
+	CreatedAt      time.Time `json:"CreatedAt"`
+}
+
+// WebhookPayload é o corpo JSON enviado ao endpoint da assinatura
+type WebhookPayload struct {
+	ID        string                 `json:"id"`
+	Event     EventType              `json:"event"`
+	EntitySet string                 `json:"entitySet"`
+	Keys      map[string]interface{} `json:"keys,omitempty"`
+	TenantID  string                 `json:"tenantId,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+	Data      interface{}            `json:"data,omitempty"` // Entidade gravada (ou removida)
+}
+
+// WebhookStore persiste as assinaturas e o log de entregas
+type WebhookStore interface {
+	ListSubscriptions(ctx context.Context) ([]*WebhookSubscription, error)
+	CreateSubscription(ctx context.Context, subscription *WebhookSubscription) error
+	UpdateSubscription(ctx context.Context, subscription *WebhookSubscription) error
+	DeleteSubscription(ctx context.Context, id string) error
+	AppendDelivery(ctx context.Context, delivery *WebhookDelivery) error
+	// ListDeliveries retorna as entregas da assinatura, das mais recentes para as mais antigas
+	ListDeliveries(ctx context.Context, subscriptionID string, limit int) ([]*WebhookDelivery, error)
+}
+
+// WebhookConfig configura o subsistema de webhooks
+type WebhookConfig struct {
+	Store           WebhookStore     // Assinaturas e log de entregas (padrão em memória)
+	Retry           AsyncEventConfig // Workers e novas tentativas das entregas; MaxRetries 0 desativa as novas tentativas
+	Timeout         time.Duration    // Tempo máximo de cada requisição (padrão 10s)
+	Client          *http.Client     // Cliente HTTP das entregas (padrão recusa conexões a endereços internos)
+	ManagementRoles []string         // Roles exigidas em $webhooks quando o JWT está habilitado (padrão admin)
+	// AllowPrivateNetworks aceita URLs de loopback, redes privadas e link-local, bloqueadas por padrão
+	AllowPrivateNetworks bool
+}
+
+// DefaultWebhookConfig retorna a configuração padrão dos webhooks
+func DefaultWebhookConfig() WebhookConfig {
+	retry := DefaultAsyncEventConfig()
+	retry.MaxRetries = 5
+	return WebhookConfig{
+		Retry:           retry,
+		Timeout:         10 * time.Second,
+		ManagementRoles: []string{"admin"},
+	}
+}
+
+// webhookEntry é uma assinatura com o filtro já interpretado
+type webhookEntry struct {
+	subscription WebhookSubscription
+	filter       *GoDataFilterQuery
+}
+
+// WebhookManager mantém as assinaturas e entrega os eventos de gravação aos seus endpoints.
+// Os eventos chegam pelos handlers assíncronos, ou seja, apenas depois da confirmação da transação
+type WebhookManager struct {
+	server  *Server
+	config  WebhookConfig
+	store   WebhookStore
+	client  *http.Client
+	bus     *EventBus
+	mu      sync.RWMutex
+	entries map[string]*webhookEntry
+	hooked  map[string]bool // Entidades cujos eventos já são observados
+}
+
+// EnableWebhooks inicia o subsistema de webhooks, carrega as assinaturas do store e registra as rotas $webhooks
+func (s *Server) EnableWebhooks(config WebhookConfig) (*WebhookManager, error) {
+	if config.Store == nil {
+		config.Store = NewMemoryWebhookStore()
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultWebhookConfig().Timeout
+	}
+	if config.Client == nil {
+		config.Client = newWebhookClient(config.AllowPrivateNetworks)
+	}
+	if len(config.ManagementRoles) == 0 {
+		config.ManagementRoles = DefaultWebhookConfig().ManagementRoles
+	}
+
+	manager := &WebhookManager{
+		server:  s,
+		config:  config,
+		store:   config.Store,
+		client:  config.Client,
+		entries: make(map[string]*webhookEntry),
+		hooked:  make(map[string]bool),
+	}
+	if !s.webhooks.CompareAndSwap(nil, manager) {
+		return nil, fmt.Errorf("webhooks are already enabled")
+	}
+
+	manager.bus = NewEventBus(config.Retry, s.logger)
+	if err := manager.Reload(context.Background()); err != nil {
+		s.webhooks.Store(nil)
+		_ = manager.bus.Close(context.Background())
+		return nil, err
+	}
+	s.setupWebhookRoutes(config.ManagementRoles)
+	return manager, nil
+}
+
+// GetWebhookManager retorna o gerenciador de webhooks, se habilitado
+func (s *Server) GetWebhookManager() *WebhookManager {
+	return s.webhooks.Load()
+}
+
+// Reload recarrega as assinaturas do store, útil quando outra instância as alterou
+func (m *WebhookManager) Reload(ctx context.Context) error {
+	subscriptions, err := m.store.ListSubscriptions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load webhook subscriptions: %w", err)
+	}
+
+	entries := make(map[string]*webhookEntry, len(subscriptions))
+	for _, subscription := range subscriptions {
+		entry, err := m.newEntry(ctx, *subscription)
+		if err != nil {
+			m.server.logger.Printf("⚠️ Assinatura de webhook %s ignorada: %v", subscription.ID, err)
+			continue
+		}
+		entries[subscription.ID] = entry
+	}
+
+	m.mu.Lock()
+	m.entries = entries
+	m.mu.Unlock()
+
+	for _, entry := range entries {
+		if err := m.hook(entry.subscription.EntityName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subscribe valida e grava uma nova assinatura. ID e Secret são gerados quando não informados
+func (m *WebhookManager) Subscribe(ctx context.Context, subscription WebhookSubscription) (*WebhookSubscription, error) {
+	if subscription.ID == "" {
+		subscription.ID = formatUUID(newSmartGuid())
+	}
+	if subscription.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		subscription.Secret = secret
+	}
+	subscription.CreatedAt = time.Now()
+	subscription.UpdatedAt = subscription.CreatedAt
+
+	entry, err := m.newEntry(ctx, subscription)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.store.CreateSubscription(ctx, &entry.subscription); err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	if err := m.hook(subscription.EntityName); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.entries[subscription.ID] = entry
+	m.mu.Unlock()
+
+	created := entry.subscription
+	return &created, nil
+}
+
+// UpdateSubscription valida e grava as alterações de uma assinatura existente
+func (m *WebhookManager) UpdateSubscription(ctx context.Context, subscription WebhookSubscription) (*WebhookSubscription, error) {
+	current, ok := m.Subscription(subscription.ID)
+	if !ok {
+		return nil, ErrWebhookNotFound
+	}
+	subscription.CreatedAt = current.CreatedAt
+	subscription.UpdatedAt = time.Now()
+
+	entry, err := m.newEntry(ctx, subscription)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.store.UpdateSubscription(ctx, &entry.subscription); err != nil {
+		return nil, fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+	if err := m.hook(subscription.EntityName); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.entries[subscription.ID] = entry
+	m.mu.Unlock()
+
+	updated := entry.subscription
+	return &updated, nil
+}
+
+// Unsubscribe remove a assinatura; o log de entregas é mantido
+func (m *WebhookManager) Unsubscribe(ctx context.Context, id string) error {
+	if _, ok := m.Subscription(id); !ok {
+		return ErrWebhookNotFound
+	}
+	if err := m.store.DeleteSubscription(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+
+	m.mu.Lock()
+	delete(m.entries, id)
+	m.mu.Unlock()
+	return nil
+}
+
+// Subscription retorna uma cópia da assinatura
+func (m *WebhookManager) Subscription(id string) (WebhookSubscription, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entry, ok := m.entries[id]
+	if !ok {
+		return WebhookSubscription{}, false
+	}
+	return entry.subscription, true
+}
+
+// Subscriptions retorna cópias das assinaturas ordenadas pela criação
+func (m *WebhookManager) Subscriptions() []WebhookSubscription {
+	m.mu.RLock()
+	subscriptions := make([]WebhookSubscription, 0, len(m.entries))
+	for _, entry := range m.entries {
+		subscriptions = append(subscriptions, entry.subscription)
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(subscriptions, func(i, j int) bool {
+		if subscriptions[i].CreatedAt.Equal(subscriptions[j].CreatedAt) {
+			return subscriptions[i].ID < subscriptions[j].ID
+		}
+		return subscriptions[i].CreatedAt.Before(subscriptions[j].CreatedAt)
+	})
+	return subscriptions
+}
+
+// Deliveries retorna o log de entregas da assinatura
+func (m *WebhookManager) Deliveries(ctx context.Context, subscriptionID string, limit int) ([]*WebhookDelivery, error) {
+	return m.store.ListDeliveries(ctx, subscriptionID, limit)
+}
+
+// DeadLetters retorna as entregas que esgotaram as tentativas
+func (m *WebhookManager) DeadLetters() []DeadLetter {
+	return m.bus.DeadLetters()
+}
+
+// Wait aguarda as entregas em andamento, inclusive as novas tentativas agendadas
+func (m *WebhookManager) Wait(ctx context.Context) error {
+	return m.bus.Wait(ctx)
+}
+
+// Close aguarda as entregas em andamento até o fim do contexto e encerra o barramento das entregas
+func (m *WebhookManager) Close(ctx context.Context) error {
+	return m.bus.Close(ctx)
+}
+
+// newEntry valida a assinatura e interpreta o seu filtro
+func (m *WebhookManager) newEntry(ctx context.Context, subscription WebhookSubscription) (*webhookEntry, error) {
+	if subscription.ID == "" {
+		return nil, fmt.Errorf("%w: ID is required", ErrInvalidWebhook)
+	}
+	if _, exists := m.server.entityService(subscription.EntityName); !exists {
+		return nil, fmt.Errorf("%w: entity '%s' not found", ErrInvalidWebhook, subscription.EntityName)
+	}
+
+	target, err := url.Parse(subscription.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("%w: URL must be an absolute http or https address", ErrInvalidWebhook)
+	}
+	if !m.config.AllowPrivateNetworks && isPrivateWebhookHost(target.Hostname()) {
+		return nil, fmt.Errorf("%w: URL must not point to a loopback, private or link-local address", ErrInvalidWebhook)
+	}
+
+	if len(subscription.Events) == 0 {
+		return nil, fmt.Errorf("%w: at least one event is required", ErrInvalidWebhook)
+	}
+	for _, eventType := range subscription.Events {
+		switch eventType {
+		case EventEntityInserted, EventEntityModified, EventEntityDeleted:
+		default:
+			return nil, fmt.Errorf("%w: event %s is not supported, use %s, %s or %s", ErrInvalidWebhook,
+				eventType, EventEntityInserted, EventEntityModified, EventEntityDeleted)
+		}
+	}
+
+	if subscription.Secret == "" {
+		return nil, fmt.Errorf("%w: secret is required", ErrInvalidWebhook)
+	}
+
+	entry := &webhookEntry{subscription: subscription}
+	if subscription.Filter != "" {
+		filter, err := ParseFilterString(ctx, subscription.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid filter: %v", ErrInvalidWebhook, err)
+		}
+		entry.filter = filter
+	}
+	return entry, nil
+}
+
+// hook registra, uma única vez por entidade, o handler assíncrono que recebe os eventos de gravação
+func (m *WebhookManager) hook(entityName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.hooked[entityName] {
+		return nil
+	}
+
+	for _, eventType := range []EventType{EventEntityInserted, EventEntityModified, EventEntityDeleted} {
+		if err := m.server.eventManager.SubscribeAsync(eventType, entityName, EventHandlerFunc(m.dispatch)); err != nil {
+			return err
+		}
+	}
+	m.hooked[entityName] = true
+	return nil
+}
+
+// dispatch publica uma entrega para cada assinatura ativa que corresponde ao evento e ao filtro
+func (m *WebhookManager) dispatch(args EventArgs) error {
+	eventType := args.GetEventType()
+	entityName := args.GetEntityName()
+	service, exists := m.server.entityService(entityName)
+	if !exists {
+		return nil
+	}
+
+	var entity interface{}
+	var keys map[string]interface{}
+	switch a := args.(type) {
+	case *EntityInsertedArgs:
+		entity = a.CreatedEntity
+		keys = make(map[string]interface{})
+		for _, name := range keyPropertyNames(service.GetMetadata()) {
+			keys[name] = entityValue(entity, name)
+		}
+	case *EntityModifiedArgs:
+		entity, keys = a.UpdatedEntity, a.Keys
+	case *EntityDeletedArgs:
+		entity, keys = a.DeletedEntity, a.Keys
+	default:
+		return nil
+	}
+
+	tenantID := ""
+	if eventCtx := args.GetContext(); eventCtx != nil && m.server.multiTenantPool != nil {
+		tenantID = contextTenant(eventCtx)
+	}
+
+	matcher := newEventFilterMatcher(service.GetMetadata())
+	var matched []WebhookSubscription
+	m.mu.RLock()
+	for _, entry := range m.entries {
+		subscription := entry.subscription
+		if !subscription.Active || subscription.EntityName != entityName || !containsEventType(subscription.Events, eventType) {
+			continue
+		}
+		if subscription.TenantID != "" && subscription.TenantID != tenantID {
+			continue
+		}
+		if !matcher.matches(entity, entry.filter) {
+			continue
+		}
+		matched = append(matched, subscription)
+	}
+	m.mu.RUnlock()
+	if len(matched) == 0 {
+		return nil
+	}
+
+	payload := &WebhookPayload{
+		ID:        formatUUID(newSmartGuid()),
+		Event:     eventType,
+		EntitySet: entityName,
+		Keys:      keys,
+		TenantID:  tenantID,
+		Timestamp: time.Now().UTC(),
+		Data:      entity,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Permanent(fmt.Errorf("failed to encode webhook payload: %w", err))
+	}
+
+	for _, subscription := range matched {
+		// As tentativas de uma entrega são sequenciais, então o contador não precisa de sincronização
+		attempt := 0
+		handler := EventHandlerFunc(func(EventArgs) error {
+			attempt++
+			return m.deliver(subscription, payload, body, attempt)
+		})
+		if err := m.bus.Publish(handler, args, subscription.ID); err != nil {
+			m.server.logger.Printf("❌ Erro ao publicar a entrega do webhook %s: %v", subscription.ID, err)
+		}
+	}
+	return nil
+}
+
+// eventFilterMatcher avalia o filtro de uma assinatura sobre a entidade do evento. Diferente do filtro em memória
+// do $expand, aceita mapas e structs, como os de serviços customizados, e compara números pelo valor, pois a
+// entidade do evento traz float64 do JSON e o literal do filtro é int64
+type eventFilterMatcher struct {
+	service *BaseEntityService
+}
+
+func newEventFilterMatcher(metadata EntityMetadata) eventFilterMatcher {
+	return eventFilterMatcher{service: &BaseEntityService{metadata: metadata, numericFilter: true}}
+}
+
+// matches indica se a entidade atende ao filtro; sem filtro, toda entidade atende
+func (m eventFilterMatcher) matches(entity any, filter *GoDataFilterQuery) bool {
+	if filter == nil || filter.Tree == nil {
+		return true
+	}
+	ordered, ok := toOrderedEntity(entity)
+	if !ok {
+		return false
+	}
+	return m.service.evaluateFilterNode(ordered, filter.Tree, m.service.metadata)
+}
+
+// toOrderedEntity converte mapas e structs para OrderedEntity
+func toOrderedEntity(entity any) (*OrderedEntity, bool) {
+	switch e := entity.(type) {
+	case *OrderedEntity:
+		return e, e != nil
+	case nil:
+		return nil, false
+	case map[string]interface{}:
+		ordered := NewOrderedEntity()
+		for name, value := range e {
+			ordered.Set(name, value)
+		}
+		return ordered, true
+	}
+
+	encoded, err := json.Marshal(entity)
+	if err != nil {
+		return nil, false
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(encoded, &values); err != nil {
+		return nil, false
+	}
+	return toOrderedEntity(values)
+}
+
+// compareNumbers compara dois números com o operador do filtro
+func compareNumbers(left, right float64, operator string) bool {
+	switch operator {
+	case "eq":
+		return left == right
+	case "ne":
+		return left != right
+	case "gt":
+		return left > right
+	case "lt":
+		return left < right
+	case "ge":
+		return left >= right
+	case "le":
+		return left <= right
+	}
+	return false
+}
+
+// deliver envia o payload assinado ao endpoint e registra a tentativa no log de entregas.
+// Respostas 4xx, exceto 408 e 429, não são repetidas
+func (m *WebhookManager) deliver(subscription WebhookSubscription, payload *WebhookPayload, body []byte, attempt int) error {
+	started := time.Now()
+	delivery := &WebhookDelivery{
+		ID:             formatUUID(newSmartGuid()),
+		SubscriptionID: subscription.ID,
+		EventID:        payload.ID,
+		EventType:      payload.Event,
+		EntityName:     payload.EntitySet,
+		Attempt:        attempt,
+		CreatedAt:      started,
+	}
+
+	err := m.send(subscription, payload, body, delivery)
+	delivery.DurationMs = time.Since(started).Milliseconds()
+	delivery.Success = err == nil
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+
+	if logErr := m.store.AppendDelivery(context.Background(), delivery); logErr != nil {
+		m.server.logger.Printf("⚠️ Erro ao gravar o log de entrega do webhook %s: %v", subscription.ID, logErr)
+	}
+	return err
+}
+
+// send faz a requisição ao endpoint da assinatura
+func (m *WebhookManager) send(subscription WebhookSubscription, payload *WebhookPayload, body []byte, delivery *WebhookDelivery) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return Permanent(fmt.Errorf("invalid webhook request: %w", err))
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GoData-Webhooks")
+	req.Header.Set(WebhookEventHeader, string(payload.Event))
+	req.Header.Set(WebhookDeliveryHeader, payload.ID)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(subscription.Secret, timestamp, body))
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		if errors.Is(err, errWebhookDestination) {
+			return Permanent(fmt.Errorf("webhook request failed: %w", err))
+		}
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	delivery.StatusCode = resp.StatusCode
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests:
+		return Permanent(fmt.Errorf("webhook responded with status %d", resp.StatusCode))
+	}
+	return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+}
+
+// errWebhookDestination indica uma conexão recusada por apontar para um endereço interno
+var errWebhookDestination = errors.New("webhook destination address is not allowed")
+
+// newWebhookClient cria o cliente padrão das entregas. Sem AllowPrivateNetworks, o endereço é verificado
+// no momento da conexão, depois da resolução do DNS, o que cobre nomes que apontam para a rede interna e redirecionamentos
+func newWebhookClient(allowPrivate bool) *http.Client {
+	if allowPrivate {
+		return &http.Client{}
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivateWebhookIP(ip) {
+				return fmt.Errorf("%w: %s", errWebhookDestination, host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// Com um proxy a conexão seria feita ao proxy, e o destino não poderia ser verificado
+	transport.Proxy = nil
+	return &http.Client{Transport: transport}
+}
+
+// isPrivateWebhookHost verifica se o host da URL é um nome local ou um IP interno
+func isPrivateWebhookHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	if zone := strings.IndexByte(host, '%'); zone != -1 {
+		host = host[:zone]
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && isPrivateWebhookIP(ip)
+}
+
+// isPrivateWebhookIP verifica se o IP é de loopback, rede privada, link-local, multicast ou não especificado
+func isPrivateWebhookIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
+
+// SignWebhookPayload calcula a assinatura enviada em X-GoData-Signature: sha256=HMAC(secret, timestamp + "." + body)
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature verifica, em tempo constante, a assinatura recebida por um endpoint de webhook
+func VerifyWebhookSignature(secret, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(SignWebhookPayload(secret, timestamp, body)), []byte(signature))
+}
+
+// newWebhookSecret gera uma chave aleatória para o HMAC
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// containsEventType verifica se o evento está na lista
+func containsEventType(events []EventType, eventType EventType) bool {
+	for _, event := range events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// MemoryWebhookStore guarda as assinaturas e o log de entregas em memória
+type MemoryWebhookStore struct {
+	mu            sync.Mutex
+	subscriptions map[string]*WebhookSubscription
+	deliveries    map[string][]*WebhookDelivery
+	deliveryLimit int
+}
+
+// NewMemoryWebhookStore cria o store em memória, que mantém as 1000 entregas mais recentes de cada assinatura
+func NewMemoryWebhookStore() *MemoryWebhookStore {
+	return &MemoryWebhookStore{
+		subscriptions: make(map[string]*WebhookSubscription),
+		deliveries:    make(map[string][]*WebhookDelivery),
+		deliveryLimit: 1000,
+	}
+}
+
+// ListSubscriptions retorna as assinaturas
+func (st *MemoryWebhookStore) ListSubscriptions(ctx context.Context) ([]*WebhookSubscription, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	subscriptions := make([]*WebhookSubscription, 0, len(st.subscriptions))
+	for _, subscription := range st.subscriptions {
+		copied := *subscription
+		subscriptions = append(subscriptions, &copied)
+	}
+	return subscriptions, nil
+}
+
+// CreateSubscription grava uma nova assinatura
+func (st *MemoryWebhookStore) CreateSubscription(ctx context.Context, subscription *WebhookSubscription) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, exists := st.subscriptions[subscription.ID]; exists {
+		return fmt.Errorf("webhook subscription %s already exists", subscription.ID)
+	}
+	copied := *subscription
+	st.subscriptions[subscription.ID] = &copied
+	return nil
+}
+
+// UpdateSubscription grava as alterações da assinatura
+func (st *MemoryWebhookStore) UpdateSubscription(ctx context.Context, subscription *WebhookSubscription) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, exists := st.subscriptions[subscription.ID]; !exists {
+		return ErrWebhookNotFound
+	}
+	copied := *subscription
+	st.subscriptions[subscription.ID] = &copied
+	return nil
+}
+
+// DeleteSubscription remove a assinatura
+func (st *MemoryWebhookStore) DeleteSubscription(ctx context.Context, id string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	delete(st.subscriptions, id)
+	return nil
+}
+
+// AppendDelivery registra uma tentativa de entrega
+func (st *MemoryWebhookStore) AppendDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	copied := *delivery
+	deliveries := append(st.deliveries[delivery.SubscriptionID], &copied)
+	if len(deliveries) > st.deliveryLimit {
+		deliveries = deliveries[len(deliveries)-st.deliveryLimit:]
+	}
+	st.deliveries[delivery.SubscriptionID] = deliveries
+	return nil
+}
+
+// ListDeliveries retorna as entregas da assinatura, das mais recentes para as mais antigas
+func (st *MemoryWebhookStore) ListDeliveries(ctx context.Context, subscriptionID string, limit int) ([]*WebhookDelivery, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	stored := st.deliveries[subscriptionID]
+	deliveries := make([]*WebhookDelivery, 0, min(len(stored), max(limit, 0)))
+	for i := len(stored) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		copied := *stored[i]
+		deliveries = append(deliveries, &copied)
+	}
+	return deliveries, nil
+}
+
+// Tabelas usadas pelo SQLWebhookStore quando nenhuma é informada
+const (
+	DefaultWebhookTable         = "godata_webhooks"
+	DefaultWebhookDeliveryTable = "godata_webhook_deliveries"
+)
+
+// SQLWebhookStore grava as assinaturas e o log de entregas em tabelas do banco usando as queries do provider
+type SQLWebhookStore struct {
+	provider      DatabaseProvider
+	subscriptions *BaseEntityService
+	deliveries    *BaseEntityService
+}
+
+// NewSQLWebhookStore cria o store nas tabelas informadas (padrão godata_webhooks e godata_webhook_deliveries)
+func NewSQLWebhookStore(provider DatabaseProvider, subscriptionTable, deliveryTable string) *SQLWebhookStore {
+	if subscriptionTable == "" {
+		subscriptionTable = DefaultWebhookTable
+	}
+	if deliveryTable == "" {
+		deliveryTable = DefaultWebhookDeliveryTable
+	}
+	return &SQLWebhookStore{
+		provider:      provider,
+		subscriptions: NewBaseEntityService(provider, webhookSubscriptionMetadata(subscriptionTable), nil),
+		deliveries:    NewBaseEntityService(provider, webhookDeliveryMetadata(deliveryTable), nil),
+	}
+}
+
+// webhookSubscriptionMetadata descreve a tabela das assinaturas
+func webhookSubscriptionMetadata(tableName string) EntityMetadata {
+	return EntityMetadata{
+		Name:      "WebhookSubscription",
+		TableName: tableName,
+		Keys:      []string{"ID"},
+		Properties: []PropertyMetadata{
+			{Name: "ID", Type: "string", ColumnName: "id", IsKey: true, MaxLength: 36},
+			{Name: "EntityName", Type: "string", ColumnName: "entity_name", MaxLength: 128},
+			{Name: "Events", Type: "string", ColumnName: "events", MaxLength: 128},
+			{Name: "URL", Type: "string", ColumnName: "url", MaxLength: 2048},
+			{Name: "Secret", Type: "string", ColumnName: "secret", MaxLength: 256},
+			{Name: "Filter", Type: "string", ColumnName: "filter", IsNullable: true},
+			{Name: "Active", Type: "bool", ColumnName: "active"},
+			{Name: "TenantID", Type: "string", ColumnName: "tenant_id", IsNullable: true, MaxLength: 128},
+			{Name: "CreatedAt", Type: "time.Time", ColumnName: "created_at"},
+			{Name: "UpdatedAt", Type: "time.Time", ColumnName: "updated_at"},
+		},
+	}
+}
+
+// webhookDeliveryMetadata descreve a tabela do log de entregas
+func webhookDeliveryMetadata(tableName string) EntityMetadata {
+	return EntityMetadata{
+		Name:      "WebhookDelivery",
+		TableName: tableName,
+		Keys:      []string{"ID"},
+		Properties: []PropertyMetadata{
+			{Name: "ID", Type: "string", ColumnName: "id", IsKey: true, MaxLength: 36},
+			{Name: "SubscriptionID", Type: "string", ColumnName: "subscription_id", MaxLength: 36},
+			{Name: "EventID", Type: "string", ColumnName: "event_id", MaxLength: 36},
+			{Name: "EventType", Type: "string", ColumnName: "event_type", MaxLength: 64},
+			{Name: "EntityName", Type: "string", ColumnName: "entity_name", MaxLength: 128},
+			{Name: "Attempt", Type: "int64", ColumnName: "attempt"},
+			{Name: "StatusCode", Type: "int64", ColumnName: "status_code", IsNullable: true},
+			{Name: "Success", Type: "bool", ColumnName: "success"},
+			{Name: "Error", Type: "string", ColumnName: "error", IsNullable: true},
+			{Name: "DurationMs", Type: "int64", ColumnName: "duration_ms"},
+			{Name: "CreatedAt", Type: "time.Time", ColumnName: "created_at"},
+		},
+	}
+}
+
+// ListSubscriptions lê todas as assinaturas
+func (st *SQLWebhookStore) ListSubscriptions(ctx context.Context) ([]*WebhookSubscription, error) {
+	response, err := st.subscriptions.Query(ctx, QueryOptions{OrderBy: "CreatedAt asc"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook subscriptions: %w", err)
+	}
+
+	values, _ := response.Value.([]interface{})
+	subscriptions := make([]*WebhookSubscription, 0, len(values))
+	for _, value := range values {
+		subscriptions = append(subscriptions, webhookSubscriptionFromRow(value))
+	}
+	return subscriptions, nil
+}
+
+// CreateSubscription insere a assinatura
+func (st *SQLWebhookStore) CreateSubscription(ctx context.Context, subscription *WebhookSubscription) error {
+	query, args, err := st.provider.BuildInsertQuery(st.subscriptions.metadata, webhookSubscriptionRow(subscription))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook subscription insert: %w", err)
+	}
+	if _, err := st.subscriptions.executeExec(ctx, query, args); err != nil {
+		return fmt.Errorf("failed to write webhook subscription: %w", err)
+	}
+	return nil
+}
+
+// UpdateSubscription grava as alterações da assinatura
+func (st *SQLWebhookStore) UpdateSubscription(ctx context.Context, subscription *WebhookSubscription) error {
+	data := webhookSubscriptionRow(subscription)
+	delete(data, "ID")
+	delete(data, "CreatedAt")
+
+	query, args, err := st.provider.BuildUpdateQuery(st.subscriptions.metadata, data, map[string]interface{}{"ID": subscription.ID})
+	if err != nil {
+		return fmt.Errorf("failed to build webhook subscription update: %w", err)
+	}
+	if _, err := st.subscriptions.executeExec(ctx, query, args); err != nil {
+		return fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+	return nil
+}
+
+// DeleteSubscription remove a assinatura; as entregas registradas são mantidas
+func (st *SQLWebhookStore) DeleteSubscription(ctx context.Context, id string) error {
+	query, args, err := st.provider.BuildDeleteQuery(st.subscriptions.metadata, map[string]interface{}{"ID": id})
+	if err != nil {
+		return fmt.Errorf("failed to build webhook subscription delete: %w", err)
+	}
+	if _, err := st.subscriptions.executeExec(ctx, query, args); err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+	return nil
+}
+
+// AppendDelivery registra uma tentativa de entrega
+func (st *SQLWebhookStore) AppendDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	data := map[string]interface{}{
+		"ID":             delivery.ID,
+		"SubscriptionID": delivery.SubscriptionID,
+		"EventID":        delivery.EventID,
+		"EventType":      string(delivery.EventType),
+		"EntityName":     delivery.EntityName,
+		"Attempt":        int64(delivery.Attempt),
+		"Success":        delivery.Success,
+		"DurationMs":     delivery.DurationMs,
+		"CreatedAt":      delivery.CreatedAt,
+	}
+	if delivery.StatusCode != 0 {
+		data["StatusCode"] = int64(delivery.StatusCode)
+	}
+	if delivery.Error != "" {
+		data["Error"] = delivery.Error
+	}
+
+	query, args, err := st.provider.BuildInsertQuery(st.deliveries.metadata, data)
+	if err != nil {
+		return fmt.Errorf("failed to build webhook delivery insert: %w", err)
+	}
+	if _, err := st.deliveries.executeExec(ctx, query, args); err != nil {
+		return fmt.Errorf("failed to write webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// ListDeliveries retorna as entregas da assinatura, das mais recentes para as mais antigas
+func (st *SQLWebhookStore) ListDeliveries(ctx context.Context, subscriptionID string, limit int) ([]*WebhookDelivery, error) {
+	top := GoDataTopQuery(limit)
+	options := QueryOptions{
+		Filter:  &GoDataFilterQuery{Tree: keysetComparison("eq", "SubscriptionID", subscriptionID)},
+		OrderBy: "CreatedAt desc",
+		Top:     &top,
+	}
+
+	response, err := st.deliveries.Query(ctx, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook deliveries: %w", err)
+	}
+
+	values, _ := response.Value.([]interface{})
+	deliveries := make([]*WebhookDelivery, 0, len(values))
+	for _, value := range values {
+		deliveries = append(deliveries, webhookDeliveryFromRow(value))
+	}
+	return deliveries, nil
+}
+
+// webhookSubscriptionRow converte a assinatura nas colunas da tabela
+func webhookSubscriptionRow(subscription *WebhookSubscription) map[string]interface{} {
+	events := make([]string, len(subscription.Events))
+	for i, eventType := range subscription.Events {
+		events[i] = string(eventType)
+	}
+
+	data := map[string]interface{}{
+		"ID":         subscription.ID,
+		"EntityName": subscription.EntityName,
+		"Events":     strings.Join(events, ","),
+		"URL":        subscription.URL,
+		"Secret":     subscription.Secret,
+		"Filter":     subscription.Filter,
+		"Active":     subscription.Active,
+		"CreatedAt":  subscription.CreatedAt,
+		"UpdatedAt":  subscription.UpdatedAt,
+	}
+	if subscription.TenantID != "" {
+		data["TenantID"] = subscription.TenantID
+	}
+	return data
+}
+
+// webhookSubscriptionFromRow converte uma linha da tabela das assinaturas
+func webhookSubscriptionFromRow(row interface{}) *WebhookSubscription {
+	subscription := &WebhookSubscription{
+		ID:         rowText(row, "ID"),
+		EntityName: rowText(row, "EntityName"),
+		URL:        rowText(row, "URL"),
+		Secret:     rowText(row, "Secret"),
+		Filter:     rowText(row, "Filter"),
+		Active:     rowBool(row, "Active"),
+		TenantID:   rowText(row, "TenantID"),
+	}
+	for _, eventType := range strings.Split(rowText(row, "Events"), ",") {
+		if eventType = strings.TrimSpace(eventType); eventType != "" {
+			subscription.Events = append(subscription.Events, EventType(eventType))
+		}
+	}
+	subscription.CreatedAt, _ = entityValue(row, "CreatedAt").(time.Time)
+	subscription.UpdatedAt, _ = entityValue(row, "UpdatedAt").(time.Time)
+	return subscription
+}
+
+// webhookDeliveryFromRow converte uma linha do log de entregas
+func webhookDeliveryFromRow(row interface{}) *WebhookDelivery {
+	delivery := &WebhookDelivery{
+		ID:             rowText(row, "ID"),
+		SubscriptionID: rowText(row, "SubscriptionID"),
+		EventID:        rowText(row, "EventID"),
+		EventType:      EventType(rowText(row, "EventType")),
+		EntityName:     rowText(row, "EntityName"),
+		Success:        rowBool(row, "Success"),
+		Error:          rowText(row, "Error"),
+	}
+	if attempt, ok := numericValue(entityValue(row, "Attempt")); ok {
+		delivery.Attempt = int(attempt)
+	}
+	if statusCode, ok := numericValue(entityValue(row, "StatusCode")); ok {
+		delivery.StatusCode = int(statusCode)
+	}
+	if duration, ok := numericValue(entityValue(row, "DurationMs")); ok {
+		delivery.DurationMs = int64(duration)
+	}
+	delivery.CreatedAt, _ = entityValue(row, "CreatedAt").(time.Time)
+	return delivery
+}
+
+// rowText lê uma coluna como texto
+func rowText(row interface{}, name string) string {
+	if value := entityValue(row, name); value != nil {
+		return fmt.Sprint(value)
+	}
+	return ""
+}
+
+// rowBool lê uma coluna booleana, que alguns bancos devolvem como número
+func rowBool(row interface{}, name string) bool {
+	value := entityValue(row, name)
+	if b, ok := value.(bool); ok {
+		return b
+	}
+	number, ok := numericValue(value)
+	return ok && number != 0
+}
+
+// setupWebhookRoutes registra as rotas de gerenciamento das assinaturas sob o prefixo do servidor
+func (s *Server) setupWebhookRoutes(roles []string) {
+	prefix := s.config.RoutePrefix + "/" + webhooksSegment
+
+	var middlewares []fiber.Handler
+	if s.config.EnableJWT {
+		middlewares = append(middlewares, s.AuthMiddleware(), RequireAnyRole(roles...))
+	} else {
+		s.logger.Printf("⚠️ Rotas %s registradas sem autenticação; habilite o JWT para protegê-las", prefix)
+	}
+
+	route := func(method, path string, handler fiber.Handler) {
+		s.addRoute(method, path, append(append([]fiber.Handler(nil), middlewares...), handler)...)
+	}
+	route(fiber.MethodGet, prefix, s.handleWebhookList)
+	route(fiber.MethodPost, prefix, s.handleWebhookCreate)
+	route(fiber.MethodGet, prefix+"(*)/Deliveries", s.handleWebhookDeliveries)
+	route(fiber.MethodGet, prefix+"(*)", s.handleWebhookGet)
+	route(fiber.MethodPatch, prefix+"(*)", s.handleWebhookUpdate)
+	route(fiber.MethodDelete, prefix+"(*)", s.handleWebhookDelete)
+}
+
+// webhookRequest é o corpo aceito na criação e na alteração de uma assinatura
+type webhookRequest struct {
+	EntityName *string     `json:"EntityName"`
+	Events     []EventType `json:"Events"`
+	URL        *string     `json:"URL"`
+	Secret     *string     `json:"Secret"`
+	Filter     *string     `json:"Filter"`
+	Active     *bool       `json:"Active"`
+}
+
+// apply copia para a assinatura os campos informados
+func (r webhookRequest) apply(subscription *WebhookSubscription) {
+	if r.EntityName != nil {
+		subscription.EntityName = *r.EntityName
+	}
+	if r.Events != nil {
+		subscription.Events = r.Events
+	}
+	if r.URL != nil {
+		subscription.URL = *r.URL
+	}
+	if r.Secret != nil {
+		subscription.Secret = *r.Secret
+	}
+	if r.Filter != nil {
+		subscription.Filter = *r.Filter
+	}
+	if r.Active != nil {
+		subscription.Active = *r.Active
+	}
+}
+
+// handleWebhookList lista as assinaturas visíveis para o tenant da requisição
+func (s *Server) handleWebhookList(c fiber.Ctx) error {
+	manager := s.webhooks.Load()
+	tenantID := s.webhookTenant(c)
+
+	value := make([]WebhookSubscription, 0)
+	for _, subscription := range manager.Subscriptions() {
+		if subscription.TenantID == tenantID {
+			subscription.Secret = ""
+			value = append(value, subscription)
+		}
+	}
+	return c.JSON(map[string]interface{}{
+		"@odata.context": "$metadata#Webhooks",
+		"value":          value,
+	})
+}
+
+// handleWebhookCreate cria uma assinatura e devolve o seu segredo, exibido apenas nesta resposta
+func (s *Server) handleWebhookCreate(c fiber.Ctx) error {
+	var request webhookRequest
+	if err := json.Unmarshal(c.Body(), &request); err != nil {
+		s.writeError(c, fiber.StatusBadRequest, "InvalidRequest", fmt.Sprintf("Invalid JSON: %v", err))
+		return nil
+	}
+
+	subscription := WebhookSubscription{Active: true, TenantID: s.webhookTenant(c)}
+	request.apply(&subscription)
+
+	created, err := s.webhooks.Load().Subscribe(s.requestContext(c), subscription)
+	if err != nil {
+		s.writeWebhookError(c, err)
+		return nil
+	}
+
+	c.Set(fiber.HeaderLocation, fmt.Sprintf("%s/%s('%s')", s.config.RoutePrefix, webhooksSegment, created.ID))
+	return c.Status(fiber.StatusCreated).JSON(webhookResponse(*created))
+}
+
+// handleWebhookGet retorna uma assinatura sem o segredo
+func (s *Server) handleWebhookGet(c fiber.Ctx) error {
+	subscription, ok := s.findWebhook(c)
+	if !ok {
+		return nil
+	}
+	subscription.Secret = ""
+	return c.JSON(webhookResponse(subscription))
+}
+
+// handleWebhookUpdate altera os campos informados da assinatura
+func (s *Server) handleWebhookUpdate(c fiber.Ctx) error {
+	subscription, ok := s.findWebhook(c)
+	if !ok {
+		return nil
+	}
+
+	var request webhookRequest
+	if err := json.Unmarshal(c.Body(), &request); err != nil {
+		s.writeError(c, fiber.StatusBadRequest, "InvalidRequest", fmt.Sprintf("Invalid JSON: %v", err))
+		return nil
+	}
+	request.apply(&subscription)
+
+	updated, err := s.webhooks.Load().UpdateSubscription(s.requestContext(c), subscription)
+	if err != nil {
+		s.writeWebhookError(c, err)
+		return nil
+	}
+	updated.Secret = ""
+	return c.JSON(webhookResponse(*updated))
+}
+
+// handleWebhookDelete remove a assinatura
+func (s *Server) handleWebhookDelete(c fiber.Ctx) error {
+	subscription, ok := s.findWebhook(c)
+	if !ok {
+		return nil
+	}
+	if err := s.webhooks.Load().Unsubscribe(s.requestContext(c), subscription.ID); err != nil {
+		s.writeWebhookError(c, err)
+		return nil
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// handleWebhookDeliveries retorna o log de entregas da assinatura; $top limita a quantidade (padrão 50)
+func (s *Server) handleWebhookDeliveries(c fiber.Ctx) error {
+	subscription, ok := s.findWebhook(c)
+	if !ok {
+		return nil
+	}
+
+	limit := 50
+	if top := c.Query("$top"); top != "" {
+		value, err := strconv.Atoi(top)
+		if err != nil || value < 0 {
+			s.writeError(c, fiber.StatusBadRequest, "InvalidQuery", "$top must be a non-negative integer")
+			return nil
+		}
+		limit = value
+	}
+
+	deliveries, err := s.webhooks.Load().Deliveries(s.requestContext(c), subscription.ID, limit)
+	if err != nil {
+		s.writeError(c, fiber.StatusInternalServerError, "WebhookError", err.Error())
+		return nil
+	}
+	return c.JSON(map[string]interface{}{
+		"@odata.context": "$metadata#Webhooks/Deliveries",
+		"value":          deliveries,
+	})
+}
+
+// findWebhook lê a chave da URL, como $webhooks('id'), e retorna a assinatura do tenant da requisição
+func (s *Server) findWebhook(c fiber.Ctx) (WebhookSubscription, bool) {
+	key := strings.TrimSpace(c.Params("*"))
+	key = strings.TrimPrefix(key, "ID=")
+	if unescaped, err := url.PathUnescape(key); err == nil {
+		key = unescaped
+	}
+	key = strings.Trim(key, "'")
+
+	subscription, ok := s.webhooks.Load().Subscription(key)
+	if !ok || subscription.TenantID != s.webhookTenant(c) {
+		s.writeError(c, fiber.StatusNotFound, "WebhookNotFound", fmt.Sprintf("Webhook subscription '%s' not found", key))
+		return WebhookSubscription{}, false
+	}
+	return subscription, true
+}
+
+// webhookTenant retorna o tenant da requisição quando o multi-tenant está habilitado
+func (s *Server) webhookTenant(c fiber.Ctx) string {
+	if s.multiTenantPool == nil {
+		return ""
+	}
+	return GetCurrentTenant(c)
+}
+
+// writeWebhookError responde 400 para assinaturas inválidas, 404 para as inexistentes e 500 para falhas do store
+func (s *Server) writeWebhookError(c fiber.Ctx, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidWebhook):
+		s.writeError(c, fiber.StatusBadRequest, "InvalidWebhook", err.Error())
+	case errors.Is(err, ErrWebhookNotFound):
+		s.writeError(c, fiber.StatusNotFound, "WebhookNotFound", err.Error())
+	default:
+		s.writeError(c, fiber.StatusInternalServerError, "WebhookError", err.Error())
+	}
+}
+
+// webhookResponse adiciona o contexto OData à assinatura
+func webhookResponse(subscription WebhookSubscription) map[string]interface{} {
+	response := map[string]interface{}{
+		"@odata.context": "$metadata#Webhooks/$entity",
+		"ID":             subscription.ID,
+		"EntityName":     subscription.EntityName,
+		"Events":         subscription.Events,
+		"URL":            subscription.URL,
+		"Filter":         subscription.Filter,
+		"Active":         subscription.Active,
+		"CreatedAt":      subscription.CreatedAt,
+		"UpdatedAt":      subscription.UpdatedAt,
+	}
+	if subscription.Secret != "" {
+		response["Secret"] = subscription.Secret
+	}
+	if subscription.TenantID != "" {
+		response["TenantID"] = subscription.TenantID
+	}
+	return response
+}
@@ -0,0 +1,270 @@
+package odata
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookTestReceiver é um endpoint local que registra as entregas e responde com os status informados
+type webhookTestReceiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newWebhookTestReceiver(t *testing.T, statuses ...int) *webhookTestReceiver {
+	receiver := &webhookTestReceiver{statuses: statuses}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+		receiver.requests = append(receiver.requests, r)
+		receiver.bodies = append(receiver.bodies, body)
+
+		status := http.StatusOK
+		if len(receiver.statuses) > 0 {
+			status, receiver.statuses = receiver.statuses[0], receiver.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(receiver.Close)
+	return receiver
+}
+
+func waitWebhooks(t *testing.T, server *Server, manager *WebhookManager) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, server.GetEventManager().WaitAsync(ctx))
+	require.NoError(t, manager.Wait(ctx))
+}
+
+func TestWebhooks_SignedDeliveriesWithFilterAndRetries(t *testing.T) {
+	server, _, _, _ := newLifecycleTestServer(t)
+	manager, err := server.EnableWebhooks(WebhookConfig{
+		Retry:                AsyncEventConfig{MaxRetries: 3, InitialBackoff: time.Millisecond},
+		AllowPrivateNetworks: true,
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	partner := newWebhookTestReceiver(t, http.StatusServiceUnavailable)
+	subscription, err := manager.Subscribe(ctx, WebhookSubscription{
+		EntityName: "Notes",
+		Events:     []EventType{EventEntityInserted, EventEntityModified},
+		URL:        partner.URL,
+		Secret:     "s3cret",
+		Filter:     "text eq 'urgent'",
+		Active:     true,
+	})
+	require.NoError(t, err)
+
+	rejecting := newWebhookTestReceiver(t, http.StatusBadRequest)
+	rejected, err := manager.Subscribe(ctx, WebhookSubscription{
+		EntityName: "Notes",
+		Events:     []EventType{EventEntityDeleted},
+		URL:        rejecting.URL,
+		Active:     true,
+	})
+	require.NoError(t, err)
+	assert.Len(t, rejected.Secret, 64)
+
+	status, _ := sendLifecycleRequest(t, server, "POST", "/odata/Notes", `{"id": "n2", "text": "urgent"}`)
+	require.Equal(t, 201, status)
+	status, _ = sendLifecycleRequest(t, server, "POST", "/odata/Notes", `{"id": "n3", "text": "later"}`)
+	require.Equal(t, 201, status)
+	status, _ = sendLifecycleRequest(t, server, "DELETE", "/odata/Notes('n1')", "")
+	require.Equal(t, 204, status)
+	waitWebhooks(t, server, manager)
+
+	// Só a nota que atende ao filtro é entregue; a falha 503 é repetida com o mesmo corpo assinado
+	require.Len(t, partner.requests, 2)
+	for i, request := range partner.requests {
+		assert.Equal(t, "EntityInserted", request.Header.Get(WebhookEventHeader))
+		assert.True(t, VerifyWebhookSignature("s3cret", request.Header.Get(WebhookTimestampHeader), partner.bodies[i],
+			request.Header.Get(WebhookSignatureHeader)))
+	}
+	assert.Equal(t, partner.bodies[0], partner.bodies[1])
+	assert.False(t, VerifyWebhookSignature("other", partner.requests[1].Header.Get(WebhookTimestampHeader), partner.bodies[1],
+		partner.requests[1].Header.Get(WebhookSignatureHeader)))
+
+	var payload WebhookPayload
+	require.NoError(t, json.Unmarshal(partner.bodies[0], &payload))
+	assert.Equal(t, partner.requests[0].Header.Get(WebhookDeliveryHeader), payload.ID)
+	assert.Equal(t, EventEntityInserted, payload.Event)
+	assert.Equal(t, "Notes", payload.EntitySet)
+	assert.Equal(t, map[string]interface{}{"id": "n2"}, payload.Keys)
+	assert.Equal(t, map[string]interface{}{"id": "n2", "text": "urgent"}, payload.Data)
+
+	deliveries, err := manager.Deliveries(ctx, subscription.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, 2, deliveries[0].Attempt)
+	assert.True(t, deliveries[0].Success)
+	assert.Equal(t, 1, deliveries[1].Attempt)
+	assert.Equal(t, http.StatusServiceUnavailable, deliveries[1].StatusCode)
+	assert.Equal(t, "webhook responded with status 503", deliveries[1].Error)
+	assert.Equal(t, payload.ID, deliveries[1].EventID)
+
+	// Um 4xx não é repetido e a entrega vai direto para o dead-letter
+	require.Len(t, rejecting.requests, 1)
+	deliveries, err = manager.Deliveries(ctx, rejected.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.False(t, deliveries[0].Success)
+	assert.Equal(t, http.StatusBadRequest, deliveries[0].StatusCode)
+	letters := manager.DeadLetters()
+	require.Len(t, letters, 1)
+	assert.Equal(t, rejected.ID, letters[0].Scope)
+	assert.Equal(t, 1, letters[0].Attempts)
+}
+
+func sendWebhookRequest(t *testing.T, server *Server, method, url, body string) (int, map[string]interface{}) {
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := server.GetRouter().Test(req)
+	require.NoError(t, err)
+
+	var response map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&response)
+	return resp.StatusCode, response
+}
+
+func TestWebhooks_ManagementEndpoints(t *testing.T) {
+	server, _, _, _ := newLifecycleTestServer(t)
+	_, err := server.EnableWebhooks(DefaultWebhookConfig())
+	require.NoError(t, err)
+	_, err = server.EnableWebhooks(DefaultWebhookConfig())
+	assert.Error(t, err)
+
+	status, created := sendWebhookRequest(t, server, "POST", "/odata/$webhooks",
+		`{"EntityName": "Notes", "Events": ["EntityInserted"], "URL": "https://partner.example/hooks", "Filter": "text eq 'urgent'"}`)
+	require.Equal(t, 201, status)
+	id, _ := created["ID"].(string)
+	require.NotEmpty(t, id)
+	assert.NotEmpty(t, created["Secret"])
+	assert.Equal(t, true, created["Active"])
+
+	for _, body := range []string{
+		`{"EntityName": "Invoices", "Events": ["EntityInserted"], "URL": "https://partner.example/hooks"}`,
+		`{"EntityName": "Notes", "Events": ["EntityInserting"], "URL": "https://partner.example/hooks"}`,
+		`{"EntityName": "Notes", "Events": ["EntityInserted"], "URL": "ftp://partner.example/hooks"}`,
+		`{"EntityName": "Notes", "Events": ["EntityInserted"], "URL": "https://partner.example/hooks", "Filter": "text eq"}`,
+	} {
+		status, response := sendWebhookRequest(t, server, "POST", "/odata/$webhooks", body)
+		assert.Equal(t, 400, status, body)
+		assert.Equal(t, "InvalidWebhook", response["error"].(map[string]interface{})["code"], body)
+	}
+
+	// O segredo só aparece na resposta da criação
+	status, list := sendWebhookRequest(t, server, "GET", "/odata/$webhooks", "")
+	require.Equal(t, 200, status)
+	value := list["value"].([]interface{})
+	require.Len(t, value, 1)
+	assert.NotContains(t, value[0], "Secret")
+
+	status, updated := sendWebhookRequest(t, server, "PATCH", "/odata/$webhooks('"+id+"')", `{"Active": false}`)
+	require.Equal(t, 200, status)
+	assert.Equal(t, false, updated["Active"])
+	assert.Equal(t, "text eq 'urgent'", updated["Filter"])
+	assert.NotContains(t, updated, "Secret")
+
+	status, deliveries := sendWebhookRequest(t, server, "GET", "/odata/$webhooks('"+id+"')/Deliveries", "")
+	require.Equal(t, 200, status)
+	assert.Empty(t, deliveries["value"])
+
+	status, _ = sendWebhookRequest(t, server, "DELETE", "/odata/$webhooks('"+id+"')", "")
+	require.Equal(t, 204, status)
+	status, _ = sendWebhookRequest(t, server, "GET", "/odata/$webhooks('"+id+"')", "")
+	assert.Equal(t, 404, status)
+}
+
+func TestEventFilterMatcher(t *testing.T) {
+	matcher := newEventFilterMatcher(EntityMetadata{})
+	entity := map[string]interface{}{"Status": "paid", "Total": float64(250)}
+
+	// Mapas são aceitos e números são comparados pelo valor, não pelo texto
+	for filter, expected := range map[string]bool{
+		"Status eq 'paid' and Total gt 100": true,
+		"Total gt 1000":                     false,
+		"Total ge 90":                       true,
+		"Total eq 250":                      true,
+	} {
+		parsed, err := ParseFilterString(context.Background(), filter)
+		require.NoError(t, err)
+		assert.Equal(t, expected, matcher.matches(entity, parsed), filter)
+	}
+	assert.True(t, matcher.matches(nil, nil))
+
+	// O filtro em memória do $expand não muda: só aceita OrderedEntity e compara como texto
+	parsed, err := ParseFilterString(context.Background(), "Total ge 90")
+	require.NoError(t, err)
+	assert.False(t, (&BaseEntityService{}).entityMatchesFilter(entity, parsed, EntityMetadata{}))
+}
+
+func TestWebhooks_PrivateDestinations(t *testing.T) {
+	server, _, _, _ := newLifecycleTestServer(t)
+	manager, err := server.EnableWebhooks(DefaultWebhookConfig())
+	require.NoError(t, err)
+
+	for _, target := range []string{
+		"http://127.0.0.1:8080/hooks",
+		"http://localhost/hooks",
+		"http://api.localhost/hooks",
+		"http://10.0.0.5/hooks",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/hooks",
+		"http://[fe80::1%25eth0]/hooks",
+		"http://0.0.0.0/hooks",
+	} {
+		status, response := sendWebhookRequest(t, server, "POST", "/odata/$webhooks",
+			`{"EntityName": "Notes", "Events": ["EntityInserted"], "URL": "`+target+`"}`)
+		assert.Equal(t, 400, status, target)
+		assert.Equal(t, "InvalidWebhook", response["error"].(map[string]interface{})["code"], target)
+	}
+	assert.Empty(t, manager.Subscriptions())
+
+	// Nomes que resolvem para a rede interna são recusados na conexão, sem novas tentativas
+	receiver := newWebhookTestReceiver(t)
+	_, err = manager.client.Get(receiver.URL)
+	assert.ErrorIs(t, err, errWebhookDestination)
+	assert.Empty(t, receiver.requests)
+
+	err = manager.send(WebhookSubscription{URL: receiver.URL, Secret: "s3cret"}, &WebhookPayload{}, []byte("{}"), &WebhookDelivery{})
+	var permanent *PermanentError
+	assert.ErrorAs(t, err, &permanent)
+}
+
+func TestWebhooks_ManagementRequiresRole(t *testing.T) {
+	server, _ := newJWTTestServer(t, time.Minute)
+	_, err := server.EnableWebhooks(DefaultWebhookConfig())
+	require.NoError(t, err)
+
+	viewer, err := server.jwtService.GenerateToken(&UserIdentity{Username: "ana", Roles: []string{"viewer"}})
+	require.NoError(t, err)
+	admin, err := server.jwtService.GenerateToken(&UserIdentity{Username: "bia", Roles: []string{"admin"}})
+	require.NoError(t, err)
+
+	body := `{"EntityName": "Notes", "Events": ["EntityInserted"], "URL": "https://partner.example/hooks"}`
+	for token, expected := range map[string]int{"": 401, viewer: 403, admin: 201} {
+		req := httptest.NewRequest("POST", "/odata/$webhooks", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := server.GetRouter().Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, expected, resp.StatusCode, token)
+	}
+}
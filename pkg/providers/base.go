@@ -495,10 +495,10 @@ func (p *BaseProvider) buildDateTimeFunctionCondition(expr odata.FilterExpressio
 // BuildSelectClause constrói a cláusula SELECT baseada no select OData
 func (p *BaseProvider) BuildSelectClause(selectFields []string, metadata odata.EntityMetadata) (string, error) {
 	if len(selectFields) == 0 {
-		// Seleciona todos os campos não-navegação
+		// Seleciona todos os campos não-navegação; Lazy só vem quando citado no $select
 		var columns []string
 		for _, prop := range metadata.Properties {
-			if !prop.IsNavigation {
+			if !prop.IsNavigation && !prop.HasPropFlag(odata.PropLazy) {
 				columnName := prop.ColumnName
 				if columnName == "" {
 					columnName = prop.Name
@@ -639,3 +639,36 @@ func (p *BaseProvider) ConvertValue(value interface{}, targetType string) (inter
 		return value, nil
 	}
 }
+
+// queryReturningRow executa uma instrução que devolve linhas (RETURNING) e retorna a primeira como coluna → valor.
+// Retorna nil quando nenhuma linha foi afetada
+func queryReturningRow(ctx context.Context, exec odata.SQLExecutor, query string, args []interface{}) (map[string]interface{}, error) {
+	rows, err := exec.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+	if err := rows.Scan(valuePtrs...); err != nil {
+		return nil, err
+	}
+
+	row := make(map[string]interface{}, len(columns))
+	for i, column := range columns {
+		row[column] = values[i]
+	}
+	return row, rows.Err()
+}
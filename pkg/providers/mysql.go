@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"reflect"
 	"strings"
 	"time"
 
@@ -197,8 +198,8 @@ func (p *MySQLProvider) BuildInsertQuery(entity odata.EntityMetadata, data map[s
 			continue // Ignora propriedades não encontradas
 		}
 
-		if prop.IsNavigation {
-			continue // Ignora propriedades de navegação
+		if !prop.IsInsertable() {
+			continue // Ignora propriedades de navegação e NoInsert
 		}
 
 		columnName := prop.ColumnName
@@ -255,8 +256,12 @@ func (p *MySQLProvider) BuildUpdateQuery(entity odata.EntityMetadata, data map[s
 			continue // Ignora propriedades não encontradas
 		}
 
-		if prop.IsNavigation || prop.IsKey {
-			continue // Ignora propriedades de navegação e chaves
+		if !prop.IsUpdatable() {
+			continue // Ignora propriedades de navegação, chaves e NoUpdate
+		}
+
+		if versionProp := entity.VersionProperty(); versionProp != nil && versionProp.Name == prop.Name {
+			continue // A coluna de versão é incrementada abaixo, nunca recebida do cliente
 		}
 
 		columnName := prop.ColumnName
@@ -275,6 +280,15 @@ func (p *MySQLProvider) BuildUpdateQuery(entity odata.EntityMetadata, data map[s
 		args = append(args, convertedValue)
 	}
 
+	// Incrementa a coluna de versão usada como ETag
+	if versionProp := entity.VersionProperty(); versionProp != nil {
+		versionColumn := versionProp.ColumnName
+		if versionColumn == "" {
+			versionColumn = versionProp.Name
+		}
+		setClauses = append(setClauses, fmt.Sprintf("%s = %s + 1", versionColumn, versionColumn))
+	}
+
 	if len(setClauses) == 0 {
 		return "", nil, fmt.Errorf("no valid columns found for update")
 	}
@@ -300,6 +314,12 @@ func (p *MySQLProvider) BuildUpdateQuery(entity odata.EntityMetadata, data map[s
 			columnName = prop.Name
 		}
 
+		// Tokens de concorrência podem ser nulos e exigem IS NULL
+		if value == nil {
+			whereClauses = append(whereClauses, fmt.Sprintf("%s IS NULL", columnName))
+			continue
+		}
+
 		whereClauses = append(whereClauses, fmt.Sprintf("%s = ?", columnName))
 
 		// Converte o valor para o tipo apropriado
@@ -353,6 +373,12 @@ func (p *MySQLProvider) BuildDeleteQuery(entity odata.EntityMetadata, keyValues
 			columnName = prop.Name
 		}
 
+		// Tokens de concorrência podem ser nulos e exigem IS NULL
+		if value == nil {
+			whereClauses = append(whereClauses, fmt.Sprintf("%s IS NULL", columnName))
+			continue
+		}
+
 		whereClauses = append(whereClauses, fmt.Sprintf("%s = ?", columnName))
 
 		// Converte o valor para o tipo apropriado
@@ -403,3 +429,123 @@ func (p *MySQLProvider) MapGoTypeToSQL(goType string) string {
 func (p *MySQLProvider) FormatDateTime(t time.Time) string {
 	return t.Format("2006-01-02 15:04:05")
 }
+
+// InsertReturning executa o INSERT e relê a linha pela chave (informada ou obtida do LastInsertId),
+// já que o MySQL não possui RETURNING
+func (p *MySQLProvider) InsertReturning(ctx context.Context, exec odata.SQLExecutor, entity odata.EntityMetadata, data map[string]interface{}) (map[string]interface{}, error) {
+	query, args, err := p.BuildInsertQuery(entity, data)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := exec.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected == 0 {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{})
+	for _, prop := range entity.Properties {
+		if !prop.IsKey {
+			continue
+		}
+		// Chaves informadas, inclusive 0 e "", são usadas como gravadas. O 0 em uma coluna AUTO_INCREMENT
+		// gera a chave, que então vem do LastInsertId
+		if value, sent := data[prop.Name]; sent && value != nil {
+			keys[prop.Name] = value
+			switch prop.Type {
+			case "int", "int32", "int64":
+				if lastID, err := result.LastInsertId(); err == nil && lastID != 0 && reflect.ValueOf(value).IsZero() {
+					keys[prop.Name] = lastID
+				}
+			}
+			continue
+		}
+		lastID, err := result.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get last insert id: %w", err)
+		}
+		keys[prop.Name] = lastID
+	}
+
+	return p.selectReturningRow(ctx, exec, entity, keys)
+}
+
+// UpdateReturning executa o UPDATE e relê a linha pela chave na mesma conexão ou transação
+func (p *MySQLProvider) UpdateReturning(ctx context.Context, exec odata.SQLExecutor, entity odata.EntityMetadata, data map[string]interface{}, keyValues map[string]interface{}) (map[string]interface{}, error) {
+	query, args, err := p.BuildUpdateQuery(entity, data, keyValues)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := exec.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	// O MySQL não conta linhas cujos valores não mudaram: a linha só existe sem alterações
+	// se ainda atender a todas as condições do WHERE
+	if rowsAffected == 0 {
+		return p.selectReturningRow(ctx, exec, entity, keyValues)
+	}
+
+	keys := make(map[string]interface{})
+	for _, prop := range entity.Properties {
+		if value, exists := keyValues[prop.Name]; exists && prop.IsKey {
+			keys[prop.Name] = value
+		}
+	}
+	return p.selectReturningRow(ctx, exec, entity, keys)
+}
+
+// selectReturningRow lê a linha que atende às condições informadas (propriedade → valor)
+func (p *MySQLProvider) selectReturningRow(ctx context.Context, exec odata.SQLExecutor, entity odata.EntityMetadata, conditions map[string]interface{}) (map[string]interface{}, error) {
+	tableName := entity.TableName
+	if tableName == "" {
+		tableName = entity.Name
+	}
+
+	selectClause, err := p.BuildSelectClause(nil, entity)
+	if err != nil {
+		return nil, err
+	}
+
+	var whereClauses []string
+	var args []interface{}
+	for _, prop := range entity.Properties {
+		value, exists := conditions[prop.Name]
+		if !exists {
+			continue
+		}
+
+		columnName := prop.ColumnName
+		if columnName == "" {
+			columnName = prop.Name
+		}
+
+		if value == nil {
+			whereClauses = append(whereClauses, fmt.Sprintf("%s IS NULL", columnName))
+			continue
+		}
+		whereClauses = append(whereClauses, fmt.Sprintf("%s = ?", columnName))
+
+		convertedValue, err := p.ConvertValue(value, prop.Type)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, convertedValue)
+	}
+
+	if len(whereClauses) == 0 {
+		return nil, fmt.Errorf("no valid keys found to read the written row")
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s", selectClause, tableName, strings.Join(whereClauses, " AND "))
+	return queryReturningRow(ctx, exec, query, args)
+}
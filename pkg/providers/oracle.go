@@ -315,8 +315,8 @@ func (p *OracleProvider) BuildInsertQuery(entity odata.EntityMetadata, data map[
 			continue // Ignora propriedades não encontradas
 		}
 
-		if prop.IsNavigation {
-			continue // Ignora propriedades de navegação
+		if !prop.IsInsertable() {
+			continue // Ignora propriedades de navegação e NoInsert
 		}
 
 		columnName := prop.ColumnName
@@ -377,8 +377,12 @@ func (p *OracleProvider) BuildUpdateQuery(entity odata.EntityMetadata, data map[
 			continue // Ignora propriedades não encontradas
 		}
 
-		if prop.IsNavigation || prop.IsKey {
-			continue // Ignora propriedades de navegação e chaves
+		if !prop.IsUpdatable() {
+			continue // Ignora propriedades de navegação, chaves e NoUpdate
+		}
+
+		if versionProp := entity.VersionProperty(); versionProp != nil && versionProp.Name == prop.Name {
+			continue // A coluna de versão é incrementada abaixo, nunca recebida do cliente
 		}
 
 		columnName := prop.ColumnName
@@ -396,6 +400,15 @@ func (p *OracleProvider) BuildUpdateQuery(entity odata.EntityMetadata, data map[
 		setClauses = append(setClauses, fmt.Sprintf("%s = %s", columnName, placeholder))
 	}
 
+	// Incrementa a coluna de versão usada como ETag
+	if versionProp := entity.VersionProperty(); versionProp != nil {
+		versionColumn := versionProp.ColumnName
+		if versionColumn == "" {
+			versionColumn = versionProp.Name
+		}
+		setClauses = append(setClauses, fmt.Sprintf("%s = %s + 1", versionColumn, versionColumn))
+	}
+
 	if len(setClauses) == 0 {
 		return "", nil, fmt.Errorf("no valid columns found for update")
 	}
@@ -421,6 +434,12 @@ func (p *OracleProvider) BuildUpdateQuery(entity odata.EntityMetadata, data map[
 			columnName = prop.Name
 		}
 
+		// Tokens de concorrência podem ser nulos e exigem IS NULL
+		if value == nil {
+			whereClauses = append(whereClauses, fmt.Sprintf("%s IS NULL", columnName))
+			continue
+		}
+
 		// Converte o valor para o tipo apropriado
 		convertedValue, err := p.ConvertValue(value, prop.Type)
 		if err != nil {
@@ -474,6 +493,12 @@ func (p *OracleProvider) BuildDeleteQuery(entity odata.EntityMetadata, keyValues
 			columnName = prop.Name
 		}
 
+		// Tokens de concorrência podem ser nulos e exigem IS NULL
+		if value == nil {
+			whereClauses = append(whereClauses, fmt.Sprintf("%s IS NULL", columnName))
+			continue
+		}
+
 		// Converte o valor para o tipo apropriado
 		convertedValue, err := p.ConvertValue(value, prop.Type)
 		if err != nil {
@@ -813,3 +838,107 @@ func (p *OracleProvider) buildOracleDateTimeFunctionCondition(expr odata.FilterE
 		return "", nil, fmt.Errorf("unsupported datetime function: %s", expr.Operator)
 	}
 }
+
+// InsertReturning executa o INSERT ... RETURNING ... INTO e devolve a linha inserida pelos binds de saída
+func (p *OracleProvider) InsertReturning(ctx context.Context, exec odata.SQLExecutor, entity odata.EntityMetadata, data map[string]interface{}) (map[string]interface{}, error) {
+	query, args, err := p.BuildInsertQuery(entity, data)
+	if err != nil {
+		return nil, err
+	}
+	return p.execReturning(ctx, exec, entity, query, args)
+}
+
+// UpdateReturning executa o UPDATE ... RETURNING ... INTO e devolve a linha atualizada pelos binds de saída
+func (p *OracleProvider) UpdateReturning(ctx context.Context, exec odata.SQLExecutor, entity odata.EntityMetadata, data map[string]interface{}, keyValues map[string]interface{}) (map[string]interface{}, error) {
+	query, args, err := p.BuildUpdateQuery(entity, data, keyValues)
+	if err != nil {
+		return nil, err
+	}
+	return p.execReturning(ctx, exec, entity, query, args)
+}
+
+// execReturning acrescenta a cláusula RETURNING ... INTO com um bind de saída por coluna e executa o comando.
+// As colunas devolvidas são as do SELECT padrão: ficam de fora apenas navegações e Lazy
+func (p *OracleProvider) execReturning(ctx context.Context, exec odata.SQLExecutor, entity odata.EntityMetadata, query string, args []interface{}) (map[string]interface{}, error) {
+	// Os builders devolvem os argumentos nomeados agrupados em um único slice
+	var namedArgs []interface{}
+	for _, arg := range args {
+		if group, ok := arg.([]interface{}); ok {
+			namedArgs = append(namedArgs, group...)
+		} else {
+			namedArgs = append(namedArgs, arg)
+		}
+	}
+
+	var columns, binds []string
+	dests := make(map[string]interface{})
+	for _, prop := range entity.Properties {
+		if prop.IsNavigation || prop.HasPropFlag(odata.PropLazy) {
+			continue
+		}
+
+		columnName := prop.ColumnName
+		if columnName == "" {
+			columnName = prop.Name
+		}
+
+		var dest interface{}
+		switch prop.Type {
+		case "int32", "int64", "bool":
+			dest = &sql.NullInt64{}
+		case "float32", "float64":
+			dest = &sql.NullFloat64{}
+		case "time.Time":
+			dest = &sql.NullTime{}
+		case "[]byte":
+			dest = &[]byte{}
+		default:
+			dest = &sql.NullString{}
+		}
+
+		bind := fmt.Sprintf("ret%d", len(binds)+1)
+		columns = append(columns, columnName)
+		binds = append(binds, ":"+bind)
+		dests[columnName] = dest
+		namedArgs = append(namedArgs, sql.Named(bind, sql.Out{Dest: dest}))
+	}
+
+	query = fmt.Sprintf("%s RETURNING %s INTO %s", query, strings.Join(columns, ", "), strings.Join(binds, ", "))
+
+	result, err := exec.ExecContext(ctx, query, namedArgs...)
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected == 0 {
+		return nil, err
+	}
+
+	row := make(map[string]interface{}, len(dests))
+	for column, dest := range dests {
+		switch v := dest.(type) {
+		case *sql.NullInt64:
+			row[column] = nil
+			if v.Valid {
+				row[column] = v.Int64
+			}
+		case *sql.NullFloat64:
+			row[column] = nil
+			if v.Valid {
+				row[column] = v.Float64
+			}
+		case *sql.NullTime:
+			row[column] = nil
+			if v.Valid {
+				row[column] = v.Time
+			}
+		case *sql.NullString:
+			row[column] = nil
+			if v.Valid {
+				row[column] = v.String
+			}
+		case *[]byte:
+			row[column] = *v
+		}
+	}
+	return row, nil
+}
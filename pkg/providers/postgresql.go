@@ -198,8 +198,8 @@ func (p *PostgreSQLProvider) BuildInsertQuery(entity odata.EntityMetadata, data
 			continue // Ignora propriedades não encontradas
 		}
 
-		if prop.IsNavigation {
-			continue // Ignora propriedades de navegação
+		if !prop.IsInsertable() {
+			continue // Ignora propriedades de navegação e NoInsert
 		}
 
 		columnName := prop.ColumnName
@@ -224,10 +224,17 @@ func (p *PostgreSQLProvider) BuildInsertQuery(entity odata.EntityMetadata, data
 		return "", nil, fmt.Errorf("no valid columns found for insert")
 	}
 
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING *",
+	// As colunas devolvidas são as do SELECT padrão (sem navegações e Lazy), como nos demais providers
+	returning, err := p.BuildSelectClause(nil, entity)
+	if err != nil {
+		return "", nil, err
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING %s",
 		tableName,
 		strings.Join(columns, ", "),
-		strings.Join(placeholders, ", "))
+		strings.Join(placeholders, ", "),
+		returning)
 
 	return query, args, nil
 }
@@ -258,8 +265,12 @@ func (p *PostgreSQLProvider) BuildUpdateQuery(entity odata.EntityMetadata, data
 			continue // Ignora propriedades não encontradas
 		}
 
-		if prop.IsNavigation || prop.IsKey {
-			continue // Ignora propriedades de navegação e chaves
+		if !prop.IsUpdatable() {
+			continue // Ignora propriedades de navegação, chaves e NoUpdate
+		}
+
+		if versionProp := entity.VersionProperty(); versionProp != nil && versionProp.Name == prop.Name {
+			continue // A coluna de versão é incrementada abaixo, nunca recebida do cliente
 		}
 
 		columnName := prop.ColumnName
@@ -279,6 +290,15 @@ func (p *PostgreSQLProvider) BuildUpdateQuery(entity odata.EntityMetadata, data
 		argIndex++
 	}
 
+	// Incrementa a coluna de versão usada como ETag
+	if versionProp := entity.VersionProperty(); versionProp != nil {
+		versionColumn := versionProp.ColumnName
+		if versionColumn == "" {
+			versionColumn = versionProp.Name
+		}
+		setClauses = append(setClauses, fmt.Sprintf("%s = %s + 1", versionColumn, versionColumn))
+	}
+
 	if len(setClauses) == 0 {
 		return "", nil, fmt.Errorf("no valid columns found for update")
 	}
@@ -304,6 +324,12 @@ func (p *PostgreSQLProvider) BuildUpdateQuery(entity odata.EntityMetadata, data
 			columnName = prop.Name
 		}
 
+		// Tokens de concorrência podem ser nulos e exigem IS NULL
+		if value == nil {
+			whereClauses = append(whereClauses, fmt.Sprintf("%s IS NULL", columnName))
+			continue
+		}
+
 		whereClauses = append(whereClauses, fmt.Sprintf("%s = $%d", columnName, argIndex))
 
 		// Converte o valor para o tipo apropriado
@@ -320,10 +346,16 @@ func (p *PostgreSQLProvider) BuildUpdateQuery(entity odata.EntityMetadata, data
 		return "", nil, fmt.Errorf("no valid keys found for update")
 	}
 
-	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s RETURNING *",
+	returning, err := p.BuildSelectClause(nil, entity)
+	if err != nil {
+		return "", nil, err
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s RETURNING %s",
 		tableName,
 		strings.Join(setClauses, ", "),
-		strings.Join(whereClauses, " AND "))
+		strings.Join(whereClauses, " AND "),
+		returning)
 
 	return query, args, nil
 }
@@ -359,6 +391,12 @@ func (p *PostgreSQLProvider) BuildDeleteQuery(entity odata.EntityMetadata, keyVa
 			columnName = prop.Name
 		}
 
+		// Tokens de concorrência podem ser nulos e exigem IS NULL
+		if value == nil {
+			whereClauses = append(whereClauses, fmt.Sprintf("%s IS NULL", columnName))
+			continue
+		}
+
 		whereClauses = append(whereClauses, fmt.Sprintf("%s = $%d", columnName, argIndex))
 
 		// Converte o valor para o tipo apropriado
@@ -441,3 +479,21 @@ func (p *PostgreSQLProvider) buildCondition(expr odata.FilterExpression, prop od
 		return "", nil, fmt.Errorf("unsupported operator: %s", expr.Operator)
 	}
 }
+
+// InsertReturning executa o INSERT ... RETURNING e devolve a linha inserida
+func (p *PostgreSQLProvider) InsertReturning(ctx context.Context, exec odata.SQLExecutor, entity odata.EntityMetadata, data map[string]interface{}) (map[string]interface{}, error) {
+	query, args, err := p.BuildInsertQuery(entity, data)
+	if err != nil {
+		return nil, err
+	}
+	return queryReturningRow(ctx, exec, query, args)
+}
+
+// UpdateReturning executa o UPDATE ... RETURNING e devolve a linha atualizada
+func (p *PostgreSQLProvider) UpdateReturning(ctx context.Context, exec odata.SQLExecutor, entity odata.EntityMetadata, data map[string]interface{}, keyValues map[string]interface{}) (map[string]interface{}, error) {
+	query, args, err := p.BuildUpdateQuery(entity, data, keyValues)
+	if err != nil {
+		return nil, err
+	}
+	return queryReturningRow(ctx, exec, query, args)
+}